@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// abbreviationFileName is the file, in the history directory, holding the
+// abbreviations declared at the interactive prompt.
+const abbreviationFileName = "msh_abbr.json"
+
+// AbbreviationTable holds the fish-style abbreviations the interactive CLI
+// expands in command position. Abbreviations declared by init.msh last for
+// the session; once the prompt is running, 'abbr' and 'abbrRemove' are also
+// saved to the persisted file so they survive into later sessions.
+type AbbreviationTable struct {
+	items       map[string]string
+	persistPath string
+}
+
+func NewAbbreviationTable() *AbbreviationTable {
+	return &AbbreviationTable{items: make(map[string]string)}
+}
+
+func (table *AbbreviationTable) Lookup(name string) (string, bool) {
+	expansion, ok := table.items[name]
+	return expansion, ok
+}
+
+// Names returns the abbreviation names in sorted order.
+func (table *AbbreviationTable) Names() []string {
+	names := make([]string, 0, len(table.items))
+	for name := range table.items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (table *AbbreviationTable) Set(name, expansion string) error {
+	table.items[name] = expansion
+	if table.persistPath == "" {
+		return nil
+	}
+	return table.updatePersisted(func(saved map[string]string) {
+		saved[name] = expansion
+	})
+}
+
+// Remove deletes an abbreviation, returning false if it was not defined.
+func (table *AbbreviationTable) Remove(name string) (bool, error) {
+	_, ok := table.items[name]
+	delete(table.items, name)
+	if table.persistPath == "" {
+		return ok, nil
+	}
+	return ok, table.updatePersisted(func(saved map[string]string) {
+		delete(saved, name)
+	})
+}
+
+// LoadPersisted adds the saved abbreviations from path to the table. A
+// missing file is not an error.
+func (table *AbbreviationTable) LoadPersisted(path string) error {
+	saved, err := readAbbreviationFile(path)
+	if err != nil {
+		return err
+	}
+	for name, expansion := range saved {
+		table.items[name] = expansion
+	}
+	return nil
+}
+
+// Persist makes later changes to the table save to path.
+func (table *AbbreviationTable) Persist(path string) {
+	table.persistPath = path
+}
+
+func (table *AbbreviationTable) updatePersisted(update func(map[string]string)) error {
+	saved, err := readAbbreviationFile(table.persistPath)
+	if err != nil {
+		return err
+	}
+	update(saved)
+	return writeAbbreviationFile(table.persistPath, saved)
+}
+
+func readAbbreviationFile(path string) (map[string]string, error) {
+	saved := make(map[string]string)
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return saved, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(contents, &saved); err != nil {
+		return nil, fmt.Errorf("error parsing abbreviation file %s: %w", path, err)
+	}
+	return saved, nil
+}
+
+func writeAbbreviationFile(path string, saved map[string]string) error {
+	contents, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	contents = append(contents, '\n')
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), abbreviationFileName+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// abbreviationPath returns the persisted abbreviation file location.
+func abbreviationPath() (string, error) {
+	dir, err := GetHistoryDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, abbreviationFileName), nil
+}
+
+// abbreviationTable returns the state's abbreviation table, creating it on
+// first use so init.msh can declare abbreviations in any kind of session.
+func (state *EvalState) abbreviationTable() *AbbreviationTable {
+	if state.Abbreviations == nil {
+		state.Abbreviations = NewAbbreviationTable()
+	}
+	return state.Abbreviations
+}
+
+// evaluateAbbr implements `'gco' 'git checkout' abbr`.
+func (state *EvalState) evaluateAbbr(t Token, stack *MShellStack) EvalResult {
+	obj1, obj2, err := stack.Pop2(t)
+	if err != nil {
+		return state.FailWithMessage(err.Error())
+	}
+
+	expansion, err := obj1.CastString()
+	if err != nil {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: Expected a string expansion for 'abbr', received a %s.\n", t.Line, t.Column, obj1.TypeName()))
+	}
+	name, err := obj2.CastString()
+	if err != nil {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: Expected a string name for 'abbr', received a %s.\n", t.Line, t.Column, obj2.TypeName()))
+	}
+	if !isAbbreviationName(name) {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: Invalid abbreviation name '%s'. Names must be a single word without whitespace, quotes, or brackets.\n", t.Line, t.Column, name))
+	}
+
+	if err := state.abbreviationTable().Set(name, expansion); err != nil {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: Error saving abbreviation '%s': %s\n", t.Line, t.Column, name, err))
+	}
+	return SimpleSuccess()
+}
+
+// evaluateAbbrRemove implements `'gco' abbrRemove`.
+func (state *EvalState) evaluateAbbrRemove(t Token, stack *MShellStack) EvalResult {
+	obj, err := stack.Pop1(t)
+	if err != nil {
+		return state.FailWithMessage(err.Error())
+	}
+	name, err := obj.CastString()
+	if err != nil {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: Expected a string name for 'abbrRemove', received a %s.\n", t.Line, t.Column, obj.TypeName()))
+	}
+
+	removed, err := state.abbreviationTable().Remove(name)
+	if err != nil {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: Error saving abbreviations: %s\n", t.Line, t.Column, err))
+	}
+	if !removed {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: No abbreviation named '%s'.\n", t.Line, t.Column, name))
+	}
+	return SimpleSuccess()
+}
+
+// evaluateAbbrs implements 'abbrs', pushing a dictionary of name to expansion.
+func (state *EvalState) evaluateAbbrs(stack *MShellStack) EvalResult {
+	table := state.abbreviationTable()
+	dict := NewDict()
+	for _, name := range table.Names() {
+		expansion, _ := table.Lookup(name)
+		dict.Items[name] = MShellString{expansion}
+	}
+	stack.Push(dict)
+	return SimpleSuccess()
+}
+
+// isAbbreviationName reports whether name lexes as a single bare literal, the
+// only kind of word the CLI expands.
+func isAbbreviationName(name string) bool {
+	if name == "" {
+		return false
+	}
+	tokens, err := NewLexer(name, nil).Tokenize()
+	return err == nil && len(tokens) == 2 && tokens[0].Type == LITERAL && tokens[0].Lexeme == name
+}
+
+// abbreviationWordStart finds the word that ends at the cursor and returns
+// the index it starts at, if it is an abbreviation in command position: the
+// first word of the line, or the first word after '[' or '|'. Arguments are
+// never expanded, so 'git gco' keeps its 'gco'.
+func (s *TermState) abbreviationWordStart() (int, string, bool) {
+	table := s.evalState.Abbreviations
+	if table == nil || s.index == 0 {
+		return 0, "", false
+	}
+
+	l := NewLexer(string(s.currentCommand[:s.index]), nil)
+	l.allowUnterminatedString = true
+	l.emitWhitespace = true
+	l.emitComments = true
+	tokens, err := l.Tokenize()
+	if err != nil || len(tokens) < 2 {
+		return 0, "", false
+	}
+
+	// The last token before EOF is the word under the cursor.
+	last := len(tokens) - 2
+	word := tokens[last]
+	if word.Type != LITERAL || word.Start+len([]rune(word.Lexeme)) != s.index {
+		return 0, "", false
+	}
+
+	for i := last - 1; i >= 0; i-- {
+		t := tokens[i]
+		if t.Type == WHITESPACE || t.Type == LINECOMMENT {
+			continue
+		}
+		if t.Type != LEFT_SQUARE_BRACKET && t.Type != PIPE {
+			return 0, "", false
+		}
+		break
+	}
+
+	expansion, ok := table.Lookup(word.Lexeme)
+	if !ok {
+		return 0, "", false
+	}
+	return word.Start, expansion, true
+}
+
+// expandAbbreviation replaces an abbreviation ending at the cursor with its
+// expansion, followed by a space when addSpace is set (the space key).
+func (s *TermState) expandAbbreviation(addSpace bool) bool {
+	start, expansion, ok := s.abbreviationWordStart()
+	if !ok {
+		return false
+	}
+
+	replacement := []rune(expansion)
+	if addSpace {
+		replacement = append(replacement, ' ')
+	}
+
+	endText := append([]rune(nil), s.currentCommand[s.index:]...)
+	s.currentCommand = append(s.currentCommand[:start], replacement...)
+	s.currentCommand = append(s.currentCommand, endText...)
+	s.index = start + len(replacement)
+
+	s.Logf("Abbreviation expanded: %s\n", expansion)
+	return true
+}
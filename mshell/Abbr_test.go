@@ -0,0 +1,84 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandAbbreviationOnlyInCommandPosition(t *testing.T) {
+	table := NewAbbreviationTable()
+	table.Set("gco", "git checkout")
+
+	cases := []struct {
+		input    string
+		addSpace bool
+		want     string
+	}{
+		{"gco", true, "git checkout "},
+		{"gco", false, "git checkout"},
+		{"[gco", true, "[git checkout "},
+		{"cat log.txt | gco", true, "cat log.txt | git checkout "},
+		{"git gco", true, "git gco"},
+		{"'gco'", true, "'gco'"},
+		{"gcox", true, "gcox"},
+	}
+
+	for _, c := range cases {
+		state := &TermState{
+			currentCommand: []rune(c.input),
+			index:          len([]rune(c.input)),
+			evalState:      EvalState{Abbreviations: table},
+		}
+		state.expandAbbreviation(c.addSpace)
+		if got := string(state.currentCommand); got != c.want {
+			t.Errorf("expand %q = %q; want %q", c.input, got, c.want)
+		}
+		if state.index > len(state.currentCommand) {
+			t.Errorf("expand %q left the cursor past the end", c.input)
+		}
+	}
+}
+
+func TestAbbreviationsPersistOnlyAfterPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), abbreviationFileName)
+
+	table := NewAbbreviationTable()
+	if err := table.Set("fromInit", "echo init"); err != nil {
+		t.Fatal(err)
+	}
+	table.Persist(path)
+	if err := table.Set("gco", "git checkout"); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Set("gs", "git status"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := table.Remove("gs"); err != nil {
+		t.Fatal(err)
+	}
+
+	next := NewAbbreviationTable()
+	if err := next.LoadPersisted(path); err != nil {
+		t.Fatal(err)
+	}
+	names := next.Names()
+	if len(names) != 1 || names[0] != "gco" {
+		t.Fatalf("persisted names = %v; want [gco]", names)
+	}
+	if expansion, _ := next.Lookup("gco"); expansion != "git checkout" {
+		t.Fatalf("gco expands to %q", expansion)
+	}
+}
+
+func TestIsAbbreviationName(t *testing.T) {
+	for _, name := range []string{"gco", "g-co", "dc"} {
+		if !isAbbreviationName(name) {
+			t.Errorf("%q should be a valid abbreviation name", name)
+		}
+	}
+	for _, name := range []string{"", "g c", "[g", "'g'"} {
+		if isAbbreviationName(name) {
+			t.Errorf("%q should not be a valid abbreviation name", name)
+		}
+	}
+}
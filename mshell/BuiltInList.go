@@ -7,50 +7,68 @@ var BuiltInList = map[string]struct{}{
 	"defs": {},
 	"env": {},
 	"stack": {},
+	"abbr": {},
+	"abbrRemove": {},
+	"abbrs": {},
 	"abs": {},
+	"antiJoin": {},
 	"absPath": {},
 	"addDays": {},
 	"append": {},
 	"appendFile": {},
 	"args": {},
 	"arctan": {},
+	"assertEq": {},
+	"assertFails": {},
+	"assertTrue": {},
 	"base64decode": {},
 	"base64encode": {},
 	"basename": {},
 	"binPaths": {},
+	"bg": {},
 	"bind": {},
 	"cd": {},
 	"cdh": {},
 	"cdp": {},
 	"clip": {},
+	"collect": {},
 	"completionDefs": {},
+	"corr": {},
 	"ceil": {},
 	"countSubStr": {},
 	"cp": {},
+	"cssSelect": {},
 	"cstToUtc": {},
 	"date": {},
 	"dateFmt": {},
+	"dateTrunc": {},
 	"day": {},
 	"dbg": {},
 	"del": {},
+	"describe": {},
 	"derive": {},
 	"dirname": {},
 	"dirs": {},
+	"distinct": {},
 	"dow": {},
 	"drop": {},
+	"dropNull": {},
 	"dup": {},
 	"each": {},
 	"e": {},
 	"ec": {},
 	"endsWith": {},
 	"es": {},
+	"except": {},
 	"exit": {},
 	"exclude": {},
 	"ext": {},
 	"extend": {},
+	"fg": {},
 	"fileExists": {},
 	"fileSize": {},
 	"files": {},
+	"fillNull": {},
 	"filter": {},
 	"findReplace": {},
 	"floatCmp": {},
@@ -72,13 +90,16 @@ var BuiltInList = map[string]struct{}{
 	"gridCols": {},
 	"gridCompact": {},
 	"gridMeta": {},
+	"gridPager": {},
 	"gridRemoveCol": {},
 	"gridRenameCol": {},
 	"gridRows": {},
 	"gridSetCell": {},
+	"gridSort": {},
 	"gridValues": {},
 	"groupBy": {},
 	"hardLink": {},
+	"histogram": {},
 	"hostname": {},
 	"hour": {},
 	"httpGet": {},
@@ -87,13 +108,17 @@ var BuiltInList = map[string]struct{}{
 	"inc": {},
 	"index": {},
 	"insert": {},
+	"intersect": {},
 	"isCmd": {},
 	"isDir": {},
 	"isFile": {},
 	"isNone": {},
+	"isNullCol": {},
 	"isWeekday": {},
 	"isWeekend": {},
+	"jobs": {},
 	"join": {},
+	"jsonPath": {},
 	"just": {},
 	"keyValues": {},
 	"keys": {},
@@ -126,21 +151,28 @@ var BuiltInList = map[string]struct{}{
 	"now": {},
 	"nth": {},
 	"null": {},
+	"nullCount": {},
 	"nullDevice": {},
 	"numFmt": {},
 	"outerJoin": {},
 	"over": {},
+	"parallel": {},
 	"parseCsv": {},
 	"parseExcel": {},
 	"parseHtml": {},
 	"parseJson": {},
+	"parseJsonLines": {},
 	"parseLinkHeader": {},
+	"parseToml": {},
+	"parseXml": {},
+	"parseYaml": {},
 	"pivot": {},
 	"pop": {},
 	"pow": {},
 	"prompt": {},
 	"psub": {},
 	"pwd": {},
+	"quantile": {},
 	"random": {},
 	"randomFixed": {},
 	"reFindAll": {},
@@ -148,9 +180,13 @@ var BuiltInList = map[string]struct{}{
 	"reMatch": {},
 	"reReplace": {},
 	"reSplit": {},
+	"readCsvGrid": {},
+	"readExcelGrid": {},
 	"readFile": {},
 	"readFileBytes": {},
+	"readJsonLinesGrid": {},
 	"removeWindowsVolumePrefix": {},
+	"resample": {},
 	"return": {},
 	"reverse": {},
 	"rm": {},
@@ -158,7 +194,9 @@ var BuiltInList = map[string]struct{}{
 	"rot": {},
 	"round": {},
 	"runtime": {},
+	"scanCsv": {},
 	"select": {},
+	"semiJoin": {},
 	"set": {},
 	"setAt": {},
 	"setd": {},
@@ -189,6 +227,8 @@ var BuiltInList = map[string]struct{}{
 	"tempFileExt": {},
 	"title": {},
 	"toBase": {},
+	"toCsv": {},
+	"toExcel": {},
 	"toDict": {},
 	"toDt": {},
 	"toFixed": {},
@@ -196,16 +236,22 @@ var BuiltInList = map[string]struct{}{
 	"toGrid": {},
 	"toInt": {},
 	"toJson": {},
+	"toJsonLines": {},
+	"toMarkdownTable": {},
 	"toOleDate": {},
 	"toPath": {},
+	"toToml": {},
+	"toTsv": {},
 	"toUnixTime": {},
 	"toUnixTimeMicro": {},
 	"toUnixTimeMilli": {},
 	"toUnixTimeNano": {},
+	"toYaml": {},
 	"trim": {},
 	"trimEnd": {},
 	"trimStart": {},
 	"typeof": {},
+	"unpivot": {},
 	"updateCol": {},
 	"uniq": {},
 	"unsetenv": {},
@@ -222,8 +268,10 @@ var BuiltInList = map[string]struct{}{
 	"we": {},
 	"wl": {},
 	"wle": {},
+	"window": {},
 	"writeFile": {},
 	"wsplit": {},
+	"xpath": {},
 	"year": {},
 	"tarDirExc": {},
 	"tarDirInc": {},
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CSS selector subset for cssSelect: type, universal, id, class, and
+// attribute selectors; the descendant, child, and sibling combinators;
+// selector lists; and the structural pseudo-classes below. A namespace
+// prefix is written 'svg|rect' and matches the tag 'svg:rect'.
+
+type cssSelector struct {
+	compounds []cssCompound
+	// combinators[i] joins compounds[i-1] and compounds[i]: ' ', '>', '+'
+	// or '~'. combinators[0] is unused.
+	combinators []byte
+}
+
+type cssCompound struct {
+	tag     string // "" or "*" matches any element
+	ids     []string
+	classes []string
+	attrs   []cssAttrTest
+	pseudos []cssPseudo
+}
+
+type cssAttrTest struct {
+	name  string
+	op    string // "" for presence, or "=", "~=", "|=", "^=", "$=", "*="
+	value string
+}
+
+type cssPseudo struct {
+	name string
+	a, b int           // for the nth- pseudo-classes
+	not  []cssSelector // for :not
+}
+
+type cssParser struct {
+	src string
+	pos int
+}
+
+// compileCssSelector parses a comma-separated selector list.
+func compileCssSelector(src string) ([]cssSelector, error) {
+	p := &cssParser{src: src}
+	selectors, err := p.parseSelectorList()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected '%c'", p.src[p.pos])
+	}
+	return selectors, nil
+}
+
+func (p *cssParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid selector '%s': %s", p.src, fmt.Sprintf(format, args...))
+}
+
+func (p *cssParser) peek() byte {
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *cssParser) skipSpace() bool {
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte(" \t\n\r\f", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *cssParser) parseSelectorList() ([]cssSelector, error) {
+	var selectors []cssSelector
+	for {
+		p.skipSpace()
+		selector, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+		p.skipSpace()
+		if p.peek() != ',' {
+			return selectors, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *cssParser) parseComplex() (cssSelector, error) {
+	var selector cssSelector
+	combinator := byte(' ')
+	for {
+		compound, err := p.parseCompound()
+		if err != nil {
+			return selector, err
+		}
+		selector.compounds = append(selector.compounds, compound)
+		selector.combinators = append(selector.combinators, combinator)
+
+		spaced := p.skipSpace()
+		switch c := p.peek(); {
+		case c == '>' || c == '+' || c == '~':
+			combinator = c
+			p.pos++
+			p.skipSpace()
+		case spaced && c != 0 && c != ',' && c != ')':
+			combinator = ' '
+		default:
+			return selector, nil
+		}
+	}
+}
+
+func isCssNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '\\' || c >= 0x80
+}
+
+// parseIdent reads an identifier, resolving backslash escapes.
+func (p *cssParser) parseIdent() (string, error) {
+	var sb strings.Builder
+	for p.pos < len(p.src) && isCssNameChar(p.src[p.pos]) {
+		if p.src[p.pos] == '\\' && p.pos+1 < len(p.src) {
+			p.pos++
+		}
+		sb.WriteByte(p.src[p.pos])
+		p.pos++
+	}
+	if sb.Len() == 0 {
+		if p.pos >= len(p.src) {
+			return "", p.errorf("expected a name at the end")
+		}
+		return "", p.errorf("expected a name at '%c'", p.src[p.pos])
+	}
+	return sb.String(), nil
+}
+
+// parseQualifiedName reads a name with an optional 'prefix|' namespace.
+func (p *cssParser) parseQualifiedName() (string, error) {
+	var name string
+	if p.peek() == '*' {
+		p.pos++
+		name = "*"
+	} else {
+		ident, err := p.parseIdent()
+		if err != nil {
+			return "", err
+		}
+		name = ident
+	}
+	if p.peek() == '|' && p.pos+1 < len(p.src) && p.src[p.pos+1] != '=' {
+		p.pos++
+		local, err := p.parseIdent()
+		if err != nil {
+			return "", err
+		}
+		if name == "*" {
+			return local, nil
+		}
+		return name + ":" + local, nil
+	}
+	return name, nil
+}
+
+func (p *cssParser) parseCompound() (cssCompound, error) {
+	var compound cssCompound
+	start := p.pos
+	if c := p.peek(); c == '*' || isCssNameChar(c) {
+		tag, err := p.parseQualifiedName()
+		if err != nil {
+			return compound, err
+		}
+		compound.tag = tag
+	}
+	for {
+		switch p.peek() {
+		case '#':
+			p.pos++
+			id, err := p.parseIdent()
+			if err != nil {
+				return compound, err
+			}
+			compound.ids = append(compound.ids, id)
+		case '.':
+			p.pos++
+			class, err := p.parseIdent()
+			if err != nil {
+				return compound, err
+			}
+			compound.classes = append(compound.classes, class)
+		case '[':
+			test, err := p.parseAttrTest()
+			if err != nil {
+				return compound, err
+			}
+			compound.attrs = append(compound.attrs, test)
+		case ':':
+			pseudo, err := p.parsePseudo()
+			if err != nil {
+				return compound, err
+			}
+			compound.pseudos = append(compound.pseudos, pseudo)
+		default:
+			if p.pos == start {
+				if p.pos >= len(p.src) {
+					return compound, p.errorf("expected a selector at the end")
+				}
+				return compound, p.errorf("expected a selector at '%c'", p.src[p.pos])
+			}
+			return compound, nil
+		}
+	}
+}
+
+func (p *cssParser) parseAttrTest() (cssAttrTest, error) {
+	var test cssAttrTest
+	p.pos++ // '['
+	p.skipSpace()
+	name, err := p.parseQualifiedName()
+	if err != nil {
+		return test, err
+	}
+	test.name = name
+	p.skipSpace()
+	if p.peek() == ']' {
+		p.pos++
+		return test, nil
+	}
+	for _, op := range []string{"=", "~=", "|=", "^=", "$=", "*="} {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			test.op = op
+		}
+	}
+	if test.op == "" {
+		return test, p.errorf("expected an attribute operator or ']'")
+	}
+	p.pos += len(test.op)
+	p.skipSpace()
+	if quote := p.peek(); quote == '"' || quote == '\'' {
+		end := strings.IndexByte(p.src[p.pos+1:], quote)
+		if end < 0 {
+			return test, p.errorf("unterminated string")
+		}
+		test.value = p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+	} else {
+		value, err := p.parseIdent()
+		if err != nil {
+			return test, err
+		}
+		test.value = value
+	}
+	p.skipSpace()
+	if p.peek() != ']' {
+		return test, p.errorf("expected ']'")
+	}
+	p.pos++
+	return test, nil
+}
+
+func (p *cssParser) parsePseudo() (cssPseudo, error) {
+	var pseudo cssPseudo
+	p.pos++ // ':'
+	name, err := p.parseIdent()
+	if err != nil {
+		return pseudo, err
+	}
+	pseudo.name = strings.ToLower(name)
+	switch pseudo.name {
+	case "first-child", "last-child", "only-child", "first-of-type", "last-of-type", "only-of-type", "empty", "root":
+		return pseudo, nil
+	case "not", "nth-child", "nth-last-child", "nth-of-type", "nth-last-of-type":
+	default:
+		return pseudo, p.errorf("unsupported pseudo-class ':%s'", name)
+	}
+
+	if p.peek() != '(' {
+		return pseudo, p.errorf("expected '(' after ':%s'", name)
+	}
+	p.pos++
+	if pseudo.name == "not" {
+		selectors, err := p.parseSelectorList()
+		if err != nil {
+			return pseudo, err
+		}
+		pseudo.not = selectors
+	} else {
+		end := strings.IndexByte(p.src[p.pos:], ')')
+		if end < 0 {
+			return pseudo, p.errorf("expected ')'")
+		}
+		a, b, ok := parseCssNth(p.src[p.pos : p.pos+end])
+		if !ok {
+			return pseudo, p.errorf("invalid argument to ':%s'", name)
+		}
+		pseudo.a, pseudo.b = a, b
+		p.pos += end
+	}
+	p.skipSpace()
+	if p.peek() != ')' {
+		return pseudo, p.errorf("expected ')'")
+	}
+	p.pos++
+	return pseudo, nil
+}
+
+// parseCssNth parses the an+b argument of the nth- pseudo-classes.
+func parseCssNth(arg string) (int, int, bool) {
+	arg = strings.ToLower(strings.Join(strings.Fields(arg), ""))
+	switch arg {
+	case "odd":
+		return 2, 1, true
+	case "even":
+		return 2, 0, true
+	}
+	n := strings.IndexByte(arg, 'n')
+	if n < 0 {
+		b, err := strconv.Atoi(arg)
+		return 0, b, err == nil
+	}
+	a := 1
+	switch coefficient := arg[:n]; coefficient {
+	case "", "+":
+	case "-":
+		a = -1
+	default:
+		var err error
+		if a, err = strconv.Atoi(coefficient); err != nil {
+			return 0, 0, false
+		}
+	}
+	b := 0
+	if rest := arg[n+1:]; rest != "" {
+		var err error
+		if b, err = strconv.Atoi(rest); err != nil || (rest[0] != '+' && rest[0] != '-') {
+			return 0, 0, false
+		}
+	}
+	return a, b, true
+}
+
+// cssNthMatches reports whether a 1-based position is a*k+b for some k >= 0.
+func cssNthMatches(a, b, position int) bool {
+	if a == 0 {
+		return position == b
+	}
+	return (position-b)%a == 0 && (position-b)/a >= 0
+}
+
+func (s cssSelector) matches(n *queryNode) bool {
+	return s.matchFrom(len(s.compounds)-1, n)
+}
+
+// matchFrom matches compounds[:i+1] right to left, ending at n.
+func (s cssSelector) matchFrom(i int, n *queryNode) bool {
+	if !s.compounds[i].matches(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch s.combinators[i] {
+	case '>':
+		return n.parent != nil && s.matchFrom(i-1, n.parent)
+	case '+':
+		return n.parent != nil && n.index > 0 && s.matchFrom(i-1, n.parent.children[n.index-1])
+	case '~':
+		if n.parent != nil {
+			for j := n.index - 1; j >= 0; j-- {
+				if s.matchFrom(i-1, n.parent.children[j]) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for a := n.parent; a != nil; a = a.parent {
+		if s.matchFrom(i-1, a) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c cssCompound) matches(n *queryNode) bool {
+	tag := n.tag()
+	if tag == "" || (c.tag != "" && c.tag != "*" && c.tag != tag) {
+		return false
+	}
+	for _, id := range c.ids {
+		if value, ok := n.attr("id"); !ok || value != id {
+			return false
+		}
+	}
+	if len(c.classes) > 0 {
+		value, _ := n.attr("class")
+		classes := strings.Fields(value)
+		for _, class := range c.classes {
+			found := false
+			for _, have := range classes {
+				if have == class {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	for _, test := range c.attrs {
+		if !test.matches(n) {
+			return false
+		}
+	}
+	for _, pseudo := range c.pseudos {
+		if !pseudo.matches(n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t cssAttrTest) matches(n *queryNode) bool {
+	value, ok := n.attr(t.name)
+	if !ok {
+		return false
+	}
+	switch t.op {
+	case "=":
+		return value == t.value
+	case "~=":
+		for _, word := range strings.Fields(value) {
+			if word == t.value {
+				return true
+			}
+		}
+		return false
+	case "|=":
+		return value == t.value || strings.HasPrefix(value, t.value+"-")
+	case "^=":
+		return t.value != "" && strings.HasPrefix(value, t.value)
+	case "$=":
+		return t.value != "" && strings.HasSuffix(value, t.value)
+	case "*=":
+		return t.value != "" && strings.Contains(value, t.value)
+	}
+	return true
+}
+
+func (ps cssPseudo) matches(n *queryNode) bool {
+	siblings := []*queryNode{n}
+	index := 0
+	if n.parent != nil {
+		siblings = n.parent.children
+		index = n.index
+	}
+	// ofType narrows the siblings to those with n's tag and finds n among them.
+	ofType := func() ([]*queryNode, int) {
+		var same []*queryNode
+		position := 0
+		for _, sibling := range siblings {
+			if sibling == n {
+				position = len(same)
+			}
+			if sibling.tag() == n.tag() {
+				same = append(same, sibling)
+			}
+		}
+		return same, position
+	}
+
+	switch ps.name {
+	case "first-child":
+		return index == 0
+	case "last-child":
+		return index == len(siblings)-1
+	case "only-child":
+		return len(siblings) == 1
+	case "nth-child":
+		return cssNthMatches(ps.a, ps.b, index+1)
+	case "nth-last-child":
+		return cssNthMatches(ps.a, ps.b, len(siblings)-index)
+	case "first-of-type", "last-of-type", "only-of-type", "nth-of-type", "nth-last-of-type":
+		same, position := ofType()
+		switch ps.name {
+		case "first-of-type":
+			return position == 0
+		case "last-of-type":
+			return position == len(same)-1
+		case "only-of-type":
+			return len(same) == 1
+		case "nth-of-type":
+			return cssNthMatches(ps.a, ps.b, position+1)
+		}
+		return cssNthMatches(ps.a, ps.b, len(same)-position)
+	case "empty":
+		return len(n.children) == 0 && n.text() == ""
+	case "root":
+		return n.parent == nil || n.parent.tag() == ""
+	case "not":
+		for _, selector := range ps.not {
+			if selector.matches(n) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// cssSelect returns the elements below root that match selector, in
+// document order. Root itself is not a candidate, as with querySelectorAll.
+func cssSelect(root *MShellDict, selector string) (*MShellList, error) {
+	selectors, err := compileCssSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	result := NewList(0)
+	for _, n := range buildQueryTree(root).descendants() {
+		for _, s := range selectors {
+			if s.matches(n) {
+				result.Items = append(result.Items, n.dict)
+				break
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCssSelect(t *testing.T) {
+	doc, err := parseXml(strings.NewReader(`<body xmlns:svg="urn:svg">
+  <ul id="menu">
+    <li class="item first">One</li>
+    <li class="item" data-lang="en-US">Two</li>
+    <li class="other">Three</li>
+  </ul>
+  <p>After</p>
+  <svg:svg><svg:rect width="10"/></svg:svg>
+</body>`))
+	if err != nil {
+		t.Fatalf("parseXml error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		selector string
+		want     string
+	}{
+		{"li.item", "One Two"},
+		{"#menu > li:last-child", "Three"},
+		{"li:nth-child(odd)", "One Three"},
+		{"li:not(.first):not(.other)", "Two"},
+		{"[data-lang|=en]", "Two"},
+		{"li[class^=ot], li[class~=first]", "One Three"},
+		{"ul + p", "After"},
+		{"li.first ~ li", "Two Three"},
+		{"body li:first-of-type", "One"},
+		{"svg|rect[width='10']", "rect"},
+	} {
+		result, err := cssSelect(doc, tt.selector)
+		if err != nil {
+			t.Errorf("cssSelect(%q) error: %v", tt.selector, err)
+			continue
+		}
+		var got []string
+		for _, item := range result.Items {
+			node := item.(*MShellDict)
+			text := node.Items["text"].(MShellString).Content
+			if text == "" {
+				text = strings.TrimPrefix(node.Items["tag"].(MShellString).Content, "svg:")
+			}
+			got = append(got, text)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("cssSelect(%q) = %v, want %s", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestCompileCssSelectorErrors(t *testing.T) {
+	for _, tt := range []struct {
+		selector string
+		want     string
+	}{
+		{"li >", "expected a selector at the end"},
+		{"li:hover", "unsupported pseudo-class ':hover'"},
+		{"li:nth-child(x)", "invalid argument"},
+		{"[href", "expected an attribute operator"},
+	} {
+		if _, err := compileCssSelector(tt.selector); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("compileCssSelector(%q) error = %v, want one containing %q", tt.selector, err, tt.want)
+		}
+	}
+}
+
+func TestParseCssNth(t *testing.T) {
+	for _, tt := range []struct {
+		arg  string
+		a, b int
+	}{{"odd", 2, 1}, {"even", 2, 0}, {"3", 0, 3}, {"-n+3", -1, 3}, {"2n - 1", 2, -1}, {"n", 1, 0}} {
+		a, b, ok := parseCssNth(tt.arg)
+		if !ok || a != tt.a || b != tt.b {
+			t.Errorf("parseCssNth(%q) = %d, %d, %v, want %d, %d", tt.arg, a, b, ok, tt.a, tt.b)
+		}
+	}
+	if !cssNthMatches(-1, 3, 3) || cssNthMatches(-1, 3, 4) {
+		t.Errorf("-n+3 should match positions up to 3")
+	}
+}
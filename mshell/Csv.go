@@ -0,0 +1,635 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// csvGridOptions configures readCsvGrid. The zero value is not usable; start
+// from defaultCsvGridOptions.
+type csvGridOptions struct {
+	delimiter  rune
+	quote      rune // 0 disables quoting
+	header     bool
+	comment    string // lines starting with this prefix are skipped; "" for none
+	nulls      map[string]struct{}
+	stripBom   bool
+	lazyQuotes bool
+	maxRows    int // -1 for no limit
+	inferRows  int
+	types      map[string]ColumnType
+}
+
+func defaultCsvGridOptions() csvGridOptions {
+	return csvGridOptions{
+		delimiter: ',',
+		quote:     '"',
+		header:    true,
+		nulls:     map[string]struct{}{"": {}},
+		stripBom:  true,
+		maxRows:   -1,
+		inferRows: 1000,
+	}
+}
+
+// parseCsvGridOptions reads the readCsvGrid options dictionary.
+func parseCsvGridOptions(dict *MShellDict) (csvGridOptions, error) {
+	options := defaultCsvGridOptions()
+
+	singleRune := func(key string, allowEmpty bool) (rune, bool, error) {
+		val, ok, err := stringOption(dict, key)
+		if err != nil || !ok {
+			return 0, ok, err
+		}
+		if val == "" && allowEmpty {
+			return 0, true, nil
+		}
+		if utf8.RuneCountInString(val) != 1 {
+			return 0, true, fmt.Errorf("Option '%s' must be a single character, found %q", key, val)
+		}
+		r, _ := utf8.DecodeRuneInString(val)
+		if r == '\n' || r == '\r' {
+			return 0, true, fmt.Errorf("Option '%s' cannot be a line break", key)
+		}
+		return r, true, nil
+	}
+
+	if r, ok, err := singleRune("delimiter", false); err != nil {
+		return options, err
+	} else if ok {
+		options.delimiter = r
+	}
+	if r, ok, err := singleRune("quote", true); err != nil {
+		return options, err
+	} else if ok {
+		options.quote = r
+	}
+	if options.quote != 0 && options.quote == options.delimiter {
+		return options, fmt.Errorf("Options 'delimiter' and 'quote' must differ")
+	}
+
+	if val, ok, err := boolOption(dict, "header"); err != nil {
+		return options, err
+	} else if ok {
+		options.header = val
+	}
+	if val, ok, err := stringOption(dict, "comment"); err != nil {
+		return options, err
+	} else if ok {
+		options.comment = val
+	}
+	if val, ok, err := boolOption(dict, "stripBom"); err != nil {
+		return options, err
+	} else if ok {
+		options.stripBom = val
+	}
+	if val, ok, err := boolOption(dict, "lazyQuotes"); err != nil {
+		return options, err
+	} else if ok {
+		options.lazyQuotes = val
+	}
+	if val, ok, err := intOption(dict, "maxRows"); err != nil {
+		return options, err
+	} else if ok {
+		if val < 0 {
+			return options, fmt.Errorf("Option 'maxRows' must be >= 0")
+		}
+		options.maxRows = val
+	}
+	if val, ok, err := intOption(dict, "inferRows"); err != nil {
+		return options, err
+	} else if ok {
+		if val < 1 {
+			return options, fmt.Errorf("Option 'inferRows' must be >= 1")
+		}
+		options.inferRows = val
+	}
+
+	if item, ok := dict.Items["nulls"]; ok {
+		list, ok := item.(*MShellList)
+		if !ok {
+			return options, fmt.Errorf("Option 'nulls' must be a list of strings, found %s", item.TypeName())
+		}
+		options.nulls = make(map[string]struct{}, len(list.Items))
+		for _, v := range list.Items {
+			s, ok := v.(MShellString)
+			if !ok {
+				return options, fmt.Errorf("Option 'nulls' must be a list of strings, found an item of type %s", v.TypeName())
+			}
+			options.nulls[s.Content] = struct{}{}
+		}
+	}
+
+	if item, ok := dict.Items["types"]; ok {
+		typesDict, ok := item.(*MShellDict)
+		if !ok {
+			return options, fmt.Errorf("Option 'types' must be a dictionary, found %s", item.TypeName())
+		}
+		options.types = make(map[string]ColumnType, len(typesDict.Items))
+		for col, v := range typesDict.Items {
+			s, ok := v.(MShellString)
+			if !ok {
+				return options, fmt.Errorf("Type for column '%s' must be a string, found %s", col, v.TypeName())
+			}
+			colType, err := csvColumnTypeFromName(s.Content)
+			if err != nil {
+				return options, fmt.Errorf("Column '%s': %s", col, err.Error())
+			}
+			options.types[col] = colType
+		}
+	}
+
+	return options, nil
+}
+
+func csvColumnTypeFromName(name string) (ColumnType, error) {
+	switch name {
+	case "int":
+		return COL_INT, nil
+	case "float":
+		return COL_FLOAT, nil
+	case "str":
+		return COL_STRING, nil
+	case "datetime":
+		return COL_DATETIME, nil
+	default:
+		return COL_GENERIC, fmt.Errorf("unknown column type %q; expected int, float, str, or datetime", name)
+	}
+}
+
+// csvRecordReader is a streaming CSV/TSV record reader. Unlike encoding/csv
+// it supports any quote character (or none). Line breaks inside quoted
+// fields are normalized to \n. Blank lines and lines starting with the
+// comment prefix are skipped.
+type csvRecordReader struct {
+	r          *bufio.Reader
+	delimiter  rune
+	quote      rune
+	comment    string
+	lazyQuotes bool
+
+	line      int // number of the last line read
+	recLine   int // line the current record started on
+	eof       bool
+	recordBuf []byte
+	fieldEnds []int
+	fields    []string
+}
+
+func newCsvRecordReader(r io.Reader, options csvGridOptions) *csvRecordReader {
+	if options.stripBom {
+		// Strips a UTF-8 byte order mark and decodes UTF-16 input that
+		// starts with a UTF-16 byte order mark.
+		r = transform.NewReader(r, unicode.BOMOverride(transform.Nop))
+	}
+	return &csvRecordReader{
+		r:          bufio.NewReaderSize(r, 64*1024),
+		delimiter:  options.delimiter,
+		quote:      options.quote,
+		comment:    options.comment,
+		lazyQuotes: options.lazyQuotes,
+	}
+}
+
+// readLine returns the next physical line without its line ending. ok is
+// false at end of input.
+func (cr *csvRecordReader) readLine() (string, bool, error) {
+	if cr.eof {
+		return "", false, nil
+	}
+	line, err := cr.r.ReadString('\n')
+	if err == io.EOF {
+		cr.eof = true
+		if line == "" {
+			return "", false, nil
+		}
+	} else if err != nil {
+		return "", false, err
+	}
+	cr.line++
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return line, true, nil
+}
+
+// Read returns the next record. The returned slice is reused by the next
+// call; the strings themselves are not. Returns io.EOF at end of input.
+func (cr *csvRecordReader) Read() ([]string, error) {
+	var line string
+	for {
+		l, ok, err := cr.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, io.EOF
+		}
+		if l == "" || (cr.comment != "" && strings.HasPrefix(l, cr.comment)) {
+			continue
+		}
+		line = l
+		break
+	}
+	cr.recLine = cr.line
+
+	quoteStr := string(cr.quote)
+	delimStr := string(cr.delimiter)
+	cr.recordBuf = cr.recordBuf[:0]
+	cr.fieldEnds = cr.fieldEnds[:0]
+	pos := 0
+
+parseField:
+	for {
+		if cr.quote != 0 && strings.HasPrefix(line[pos:], quoteStr) {
+			pos += len(quoteStr)
+			for {
+				i := strings.Index(line[pos:], quoteStr)
+				if i >= 0 {
+					cr.recordBuf = append(cr.recordBuf, line[pos:pos+i]...)
+					pos += i + len(quoteStr)
+					rest := line[pos:]
+					switch {
+					case strings.HasPrefix(rest, quoteStr):
+						cr.recordBuf = append(cr.recordBuf, quoteStr...)
+						pos += len(quoteStr)
+					case strings.HasPrefix(rest, delimStr):
+						pos += len(delimStr)
+						cr.fieldEnds = append(cr.fieldEnds, len(cr.recordBuf))
+						continue parseField
+					case rest == "":
+						cr.fieldEnds = append(cr.fieldEnds, len(cr.recordBuf))
+						break parseField
+					case cr.lazyQuotes:
+						cr.recordBuf = append(cr.recordBuf, quoteStr...)
+					default:
+						return nil, cr.errorf("extraneous or missing %s in quoted field", quoteStr)
+					}
+					continue
+				}
+				// The quoted field continues onto the next line.
+				cr.recordBuf = append(cr.recordBuf, line[pos:]...)
+				next, ok, err := cr.readLine()
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					if !cr.lazyQuotes {
+						return nil, cr.errorf("quoted field is not closed before the end of input")
+					}
+					cr.fieldEnds = append(cr.fieldEnds, len(cr.recordBuf))
+					break parseField
+				}
+				cr.recordBuf = append(cr.recordBuf, '\n')
+				line = next
+				pos = 0
+			}
+		}
+
+		i := strings.Index(line[pos:], delimStr)
+		field := line[pos:]
+		if i >= 0 {
+			field = line[pos : pos+i]
+		}
+		if cr.quote != 0 && !cr.lazyQuotes && strings.Contains(field, quoteStr) {
+			return nil, cr.errorf("bare %s in non-quoted field", quoteStr)
+		}
+		cr.recordBuf = append(cr.recordBuf, field...)
+		cr.fieldEnds = append(cr.fieldEnds, len(cr.recordBuf))
+		if i < 0 {
+			break
+		}
+		pos += i + len(delimStr)
+	}
+
+	// One string per record; fields are substrings of it.
+	str := string(cr.recordBuf)
+	cr.fields = cr.fields[:0]
+	prev := 0
+	for _, end := range cr.fieldEnds {
+		cr.fields = append(cr.fields, str[prev:end])
+		prev = end
+	}
+	return cr.fields, nil
+}
+
+func (cr *csvRecordReader) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", cr.recLine, fmt.Sprintf(format, args...))
+}
+
+// csvColumnBuilder appends parsed cells straight into a GridColumn's typed
+// storage. Nulls are kept in the column's null mask, so a column with gaps
+// stays typed. A column whose later values don't fit its inferred type falls
+// back to generic storage; values already read keep their parsed types.
+type csvColumnBuilder struct {
+	col      *GridColumn
+	kind     ColumnType // the type cells are parsed as
+	declared bool
+}
+
+func newCsvColumnBuilder(name string, kind ColumnType, declared bool) *csvColumnBuilder {
+	col := &GridColumn{Name: name, ColType: kind}
+	return &csvColumnBuilder{col: col, kind: kind, declared: declared}
+}
+
+// add appends one cell. Null tokens become none, except in string columns
+// where the text is kept.
+func (b *csvColumnBuilder) add(value string, isNull bool) error {
+	col := b.col
+	if b.kind == COL_STRING {
+		// Clone so the column doesn't pin the whole record's text.
+		col.StringData = append(col.StringData, strings.Clone(value))
+		return nil
+	}
+
+	if isNull {
+		appendNone(col)
+		return nil
+	}
+
+	var (
+		intVal   int64
+		floatVal float64
+		dtVal    time.Time
+		err      error
+	)
+	switch b.kind {
+	case COL_INT:
+		intVal, err = parseCsvInt(value)
+	case COL_FLOAT:
+		floatVal, err = parseCsvFloat(value)
+	case COL_DATETIME:
+		dtVal, err = ParseDateTime(value)
+	}
+	if err != nil {
+		if b.declared {
+			return fmt.Errorf("value %q in column '%s' is not a valid %s", value, col.Name, csvColumnTypeName(b.kind))
+		}
+		widenColumnToGeneric(col)
+		col.GenericData = append(col.GenericData, MShellString{Content: strings.Clone(value)})
+		return nil
+	}
+
+	if col.ColType == COL_GENERIC {
+		switch b.kind {
+		case COL_INT:
+			col.GenericData = append(col.GenericData, MShellInt{Value: int(intVal)})
+		case COL_FLOAT:
+			col.GenericData = append(col.GenericData, MShellFloat{Value: floatVal})
+		case COL_DATETIME:
+			col.GenericData = append(col.GenericData, &MShellDateTime{Time: dtVal})
+		}
+		return nil
+	}
+	switch b.kind {
+	case COL_INT:
+		col.IntData = append(col.IntData, intVal)
+	case COL_FLOAT:
+		col.FloatData = append(col.FloatData, floatVal)
+	case COL_DATETIME:
+		col.DateTimeData = append(col.DateTimeData, dtVal)
+	}
+	return nil
+}
+
+func csvColumnTypeName(kind ColumnType) string {
+	switch kind {
+	case COL_INT:
+		return "int"
+	case COL_FLOAT:
+		return "float"
+	case COL_DATETIME:
+		return "datetime"
+	default:
+		return "str"
+	}
+}
+
+// parseCsvInt accepts plain base-10 integers. Values with leading zeros,
+// like ZIP codes or zero-padded IDs, are rejected so they stay strings.
+func parseCsvInt(s string) (int64, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	if len(digits) > 1 && digits[0] == '0' {
+		return 0, errors.New("leading zero")
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// parseCsvFloat accepts decimal and exponent notation only, so words like
+// "nan" or "Infinity" stay strings.
+func parseCsvFloat(s string) (float64, error) {
+	if s == "" {
+		return 0, errors.New("empty")
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9') && c != '.' && c != '-' && c != '+' && c != 'e' && c != 'E' {
+			return 0, errors.New("not a decimal number")
+		}
+	}
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' && digits[1] != 'e' && digits[1] != 'E' {
+		return 0, errors.New("leading zero")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// inferCsvColumnType picks the narrowest of int, float, datetime, and str
+// that parses every non-null sample value. A column with only nulls is str.
+func inferCsvColumnType(samples [][]string, colIdx int, nulls map[string]struct{}) ColumnType {
+	canInt, canFloat, canDateTime := true, true, true
+	seen := false
+	for _, row := range samples {
+		v := row[colIdx]
+		if _, isNull := nulls[v]; isNull {
+			continue
+		}
+		seen = true
+		if canInt {
+			if _, err := parseCsvInt(v); err != nil {
+				canInt = false
+			}
+		}
+		if !canInt && canFloat {
+			if _, err := parseCsvFloat(v); err != nil {
+				canFloat = false
+			}
+		}
+		if !canInt && !canFloat && canDateTime {
+			if _, err := ParseDateTime(v); err != nil {
+				canDateTime = false
+			}
+		}
+		if !canInt && !canFloat && !canDateTime {
+			return COL_STRING
+		}
+	}
+	switch {
+	case !seen:
+		return COL_STRING
+	case canInt:
+		return COL_INT
+	case canFloat:
+		return COL_FLOAT
+	default:
+		return COL_DATETIME
+	}
+}
+
+// readCsvSchema reads the header, or names columns col1, col2, ... when
+// options.header is false, and buffers up to options.inferRows data rows for
+// type inference. colNames is nil for empty input without a header.
+func readCsvSchema(reader *csvRecordReader, options csvGridOptions) ([]string, [][]string, error) {
+	var colNames []string
+	first, err := reader.Read()
+	if err == io.EOF {
+		if options.header {
+			return nil, nil, fmt.Errorf("CSV input has no header row")
+		}
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	var pending [][]string
+	if options.header {
+		colNames = make([]string, len(first))
+		seenCols := make(map[string]struct{}, len(first))
+		for i, name := range first {
+			if _, exists := seenCols[name]; exists {
+				return nil, nil, fmt.Errorf("CSV header has duplicate column '%s'", name)
+			}
+			seenCols[name] = struct{}{}
+			colNames[i] = strings.Clone(name)
+		}
+	} else {
+		colNames = make([]string, len(first))
+		for i := range first {
+			colNames[i] = fmt.Sprintf("col%d", i+1)
+		}
+		pending = append(pending, append([]string(nil), first...))
+	}
+
+	for name := range options.types {
+		found := false
+		for _, c := range colNames {
+			if c == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("Option 'types' names column '%s', which is not in the CSV", name)
+		}
+	}
+
+	// Buffer the inference sample.
+	for len(pending) < options.inferRows && !csvRowLimitReached(options, len(pending)) {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+		if len(record) != len(colNames) {
+			return nil, nil, reader.errorf("record has %d fields but the CSV has %d columns", len(record), len(colNames))
+		}
+		pending = append(pending, append([]string(nil), record...))
+	}
+	if csvRowLimitReached(options, 0) {
+		pending = nil
+	} else if options.maxRows >= 0 && len(pending) > options.maxRows {
+		pending = pending[:options.maxRows]
+	}
+	return colNames, pending, nil
+}
+
+func csvRowLimitReached(options csvGridOptions, rows int) bool {
+	return options.maxRows >= 0 && rows >= options.maxRows
+}
+
+// csvColumnKinds returns the parse type of each column: the declared type
+// from options.types, or one inferred from the sample rows.
+func csvColumnKinds(colNames []string, sample [][]string, options csvGridOptions) ([]ColumnType, []bool) {
+	kinds := make([]ColumnType, len(colNames))
+	declared := make([]bool, len(colNames))
+	for i, name := range colNames {
+		if kind, ok := options.types[name]; ok {
+			kinds[i], declared[i] = kind, true
+		} else {
+			kinds[i] = inferCsvColumnType(sample, i, options.nulls)
+		}
+	}
+	return kinds, declared
+}
+
+// readCsvGrid streams CSV records into a Grid with typed columns. Column
+// types come from options.types when declared, and otherwise are inferred
+// from the first options.inferRows data rows. Every record must have as
+// many fields as the header.
+func readCsvGrid(r io.Reader, options csvGridOptions) (*MShellGrid, error) {
+	reader := newCsvRecordReader(r, options)
+
+	colNames, pending, err := readCsvSchema(reader, options)
+	if err != nil {
+		return nil, err
+	}
+	if colNames == nil {
+		return NewGrid(), nil
+	}
+
+	kinds, declared := csvColumnKinds(colNames, pending, options)
+	builders := make([]*csvColumnBuilder, len(colNames))
+	for i, name := range colNames {
+		builders[i] = newCsvColumnBuilder(name, kinds[i], declared[i])
+	}
+
+	rows := 0
+	addRecord := func(record []string) error {
+		for i, v := range record {
+			_, isNull := options.nulls[v]
+			if err := builders[i].add(v, isNull); err != nil {
+				return fmt.Errorf("data row %d: %s", rows+1, err.Error())
+			}
+		}
+		rows++
+		return nil
+	}
+
+	for _, record := range pending {
+		if err := addRecord(record); err != nil {
+			return nil, err
+		}
+	}
+	pending = nil
+
+	for !csvRowLimitReached(options, rows) {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if len(record) != len(colNames) {
+			return nil, reader.errorf("record has %d fields but the CSV has %d columns", len(record), len(colNames))
+		}
+		if err := addRecord(record); err != nil {
+			return nil, err
+		}
+	}
+
+	grid := NewGrid()
+	grid.RowCount = rows
+	for _, b := range builders {
+		grid.AddColumn(b.col)
+	}
+	return grid, nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readAllCsvRecords(t *testing.T, input string, options csvGridOptions) [][]string {
+	t.Helper()
+	reader := newCsvRecordReader(strings.NewReader(input), options)
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+		records = append(records, append([]string(nil), record...))
+	}
+}
+
+func TestCsvRecordReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		modify func(*csvGridOptions)
+		expect [][]string
+	}{
+		{
+			name:   "quoted fields with delimiters, quotes, and line breaks",
+			input:  "a,\"b,c\",\"say \"\"hi\"\"\"\r\n\"multi\r\nline\",,x\n",
+			expect: [][]string{{"a", "b,c", "say \"hi\""}, {"multi\nline", "", "x"}},
+		},
+		{
+			name:   "skips blank lines and comments",
+			input:  "# comment\n\na,b\n\n# another\nc,d",
+			modify: func(o *csvGridOptions) { o.comment = "#" },
+			expect: [][]string{{"a", "b"}, {"c", "d"}},
+		},
+		{
+			name:   "custom delimiter and quote",
+			input:  "'a\tb'\tc\n",
+			modify: func(o *csvGridOptions) { o.delimiter = '\t'; o.quote = '\'' },
+			expect: [][]string{{"a\tb", "c"}},
+		},
+		{
+			name:   "quoting disabled",
+			input:  "\"a\",b\n",
+			modify: func(o *csvGridOptions) { o.quote = 0 },
+			expect: [][]string{{"\"a\"", "b"}},
+		},
+		{
+			name:   "lazy quotes",
+			input:  "a\"b,\"c\"d\"\n",
+			modify: func(o *csvGridOptions) { o.lazyQuotes = true },
+			expect: [][]string{{"a\"b", "c\"d"}},
+		},
+		{
+			name:   "strips a UTF-8 byte order mark",
+			input:  "\xef\xbb\xbfa,b\n",
+			expect: [][]string{{"a", "b"}},
+		},
+		{
+			name:   "decodes UTF-16 with a byte order mark",
+			input:  "\xff\xfea\x00,\x00b\x00\n\x00",
+			expect: [][]string{{"a", "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := defaultCsvGridOptions()
+			if tt.modify != nil {
+				tt.modify(&options)
+			}
+			got := readAllCsvRecords(t, tt.input, options)
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Fatalf("records = %q, want %q", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestCsvRecordReaderErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "bare quote", input: "a,b\"c\n", want: "line 1: bare \" in non-quoted field"},
+		{name: "text after closing quote", input: "a\n\"b\"c,d\n", want: "line 2: extraneous or missing \" in quoted field"},
+		{name: "unterminated quote", input: "a,\"b\nc\n", want: "line 1: quoted field is not closed before the end of input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := newCsvRecordReader(strings.NewReader(tt.input), defaultCsvGridOptions())
+			var err error
+			for err == nil {
+				_, err = reader.Read()
+			}
+			if err == io.EOF || err.Error() != tt.want {
+				t.Fatalf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCsvGridInfersColumnTypes(t *testing.T) {
+	input := "id,kwh,zip,when,label\n1,1.5,02134,2024-01-02,x\n2,,10001,2024-01-03,\n3,2,60601,2024-01-04,z\n"
+	grid, err := readCsvGrid(strings.NewReader(input), defaultCsvGridOptions())
+	if err != nil {
+		t.Fatalf("readCsvGrid error: %v", err)
+	}
+	if grid.RowCount != 3 {
+		t.Fatalf("RowCount = %d, want 3", grid.RowCount)
+	}
+	wantTypes := map[string]ColumnType{
+		"id":    COL_INT,
+		"kwh":   COL_FLOAT,  // a null doesn't force generic storage
+		"zip":   COL_STRING, // leading zeros stay text
+		"when":  COL_DATETIME,
+		"label": COL_STRING,
+	}
+	for name, want := range wantTypes {
+		if got := grid.GetColumn(name).ColType; got != want {
+			t.Errorf("column %s type = %d, want %d", name, got, want)
+		}
+	}
+	kwh := grid.GetColumn("kwh")
+	if f, ok := kwh.Get(0).(MShellFloat); !ok || f.Value != 1.5 {
+		t.Errorf("kwh[0] = %#v, want float 1.5", kwh.Get(0))
+	}
+	if m, ok := kwh.Get(1).(*Maybe); !ok || m.obj != nil {
+		t.Errorf("kwh[1] = %#v, want none", kwh.Get(1))
+	}
+	if !math.IsNaN(kwh.FloatData[1]) || !kwh.Nulls.has(1) {
+		t.Errorf("kwh[1] should be stored as a NaN null, got %v", kwh.FloatData[1])
+	}
+	if f, ok := kwh.Get(2).(MShellFloat); !ok || f.Value != 2 {
+		t.Errorf("kwh[2] = %#v, want float 2", kwh.Get(2))
+	}
+	if got := grid.GetColumn("label").StringData; !reflect.DeepEqual(got, []string{"x", "", "z"}) {
+		t.Errorf("label = %q, want empty string kept", got)
+	}
+}
+
+func TestReadCsvGridNullableColumnsStayTyped(t *testing.T) {
+	input := "meter,read_at,kwh\n1,2024-01-01,1.5\n,,\n3,2024-01-03,2\n"
+	grid, err := readCsvGrid(strings.NewReader(input), defaultCsvGridOptions())
+	if err != nil {
+		t.Fatalf("readCsvGrid error: %v", err)
+	}
+	for name, want := range map[string]ColumnType{"meter": COL_INT, "read_at": COL_DATETIME, "kwh": COL_FLOAT} {
+		col := grid.GetColumn(name)
+		if col.ColType != want {
+			t.Errorf("column %s type = %d, want %d", name, col.ColType, want)
+		}
+		if col.GenericData != nil {
+			t.Errorf("column %s keeps boxed cells", name)
+		}
+		if !col.Nulls.has(1) || col.Nulls.has(0) || col.Nulls.has(2) {
+			t.Errorf("column %s: only row 1 should be null", name)
+		}
+		if m, ok := col.Get(1).(*Maybe); !ok || m.obj != nil {
+			t.Errorf("%s[1] = %#v, want none", name, col.Get(1))
+		}
+	}
+	if got := grid.GetColumn("meter").IntData; !reflect.DeepEqual(got, []int64{1, 0, 3}) {
+		t.Errorf("meter = %v, want [1 0 3]", got)
+	}
+}
+
+func TestReadCsvGridFallsBackToGenericAfterSample(t *testing.T) {
+	options := defaultCsvGridOptions()
+	options.inferRows = 2
+	grid, err := readCsvGrid(strings.NewReader("n\n1\n2\nthree\n"), options)
+	if err != nil {
+		t.Fatalf("readCsvGrid error: %v", err)
+	}
+	col := grid.GetColumn("n")
+	if col.ColType != COL_GENERIC {
+		t.Fatalf("column type = %d, want generic", col.ColType)
+	}
+	if i, ok := col.Get(1).(MShellInt); !ok || i.Value != 2 {
+		t.Errorf("n[1] = %#v, want int 2", col.Get(1))
+	}
+	if s, ok := col.Get(2).(MShellString); !ok || s.Content != "three" {
+		t.Errorf("n[2] = %#v, want str three", col.Get(2))
+	}
+}
+
+func TestReadCsvGridOptionErrors(t *testing.T) {
+	options := defaultCsvGridOptions()
+	options.types = map[string]ColumnType{"missing": COL_INT}
+	if _, err := readCsvGrid(strings.NewReader("a\n1\n"), options); err == nil || !strings.Contains(err.Error(), "'missing'") {
+		t.Fatalf("expected an unknown column error, got %v", err)
+	}
+
+	if _, err := readCsvGrid(strings.NewReader("a,a\n1,2\n"), defaultCsvGridOptions()); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Fatalf("expected a duplicate header error, got %v", err)
+	}
+
+	if _, err := readCsvGrid(strings.NewReader("a,b\n1\n"), defaultCsvGridOptions()); err == nil || !strings.Contains(err.Error(), "line 2: record has 1 fields") {
+		t.Fatalf("expected a field count error, got %v", err)
+	}
+
+	dict := NewDict()
+	dict.Items["delimiter"] = MShellString{Content: ";;"}
+	if _, err := parseCsvGridOptions(dict); err == nil {
+		t.Fatal("expected an error for a multi-character delimiter")
+	}
+}
@@ -44,6 +44,17 @@ import (
 )
 
 var oleAutomationEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// toOleDate returns t as an OLE Automation date: fractional days since
+// 1899-12-30 UTC, the serial number Excel stores for dates.
+func toOleDate(t time.Time) float64 {
+	return t.In(time.UTC).Sub(oleAutomationEpoch).Hours() / 24
+}
+
+// fromOleDate is the inverse of toOleDate.
+func fromOleDate(days float64) time.Time {
+	return oleAutomationEpoch.Add(time.Duration(days * float64(24*time.Hour)))
+}
 var randomFixedRand = rand.New(rand.NewSource(1))
 
 type MShellStack []MShellObject
@@ -385,6 +396,141 @@ func groupIntPart(intPart string, sep string, grouping []int, hasSep bool, hasGr
 	return strings.Join(parts, sep)
 }
 
+// numFmtSpec is a parsed 'numFmt' options dictionary, so callers that format
+// many numbers (like the grid writers) only validate the options once.
+type numFmtSpec struct {
+	decimals        int
+	hasDecimals     bool
+	sigfigs         int
+	hasSigfigs      bool
+	preserveInt     bool
+	thousandsSep    string
+	hasThousandsSep bool
+	decimalPoint    string
+	grouping        []int
+	hasGrouping     bool
+}
+
+func parseNumFmtSpec(optionsDict *MShellDict) (numFmtSpec, error) {
+	var spec numFmtSpec
+	var err error
+	spec.decimals, spec.hasDecimals, err = getIntOption(optionsDict, "decimals")
+	if err != nil {
+		return spec, err
+	}
+	spec.sigfigs, spec.hasSigfigs, err = getIntOption(optionsDict, "sigFigs")
+	if err == nil && !spec.hasSigfigs {
+		spec.sigfigs, spec.hasSigfigs, err = getIntOption(optionsDict, "sigfigs")
+	}
+	if err != nil {
+		return spec, err
+	}
+	spec.preserveInt, _, err = getBoolOption(optionsDict, "preserveInt")
+	if err != nil {
+		return spec, err
+	}
+	spec.thousandsSep, spec.hasThousandsSep, err = getStringOption(optionsDict, "thousandsSep")
+	if err != nil {
+		return spec, err
+	}
+	decimalPoint, hasDecimalPoint, err := getStringOption(optionsDict, "decimalPoint")
+	if err != nil {
+		return spec, err
+	}
+	spec.grouping, spec.hasGrouping, err = getGroupingOption(optionsDict, "grouping")
+	if err != nil {
+		return spec, err
+	}
+
+	if spec.hasDecimals && spec.decimals < 0 {
+		return spec, fmt.Errorf("'decimals' in 'numFmt' must be non-negative, got %d", spec.decimals)
+	}
+	if spec.hasSigfigs && spec.sigfigs <= 0 {
+		return spec, fmt.Errorf("'sigfigs' in 'numFmt' must be positive, got %d", spec.sigfigs)
+	}
+
+	if !spec.hasDecimals && !spec.hasSigfigs {
+		spec.sigfigs = 3
+		spec.hasSigfigs = true
+	}
+
+	if hasDecimalPoint {
+		spec.decimalPoint = decimalPoint
+	} else {
+		spec.decimalPoint = "."
+	}
+	return spec, nil
+}
+
+// Format formats an MShellInt or MShellFloat.
+func (spec numFmtSpec) Format(numberObj MShellObject) string {
+	var num float64
+	switch n := numberObj.(type) {
+	case MShellInt:
+		num = float64(n.Value)
+	case MShellFloat:
+		num = n.Value
+	}
+
+	// I Basically hate all this codex generated code, but it works for now.
+	// Turns out formating a float isn't trivial.
+	// See:
+	// G. L. Steele and J. L. White. How to print floating-point numbers accurately. In PLDI, 1991.
+	// F. Loitsch. Printing floating-point numbers quickly and accurately with integers. In PLDI, 2010.
+	// Andrysco Jhala Lerner - Printing Floating-Point Numbers An Always Correct Method
+	var formatted string
+	if spec.hasDecimals {
+		formatted = fmt.Sprintf("%.*f", spec.decimals, num)
+	} else if spec.hasSigfigs {
+		if spec.preserveInt {
+			if intObj, ok := numberObj.(MShellInt); ok {
+				absStr := fmt.Sprintf("%d", int(math.Abs(float64(intObj.Value))))
+				if len(absStr) > spec.sigfigs {
+					formatted = fmt.Sprintf("%d", intObj.Value)
+				}
+			}
+		}
+		if formatted == "" {
+			formatted = formatWithSigFigs(num, spec.sigfigs)
+		}
+	} else {
+		switch n := numberObj.(type) {
+		case MShellInt:
+			formatted = fmt.Sprintf("%d", n.Value)
+		case MShellFloat:
+			formatted = strconv.FormatFloat(n.Value, 'f', -1, 64)
+		}
+	}
+
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign = "-"
+		formatted = formatted[1:]
+	}
+
+	intPart := formatted
+	fracPart := ""
+	if idx := strings.IndexByte(formatted, '.'); idx != -1 {
+		intPart = formatted[:idx]
+		fracPart = formatted[idx+1:]
+	}
+
+	intPart = groupIntPart(intPart, spec.thousandsSep, spec.grouping, spec.hasThousandsSep, spec.hasGrouping)
+
+	if fracPart != "" {
+		formatted = sign + intPart + "." + fracPart
+	} else {
+		formatted = sign + intPart
+	}
+
+	if spec.decimalPoint != "." {
+		if idx := strings.IndexByte(formatted, '.'); idx != -1 {
+			formatted = formatted[:idx] + spec.decimalPoint + formatted[idx+1:]
+		}
+	}
+	return formatted
+}
+
 type EvalState struct {
 	PositionalArgs []string
 	LoopDepth      int
@@ -394,6 +540,23 @@ type EvalState struct {
 	CompletionDefinitions map[string][]MShellDefinition
 	PreviousDirectories   []string
 
+	// TryDepth counts the enclosing 'try' bodies. While it is positive,
+	// failures are recorded in TryError instead of being printed.
+	TryDepth int
+	TryError *TryError
+	// TryCommand is the token running a command whose failure 'try' would
+	// catch. While it is set, RunProcess records a command missing from PATH
+	// as the try error, at that token, instead of printing it.
+	TryCommand *Token
+
+	// Jobs is the job table for 'jobs', 'fg', and 'bg'. It is only set for
+	// the interactive shell; scripts have no job control.
+	Jobs *JobTable
+
+	// Abbreviations are the interactive CLI's abbreviations, declared with
+	// 'abbr'. Created on first use.
+	Abbreviations *AbbreviationTable
+
 	defIndex    map[string]int
 	defIndexLen int
 }
@@ -579,6 +742,11 @@ func SimpleSuccess() EvalResult {
 }
 
 func (state *EvalState) FailWithMessage(message string) EvalResult {
+	if state.TryDepth > 0 {
+		state.recordTryError(message, 1)
+		return EvalResult{false, false, -1, 1, false}
+	}
+
 	// Log message to stderr
 	if state.CallStack == nil {
 		fmt.Fprintf(os.Stderr, "No call stack available.\n")
@@ -1900,6 +2068,45 @@ func getGridSourceAndIndices(obj MShellObject) (*MShellGrid, []int, error) {
 	}
 }
 
+// gridScanFilter adapts filter quotations to a scan predicate with the same
+// rules as filter on a Grid. A quotation that fails, returns, or exits stops
+// the scan with errGridScanStopped, leaving its result in *stopped.
+func (state *EvalState) gridScanFilter(t Token, context ExecuteContext, definitions []MShellDefinition, stopped *EvalResult) gridScanPredicate {
+	return func(quote *MShellQuotation, row *MShellGridRow) (bool, error) {
+		filterStack := MShellStack{row}
+		result, err := state.EvaluateQuote(*quote, &filterStack, context, definitions)
+		if err != nil {
+			return false, err
+		}
+		if result.ShouldPassResultUpStack() {
+			*stopped = result
+			return false, errGridScanStopped
+		}
+		if len(filterStack) == 0 {
+			return false, fmt.Errorf("%d:%d: filter predicate returned empty stack", t.Line, t.Column)
+		}
+		predResult, _ := filterStack.Pop()
+		switch predTyped := predResult.(type) {
+		case MShellBool:
+			return predTyped.Value, nil
+		case MShellInt:
+			return predTyped.Value == 0, nil
+		default:
+			return false, fmt.Errorf("%d:%d: filter predicate must return a boolean or integer, got %s", t.Line, t.Column, predResult.TypeName())
+		}
+	}
+}
+
+// gridScanAt reports whether the item depth places below the top of the
+// stack (1 for the top) is a GridScan.
+func gridScanAt(stack *MShellStack, depth int) bool {
+	if len(*stack) < depth {
+		return false
+	}
+	_, ok := (*stack)[len(*stack)-depth].(*MShellGridScan)
+	return ok
+}
+
 func projectGridColumns(sourceGrid *MShellGrid, sourceIndices []int, colNames []string) *MShellGrid {
 	newGrid := NewGrid()
 	newGrid.Meta = sourceGrid.Meta
@@ -2125,6 +2332,12 @@ func (state *EvalState) mergeSortMShellByQuotation(t Token, items []MShellObject
 // semantics. Returns -1, 0, or 1, or an error on incompatible types in a
 // generic column.
 func compareGridCellsForSort(col *GridColumn, idxA, idxB int) (int, error) {
+	if col.Nulls != nil {
+		aNone, bNone := col.Nulls.has(idxA), col.Nulls.has(idxB)
+		if aNone || bNone {
+			return compareGridGenericCells(col.Name, col.Get(idxA), col.Get(idxB))
+		}
+	}
 	switch col.ColType {
 	case COL_INT:
 		a, b := col.IntData[idxA], col.IntData[idxB]
@@ -2211,23 +2424,24 @@ func formatColumnNameList(names []string) string {
 }
 
 // validateGridSchemaMatch enforces strict-by-name matching. Types are not checked
-// here; type handling happens dynamically per column.
-func validateGridSchemaMatch(left, right *MShellGrid) error {
+// here; type handling happens dynamically per column. op names the operation in
+// the error, as in "Cannot concat grids".
+func validateGridSchemaMatch(op string, left, right *MShellGrid) error {
 	if len(left.ColIndex) != len(right.ColIndex) {
-		return formatGridSchemaMismatch(left, right)
+		return formatGridSchemaMismatch(op, left, right)
 	}
 	for name := range left.ColIndex {
 		if _, ok := right.ColIndex[name]; !ok {
-			return formatGridSchemaMismatch(left, right)
+			return formatGridSchemaMismatch(op, left, right)
 		}
 	}
 	return nil
 }
 
-func formatGridSchemaMismatch(left, right *MShellGrid) error {
+func formatGridSchemaMismatch(op string, left, right *MShellGrid) error {
 	missing, extra := gridSchemaDiff(left, right)
 	var sb strings.Builder
-	sb.WriteString("Cannot concat grids: column sets differ.")
+	fmt.Fprintf(&sb, "Cannot %s grids: column sets differ.", op)
 	if len(missing) > 0 {
 		sb.WriteString("\n  Missing in right: ")
 		sb.WriteString(formatColumnNameList(missing))
@@ -2252,7 +2466,7 @@ func concatGrids(leftObj, rightObj MShellObject) (*MShellGrid, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := validateGridSchemaMatch(leftSrc, rightSrc); err != nil {
+	if err := validateGridSchemaMatch("concat", leftSrc, rightSrc); err != nil {
 		return nil, err
 	}
 
@@ -2316,6 +2530,18 @@ func concatGrids(leftObj, rightObj MShellObject) (*MShellGrid, error) {
 				newCol.GenericData[leftRows+i] = rightCol.Get(idx)
 			}
 		}
+		if resolved != COL_GENERIC {
+			for i, idx := range leftIndices {
+				if leftCol.Nulls.has(idx) {
+					newCol.Nulls.set(i)
+				}
+			}
+			for i, idx := range rightIndices {
+				if rightCol.Nulls.has(idx) {
+					newCol.Nulls.set(leftRows + i)
+				}
+			}
+		}
 
 		newGrid.AddColumn(newCol)
 	}
@@ -2339,6 +2565,7 @@ func widenColumnToGeneric(col *GridColumn) {
 	col.FloatData = nil
 	col.StringData = nil
 	col.DateTimeData = nil
+	col.Nulls = nil
 	col.ColType = COL_GENERIC
 }
 
@@ -2346,6 +2573,14 @@ func widenColumnToGeneric(col *GridColumn) {
 // be widened (if needed) so that resolveColType(dst, src) == dst.ColType.
 func appendColumnRows(dst, src *GridColumn, srcIndices []int) {
 	if dst.ColType == src.ColType && dst.ColType != COL_GENERIC {
+		if src.Nulls != nil {
+			start := dst.Len()
+			for i, idx := range srcIndices {
+				if src.Nulls.has(idx) {
+					dst.Nulls.set(start + i)
+				}
+			}
+		}
 		switch dst.ColType {
 		case COL_INT:
 			for _, idx := range srcIndices {
@@ -2393,7 +2628,7 @@ func extendGrid(receiverObj, sourceObj MShellObject) (MShellObject, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := validateGridSchemaMatch(receiverGrid, sourceGrid); err != nil {
+	if err := validateGridSchemaMatch("concat", receiverGrid, sourceGrid); err != nil {
 		return nil, err
 	}
 
@@ -2490,10 +2725,15 @@ func isContainerType(obj MShellObject) bool {
 	}
 }
 
+// gridGroupByAggSpec is one groupBy output column. Quote aggregations get the
+// group as a GridView; named aggregations (Quote nil) fold column Col with
+// Agg, and a count without a column counts the group's rows.
 type gridGroupByAggSpec struct {
 	Name  string
 	Meta  *MShellDict
 	Quote *MShellQuotation
+	Agg   string
+	Col   string
 }
 
 func typedGridGroupKeyPart(obj MShellObject) (string, error) {
@@ -2557,6 +2797,7 @@ func parseGridGroupByAggSpecs(aggList *MShellList) ([]gridGroupByAggSpec, error)
 	specs := make([]gridGroupByAggSpec, len(aggList.Items))
 	allowedKeys := map[string]struct{}{
 		"agg":  {},
+		"col":  {},
 		"name": {},
 		"meta": {},
 	}
@@ -2577,9 +2818,30 @@ func parseGridGroupByAggSpecs(aggList *MShellList) ([]gridGroupByAggSpec, error)
 		if !ok {
 			return nil, fmt.Errorf("groupBy aggregation spec %d is missing required key 'agg'", i+1)
 		}
-		aggQuote, ok := aggObj.(*MShellQuotation)
-		if !ok {
-			return nil, fmt.Errorf("groupBy aggregation spec %d key 'agg' must be a quotation, got %s", i+1, aggObj.TypeName())
+		var aggQuote *MShellQuotation
+		var aggName, colName string
+		switch aggTyped := aggObj.(type) {
+		case *MShellQuotation:
+			aggQuote = aggTyped
+			if _, ok := specDict.Items["col"]; ok {
+				return nil, fmt.Errorf("groupBy aggregation spec %d key 'col' only applies to named aggregations", i+1)
+			}
+		case MShellString:
+			aggName = aggTyped.Content
+			if _, ok := gridAggNames[aggName]; !ok {
+				return nil, fmt.Errorf("groupBy aggregation spec %d key 'agg' must be sum, mean, min, max, count, first, or last, got '%s'", i+1, aggName)
+			}
+			if colObj, ok := specDict.Items["col"]; ok {
+				colStr, ok := colObj.(MShellString)
+				if !ok {
+					return nil, fmt.Errorf("groupBy aggregation spec %d key 'col' must be a string, got %s", i+1, colObj.TypeName())
+				}
+				colName = colStr.Content
+			} else if aggName != "count" {
+				return nil, fmt.Errorf("groupBy aggregation spec %d is missing required key 'col' for '%s'", i+1, aggName)
+			}
+		default:
+			return nil, fmt.Errorf("groupBy aggregation spec %d key 'agg' must be a quotation or an aggregation name, got %s", i+1, aggObj.TypeName())
 		}
 
 		name := fmt.Sprintf("AggCol%d", i+1)
@@ -2604,6 +2866,8 @@ func parseGridGroupByAggSpecs(aggList *MShellList) ([]gridGroupByAggSpec, error)
 			Name:  name,
 			Meta:  meta,
 			Quote: aggQuote,
+			Agg:   aggName,
+			Col:   colName,
 		}
 	}
 
@@ -2616,6 +2880,8 @@ const (
 	joinInner joinKind = iota
 	joinLeft
 	joinOuter
+	joinSemi
+	joinAnti
 )
 
 // evaluatedJoinKey converts a quotation result into a stable string key.
@@ -2690,7 +2956,8 @@ func (state *EvalState) evaluateJoinKeys(t Token, side string, sourceGrid *MShel
 
 // executeGridJoin pops 4 items from stack (leftGrid rightGrid leftQuote rightQuote)
 // and pushes the joined Grid. SQL-style NULL semantics: rows whose extractor returns
-// none never match anything, but appear as orphans in left/outer joins.
+// none never match anything, but appear as orphans in left/outer joins. Semi and
+// anti joins push a GridView of the left rows with and without a match instead.
 func (state *EvalState) executeGridJoin(t Token, stack *MShellStack, kind joinKind, context ExecuteContext, definitions []MShellDefinition) EvalResult {
 	obj1, obj2, obj3, obj4, err := stack.Pop4(t)
 	if err != nil {
@@ -2715,13 +2982,16 @@ func (state *EvalState) executeGridJoin(t Token, stack *MShellStack, kind joinKi
 		return state.FailWithMessage(fmt.Sprintf("%d:%d: %s requires a Grid or GridView for the right side, got %s.\n", t.Line, t.Column, t.Lexeme, obj3.TypeName()))
 	}
 
-	rightColNameSet := make(map[string]struct{}, len(rightGrid.Columns))
-	for _, col := range rightGrid.Columns {
-		rightColNameSet[col.Name] = struct{}{}
-	}
-	for _, col := range leftGrid.Columns {
-		if _, exists := rightColNameSet[col.Name]; exists {
-			return state.FailWithMessage(fmt.Sprintf("%d:%d: %s: column '%s' appears in both grids; rename or exclude before joining.\n", t.Line, t.Column, t.Lexeme, col.Name))
+	filtering := kind == joinSemi || kind == joinAnti
+	if !filtering {
+		rightColNameSet := make(map[string]struct{}, len(rightGrid.Columns))
+		for _, col := range rightGrid.Columns {
+			rightColNameSet[col.Name] = struct{}{}
+		}
+		for _, col := range leftGrid.Columns {
+			if _, exists := rightColNameSet[col.Name]; exists {
+				return state.FailWithMessage(fmt.Sprintf("%d:%d: %s: column '%s' appears in both grids; rename or exclude before joining.\n", t.Line, t.Column, t.Lexeme, col.Name))
+			}
 		}
 	}
 
@@ -2743,6 +3013,19 @@ func (state *EvalState) executeGridJoin(t Token, stack *MShellStack, kind joinKi
 		return keyResult
 	}
 
+	if filtering {
+		kept := make([]int, 0, len(leftIndices))
+		for li, srcIdx := range leftIndices {
+			_, matched := buildMap[leftKeys[li]]
+			matched = matched && !leftKeyIsNone[li]
+			if matched == (kind == joinSemi) {
+				kept = append(kept, srcIdx)
+			}
+		}
+		stack.Push(&MShellGridView{Source: leftGrid, Indices: kept})
+		return SimpleSuccess()
+	}
+
 	type joinPair struct {
 		leftLocalIdx  int
 		rightLocalIdx int
@@ -2837,7 +3120,9 @@ func (state *EvalState) evaluateItems(objects []MShellParseItem, stack *MShellSt
 			result := state.evaluateItems(list.Items, &listStack, context, definitions, callStackItem)
 
 			if !result.Success {
-				fmt.Fprint(os.Stderr, "Failed to evaluate list.\n")
+				if state.TryDepth == 0 {
+					fmt.Fprint(os.Stderr, "Failed to evaluate list.\n")
+				}
 				return result
 			}
 
@@ -2869,7 +3154,9 @@ func (state *EvalState) evaluateItems(objects []MShellParseItem, stack *MShellSt
 				callStackItem := CallStackItem{MShellParseItem: parseDict, Name: "dict", CallStackType: CALLSTACKDICT}
 				result := state.evaluateItems(keyValue.Value, &dictStack, context, definitions, callStackItem)
 				if !result.Success {
-					fmt.Fprint(os.Stderr, "Failed to evaluate dictionary.\n")
+					if state.TryDepth == 0 {
+						fmt.Fprint(os.Stderr, "Failed to evaluate dictionary.\n")
+					}
 					return result
 				}
 				if result.ExitCalled {
@@ -3782,6 +4069,16 @@ const (
 	ExitStartUnknown   = -256
 )
 
+// reportNotFound prints why a command could not be found, or records it as
+// the try error while TryCommand is set.
+func (state *EvalState) reportNotFound(message string) {
+	if t := state.TryCommand; t != nil {
+		state.recordTryError(fmt.Sprintf("%d:%d: %s", t.Line, t.Column, message), ExitNotFoundOnPath)
+		return
+	}
+	fmt.Fprint(os.Stderr, message)
+}
+
 func RunProcess(list MShellList, context ExecuteContext, state *EvalState) (EvalResult, int, []byte, []byte) {
 	// Returns the result of running the process, the exit code, and the stdout result
 
@@ -3897,13 +4194,13 @@ func RunProcess(list MShellList, context ExecuteContext, state *EvalState) (Eval
 		if context.Pbm == nil {
 			// A missing command is a recoverable runtime condition, not a hard
 			// abort: report it and leave the not-found code so ?/; continue.
-			fmt.Fprintf(os.Stderr, "Command '%s' not found: no path context available.\n", commandLineArgs[0])
+			state.reportNotFound(fmt.Sprintf("Command '%s' not found: no path context available.\n", commandLineArgs[0]))
 			return SimpleSuccess(), ExitNotFoundOnPath, nil, nil
 		}
 
 		cmdPath, found = context.Pbm.Lookup(commandLineArgs[0])
 		if !found {
-			fmt.Fprintf(os.Stderr, "Command '%s' not found in path.\n", commandLineArgs[0])
+			state.reportNotFound(fmt.Sprintf("Command '%s' not found in path.\n", commandLineArgs[0]))
 			return SimpleSuccess(), ExitNotFoundOnPath, nil, nil
 		}
 	}
@@ -3968,7 +4265,7 @@ func RunProcess(list MShellList, context ExecuteContext, state *EvalState) (Eval
 	} else if context.StandardOutput != nil {
 		cmd.Stdout = context.StandardOutput
 	} else {
-		if list.RunInBackground {
+		if list.RunInBackground && state.Jobs == nil {
 			cmd.Stdout = nil
 		} else {
 			// Default to stdout of this process itself
@@ -4034,7 +4331,7 @@ func RunProcess(list MShellList, context ExecuteContext, state *EvalState) (Eval
 	} else if context.StandardError != nil {
 		cmd.Stderr = context.StandardError
 	} else {
-		if list.RunInBackground {
+		if list.RunInBackground && state.Jobs == nil {
 			cmd.Stderr = nil
 		} else {
 			// Default to stderr of this process itself
@@ -4118,6 +4415,11 @@ func RunProcess(list MShellList, context ExecuteContext, state *EvalState) (Eval
 		} else {
 			// Backgrounded and started: we don't wait, so there is no exit code yet.
 			exitCode = 0
+			if state.Jobs != nil && !context.InPipeline {
+				state.Jobs.Background(startJob(cmd.Process.Pid, jobCommandText(&list), func() int {
+					return commandExitCode(cmd, cmd.Wait())
+				}))
+			}
 		}
 	} else {
 		// Use Start + Wait instead of Run so we can set the foreground process group
@@ -4161,28 +4463,35 @@ func RunProcess(list MShellList, context ExecuteContext, state *EvalState) (Eval
 				context.PipelineGroup.waitAllStagesLaunched()
 			}
 
-			waitErr := cmd.Wait()
-
-			// Reclaim the terminal before evaluation can resume shell input.  A
-			// reclaim failure is shell bookkeeping and must not override the
-			// child's own result: the child may have exited 0.
-			if err := foregroundLease.Release(); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: reclaiming terminal control: %s\n", err)
-			}
+			// In the interactive shell, a foreground command that owns the terminal
+			// can be stopped with Ctrl-Z. Wait for it as a job so a stop hands the
+			// terminal back and the command moves to the job table. Captured output
+			// has to be complete before evaluation continues, so those commands are
+			// always waited on directly.
+			if foregroundLease != nil && state.Jobs != nil && list.StdoutBehavior == STDOUT_NONE && list.StderrBehavior == STDERR_NONE && list.InPlaceFile == "" {
+				job := startJob(cmd.Process.Pid, jobCommandText(&list), func() int {
+					return commandExitCode(cmd, cmd.Wait())
+				})
+				exited := job.waitForeground()
+				if err := foregroundLease.Release(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: reclaiming terminal control: %s\n", err)
+				}
+				if !exited {
+					state.Jobs.Suspend(job)
+					return SimpleSuccess(), stoppedExitCode, nil, nil
+				}
+				exitCode = job.exitCode
+			} else {
+				waitErr := cmd.Wait()
 
-			if waitErr != nil {
-				if _, ok := waitErr.(*exec.ExitError); !ok {
-					fmt.Fprintf(os.Stderr, "Error running command: %s\n", waitErr.Error())
-					exitCode = ExitStartUnknown
-				} else if code, signalled := signalExitCode(cmd.ProcessState); signalled {
-					// Killed by a signal: encode it as -(128 + signal).
-					exitCode = code
-				} else {
-					// Command exited with non-zero exit code
-					exitCode = waitErr.(*exec.ExitError).ExitCode()
+				// Reclaim the terminal before evaluation can resume shell input.  A
+				// reclaim failure is shell bookkeeping and must not override the
+				// child's own result: the child may have exited 0.
+				if err := foregroundLease.Release(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: reclaiming terminal control: %s\n", err)
 				}
-			} else {
-				exitCode = cmd.ProcessState.ExitCode()
+
+				exitCode = commandExitCode(cmd, waitErr)
 			}
 		}
 	}
@@ -4198,6 +4507,23 @@ func RunProcess(list MShellList, context ExecuteContext, state *EvalState) (Eval
 	return SimpleSuccess(), exitCode, commandSubWriter.Bytes(), stderrBuffer.Bytes()
 }
 
+// commandExitCode converts the result of cmd.Wait into an mshell exit code.
+func commandExitCode(cmd *exec.Cmd, waitErr error) int {
+	if waitErr == nil {
+		return cmd.ProcessState.ExitCode()
+	}
+	if _, ok := waitErr.(*exec.ExitError); !ok {
+		fmt.Fprintf(os.Stderr, "Error running command: %s\n", waitErr.Error())
+		return ExitStartUnknown
+	}
+	if code, signalled := signalExitCode(cmd.ProcessState); signalled {
+		// Killed by a signal: encode it as -(128 + signal).
+		return code
+	}
+	// Command exited with non-zero exit code
+	return waitErr.(*exec.ExitError).ExitCode()
+}
+
 // PipelineGroup coordinates a single, shared process group for every external
 // stage of a pipeline. The first external stage to start becomes the group
 // leader (its own new process group); the remaining external stages join the
@@ -4212,6 +4538,7 @@ type PipelineGroup struct {
 	ready   chan struct{} // closed once the leader has started (or failed to)
 	terminal  *TerminalEndpoint // resolved controlling terminal for the job, if any
 	processes []*os.Process     // immediate processes retained for failed-launch cleanup
+	killed    bool              // set by killProcesses; processes registered later are killed on arrival
 
 	// Launch barrier: the leader must not reap itself (cmd.Wait) until every
 	// stage has finished launching, otherwise reaping destroys the shared
@@ -4228,18 +4555,35 @@ func (pg *PipelineGroup) registerProcess(process *os.Process) {
 	}
 	pg.mu.Lock()
 	pg.processes = append(pg.processes, process)
+	killed := pg.killed
 	pg.mu.Unlock()
+	if killed {
+		process.Kill()
+	}
 }
 
 func (pg *PipelineGroup) killProcesses() {
 	pg.mu.Lock()
 	processes := append([]*os.Process(nil), pg.processes...)
+	pg.killed = true
 	pg.mu.Unlock()
 	for _, process := range processes {
 		process.Kill()
 	}
 }
 
+// killProcessGroup kills every process in the group led by the first stage,
+// which also reaches grandchildren such as the commands run by an 'sh -c'
+// stage. It is a no-op until a leader has started.
+func (pg *PipelineGroup) killProcessGroup() {
+	pg.mu.Lock()
+	pgid := pg.pgid
+	pg.mu.Unlock()
+	if pgid > 0 {
+		KillProcessGroup(pgid)
+	}
+}
+
 // registerTerminal records a controlling terminal from a stage's resolved stdio.
 // A pipeline is one job, so RunPipeline performs one foreground transaction.
 func (pg *PipelineGroup) registerTerminal(endpoint *TerminalEndpoint) error {
@@ -4368,6 +4712,23 @@ func (state *EvalState) RunPipeline(MShellPipe MShellPipe, context ExecuteContex
 		}
 	}
 
+	if MShellPipe.RunInBackground {
+		// Background stages keep running after this returns, so they must be
+		// external commands rather than quotations evaluated by this shell.
+		for i, item := range MShellPipe.List.Items {
+			asList, ok := item.(*MShellList)
+			if !ok {
+				return state.FailWithMessage(fmt.Sprintf("Item %d (%s) in a background pipe is not a command list.\n", i, item.DebugString())), 1, nil, nil
+			}
+			if len(MShellPipe.List.Items) == 1 {
+				asList.RunInBackground = true
+			}
+		}
+		if MShellPipe.StdoutBehavior != STDOUT_NONE || MShellPipe.StderrBehavior != STDERR_NONE {
+			return state.FailWithMessage("Cannot capture the output of a background pipe.\n"), 1, nil, nil
+		}
+	}
+
 	if len(MShellPipe.List.Items) == 1 {
 		// Just run the Execute on the first item
 		asExecutable, _ := MShellPipe.List.Items[0].(Executable)
@@ -4487,6 +4848,21 @@ func (state *EvalState) RunPipeline(MShellPipe MShellPipe, context ExecuteContex
 	// the formal stream-lifecycle model.
 	pipelineGroup.waitAllStagesLaunched()
 	pgid := pipelineGroup.foregroundPgid(100 * time.Millisecond)
+
+	if MShellPipe.RunInBackground {
+		// The pipeline never takes the terminal. It is reaped on its own
+		// goroutine, and listed in the job table when there is one.
+		job := startJob(pgid, jobCommandText(&MShellPipe), func() int {
+			wg.Wait()
+			pipelineGroup.closeTerminal()
+			return exitCodes[len(exitCodes)-1]
+		})
+		if state.Jobs != nil {
+			state.Jobs.Background(job)
+		}
+		return SimpleSuccess(), 0, nil, nil
+	}
+
 	foregroundLease, foregroundErr := acquireForeground(pipelineGroup.foregroundTerminal(), pgid)
 	if foregroundErr != nil {
 		if pgid > 0 {
@@ -4495,8 +4871,28 @@ func (state *EvalState) RunPipeline(MShellPipe MShellPipe, context ExecuteContex
 		pipelineGroup.killProcesses()
 	}
 
-	// Wait for all processes to complete
-	wg.Wait()
+	// Wait for all processes to complete. As for single commands, the
+	// interactive shell waits on an uncaptured foreground pipeline as a job so
+	// Ctrl-Z can stop it.
+	if foregroundLease != nil && state.Jobs != nil && MShellPipe.StdoutBehavior == STDOUT_NONE && MShellPipe.StderrBehavior == STDERR_NONE {
+		job := startJob(pgid, jobCommandText(&MShellPipe), func() int {
+			wg.Wait()
+			return exitCodes[len(exitCodes)-1]
+		})
+		if !job.waitForeground() {
+			if err := foregroundLease.Release(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: reclaiming pipeline terminal control: %s\n", err)
+			}
+			go func() {
+				<-job.done
+				pipelineGroup.closeTerminal()
+			}()
+			state.Jobs.Suspend(job)
+			return SimpleSuccess(), stoppedExitCode, nil, nil
+		}
+	} else {
+		wg.Wait()
+	}
 
 	var reclaimErr error
 	if foregroundLease != nil {
@@ -6234,6 +6630,84 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 						return state.FailWithMessage(fmt.Sprintf("%d:%d: Error reading from stdin: %s\n", t.Line, t.Column, err.Error()))
 					}
 					stack.Push(MShellString{buffer.String()})
+				} else if t.Lexeme == "assertEq" {
+					result := state.evaluateAssertEq(t, stack)
+					if !result.Success {
+						return result
+					}
+				} else if t.Lexeme == "assertTrue" {
+					result := state.evaluateAssertTrue(t, stack)
+					if !result.Success {
+						return result
+					}
+				} else if t.Lexeme == "assertFails" {
+					result := state.evaluateAssertFails(t, stack, context, definitions)
+					if !result.Success || result.ExitCalled {
+						return result
+					}
+				} else if t.Lexeme == "abbr" {
+					result := state.evaluateAbbr(t, stack)
+					if !result.Success {
+						return result
+					}
+				} else if t.Lexeme == "abbrRemove" {
+					result := state.evaluateAbbrRemove(t, stack)
+					if !result.Success {
+						return result
+					}
+				} else if t.Lexeme == "abbrs" {
+					result := state.evaluateAbbrs(stack)
+					if !result.Success {
+						return result
+					}
+				} else if t.Lexeme == "jobs" {
+					result := state.evaluateJobs(t, context)
+					if !result.Success {
+						return result
+					}
+				} else if t.Lexeme == "fg" {
+					result := state.evaluateFg(t, stack)
+					if !result.Success {
+						return result
+					}
+				} else if t.Lexeme == "bg" {
+					result := state.evaluateBg(t, stack)
+					if !result.Success {
+						return result
+					}
+				} else if t.Lexeme == "parallel" {
+					obj, err := stack.Pop1(t)
+					if err != nil {
+						return state.FailWithMessage(err.Error())
+					}
+
+					maxJobs := runtime.NumCPU()
+					if asInt, ok := obj.(MShellInt); ok {
+						if asInt.Value <= 0 {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: The job limit for 'parallel' must be positive, received %d.\n", t.Line, t.Column, asInt.Value))
+						}
+						maxJobs = asInt.Value
+						obj, err = stack.Pop1(t)
+						if err != nil {
+							return state.FailWithMessage(err.Error())
+						}
+					}
+
+					list, ok := obj.(*MShellList)
+					if !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Expected a list of command lists for 'parallel', received a %s.\n", t.Line, t.Column, obj.TypeName()))
+					}
+
+					for i, item := range list.Items {
+						if _, ok := item.(*MShellList); !ok {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Item %d (%s) for 'parallel' is not a command list.\n", t.Line, t.Column, i, item.DebugString()))
+						}
+					}
+
+					group := NewList(0)
+					group.Items = list.Items
+					group.ParallelJobs = maxJobs
+					stack.Push(group)
 				} else if t.Lexeme == "stdinIsTerminal" {
 					stack.Push(MShellBool{streamIsTerminal(context.StandardInput, os.Stdin)})
 				} else if t.Lexeme == "stdoutIsTerminal" {
@@ -6385,11 +6859,18 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 							return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot write a binary with a newline. You are allowed to write contents directly with `w` or `we`.\n", t.Line, t.Column))
 						}
 						_, _ = writer.Write(topTyped)
+					case *MShellGrid, *MShellGridView:
+						// Grids render as a table, which already ends its last line.
+						if err := renderGridTable(writer, top, terminalTableOptions(writer)); err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: %s\n", t.Line, t.Column, err.Error()))
+						}
 					default:
 						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot write a %s.\n", t.Line, t.Column, top.TypeName()))
 					}
 
-					if t.Lexeme == "wl" || t.Lexeme == "wle" {
+					_, isGrid := top.(*MShellGrid)
+					_, isGridView := top.(*MShellGridView)
+					if (t.Lexeme == "wl" || t.Lexeme == "wle") && !isGrid && !isGridView {
 						fmt.Fprint(writer, "\n")
 					}
 				} else if t.Lexeme == "findReplace" {
@@ -6717,6 +7198,29 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 					if !result.Success {
 						return result
 					}
+				} else if t.Lexeme == "gridPager" {
+					obj, err := stack.Pop()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'gridPager' on an empty stack.\n", t.Line, t.Column))
+					}
+					if _, ok := newGridWriteSource(obj); !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: 'gridPager' expects a Grid or GridView, got a %s.\n", t.Line, t.Column, obj.TypeName()))
+					}
+
+					// Without a terminal to page on, print the whole table.
+					var writer io.Writer = os.Stdout
+					if context.StandardOutput != nil {
+						writer = context.StandardOutput
+					}
+					outFile, isFile := writer.(*os.File)
+					if isFile && term.IsTerminal(int(outFile.Fd())) && term.IsTerminal(int(os.Stdin.Fd())) {
+						err = RunGridPager(obj)
+					} else {
+						err = renderGridTable(writer, obj, gridTableOptions{})
+					}
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s\n", t.Line, t.Column, err.Error()))
+					}
 				} else if t.Lexeme == "mshFileManager" {
 					obj, err := stack.Pop()
 					if err != nil {
@@ -7350,118 +7854,18 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 						return state.FailWithMessage(fmt.Sprintf("%d:%d: The top of stack for 'numFmt' must be a dictionary, found a %s (%s)\n", t.Line, t.Column, optionsObj.TypeName(), optionsObj.DebugString()))
 					}
 
-					var num float64
-					switch n := numberObj.(type) {
-					case MShellInt:
-						num = float64(n.Value)
-					case MShellFloat:
-						num = n.Value
+					switch numberObj.(type) {
+					case MShellInt, MShellFloat:
 					default:
 						return state.FailWithMessage(fmt.Sprintf("%d:%d: The second parameter in 'numFmt' must be numeric, found a %s (%s)\n", t.Line, t.Column, numberObj.TypeName(), numberObj.DebugString()))
 					}
 
-					decimals, hasDecimals, err := getIntOption(optionsDict, "decimals")
-					if err != nil {
-						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s\n", t.Line, t.Column, err.Error()))
-					}
-					sigfigs, hasSigfigs, err := getIntOption(optionsDict, "sigFigs")
-					if err == nil && !hasSigfigs {
-						sigfigs, hasSigfigs, err = getIntOption(optionsDict, "sigfigs")
-					}
-					if err != nil {
-						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s\n", t.Line, t.Column, err.Error()))
-					}
-					preserveInt, _, err := getBoolOption(optionsDict, "preserveInt")
-					if err != nil {
-						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s\n", t.Line, t.Column, err.Error()))
-					}
-					thousandsSep, hasThousandsSep, err := getStringOption(optionsDict, "thousandsSep")
-					if err != nil {
-						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s\n", t.Line, t.Column, err.Error()))
-					}
-					decimalPoint, hasDecimalPoint, err := getStringOption(optionsDict, "decimalPoint")
-					if err != nil {
-						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s\n", t.Line, t.Column, err.Error()))
-					}
-					grouping, hasGrouping, err := getGroupingOption(optionsDict, "grouping")
+					spec, err := parseNumFmtSpec(optionsDict)
 					if err != nil {
 						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s\n", t.Line, t.Column, err.Error()))
 					}
 
-					if hasDecimals && decimals < 0 {
-						return state.FailWithMessage(fmt.Sprintf("%d:%d: 'decimals' in 'numFmt' must be non-negative, got %d\n", t.Line, t.Column, decimals))
-					}
-					if hasSigfigs && sigfigs <= 0 {
-						return state.FailWithMessage(fmt.Sprintf("%d:%d: 'sigfigs' in 'numFmt' must be positive, got %d\n", t.Line, t.Column, sigfigs))
-					}
-
-					if !hasDecimals && !hasSigfigs {
-						sigfigs = 3
-						hasSigfigs = true
-					}
-
-					if !hasDecimalPoint {
-						decimalPoint = "."
-					}
-
-					// I Basically hate all this codex generated code, but it works for now.
-					// Turns out formating a float isn't trivial.
-					// See:
-					// G. L. Steele and J. L. White. How to print floating-point numbers accurately. In PLDI, 1991.
-					// F. Loitsch. Printing floating-point numbers quickly and accurately with integers. In PLDI, 2010.
-					// Andrysco Jhala Lerner - Printing Floating-Point Numbers An Always Correct Method
-					var formatted string
-					if hasDecimals {
-						formatted = fmt.Sprintf("%.*f", decimals, num)
-					} else if hasSigfigs {
-						if preserveInt {
-							if intObj, ok := numberObj.(MShellInt); ok {
-								absStr := fmt.Sprintf("%d", int(math.Abs(float64(intObj.Value))))
-								if len(absStr) > sigfigs {
-									formatted = fmt.Sprintf("%d", intObj.Value)
-								}
-							}
-						}
-						if formatted == "" {
-							formatted = formatWithSigFigs(num, sigfigs)
-						}
-					} else {
-						switch n := numberObj.(type) {
-						case MShellInt:
-							formatted = fmt.Sprintf("%d", n.Value)
-						case MShellFloat:
-							formatted = strconv.FormatFloat(n.Value, 'f', -1, 64)
-						}
-					}
-
-					sign := ""
-					if strings.HasPrefix(formatted, "-") {
-						sign = "-"
-						formatted = formatted[1:]
-					}
-
-					intPart := formatted
-					fracPart := ""
-					if idx := strings.IndexByte(formatted, '.'); idx != -1 {
-						intPart = formatted[:idx]
-						fracPart = formatted[idx+1:]
-					}
-
-					intPart = groupIntPart(intPart, thousandsSep, grouping, hasThousandsSep, hasGrouping)
-
-					if fracPart != "" {
-						formatted = sign + intPart + "." + fracPart
-					} else {
-						formatted = sign + intPart
-					}
-
-					if decimalPoint != "." {
-						if idx := strings.IndexByte(formatted, '.'); idx != -1 {
-							formatted = formatted[:idx] + decimalPoint + formatted[idx+1:]
-						}
-					}
-
-					stack.Push(MShellString{formatted})
+					stack.Push(MShellString{spec.Format(numberObj)})
 				} else if t.Lexeme == "hardLink" {
 					newTarget, err := stack.Pop()
 					if err != nil {
@@ -7650,8 +8054,7 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot get an OLE date from a %s (%s).\n", t.Line, t.Column, obj1.TypeName(), obj1.DebugString()))
 					}
 
-					oleDays := dateTimeObj.Time.In(time.UTC).Sub(oleAutomationEpoch).Hours() / 24
-					stack.Push(MShellFloat{oleDays})
+					stack.Push(MShellFloat{toOleDate(dateTimeObj.Time)})
 				} else if t.Lexeme == "fromUnixTime" || t.Lexeme == "fromUnixTimeMilli" || t.Lexeme == "fromUnixTimeMicro" || t.Lexeme == "fromUnixTimeNano" {
 					obj1, err := stack.Pop()
 					if err != nil {
@@ -7686,9 +8089,7 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot convert a %s (%s) to a datetime.\n", t.Line, t.Column, obj1.TypeName(), obj1.DebugString()))
 					}
 
-					oleDays := obj1.FloatNumeric()
-					newTime := oleAutomationEpoch.Add(time.Duration(oleDays * float64(24*time.Hour)))
-					stack.Push(&MShellDateTime{Time: newTime, OriginalString: ""})
+					stack.Push(&MShellDateTime{Time: fromOleDate(obj1.FloatNumeric()), OriginalString: ""})
 				} else if t.Lexeme == "writeFile" || t.Lexeme == "appendFile" {
 					obj1, obj2, err := stack.Pop2(t)
 					if err != nil {
@@ -8421,8 +8822,34 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 					}
 
 					stack.Push(projectGridAllColumns(sourceGrid, permutation))
-				} else if t.Lexeme == "reverse" {
-					obj, err := stack.Pop()
+				} else if t.Lexeme == "gridSort" {
+					obj1, obj2, err := stack.Pop2(t)
+					if err != nil {
+						return state.FailWithMessage(err.Error())
+					}
+
+					specList, ok := obj1.(*MShellList)
+					if !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: gridSort requires a list of sort specs, got %s.\n", t.Line, t.Column, obj1.TypeName()))
+					}
+
+					sourceGrid, sourceIndices, err := getGridSourceAndIndices(obj2)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: gridSort requires a Grid or GridView, got %s.\n", t.Line, t.Column, obj2.TypeName()))
+					}
+
+					keys, err := parseGridSortKeys(specList, sourceGrid)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+					}
+
+					newGrid, err := sortGridByKeys(sourceGrid, sourceIndices, keys)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s\n", t.Line, t.Column, err.Error()))
+					}
+					stack.Push(newGrid)
+				} else if t.Lexeme == "reverse" {
+					obj, err := stack.Pop()
 					if err != nil {
 						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'reverse' operation on an empty stack.\n", t.Line, t.Column))
 					}
@@ -9066,6 +9493,110 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 					grid.Columns[colIdx] = newCol
 					grid.ColIndex[colName] = colIdx
 					stack.Push(grid)
+				} else if (t.Lexeme == "select" || t.Lexeme == "exclude" || t.Lexeme == "filter") && gridScanAt(stack, 2) {
+					// Projections and filters on a GridScan only extend its plan.
+					obj1, obj2, err := stack.Pop2(t)
+					if err != nil {
+						return state.FailWithMessage(err.Error())
+					}
+					scan := obj2.(*MShellGridScan)
+
+					if t.Lexeme == "filter" {
+						quote, ok := obj1.(*MShellQuotation)
+						if !ok {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: filter requires a quotation, got %s.\n", t.Line, t.Column, obj1.TypeName()))
+						}
+						stack.Push(scan.withStep(gridScanStep{quote: quote}))
+						return SimpleSuccess()
+					}
+
+					colList, ok := obj1.(*MShellList)
+					if !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s requires a list of column names, got %s.\n", t.Line, t.Column, t.Lexeme, obj1.TypeName()))
+					}
+					listed := make(map[string]struct{}, len(colList.Items))
+					var colNames []string
+					for _, item := range colList.Items {
+						colName, err := item.CastString()
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: %s column names must be strings, got %s.\n", t.Line, t.Column, t.Lexeme, item.TypeName()))
+						}
+						if _, exists := listed[colName]; exists {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: %s column '%s' was requested more than once.\n", t.Line, t.Column, t.Lexeme, colName))
+						}
+						if !scan.HasColumn(colName) {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Column '%s' not found in scan.\n", t.Line, t.Column, colName))
+						}
+						listed[colName] = struct{}{}
+						colNames = append(colNames, colName)
+					}
+					if t.Lexeme == "exclude" {
+						colNames = nil
+						for _, colName := range scan.Columns() {
+							if _, excluded := listed[colName]; !excluded {
+								colNames = append(colNames, colName)
+							}
+						}
+					}
+					stack.Push(scan.withStep(gridScanStep{cols: colNames}))
+				} else if t.Lexeme == "groupBy" && gridScanAt(stack, 3) {
+					obj1, obj2, obj3, err := stack.Pop3(t)
+					if err != nil {
+						return state.FailWithMessage(err.Error())
+					}
+					scan := obj3.(*MShellGridScan)
+
+					aggList, ok := obj1.(*MShellList)
+					if !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: grid groupBy requires a list of aggregation specs, got %s.\n", t.Line, t.Column, obj1.TypeName()))
+					}
+					keyList, ok := obj2.(*MShellList)
+					if !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: grid groupBy requires a list of key column names, got %s.\n", t.Line, t.Column, obj2.TypeName()))
+					}
+
+					keyCols := make([]string, len(keyList.Items))
+					outputNames := make(map[string]struct{}, len(keyList.Items)+len(aggList.Items))
+					for i, item := range keyList.Items {
+						colName, err := item.CastString()
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: groupBy key column names must be strings, got %s.\n", t.Line, t.Column, item.TypeName()))
+						}
+						if _, exists := outputNames[colName]; exists {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: groupBy key column '%s' was requested more than once.\n", t.Line, t.Column, colName))
+						}
+						if !scan.HasColumn(colName) {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Column '%s' not found in scan.\n", t.Line, t.Column, colName))
+						}
+						outputNames[colName] = struct{}{}
+						keyCols[i] = colName
+					}
+
+					aggSpecs, err := parseGridGroupByAggSpecs(aggList)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+					}
+					for i, aggSpec := range aggSpecs {
+						if aggSpec.Quote != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: groupBy on a GridScan needs named aggregations like { \"agg\": \"sum\", \"col\": ... }, but spec %d has a quotation. Use collect first to aggregate with quotations.\n", t.Line, t.Column, i+1))
+						}
+						if aggSpec.Col != "" && !scan.HasColumn(aggSpec.Col) {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Column '%s' not found in scan.\n", t.Line, t.Column, aggSpec.Col))
+						}
+						if _, exists := outputNames[aggSpec.Name]; exists {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: groupBy output column '%s' is duplicated or conflicts with a key column.\n", t.Line, t.Column, aggSpec.Name))
+						}
+						outputNames[aggSpec.Name] = struct{}{}
+					}
+
+					var stopped EvalResult
+					newGrid, err := groupByGridScan(scan, keyCols, aggSpecs, state.gridScanFilter(t, context, definitions, &stopped))
+					if errors.Is(err, errGridScanStopped) {
+						return stopped
+					} else if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Error scanning %s: %s.\n", t.Line, t.Column, scan.Path, err.Error()))
+					}
+					stack.Push(newGrid)
 				} else if t.Lexeme == "select" {
 					obj1, obj2, err := stack.Pop2(t)
 					if err != nil {
@@ -9296,6 +9827,11 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 					if err != nil {
 						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
 					}
+					for i, aggSpec := range aggSpecs {
+						if aggSpec.Quote == nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: groupBy aggregation spec %d key 'agg' must be a quotation, got str. Named aggregations apply to a GridScan.\n", t.Line, t.Column, i+1))
+						}
+					}
 
 					outputNames := make(map[string]struct{}, len(keyCols)+len(aggSpecs))
 					for _, keyCol := range keyCols {
@@ -9375,6 +9911,405 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 						optimizeColumnStorage(col)
 					}
 
+					stack.Push(newGrid)
+				} else if t.Lexeme == "unpivot" {
+					obj1, obj2, obj3, obj4, err := stack.Pop4(t)
+					if err != nil {
+						return state.FailWithMessage(err.Error())
+					}
+
+					valueCol, err := obj1.CastString()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: unpivot value column name must be a string, got %s.\n", t.Line, t.Column, obj1.TypeName()))
+					}
+
+					nameCol, err := obj2.CastString()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: unpivot name column name must be a string, got %s.\n", t.Line, t.Column, obj2.TypeName()))
+					}
+
+					idList, ok := obj3.(*MShellList)
+					if !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: unpivot requires a list of id column names, got %s.\n", t.Line, t.Column, obj3.TypeName()))
+					}
+
+					sourceGrid, sourceIndices, err := getGridSourceAndIndices(obj4)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: unpivot requires a Grid or GridView, got %s.\n", t.Line, t.Column, obj4.TypeName()))
+					}
+
+					idCols := make([]string, len(idList.Items))
+					for i, item := range idList.Items {
+						colName, err := item.CastString()
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: unpivot id column names must be strings, got %s.\n", t.Line, t.Column, item.TypeName()))
+						}
+						idCols[i] = colName
+					}
+
+					newGrid, err := unpivotGrid(sourceGrid, sourceIndices, idCols, nameCol, valueCol)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+					}
+					stack.Push(newGrid)
+				} else if t.Lexeme == "window" {
+					obj1, obj2, obj3, obj4, err := stack.Pop4(t)
+					if err != nil {
+						return state.FailWithMessage(err.Error())
+					}
+
+					specDict, ok := obj1.(*MShellDict)
+					if !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: window requires a dictionary of window specs, got %s.\n", t.Line, t.Column, obj1.TypeName()))
+					}
+
+					sortList, ok := obj2.(*MShellList)
+					if !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: window requires a list of sort column names, got %s.\n", t.Line, t.Column, obj2.TypeName()))
+					}
+
+					partList, ok := obj3.(*MShellList)
+					if !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: window requires a list of partition column names, got %s.\n", t.Line, t.Column, obj3.TypeName()))
+					}
+
+					sourceGrid, sourceIndices, err := getGridSourceAndIndices(obj4)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: window requires a Grid or GridView, got %s.\n", t.Line, t.Column, obj4.TypeName()))
+					}
+
+					colLists := [2][]string{}
+					for listIdx, list := range []*MShellList{partList, sortList} {
+						kind := "partition"
+						if listIdx == 1 {
+							kind = "sort"
+						}
+						seen := make(map[string]struct{}, len(list.Items))
+						for _, item := range list.Items {
+							colName, err := item.CastString()
+							if err != nil {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: window %s column names must be strings, got %s.\n", t.Line, t.Column, kind, item.TypeName()))
+							}
+							if _, exists := seen[colName]; exists {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: window %s column '%s' was requested more than once.\n", t.Line, t.Column, kind, colName))
+							}
+							if sourceGrid.GetColumn(colName) == nil {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: Column '%s' not found in grid.\n", t.Line, t.Column, colName))
+							}
+							seen[colName] = struct{}{}
+							colLists[listIdx] = append(colLists[listIdx], colName)
+						}
+					}
+
+					specs, err := parseGridWindowSpecs(specDict, sourceGrid)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+					}
+
+					newGrid, err := computeGridWindow(sourceGrid, sourceIndices, colLists[0], colLists[1], specs)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+					}
+					stack.Push(newGrid)
+				} else if t.Lexeme == "fillNull" || t.Lexeme == "dropNull" {
+					var fill gridNullFill
+					if t.Lexeme == "fillNull" {
+						obj1, err := stack.Pop()
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'fillNull' operation on an empty stack.\n", t.Line, t.Column))
+						}
+						optsDict, ok := obj1.(*MShellDict)
+						if !ok {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: fillNull requires an options dictionary, got %s.\n", t.Line, t.Column, obj1.TypeName()))
+						}
+						fill, err = parseGridNullFill(optsDict)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+						}
+					}
+
+					obj, err := stack.Pop()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do '%s' operation on a stack without a Grid or GridView.\n", t.Line, t.Column, t.Lexeme))
+					}
+					colList, hasColList := obj.(*MShellList)
+					if hasColList {
+						obj, err = stack.Pop()
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do '%s' operation on a stack without a Grid or GridView.\n", t.Line, t.Column, t.Lexeme))
+						}
+					}
+
+					sourceGrid, sourceIndices, err := getGridSourceAndIndices(obj)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s requires a Grid or GridView, got %s.\n", t.Line, t.Column, t.Lexeme, obj.TypeName()))
+					}
+
+					var colNames []string
+					if hasColList {
+						colNames = make([]string, len(colList.Items))
+						for i, item := range colList.Items {
+							colName, err := item.CastString()
+							if err != nil {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: %s column names must be strings, got %s.\n", t.Line, t.Column, t.Lexeme, item.TypeName()))
+							}
+							if sourceGrid.GetColumn(colName) == nil {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: Column '%s' not found in grid.\n", t.Line, t.Column, colName))
+							}
+							colNames[i] = colName
+						}
+					} else {
+						colNames = make([]string, len(sourceGrid.Columns))
+						for i, col := range sourceGrid.Columns {
+							colNames[i] = col.Name
+						}
+					}
+
+					if t.Lexeme == "dropNull" {
+						stack.Push(&MShellGridView{Source: sourceGrid, Indices: dropGridNullRows(sourceGrid, sourceIndices, colNames)})
+					} else {
+						newGrid, err := fillGridNulls(sourceGrid, sourceIndices, colNames, fill)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+						}
+						stack.Push(newGrid)
+					}
+				} else if t.Lexeme == "isNullCol" {
+					obj1, obj2, err := stack.Pop2(t)
+					if err != nil {
+						return state.FailWithMessage(err.Error())
+					}
+
+					colName, err := obj1.CastString()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: isNullCol column name must be a string, got %s.\n", t.Line, t.Column, obj1.TypeName()))
+					}
+
+					sourceGrid, sourceIndices, err := getGridSourceAndIndices(obj2)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: isNullCol requires a Grid or GridView, got %s.\n", t.Line, t.Column, obj2.TypeName()))
+					}
+
+					col := sourceGrid.GetColumn(colName)
+					if col == nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Column '%s' not found in grid.\n", t.Line, t.Column, colName))
+					}
+
+					flags := NewList(len(sourceIndices))
+					for i, srcIdx := range sourceIndices {
+						flags.Items[i] = MShellBool{Value: gridCellIsNull(col, srcIdx)}
+					}
+					stack.Push(flags)
+				} else if t.Lexeme == "nullCount" {
+					obj1, err := stack.Pop()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'nullCount' operation on an empty stack.\n", t.Line, t.Column))
+					}
+
+					sourceGrid, sourceIndices, err := getGridSourceAndIndices(obj1)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: nullCount requires a Grid or GridView, got %s.\n", t.Line, t.Column, obj1.TypeName()))
+					}
+
+					counts := NewDict()
+					for _, col := range sourceGrid.Columns {
+						count := 0
+						for _, srcIdx := range sourceIndices {
+							if gridCellIsNull(col, srcIdx) {
+								count++
+							}
+						}
+						counts.Items[col.Name] = MShellInt{Value: count}
+					}
+					stack.Push(counts)
+				} else if t.Lexeme == "describe" {
+					obj1, err := stack.Pop()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'describe' operation on an empty stack.\n", t.Line, t.Column))
+					}
+
+					sourceGrid, sourceIndices, err := getGridSourceAndIndices(obj1)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: describe requires a Grid or GridView, got %s.\n", t.Line, t.Column, obj1.TypeName()))
+					}
+					stack.Push(describeGrid(sourceGrid, sourceIndices))
+				} else if t.Lexeme == "quantile" {
+					obj1, obj2, obj3, err := stack.Pop3(t)
+					if err != nil {
+						return state.FailWithMessage(err.Error())
+					}
+
+					var q float64
+					switch qTyped := obj1.(type) {
+					case MShellFloat:
+						q = qTyped.Value
+					case MShellInt:
+						q = float64(qTyped.Value)
+					default:
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: quantile requires a float between 0 and 1, got %s.\n", t.Line, t.Column, obj1.TypeName()))
+					}
+
+					colName, err := obj2.CastString()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: quantile column name must be a string, got %s.\n", t.Line, t.Column, obj2.TypeName()))
+					}
+
+					sourceGrid, sourceIndices, err := getGridSourceAndIndices(obj3)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: quantile requires a Grid or GridView, got %s.\n", t.Line, t.Column, obj3.TypeName()))
+					}
+
+					col := sourceGrid.GetColumn(colName)
+					if col == nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Column '%s' not found in grid.\n", t.Line, t.Column, colName))
+					}
+
+					value, err := gridQuantile(col, sourceIndices, q)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+					}
+					stack.Push(MShellFloat{Value: value})
+				} else if t.Lexeme == "corr" {
+					obj1, obj2, obj3, err := stack.Pop3(t)
+					if err != nil {
+						return state.FailWithMessage(err.Error())
+					}
+
+					sourceGrid, sourceIndices, err := getGridSourceAndIndices(obj3)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: corr requires a Grid or GridView, got %s.\n", t.Line, t.Column, obj3.TypeName()))
+					}
+
+					cols := [2]*GridColumn{}
+					for i, obj := range []MShellObject{obj2, obj1} {
+						colName, err := obj.CastString()
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: corr column names must be strings, got %s.\n", t.Line, t.Column, obj.TypeName()))
+						}
+						cols[i] = sourceGrid.GetColumn(colName)
+						if cols[i] == nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Column '%s' not found in grid.\n", t.Line, t.Column, colName))
+						}
+					}
+
+					value, err := gridCorr(cols[0], cols[1], sourceIndices)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+					}
+					stack.Push(MShellFloat{Value: value})
+				} else if t.Lexeme == "histogram" {
+					obj1, obj2, obj3, err := stack.Pop3(t)
+					if err != nil {
+						return state.FailWithMessage(err.Error())
+					}
+
+					colName, err := obj2.CastString()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: histogram column name must be a string, got %s.\n", t.Line, t.Column, obj2.TypeName()))
+					}
+
+					sourceGrid, sourceIndices, err := getGridSourceAndIndices(obj3)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: histogram requires a Grid or GridView, got %s.\n", t.Line, t.Column, obj3.TypeName()))
+					}
+
+					col := sourceGrid.GetColumn(colName)
+					if col == nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Column '%s' not found in grid.\n", t.Line, t.Column, colName))
+					}
+
+					var edges []float64
+					switch binsTyped := obj1.(type) {
+					case MShellInt:
+						edges, err = gridHistogramEdges(col, sourceIndices, binsTyped.Value)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+						}
+					case *MShellList:
+						edges = make([]float64, len(binsTyped.Items))
+						for i, item := range binsTyped.Items {
+							switch edge := item.(type) {
+							case MShellFloat:
+								edges[i] = edge.Value
+							case MShellInt:
+								edges[i] = float64(edge.Value)
+							default:
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: histogram bin edges must be numbers, got %s.\n", t.Line, t.Column, item.TypeName()))
+							}
+						}
+					default:
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: histogram requires a bin count or a list of bin edges, got %s.\n", t.Line, t.Column, obj1.TypeName()))
+					}
+
+					hist, err := gridHistogram(col, sourceIndices, edges)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+					}
+					stack.Push(hist)
+				} else if t.Lexeme == "resample" {
+					obj1, err := stack.Pop()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'resample' operation on an empty stack.\n", t.Line, t.Column))
+					}
+					aggDict, ok := obj1.(*MShellDict)
+					if !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: resample requires an aggregation dictionary, got %s.\n", t.Line, t.Column, obj1.TypeName()))
+					}
+
+					// An options dictionary may follow the aggregations.
+					fillGaps := false
+					obj2, obj3, obj4, err := stack.Pop3(t)
+					if err != nil {
+						return state.FailWithMessage(err.Error())
+					}
+					if aggsBelow, ok := obj2.(*MShellDict); ok {
+						fillGaps, _, err = boolOption(aggDict, "fillGaps")
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+						}
+						for key := range aggDict.Items {
+							if key != "fillGaps" {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: Unknown resample option '%s'.\n", t.Line, t.Column, key))
+							}
+						}
+						aggDict = aggsBelow
+						obj2 = obj3
+						obj3 = obj4
+						obj4, err = stack.Pop()
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'resample' operation on a stack without a Grid or GridView.\n", t.Line, t.Column))
+						}
+					}
+
+					intervalStr, err := obj2.CastString()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: resample interval must be a string, got %s.\n", t.Line, t.Column, obj2.TypeName()))
+					}
+					dtCol, err := obj3.CastString()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: resample datetime column name must be a string, got %s.\n", t.Line, t.Column, obj3.TypeName()))
+					}
+					sourceGrid, sourceIndices, err := getGridSourceAndIndices(obj4)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: resample requires a Grid or GridView, got %s.\n", t.Line, t.Column, obj4.TypeName()))
+					}
+
+					iv, err := parseTimeInterval(intervalStr)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+					}
+					if sourceGrid.GetColumn(dtCol) == nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Column '%s' not found in grid.\n", t.Line, t.Column, dtCol))
+					}
+					aggs, err := parseGridResampleAggs(aggDict, sourceGrid, dtCol)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+					}
+
+					newGrid, err := resampleGrid(sourceGrid, sourceIndices, dtCol, iv, aggs, fillGaps)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+					}
 					stack.Push(newGrid)
 				} else if t.Lexeme == "pivot" {
 					if len(*stack) < 4 {
@@ -9541,6 +10476,82 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 					if result.ShouldPassResultUpStack() {
 						return result
 					}
+				} else if t.Lexeme == "semiJoin" {
+					result := state.executeGridJoin(t, stack, joinSemi, context, definitions)
+					if result.ShouldPassResultUpStack() {
+						return result
+					}
+				} else if t.Lexeme == "antiJoin" {
+					result := state.executeGridJoin(t, stack, joinAnti, context, definitions)
+					if result.ShouldPassResultUpStack() {
+						return result
+					}
+				} else if t.Lexeme == "distinct" {
+					obj, err := stack.Pop()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'distinct' operation on an empty stack.\n", t.Line, t.Column))
+					}
+					colList, hasColList := obj.(*MShellList)
+					if hasColList {
+						obj, err = stack.Pop()
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'distinct' operation on a stack without a Grid or GridView.\n", t.Line, t.Column))
+						}
+					}
+
+					sourceGrid, sourceIndices, err := getGridSourceAndIndices(obj)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: distinct requires a Grid or GridView, got %s.\n", t.Line, t.Column, obj.TypeName()))
+					}
+
+					colNames := gridColumnNames(sourceGrid)
+					if hasColList {
+						colNames = make([]string, len(colList.Items))
+						for i, item := range colList.Items {
+							colName, err := item.CastString()
+							if err != nil {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: distinct column names must be strings, got %s.\n", t.Line, t.Column, item.TypeName()))
+							}
+							if sourceGrid.GetColumn(colName) == nil {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: Column '%s' not found in grid.\n", t.Line, t.Column, colName))
+							}
+							colNames[i] = colName
+						}
+					}
+
+					indices, err := distinctGridRows(sourceGrid, sourceIndices, colNames)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+					}
+					stack.Push(&MShellGridView{Source: sourceGrid, Indices: indices})
+				} else if t.Lexeme == "except" || t.Lexeme == "intersect" {
+					obj1, obj2, err := stack.Pop2(t)
+					if err != nil {
+						return state.FailWithMessage(err.Error())
+					}
+
+					leftGrid, leftIndices, err := getGridSourceAndIndices(obj2)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s requires a Grid or GridView for the left side, got %s.\n", t.Line, t.Column, t.Lexeme, obj2.TypeName()))
+					}
+					rightGrid, rightIndices, err := getGridSourceAndIndices(obj1)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s requires a Grid or GridView for the right side, got %s.\n", t.Line, t.Column, t.Lexeme, obj1.TypeName()))
+					}
+
+					op := "subtract"
+					if t.Lexeme == "intersect" {
+						op = "intersect"
+					}
+					if err := validateGridSchemaMatch(op, leftGrid, rightGrid); err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s", t.Line, t.Column, err.Error()))
+					}
+
+					indices, err := gridSetOperation(leftGrid, leftIndices, rightGrid, rightIndices, t.Lexeme == "intersect")
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+					}
+					stack.Push(&MShellGridView{Source: leftGrid, Indices: indices})
 				} else if t.Lexeme == "reMatch" {
 					// Match a regex against a string
 					obj1, obj2, err := stack.Pop2(t)
@@ -9670,69 +10681,238 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot use a %s as a regex.\n", t.Line, t.Column, obj1.TypeName()))
 					}
 
-					str, err := obj2.CastString()
+					str, err := obj2.CastString()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot use a %s as a string to split.\n", t.Line, t.Column, obj2.TypeName()))
+					}
+
+					re, err := regexp.Compile(regexStr)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Error compiling regex '%s': %s\n", t.Line, t.Column, regexStr, err.Error()))
+					}
+
+					parts := re.Split(str, -1)
+					resultList := NewList(len(parts))
+					for i, part := range parts {
+						resultList.Items[i] = MShellString{part}
+					}
+
+					stack.Push(resultList)
+				} else if t.Lexeme == "parseCsv" {
+					obj1, err := stack.Pop()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'parseCsv' operation on an empty stack.\n", t.Line, t.Column))
+					}
+
+					// If a path or literal, read the file as UTF-8. Else, read the string as the contents directly.
+					var reader *csv.Reader
+					var file *os.File
+					switch obj1Typed := obj1.(type) {
+					case MShellPath, MShellLiteral:
+						path, _ := obj1.CastString()
+						file, err = os.Open(path)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Error opening file %s: %s\n", t.Line, t.Column, path, err.Error()))
+						}
+						reader = csv.NewReader(file)
+					case MShellString:
+						// Create a new CSV reader directly from the string contents
+						reader = csv.NewReader(strings.NewReader(obj1Typed.Content))
+					}
+					reader.FieldsPerRecord = -1
+
+					// Create a new list and add the records to it
+					newOuterList := NewList(0)
+
+					// TODO: They are going to force me to roll my own CSV parser.
+					// For now, going to have to deal with the skipped empty lines.
+					for {
+						record, err := reader.Read()
+						if err == io.EOF {
+							break
+						} else if err != nil {
+							file.Close()
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Error reading CSV: %s\n", t.Line, t.Column, err.Error()))
+						}
+						// Turn record into MShellList of strings
+						newInnerList := NewList(len(record))
+						for i, val := range record {
+							newInnerList.Items[i] = MShellString{val}
+						}
+
+						newOuterList.Items = append(newOuterList.Items, newInnerList)
+					}
+					stack.Push(newOuterList)
+					file.Close()
+				} else if t.Lexeme == "readCsvGrid" {
+					obj1, err := stack.Pop()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'readCsvGrid' operation on an empty stack.\n", t.Line, t.Column))
+					}
+
+					options := defaultCsvGridOptions()
+					if optionsDict, ok := obj1.(*MShellDict); ok {
+						options, err = parseCsvGridOptions(optionsDict)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: readCsvGrid: %s\n", t.Line, t.Column, err.Error()))
+						}
+						obj1, err = stack.Pop()
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'readCsvGrid' operation on a stack with only one item.\n", t.Line, t.Column))
+						}
+					}
+
+					// If a path or literal, stream the file. Else, read the string as the contents directly.
+					var reader io.Reader
+					var file *os.File
+					switch obj1Typed := obj1.(type) {
+					case MShellPath, MShellLiteral:
+						path, _ := obj1.CastString()
+						file, err = os.Open(path)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Error opening file %s: %s\n", t.Line, t.Column, path, err.Error()))
+						}
+						reader = file
+					case MShellString:
+						reader = strings.NewReader(obj1Typed.Content)
+					default:
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: 'readCsvGrid' expects a path or string, got a %s.\n", t.Line, t.Column, obj1.TypeName()))
+					}
+
+					grid, err := readCsvGrid(reader, options)
+					if file != nil {
+						file.Close()
+					}
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Error reading CSV: %s\n", t.Line, t.Column, err.Error()))
+					}
+					stack.Push(grid)
+				} else if t.Lexeme == "scanCsv" {
+					obj1, err := stack.Pop()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'scanCsv' operation on an empty stack.\n", t.Line, t.Column))
+					}
+
+					options := defaultCsvGridOptions()
+					if optionsDict, ok := obj1.(*MShellDict); ok {
+						options, err = parseCsvGridOptions(optionsDict)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: scanCsv: %s\n", t.Line, t.Column, err.Error()))
+						}
+						obj1, err = stack.Pop()
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'scanCsv' operation on a stack with only one item.\n", t.Line, t.Column))
+						}
+					}
+
+					switch obj1.(type) {
+					case MShellPath, MShellLiteral:
+					default:
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: 'scanCsv' expects a path, got a %s.\n", t.Line, t.Column, obj1.TypeName()))
+					}
+					path, _ := obj1.CastString()
+					scan, err := newGridScan(path, options)
 					if err != nil {
-						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot use a %s as a string to split.\n", t.Line, t.Column, obj2.TypeName()))
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Error scanning CSV: %s\n", t.Line, t.Column, err.Error()))
 					}
-
-					re, err := regexp.Compile(regexStr)
+					stack.Push(scan)
+				} else if t.Lexeme == "collect" {
+					obj1, err := stack.Pop()
 					if err != nil {
-						return state.FailWithMessage(fmt.Sprintf("%d:%d: Error compiling regex '%s': %s\n", t.Line, t.Column, regexStr, err.Error()))
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'collect' operation on an empty stack.\n", t.Line, t.Column))
 					}
-
-					parts := re.Split(str, -1)
-					resultList := NewList(len(parts))
-					for i, part := range parts {
-						resultList.Items[i] = MShellString{part}
+					scan, ok := obj1.(*MShellGridScan)
+					if !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: collect requires a GridScan, got %s.\n", t.Line, t.Column, obj1.TypeName()))
 					}
 
-					stack.Push(resultList)
-				} else if t.Lexeme == "parseCsv" {
+					var stopped EvalResult
+					grid, err := collectGridScan(scan, state.gridScanFilter(t, context, definitions, &stopped))
+					if errors.Is(err, errGridScanStopped) {
+						return stopped
+					} else if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Error scanning %s: %s.\n", t.Line, t.Column, scan.Path, err.Error()))
+					}
+					stack.Push(grid)
+				} else if t.Lexeme == "toCsv" || t.Lexeme == "toTsv" || t.Lexeme == "toJsonLines" || t.Lexeme == "toMarkdownTable" {
 					obj1, err := stack.Pop()
 					if err != nil {
-						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'parseCsv' operation on an empty stack.\n", t.Line, t.Column))
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do '%s' operation on an empty stack.\n", t.Line, t.Column, t.Lexeme))
 					}
 
-					// If a path or literal, read the file as UTF-8. Else, read the string as the contents directly.
-					var reader *csv.Reader
-					var file *os.File
-					switch obj1Typed := obj1.(type) {
-					case MShellPath, MShellLiteral:
-						path, _ := obj1.CastString()
-						file, err = os.Open(path)
+					// The list-of-rows form of toCsv, kept from the standard library version.
+					if rows, ok := obj1.(*MShellList); ok && t.Lexeme == "toCsv" {
+						csvText, err := writeStringRowsCsv(rows)
 						if err != nil {
-							return state.FailWithMessage(fmt.Sprintf("%d:%d: Error opening file %s: %s\n", t.Line, t.Column, path, err.Error()))
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: toCsv: %s.\n", t.Line, t.Column, err.Error()))
+						}
+						stack.Push(MShellString{csvText})
+					} else {
+						writer := gridWriters[t.Lexeme]
+						options := writer.defaults()
+						optionsDict, hasOptions := obj1.(*MShellDict)
+						if hasOptions {
+							options, err = parseGridWriteOptions(optionsDict, options)
+							if err != nil {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: %s: %s\n", t.Line, t.Column, t.Lexeme, err.Error()))
+							}
+							obj1, err = stack.Pop()
+							if err != nil {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do '%s' operation on a stack with only one item.\n", t.Line, t.Column, t.Lexeme))
+							}
 						}
-						reader = csv.NewReader(file)
-					case MShellString:
-						// Create a new CSV reader directly from the string contents
-						reader = csv.NewReader(strings.NewReader(obj1Typed.Content))
-					}
-					reader.FieldsPerRecord = -1
-
-					// Create a new list and add the records to it
-					newOuterList := NewList(0)
 
-					// TODO: They are going to force me to roll my own CSV parser.
-					// For now, going to have to deal with the skipped empty lines.
-					for {
-						record, err := reader.Read()
-						if err == io.EOF {
-							break
-						} else if err != nil {
-							file.Close()
-							return state.FailWithMessage(fmt.Sprintf("%d:%d: Error reading CSV: %s\n", t.Line, t.Column, err.Error()))
+						// A path target streams the output to that file, or to standard output for '-'.
+						outPath, hasOutPath := obj1.(MShellPath)
+						if hasOutPath {
+							obj1, err = stack.Pop()
+							if err != nil {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: '%s' expects a Grid below the output path.\n", t.Line, t.Column, t.Lexeme))
+							}
 						}
-						// Turn record into MShellList of strings
-						newInnerList := NewList(len(record))
-						for i, val := range record {
-							newInnerList.Items[i] = MShellString{val}
+
+						var write func(w io.Writer) error
+						if list, ok := obj1.(*MShellList); ok && t.Lexeme == "toJsonLines" {
+							// A list writes each item as one line of JSON.
+							if hasOptions {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: toJsonLines: Options apply only to a Grid or GridView.\n", t.Line, t.Column))
+							}
+							write = func(w io.Writer) error { return writeJsonLinesList(w, list) }
+						} else {
+							source, ok := newGridWriteSource(obj1)
+							if !ok {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: '%s' expects a Grid or GridView, got a %s.\n", t.Line, t.Column, t.Lexeme, obj1.TypeName()))
+							}
+							write = func(w io.Writer) error { return writer.write(w, source, options) }
 						}
 
-						newOuterList.Items = append(newOuterList.Items, newInnerList)
+						if !hasOutPath {
+							var sb strings.Builder
+							_ = write(&sb)
+							stack.Push(MShellString{sb.String()})
+						} else if outPath.Path == "-" {
+							var out io.Writer = os.Stdout
+							if context.StandardOutput != nil {
+								out = context.StandardOutput
+							}
+							if err := write(out); err != nil {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: Error writing to standard output: %s\n", t.Line, t.Column, err.Error()))
+							}
+						} else {
+							file, err := os.Create(outPath.Path)
+							if err != nil {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: Error opening file %s: %s\n", t.Line, t.Column, outPath.Path, err.Error()))
+							}
+							err = write(file)
+							closeErr := file.Close()
+							if err == nil {
+								err = closeErr
+							}
+							if err != nil {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: Error writing to file %s: %s\n", t.Line, t.Column, outPath.Path, err.Error()))
+							}
+						}
 					}
-					stack.Push(newOuterList)
-					file.Close()
 				} else if t.Lexeme == "toGrid" {
 					obj, err := stack.Pop()
 					if err != nil {
@@ -9794,10 +10974,135 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 					// Convert the parsed data to analgous MShell types
 					resultObj := ParseJsonObjToMshell(parsedData)
 					stack.Push(resultObj)
-				} else if t.Lexeme == "parseExcel" {
+				} else if t.Lexeme == "parseJsonLines" || t.Lexeme == "readJsonLinesGrid" {
+					obj1, err := stack.Pop()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do '%s' operation on an empty stack.\n", t.Line, t.Column, t.Lexeme))
+					}
+
+					options := jsonLinesGridOptions{maxRows: -1}
+					if optionsDict, ok := obj1.(*MShellDict); ok && t.Lexeme == "readJsonLinesGrid" {
+						options, err = parseJsonLinesGridOptions(optionsDict)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: readJsonLinesGrid: %s\n", t.Line, t.Column, err.Error()))
+						}
+						obj1, err = stack.Pop()
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'readJsonLinesGrid' operation on a stack with only one item.\n", t.Line, t.Column))
+						}
+					}
+
+					// A path streams the file, or standard input for '-'. Else, read the string or bytes as the contents directly.
+					var reader io.Reader
+					var file *os.File
+					switch obj1Typed := obj1.(type) {
+					case MShellPath, MShellLiteral:
+						path, _ := obj1.CastString()
+						if path == "-" {
+							reader = os.Stdin
+							if context.StandardInput != nil {
+								reader = context.StandardInput
+							}
+						} else {
+							file, err = os.Open(path)
+							if err != nil {
+								return state.FailWithMessage(fmt.Sprintf("%d:%d: Error opening file %s: %s\n", t.Line, t.Column, path, err.Error()))
+							}
+							reader = file
+						}
+					case MShellString:
+						reader = strings.NewReader(obj1Typed.Content)
+					case MShellBinary:
+						reader = bytes.NewReader(obj1Typed)
+					default:
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot parse a %s as JSON Lines.\n", t.Line, t.Column, obj1.TypeName()))
+					}
+
+					var result MShellObject
+					if t.Lexeme == "parseJsonLines" {
+						result, err = parseJsonLines(reader)
+					} else {
+						result, err = readJsonLinesGrid(reader, options)
+					}
+					if file != nil {
+						file.Close()
+					}
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Error parsing JSON Lines: %s\n", t.Line, t.Column, err.Error()))
+					}
+					stack.Push(result)
+				} else if t.Lexeme == "parseYaml" || t.Lexeme == "parseToml" {
+					format := "YAML"
+					if t.Lexeme == "parseToml" {
+						format = "TOML"
+					}
+					obj1, err := stack.Pop()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do '%s' operation on an empty stack.\n", t.Line, t.Column, t.Lexeme))
+					}
+
+					var text string
+					switch obj1Typed := obj1.(type) {
+					case MShellPath, MShellLiteral:
+						path, _ := obj1.CastString()
+						data, err := os.ReadFile(path)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Error reading file %s: %s\n", t.Line, t.Column, path, err.Error()))
+						}
+						text = string(data)
+					case MShellString:
+						text = obj1Typed.Content
+					case MShellBinary:
+						if !utf8.Valid(obj1Typed) {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Error parsing %s: input is not valid UTF-8.\n", t.Line, t.Column, format))
+						}
+						text = string(obj1Typed)
+					default:
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot parse a %s as %s.\n", t.Line, t.Column, obj1.TypeName(), format))
+					}
+
+					if t.Lexeme == "parseToml" {
+						dict, err := parseToml(text)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Error parsing TOML: %s\n", t.Line, t.Column, err.Error()))
+						}
+						stack.Push(dict)
+					} else {
+						docs, err := parseYamlDocuments(text)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Error parsing YAML: %s\n", t.Line, t.Column, err.Error()))
+						}
+						// A stream of several documents becomes a list of them.
+						switch len(docs) {
+						case 0:
+							stack.Push(MShellNull{})
+						case 1:
+							stack.Push(docs[0])
+						default:
+							stack.Push(&MShellList{Items: docs})
+						}
+					}
+				} else if t.Lexeme == "parseExcel" || t.Lexeme == "readExcelGrid" {
 					obj1, err := stack.Pop()
 					if err != nil {
-						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'parseExcel' operation on an empty stack.\n", t.Line, t.Column))
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do '%s' operation on an empty stack.\n", t.Line, t.Column, t.Lexeme))
+					}
+
+					options := excelReadOptions{}
+					optionNames := parseExcelOptionNames
+					if t.Lexeme == "readExcelGrid" {
+						options = excelReadOptions{dates: true, header: true}
+						optionNames = readExcelGridOptionNames
+					}
+					if optionsDict, ok := obj1.(*MShellDict); ok {
+						options, err = parseExcelReadOptions(optionsDict, optionNames, options)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: %s: %s.\n", t.Line, t.Column, t.Lexeme, err.Error()))
+						}
+						obj1, err = stack.Pop()
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do '%s' operation on a stack with only one item.\n", t.Line, t.Column, t.Lexeme))
+						}
 					}
 
 					var xlsxData []byte
@@ -9811,14 +11116,82 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 					case MShellBinary:
 						xlsxData = []byte(obj1Typed)
 					default:
-						return state.FailWithMessage(fmt.Sprintf("%d:%d: 'parseExcel' expects a Path or Binary, got a %s.\n", t.Line, t.Column, obj1.TypeName()))
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: '%s' expects a Path or Binary, got a %s.\n", t.Line, t.Column, t.Lexeme, obj1.TypeName()))
+					}
+
+					if t.Lexeme == "readExcelGrid" {
+						grid, err := readExcelGrid(xlsxData, options)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Error parsing Excel: %s\n", t.Line, t.Column, err.Error()))
+						}
+						stack.Push(grid)
+					} else {
+						sheets, err := parseExcelBytes(xlsxData, options)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Error parsing Excel: %s\n", t.Line, t.Column, err.Error()))
+						}
+						stack.Push(sheets)
+					}
+				} else if t.Lexeme == "toExcel" {
+					obj1, err := stack.Pop()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'toExcel' operation on an empty stack.\n", t.Line, t.Column))
+					}
+
+					// A dictionary on top is the options unless it maps sheet names to data.
+					var options excelWriteOptions
+					if optionsDict, ok := obj1.(*MShellDict); ok && !isExcelSheetDict(optionsDict) {
+						options, err = parseExcelWriteOptions(optionsDict)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: toExcel: %s.\n", t.Line, t.Column, err.Error()))
+						}
+						obj1, err = stack.Pop()
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'toExcel' operation on a stack with only one item.\n", t.Line, t.Column))
+						}
+					}
+
+					outPath, hasOutPath := obj1.(MShellPath)
+					if hasOutPath {
+						obj1, err = stack.Pop()
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: 'toExcel' expects sheet data below the output path.\n", t.Line, t.Column))
+						}
 					}
 
-					sheets, err := parseExcelBytes(xlsxData)
+					sheets, err := excelSheetsFromObject(obj1, options)
 					if err != nil {
-						return state.FailWithMessage(fmt.Sprintf("%d:%d: Error parsing Excel: %s\n", t.Line, t.Column, err.Error()))
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: toExcel: %s.\n", t.Line, t.Column, err.Error()))
+					}
+
+					if !hasOutPath {
+						var buf bytes.Buffer
+						if err := writeExcel(&buf, sheets, options); err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: toExcel: %s.\n", t.Line, t.Column, err.Error()))
+						}
+						stack.Push(MShellBinary(buf.Bytes()))
+					} else if outPath.Path == "-" {
+						var out io.Writer = os.Stdout
+						if context.StandardOutput != nil {
+							out = context.StandardOutput
+						}
+						if err := writeExcel(out, sheets, options); err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Error writing to standard output: %s\n", t.Line, t.Column, err.Error()))
+						}
+					} else {
+						file, err := os.Create(outPath.Path)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Error opening file %s: %s\n", t.Line, t.Column, outPath.Path, err.Error()))
+						}
+						err = writeExcel(file, sheets, options)
+						closeErr := file.Close()
+						if err == nil {
+							err = closeErr
+						}
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Error writing to file %s: %s\n", t.Line, t.Column, outPath.Path, err.Error()))
+						}
 					}
-					stack.Push(sheets)
 				} else if t.Lexeme == "toJson" {
 					// Convert an object to JSON
 					obj1, err := stack.Pop()
@@ -9828,6 +11201,22 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 
 					jsonStr := obj1.ToJson()
 					stack.Push(MShellString{jsonStr})
+				} else if t.Lexeme == "toYaml" || t.Lexeme == "toToml" {
+					obj1, err := stack.Pop()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do '%s' operation on an empty stack.\n", t.Line, t.Column, t.Lexeme))
+					}
+
+					var text string
+					if t.Lexeme == "toToml" {
+						text, err = writeToml(obj1)
+					} else {
+						text, err = writeYaml(obj1)
+					}
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Error in '%s': %s.\n", t.Line, t.Column, t.Lexeme, err.Error()))
+					}
+					stack.Push(MShellString{text})
 				} else if t.Lexeme == "typeof" {
 					obj1, err := stack.Pop()
 					if err != nil {
@@ -10833,6 +12222,27 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 						newDateTime := &MShellDateTime{Time: newTime, OriginalString: ""}
 						stack.Push(newDateTime)
 					}
+				} else if t.Lexeme == "dateTrunc" {
+					obj1, obj2, err := stack.Pop2(t)
+					if err != nil {
+						return state.FailWithMessage(err.Error())
+					}
+
+					intervalStr, err := obj1.CastString()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: The interval in 'dateTrunc' is expected to be a string, found a %s (%s)\n", t.Line, t.Column, obj1.TypeName(), obj1.DebugString()))
+					}
+
+					dt, ok := obj2.(*MShellDateTime)
+					if !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: The second parameter in 'dateTrunc' is expected to be a date, found a %s (%s)\n", t.Line, t.Column, obj2.TypeName(), obj2.DebugString()))
+					}
+
+					iv, err := parseTimeInterval(intervalStr)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: %s.\n", t.Line, t.Column, err.Error()))
+					}
+					stack.Push(&MShellDateTime{Time: iv.truncate(dt.Time), OriginalString: ""})
 				} else if t.Lexeme == "isCmd" {
 					// Check if the top of the stack is a known command in the PATH
 					obj, err := stack.Pop()
@@ -10881,6 +12291,74 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 					// Convert the parsed HTML document to a dictionary
 					d := nodeToDict(doc)
 					stack.Push(d)
+				} else if t.Lexeme == "parseXml" {
+					obj1, err := stack.Pop()
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot do 'parseXml' operation on an empty stack.\n", t.Line, t.Column))
+					}
+
+					var reader io.Reader
+					switch obj1Typed := obj1.(type) {
+					case MShellPath, MShellLiteral:
+						path, _ := obj1.CastString()
+						data, err := os.ReadFile(path)
+						if err != nil {
+							return state.FailWithMessage(fmt.Sprintf("%d:%d: Error reading file %s: %s\n", t.Line, t.Column, path, err.Error()))
+						}
+						reader = bytes.NewReader(data)
+					case MShellString:
+						reader = strings.NewReader(obj1Typed.Content)
+					case MShellBinary:
+						reader = bytes.NewReader(obj1Typed)
+					default:
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot parse a %s as XML.\n", t.Line, t.Column, obj1.TypeName()))
+					}
+
+					doc, err := parseXml(reader)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Error parsing XML: %s\n", t.Line, t.Column, err.Error()))
+					}
+					stack.Push(doc)
+				} else if t.Lexeme == "jsonPath" {
+					obj1, obj2, err := stack.Pop2(t)
+					if err != nil {
+						return state.FailWithMessage(err.Error())
+					}
+
+					query, ok := obj1.(MShellString)
+					if !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Expected a string query for 'jsonPath', got a %s.\n", t.Line, t.Column, obj1.TypeName()))
+					}
+					result, err := evalJsonPath(obj2, query.Content)
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Error in 'jsonPath': %s.\n", t.Line, t.Column, err.Error()))
+					}
+					stack.Push(result)
+				} else if t.Lexeme == "xpath" || t.Lexeme == "cssSelect" {
+					obj1, obj2, err := stack.Pop2(t)
+					if err != nil {
+						return state.FailWithMessage(err.Error())
+					}
+
+					query, ok := obj1.(MShellString)
+					if !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Expected a string query for '%s', got a %s.\n", t.Line, t.Column, t.Lexeme, obj1.TypeName()))
+					}
+					node, ok := obj2.(*MShellDict)
+					if !ok {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Expected a node dictionary for '%s', got a %s.\n", t.Line, t.Column, t.Lexeme, obj2.TypeName()))
+					}
+
+					var result *MShellList
+					if t.Lexeme == "xpath" {
+						result, err = evalXPath(node, query.Content)
+					} else {
+						result, err = cssSelect(node, query.Content)
+					}
+					if err != nil {
+						return state.FailWithMessage(fmt.Sprintf("%d:%d: Error in '%s': %s.\n", t.Line, t.Column, t.Lexeme, err.Error()))
+					}
+					stack.Push(result)
 				} else if t.Lexeme == "inc" {
 					// Increment an integer on the top of the stack, but do it as a reference.
 					obj, err := stack.Pop()
@@ -11955,6 +13433,10 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 					return SimpleSuccess()
 				}
 
+				if group, ok := top.(*MShellList); ok && group.ParallelJobs > 0 {
+					return state.executeParallel(t, group, stack, context)
+				}
+
 				// Switch on type
 				var result EvalResult
 				var exitCode int
@@ -11965,7 +13447,11 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 
 				switch topTyped := top.(type) {
 				case *MShellList:
+					if state.TryDepth > 0 && (state.StopOnError || t.Type == BANG) {
+						state.TryCommand = &t
+					}
 					result, exitCode, stdout, stderr = RunProcess(*topTyped, context, state)
+					state.TryCommand = nil
 					stdoutBehavior = topTyped.StdoutBehavior
 					stderrBehavior = topTyped.StderrBehavior
 				case *MShellPipe:
@@ -11978,6 +13464,9 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 
 				if (state.StopOnError || (t.Type == BANG)) && exitCode != 0 {
 					// Exit completely, with that exit code, don't need to print a different message. Usually the command itself will have printed an error.
+					if state.TryDepth > 0 {
+						state.recordTryError(fmt.Sprintf("%d:%d: Command exited with code %d.\n", t.Line, t.Column, exitCode), exitCode)
+					}
 					return EvalResult{false, false, -1, exitCode, false}
 				}
 
@@ -12796,6 +14285,13 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 				}
 
 				stack.Push(MShellBool{doesEqual})
+			} else if t.Type == TRY { // Token Type
+				result := state.evaluateTry(t, stack, context, definitions)
+				if result.ShouldPassResultUpStack() {
+					return result
+				}
+			} else if t.Type == FAIL_KEYWORD { // Token Type
+				return state.evaluateFail(t, stack)
 			} else if t.Type == INTERPRET { // Token Type
 				obj, err := stack.Pop()
 				if err != nil {
@@ -12847,7 +14343,7 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 					return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot pipe a %s.\n", t.Line, t.Column, obj1.TypeName()))
 				}
 
-				stack.Push(&MShellPipe{*list, list.StdoutBehavior, list.StderrBehavior})
+				stack.Push(&MShellPipe{*list, list.StdoutBehavior, list.StderrBehavior, list.RunInBackground})
 			} else if t.Type == READ { // Token Type
 				var reader io.Reader
 				// Check if what we are reading from is seekable. If so, we can do a buffered read and reset the position.
@@ -13063,14 +14559,15 @@ func (state *EvalState) evaluateToken(t Token, stack *MShellStack, context Execu
 				if err != nil {
 					return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot get the address of an empty stack.\n", t.Line, t.Column))
 				}
-				// Obj should be a list for now.
-				list, ok := obj.(*MShellList)
-				if !ok {
+				switch background := obj.(type) {
+				case *MShellList:
+					background.RunInBackground = true
+				case *MShellPipe:
+					background.RunInBackground = true
+				default:
 					return state.FailWithMessage(fmt.Sprintf("%d:%d: Cannot execute '&' on a %s.\n", t.Line, t.Column, obj.TypeName()))
 				}
-
-				list.RunInBackground = true
-				stack.Push(list)
+				stack.Push(obj)
 			} else if t.Type == NOTEQUAL { // Token Type
 				obj1, err := stack.Pop()
 				if err != nil {
@@ -6,9 +6,13 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"math"
 	"path"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -49,7 +53,12 @@ type xlsxRichRun struct {
 }
 
 type xlsxWorksheet struct {
-	Rows []xlsxRow `xml:"sheetData>row"`
+	Rows       []xlsxRow       `xml:"sheetData>row"`
+	MergeCells []xlsxMergeCell `xml:"mergeCells>mergeCell"`
+}
+
+type xlsxMergeCell struct {
+	Ref string `xml:"ref,attr"`
 }
 
 type xlsxRow struct {
@@ -60,10 +69,35 @@ type xlsxRow struct {
 type xlsxC struct {
 	R  string         `xml:"r,attr"`
 	T  string         `xml:"t,attr"`
+	S  int            `xml:"s,attr"`
+	F  *xlsxF         `xml:"f"`
 	V  *string        `xml:"v"`
 	Is *xlsxInlineStr `xml:"is"`
 }
 
+// xlsxF is a cell formula. A shared formula's text is stored once, on the
+// cell that owns its ref range; the other cells carry only its si index.
+type xlsxF struct {
+	Text string `xml:",chardata"`
+	T    string `xml:"t,attr"`
+	Ref  string `xml:"ref,attr"`
+	Si   string `xml:"si,attr"`
+}
+
+type xlsxStyleSheet struct {
+	NumFmts []xlsxNumFmt `xml:"numFmts>numFmt"`
+	CellXfs []xlsxXf     `xml:"cellXfs>xf"`
+}
+
+type xlsxNumFmt struct {
+	ID   int    `xml:"numFmtId,attr"`
+	Code string `xml:"formatCode,attr"`
+}
+
+type xlsxXf struct {
+	NumFmtID int `xml:"numFmtId,attr"`
+}
+
 type xlsxInlineStr struct {
 	T string        `xml:"t"`
 	R []xlsxRichRun `xml:"r"`
@@ -129,27 +163,107 @@ func readZipFile(f *zip.File) ([]byte, error) {
 	return io.ReadAll(rc)
 }
 
-// parseExcelBytes parses the bytes of a .xlsx (OOXML spreadsheet) file and
-// returns a list of sheets in workbook order. Each sheet is a dict with a
-// "name" key (the worksheet name), a "data" key (a rectangular list of rows),
-// a "hidden" key (bool; true for hidden or veryHidden sheets), and a
-// "visibility" key ("visible", "hidden", or "veryHidden"). Cells are returned
-// as MShellFloat for numbers, MShellString for
-// strings (shared, inline, or formula-string results), MShellBool for
-// booleans, and a None Maybe for error cells. Missing <v> values and padding
-// cells are empty strings. Dates are returned as floats (Excel serial dates).
-func parseExcelBytes(data []byte) (*MShellList, error) {
+// excelReadOptions holds the parseExcel and readExcelGrid options.
+type excelReadOptions struct {
+	sheets   []MShellObject // sheet names (str) or 0-based indices (int); nil reads every sheet
+	dates    bool
+	formulas bool
+	merged   bool
+	header   bool
+}
+
+var parseExcelOptionNames = map[string]struct{}{
+	"sheets":   {},
+	"dates":    {},
+	"formulas": {},
+	"merged":   {},
+}
+
+var readExcelGridOptionNames = map[string]struct{}{
+	"sheet":  {},
+	"header": {},
+	"dates":  {},
+	"merged": {},
+}
+
+// parseExcelReadOptions reads an options dictionary over opts, accepting
+// only the keys in allowed.
+func parseExcelReadOptions(dict *MShellDict, allowed map[string]struct{}, opts excelReadOptions) (excelReadOptions, error) {
+	for key := range dict.Items {
+		if _, ok := allowed[key]; !ok {
+			names := make([]string, 0, len(allowed))
+			for name := range allowed {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return opts, fmt.Errorf("Unknown option '%s', expected one of %s", key, strings.Join(names, ", "))
+		}
+	}
+	for key, target := range map[string]*bool{"dates": &opts.dates, "formulas": &opts.formulas, "merged": &opts.merged, "header": &opts.header} {
+		if value, ok, err := boolOption(dict, key); err != nil {
+			return opts, err
+		} else if ok {
+			*target = value
+		}
+	}
+
+	checkSelector := func(obj MShellObject) error {
+		switch obj.(type) {
+		case MShellString, MShellInt:
+			return nil
+		}
+		return fmt.Errorf("Sheets are selected by a name or a 0-based index, found %s", obj.TypeName())
+	}
+	if obj, ok := dict.Items["sheet"]; ok {
+		if err := checkSelector(obj); err != nil {
+			return opts, err
+		}
+		opts.sheets = []MShellObject{obj}
+	}
+	if obj, ok := dict.Items["sheets"]; ok {
+		list, ok := obj.(*MShellList)
+		if !ok {
+			return opts, fmt.Errorf("Option 'sheets' must be a list of sheet names or indices, found %s", obj.TypeName())
+		}
+		for _, item := range list.Items {
+			if err := checkSelector(item); err != nil {
+				return opts, err
+			}
+		}
+		opts.sheets = list.Items
+	}
+	return opts, nil
+}
+
+// excelWorkbook is an opened .xlsx archive with the parts every sheet read
+// needs already parsed.
+type excelWorkbook struct {
+	files         map[string]*zip.File
+	sheets        []excelSheetRef // worksheets in workbook order
+	sharedStrings []string
+	dateStyles    map[int]bool // cell style indices whose number format shows a date or time
+}
+
+type excelSheetRef struct {
+	name       string
+	visibility string // "visible", "hidden", or "veryHidden"
+	target     string // archive path of the worksheet part
+}
+
+// openExcelWorkbook reads the workbook, its relationships, the shared
+// strings, and the styles of a .xlsx file. Chartsheets are skipped.
+func openExcelWorkbook(data []byte) (*excelWorkbook, error) {
 	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
 	if err != nil {
 		return nil, fmt.Errorf("not a valid zip/xlsx file: %w", err)
 	}
 
-	files := make(map[string]*zip.File, len(zr.File))
+	wb := &excelWorkbook{files: make(map[string]*zip.File, len(zr.File))}
 	for _, f := range zr.File {
-		files[f.Name] = f
+		wb.files[f.Name] = f
 	}
 
-	workbookFile, ok := files["xl/workbook.xml"]
+	workbookFile, ok := wb.files["xl/workbook.xml"]
 	if !ok {
 		return nil, fmt.Errorf("xl/workbook.xml not found in archive")
 	}
@@ -157,12 +271,12 @@ func parseExcelBytes(data []byte) (*MShellList, error) {
 	if err != nil {
 		return nil, fmt.Errorf("reading xl/workbook.xml: %w", err)
 	}
-	var wb xlsxWorkbook
-	if err := xml.Unmarshal(workbookBytes, &wb); err != nil {
+	var workbook xlsxWorkbook
+	if err := xml.Unmarshal(workbookBytes, &workbook); err != nil {
 		return nil, fmt.Errorf("parsing xl/workbook.xml: %w", err)
 	}
 
-	relsFile, ok := files["xl/_rels/workbook.xml.rels"]
+	relsFile, ok := wb.files["xl/_rels/workbook.xml.rels"]
 	if !ok {
 		return nil, fmt.Errorf("xl/_rels/workbook.xml.rels not found in archive")
 	}
@@ -179,8 +293,7 @@ func parseExcelBytes(data []byte) (*MShellList, error) {
 		relByID[r.ID] = r
 	}
 
-	var sharedStrings []string
-	if ssFile, ok := files["xl/sharedStrings.xml"]; ok {
+	if ssFile, ok := wb.files["xl/sharedStrings.xml"]; ok {
 		ssBytes, err := readZipFile(ssFile)
 		if err != nil {
 			return nil, fmt.Errorf("reading xl/sharedStrings.xml: %w", err)
@@ -189,14 +302,36 @@ func parseExcelBytes(data []byte) (*MShellList, error) {
 		if err := xml.Unmarshal(ssBytes, &sst); err != nil {
 			return nil, fmt.Errorf("parsing xl/sharedStrings.xml: %w", err)
 		}
-		sharedStrings = make([]string, len(sst.SI))
+		wb.sharedStrings = make([]string, len(sst.SI))
 		for i, si := range sst.SI {
-			sharedStrings[i] = sharedStringText(si)
+			wb.sharedStrings[i] = sharedStringText(si)
 		}
 	}
 
-	result := NewList(0)
-	for _, sheet := range wb.Sheets {
+	wb.dateStyles = map[int]bool{}
+	if stylesFile, ok := wb.files["xl/styles.xml"]; ok {
+		stylesBytes, err := readZipFile(stylesFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading xl/styles.xml: %w", err)
+		}
+		var styles xlsxStyleSheet
+		if err := xml.Unmarshal(stylesBytes, &styles); err != nil {
+			return nil, fmt.Errorf("parsing xl/styles.xml: %w", err)
+		}
+		customFormats := make(map[int]string, len(styles.NumFmts))
+		for _, numFmt := range styles.NumFmts {
+			customFormats[numFmt.ID] = numFmt.Code
+		}
+		for i, xf := range styles.CellXfs {
+			if code, ok := customFormats[xf.NumFmtID]; ok {
+				wb.dateStyles[i] = isExcelDateFormatCode(code)
+			} else {
+				wb.dateStyles[i] = isExcelBuiltinDateFormat(xf.NumFmtID)
+			}
+		}
+	}
+
+	for _, sheet := range workbook.Sheets {
 		rel, ok := relByID[sheet.RID]
 		if !ok {
 			return nil, fmt.Errorf("sheet %q references unknown rId %q", sheet.Name, sheet.RID)
@@ -211,47 +346,350 @@ func parseExcelBytes(data []byte) (*MShellList, error) {
 		} else {
 			target = path.Join("xl", target)
 		}
-		target = path.Clean(target)
 
-		sheetFile, ok := files[target]
-		if !ok {
-			return nil, fmt.Errorf("sheet %q target %q not found in archive", sheet.Name, target)
+		// The workbook <sheet state> attribute is "hidden", "veryHidden", or
+		// absent (treated as "visible").
+		visibility := sheet.State
+		if visibility == "" {
+			visibility = "visible"
 		}
-		sheetBytes, err := readZipFile(sheetFile)
-		if err != nil {
-			return nil, fmt.Errorf("reading %s: %w", target, err)
+		wb.sheets = append(wb.sheets, excelSheetRef{name: sheet.Name, visibility: visibility, target: path.Clean(target)})
+	}
+
+	return wb, nil
+}
+
+// isExcelBuiltinDateFormat reports whether a built-in number format id shows
+// a date or time, including the locale-specific ids 27-36 and 50-58.
+func isExcelBuiltinDateFormat(id int) bool {
+	return (id >= 14 && id <= 22) || (id >= 27 && id <= 36) || (id >= 45 && id <= 47) || (id >= 50 && id <= 58)
+}
+
+// isExcelDateFormatCode reports whether a custom number format code shows a
+// date or time: it has a d, m, y, h, or s outside quoted text, escaped
+// characters, and bracketed colors or conditions. Elapsed times such as
+// [h]:mm count.
+func isExcelDateFormatCode(code string) bool {
+	lower := strings.ToLower(code)
+	for i := 0; i < len(lower); i++ {
+		switch c := lower[i]; c {
+		case '"':
+			if end := strings.IndexByte(lower[i+1:], '"'); end >= 0 {
+				i += end + 1
+			} else {
+				return false
+			}
+		case '\\', '_', '*':
+			i++
+		case '[':
+			end := strings.IndexByte(lower[i:], ']')
+			if end < 0 {
+				return false
+			}
+			if strings.Trim(lower[i+1:i+end], "hms") == "" && end > 1 {
+				return true
+			}
+			i += end
+		case 'd', 'm', 'y', 'h', 's':
+			return true
 		}
+	}
+	return false
+}
 
-		var ws xlsxWorksheet
-		if err := xml.Unmarshal(sheetBytes, &ws); err != nil {
-			return nil, fmt.Errorf("parsing %s: %w", target, err)
+// excelSerialTime converts an Excel serial date to a datetime, rounded to the
+// millisecond to drop the float error in stored times of day.
+func excelSerialTime(serial float64) time.Time {
+	return fromOleDate(serial).Round(time.Millisecond)
+}
+
+// selectSheets resolves sheet names and 0-based indices to indices into
+// wb.sheets, in the order given. Names match regardless of case, as in
+// Excel. No selectors selects every sheet.
+func (wb *excelWorkbook) selectSheets(selectors []MShellObject) ([]int, error) {
+	if selectors == nil {
+		indices := make([]int, len(wb.sheets))
+		for i := range indices {
+			indices[i] = i
 		}
+		return indices, nil
+	}
 
-		rows, err := worksheetToRows(&ws, sharedStrings)
-		if err != nil {
-			return nil, fmt.Errorf("in sheet %q: %w", sheet.Name, err)
+	indices := make([]int, 0, len(selectors))
+	for _, selector := range selectors {
+		switch typed := selector.(type) {
+		case MShellInt:
+			if typed.Value < 0 || typed.Value >= len(wb.sheets) {
+				return nil, fmt.Errorf("sheet index %d is out of range, the workbook has %d sheets", typed.Value, len(wb.sheets))
+			}
+			indices = append(indices, typed.Value)
+		case MShellString:
+			found := -1
+			names := make([]string, len(wb.sheets))
+			for i, sheet := range wb.sheets {
+				names[i] = sheet.name
+				if found < 0 && strings.EqualFold(sheet.name, typed.Content) {
+					found = i
+				}
+			}
+			if found < 0 {
+				return nil, fmt.Errorf("sheet %q not found, the workbook has %s", typed.Content, strings.Join(names, ", "))
+			}
+			indices = append(indices, found)
 		}
-		// The workbook <sheet state> attribute is "hidden", "veryHidden", or
-		// absent (treated as "visible"). Both hidden and veryHidden collapse to
-		// the hidden bool; visibility preserves the full distinction.
-		visibility := sheet.State
-		if visibility == "" {
-			visibility = "visible"
+	}
+	return indices, nil
+}
+
+// readSheet parses worksheet i into its rows and, when opts.formulas is set,
+// a matching grid of formula text.
+func (wb *excelWorkbook) readSheet(i int, opts excelReadOptions) (*MShellList, *MShellList, error) {
+	sheet := wb.sheets[i]
+	sheetFile, ok := wb.files[sheet.target]
+	if !ok {
+		return nil, nil, fmt.Errorf("sheet %q target %q not found in archive", sheet.name, sheet.target)
+	}
+	sheetBytes, err := readZipFile(sheetFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", sheet.target, err)
+	}
+
+	var ws xlsxWorksheet
+	if err := xml.Unmarshal(sheetBytes, &ws); err != nil {
+		return nil, nil, fmt.Errorf("parsing %s: %w", sheet.target, err)
+	}
+
+	rows, formulas, err := worksheetToRows(&ws, wb, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("in sheet %q: %w", sheet.name, err)
+	}
+	return rows, formulas, nil
+}
+
+// parseExcelBytes parses the bytes of a .xlsx (OOXML spreadsheet) file and
+// returns a list of sheets in workbook order, or in the order of the sheets
+// option. Each sheet is a dict with a "name" key (the worksheet name), a
+// "data" key (a rectangular list of rows), a "hidden" key (bool; true for
+// hidden or veryHidden sheets), and a "visibility" key ("visible", "hidden",
+// or "veryHidden"). Cells are returned as MShellFloat for numbers,
+// MShellString for strings (shared, inline, or formula-string results),
+// MShellBool for booleans, and a None Maybe for error cells. Missing <v>
+// values and padding cells are empty strings. Dates are returned as floats
+// (Excel serial dates) unless opts.dates is set. With opts.formulas, a
+// "formulas" key holds the formula text of each cell, or an empty string.
+func parseExcelBytes(data []byte, opts excelReadOptions) (*MShellList, error) {
+	wb, err := openExcelWorkbook(data)
+	if err != nil {
+		return nil, err
+	}
+	indices, err := wb.selectSheets(opts.sheets)
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewList(0)
+	for _, i := range indices {
+		sheet := wb.sheets[i]
+		rows, formulas, err := wb.readSheet(i, opts)
+		if err != nil {
+			return nil, err
 		}
-		hidden := visibility != "visible"
 
 		sheetDict := NewDict()
-		sheetDict.Items["name"] = MShellString{Content: sheet.Name}
+		sheetDict.Items["name"] = MShellString{Content: sheet.name}
 		sheetDict.Items["data"] = rows
-		sheetDict.Items["hidden"] = MShellBool{Value: hidden}
-		sheetDict.Items["visibility"] = MShellString{Content: visibility}
+		// Both hidden and veryHidden collapse to the hidden bool; visibility
+		// preserves the full distinction.
+		sheetDict.Items["hidden"] = MShellBool{Value: sheet.visibility != "visible"}
+		sheetDict.Items["visibility"] = MShellString{Content: sheet.visibility}
+		if formulas != nil {
+			sheetDict.Items["formulas"] = formulas
+		}
 		result.Items = append(result.Items, sheetDict)
 	}
 
 	return result, nil
 }
 
-func worksheetToRows(ws *xlsxWorksheet, sharedStrings []string) (*MShellList, error) {
+// readExcelGrid reads one worksheet, the first unless opts.sheets names
+// another, into a Grid.
+func readExcelGrid(data []byte, opts excelReadOptions) (*MShellGrid, error) {
+	wb, err := openExcelWorkbook(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(wb.sheets) == 0 {
+		return nil, fmt.Errorf("the workbook has no worksheets")
+	}
+	selectors := opts.sheets
+	if selectors == nil {
+		selectors = []MShellObject{MShellInt{Value: 0}}
+	}
+	indices, err := wb.selectSheets(selectors)
+	if err != nil {
+		return nil, err
+	}
+	rows, _, err := wb.readSheet(indices[0], opts)
+	if err != nil {
+		return nil, err
+	}
+	return excelRowsToGrid(rows, opts.header)
+}
+
+// excelRowsToGrid builds a Grid from rectangular sheet rows, taking column
+// names from the first row when header is set. Empty cells become none, and
+// a column whose numbers are all whole becomes an int column.
+func excelRowsToGrid(rows *MShellList, header bool) (*MShellGrid, error) {
+	width := 0
+	if len(rows.Items) > 0 {
+		width = len(rows.Items[0].(*MShellList).Items)
+	}
+	dataRows := rows.Items
+
+	colNames := make([]string, width)
+	seenCols := make(map[string]struct{}, width)
+	for j := range colNames {
+		colNames[j] = fmt.Sprintf("col%d", j+1)
+		if header {
+			cell := dataRows[0].(*MShellList).Items[j]
+			switch typed := cell.(type) {
+			case MShellString:
+				if typed.Content != "" {
+					colNames[j] = typed.Content
+				}
+			case MShellFloat:
+				colNames[j] = strconv.FormatFloat(typed.Value, 'f', -1, 64)
+			case MShellBool:
+				colNames[j] = strconv.FormatBool(typed.Value)
+			case *MShellDateTime:
+				colNames[j] = typed.Time.Format("2006-01-02")
+			}
+			if _, exists := seenCols[colNames[j]]; exists {
+				return nil, fmt.Errorf("Excel header has duplicate column '%s'", colNames[j])
+			}
+			seenCols[colNames[j]] = struct{}{}
+		}
+	}
+	if header && len(dataRows) > 0 {
+		dataRows = dataRows[1:]
+	}
+
+	grid := NewGrid()
+	grid.RowCount = len(dataRows)
+	for j, name := range colNames {
+		col := NewGridColumn(name, len(dataRows))
+		wholeNumbers := true
+		for i, row := range dataRows {
+			cell := row.(*MShellList).Items[j]
+			switch typed := cell.(type) {
+			case MShellString:
+				if typed.Content == "" {
+					cell = &Maybe{obj: nil}
+				} else {
+					wholeNumbers = false
+				}
+			case MShellFloat:
+				if typed.Value != math.Trunc(typed.Value) || math.Abs(typed.Value) > 1<<53 {
+					wholeNumbers = false
+				}
+			case *Maybe:
+			default:
+				wholeNumbers = false
+			}
+			col.GenericData[i] = cell
+		}
+		if wholeNumbers {
+			for i, cell := range col.GenericData {
+				if f, ok := cell.(MShellFloat); ok {
+					col.GenericData[i] = MShellInt{Value: int(f.Value)}
+				}
+			}
+		}
+		optimizeColumnStorage(col)
+		grid.AddColumn(col)
+	}
+	return grid, nil
+}
+
+// parseCellRef splits an A1-style reference into 0-based row and column
+// indices.
+func parseCellRef(cellRef string) (int, int, error) {
+	col, err := colRefToIndex(cellRef)
+	if err != nil {
+		return 0, 0, err
+	}
+	digits := strings.TrimLeft(cellRef, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
+	row, err := strconv.Atoi(digits)
+	if err != nil || row < 1 {
+		return 0, 0, fmt.Errorf("invalid cell reference %q", cellRef)
+	}
+	return row - 1, col, nil
+}
+
+// excelCellRefRegex matches a cell reference with optional $ anchors, plus
+// the character after it so that function names such as LOG10( are not
+// mistaken for references.
+var excelCellRefRegex = regexp.MustCompile(`(\$?)([A-Za-z]{1,3})(\$?)([0-9]+)([A-Za-z0-9_(]?)`)
+
+// shiftExcelFormula moves the relative cell references in a formula by
+// dRow rows and dCol columns, as Excel does when it fills a shared formula
+// from its first cell. Text in double quotes and quoted sheet names is left
+// alone.
+func shiftExcelFormula(formula string, dRow, dCol int) string {
+	var sb strings.Builder
+	segmentStart := 0
+	shiftSegment := func(segment string) {
+		last := 0
+		for _, m := range excelCellRefRegex.FindAllStringSubmatchIndex(segment, -1) {
+			prevOK := m[0] == 0 || !strings.ContainsRune("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_.", rune(segment[m[0]-1]))
+			col, colErr := colRefToIndex(segment[m[4]:m[5]])
+			row, _ := strconv.Atoi(segment[m[8]:m[9]])
+			if !prevOK || m[10] != m[11] || colErr != nil || col >= 16384 {
+				continue
+			}
+			if segment[m[2]:m[3]] == "" {
+				col += dCol
+			}
+			if segment[m[6]:m[7]] == "" {
+				row += dRow
+			}
+			if col < 0 || row < 1 {
+				continue
+			}
+			sb.WriteString(segment[last:m[0]])
+			sb.WriteString(segment[m[2]:m[3]] + colIndexToRef(col) + segment[m[6]:m[7]] + strconv.Itoa(row))
+			last = m[9]
+		}
+		sb.WriteString(segment[last:])
+	}
+
+	for i := 0; i < len(formula); i++ {
+		quote := formula[i]
+		if quote != '"' && quote != '\'' {
+			continue
+		}
+		shiftSegment(formula[segmentStart:i])
+		end := i + 1
+		for end < len(formula) {
+			if formula[end] == quote {
+				if end+1 < len(formula) && formula[end+1] == quote {
+					end += 2
+					continue
+				}
+				break
+			}
+			end++
+		}
+		end = min(end+1, len(formula))
+		sb.WriteString(formula[i:end])
+		segmentStart = end
+		i = end - 1
+	}
+	shiftSegment(formula[segmentStart:])
+	return sb.String()
+}
+
+func worksheetToRows(ws *xlsxWorksheet, wb *excelWorkbook, opts excelReadOptions) (*MShellList, *MShellList, error) {
 	maxRow := 0
 	maxCol := -1
 	type placedCell struct {
@@ -278,7 +716,7 @@ func worksheetToRows(ws *xlsxWorksheet, sharedStrings []string) (*MShellList, er
 			} else {
 				ci, err := colRefToIndex(c.R)
 				if err != nil {
-					return nil, err
+					return nil, nil, err
 				}
 				colIdx = ci
 			}
@@ -289,25 +727,82 @@ func worksheetToRows(ws *xlsxWorksheet, sharedStrings []string) (*MShellList, er
 		}
 	}
 
-	outer := NewList(maxRow)
-	for i := 0; i < maxRow; i++ {
-		inner := NewList(maxCol + 1)
-		for j := 0; j <= maxCol; j++ {
-			inner.Items[j] = MShellString{""}
+	// Merged ranges as [first row, first col, last row, last col].
+	var merges [][4]int
+	if opts.merged {
+		for _, merge := range ws.MergeCells {
+			first, last, _ := strings.Cut(merge.Ref, ":")
+			if last == "" {
+				last = first
+			}
+			r0, c0, err := parseCellRef(first)
+			if err != nil {
+				return nil, nil, err
+			}
+			r1, c1, err := parseCellRef(last)
+			if err != nil {
+				return nil, nil, err
+			}
+			merges = append(merges, [4]int{r0, c0, r1, c1})
+			maxRow = max(maxRow, r1+1)
+			maxCol = max(maxCol, c1)
+		}
+	}
+
+	newRect := func() *MShellList {
+		outer := NewList(maxRow)
+		for i := 0; i < maxRow; i++ {
+			inner := NewList(maxCol + 1)
+			for j := 0; j <= maxCol; j++ {
+				inner.Items[j] = MShellString{""}
+			}
+			outer.Items[i] = inner
+		}
+		return outer
+	}
+	outer := newRect()
+	var formulas *MShellList
+	sharedFormulas := map[string]placedCell{}
+	if opts.formulas {
+		formulas = newRect()
+		for _, p := range placed {
+			if f := p.cell.F; f != nil && f.T == "shared" && f.Ref != "" {
+				sharedFormulas[f.Si] = p
+			}
 		}
-		outer.Items[i] = inner
 	}
 
 	for _, p := range placed {
-		obj, err := cellToObject(p.cell, sharedStrings)
+		obj, err := cellToObject(p.cell, wb.sharedStrings)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		if f, ok := obj.(MShellFloat); ok && opts.dates && wb.dateStyles[p.cell.S] {
+			obj = &MShellDateTime{Time: excelSerialTime(f.Value), OriginalString: ""}
 		}
 		inner := outer.Items[p.row].(*MShellList)
 		inner.Items[p.col] = obj
+
+		if formulas != nil && p.cell.F != nil {
+			text := p.cell.F.Text
+			if master, ok := sharedFormulas[p.cell.F.Si]; ok && p.cell.F.T == "shared" && text == "" {
+				text = shiftExcelFormula(master.cell.F.Text, p.row-master.row, p.col-master.col)
+			}
+			formulas.Items[p.row].(*MShellList).Items[p.col] = MShellString{text}
+		}
+	}
+
+	// A merged range keeps its value in the top-left cell; copy it across.
+	for _, m := range merges {
+		value := outer.Items[m[0]].(*MShellList).Items[m[1]]
+		for r := m[0]; r <= m[2]; r++ {
+			for c := m[1]; c <= m[3]; c++ {
+				outer.Items[r].(*MShellList).Items[c] = value
+			}
+		}
 	}
 
-	return outer, nil
+	return outer, formulas, nil
 }
 
 func cellToObject(c *xlsxC, sharedStrings []string) (MShellObject, error) {
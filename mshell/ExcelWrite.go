@@ -0,0 +1,454 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// toExcel writes grids and lists of rows as an .xlsx workbook. Numbers,
+// bools, and datetimes become typed cells; strings are written inline, so
+// the package needs no shared string table.
+
+const (
+	xlsxNamespaceMain = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	xlsxNamespaceRels = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	xlsxRelTypeStyles = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"
+	xlsxXMLHeader     = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+)
+
+// Cell style indices into the cellXfs of xlsxStylesXML.
+const (
+	xlsxStyleDefault = iota
+	xlsxStyleBold
+	xlsxStyleDate
+	xlsxStyleDateTime
+)
+
+const xlsxStylesXML = xlsxXMLHeader +
+	`<styleSheet xmlns="` + xlsxNamespaceMain + `">` +
+	`<numFmts count="2"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+// Column widths are in characters. Automatic widths fit the longest cell
+// within these bounds.
+const (
+	xlsxMinColWidth = 8
+	xlsxMaxColWidth = 60
+)
+
+// excelWriteOptions holds the parsed toExcel options dictionary.
+type excelWriteOptions struct {
+	header    bool
+	hasHeader bool // header was given; grids default to true, lists to false
+	widths    []float64
+}
+
+var excelWriteOptionNames = map[string]struct{}{
+	"header": {},
+	"widths": {},
+}
+
+func parseExcelWriteOptions(dict *MShellDict) (excelWriteOptions, error) {
+	var opts excelWriteOptions
+	for key := range dict.Items {
+		if _, ok := excelWriteOptionNames[key]; !ok {
+			return opts, fmt.Errorf("Unknown option '%s', expected header or widths", key)
+		}
+	}
+	header, hasHeader, err := boolOption(dict, "header")
+	if err != nil {
+		return opts, err
+	}
+	opts.header, opts.hasHeader = header, hasHeader
+	if obj, ok := dict.Items["widths"]; ok {
+		list, ok := obj.(*MShellList)
+		if !ok {
+			return opts, fmt.Errorf("Option 'widths' must be a list of numbers, found %s", obj.TypeName())
+		}
+		opts.widths = make([]float64, len(list.Items))
+		for i, item := range list.Items {
+			switch num := item.(type) {
+			case MShellInt:
+				opts.widths[i] = float64(num.Value)
+			case MShellFloat:
+				opts.widths[i] = num.Value
+			default:
+				return opts, fmt.Errorf("Option 'widths' must be a list of numbers, found %s", item.TypeName())
+			}
+			if opts.widths[i] <= 0 || opts.widths[i] > 255 {
+				return opts, fmt.Errorf("Option 'widths' values must be greater than 0 and at most 255, found %s", item.DebugString())
+			}
+		}
+	}
+	return opts, nil
+}
+
+// excelSheet is one worksheet to write. When bold is set the first row is
+// written as a bold header.
+type excelSheet struct {
+	name string
+	rows [][]MShellObject
+	bold bool
+}
+
+// isExcelSheetData reports whether obj can be written as a single sheet: a
+// Grid, a GridView, or a list of row lists.
+func isExcelSheetData(obj MShellObject) bool {
+	switch typed := obj.(type) {
+	case *MShellGrid, *MShellGridView:
+		return true
+	case *MShellList:
+		for _, item := range typed.Items {
+			if _, ok := item.(*MShellList); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isExcelSheetDict reports whether dict maps sheet names to sheet data, as
+// opposed to being an options dictionary.
+func isExcelSheetDict(dict *MShellDict) bool {
+	for _, value := range dict.Items {
+		if isExcelSheetData(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// excelSheetsFromObject reads the workbook to write: a single sheet's data,
+// a dictionary of sheet name to data in name order, or a list of
+// {name, data} dictionaries such as parseExcel returns.
+func excelSheetsFromObject(obj MShellObject, opts excelWriteOptions) ([]excelSheet, error) {
+	var names []string
+	var data []MShellObject
+	switch typed := obj.(type) {
+	case *MShellDict:
+		for name := range typed.Items {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			data = append(data, typed.Items[name])
+		}
+	case *MShellList:
+		if len(typed.Items) > 0 {
+			if _, isSheetList := typed.Items[0].(*MShellDict); isSheetList {
+				for i, item := range typed.Items {
+					sheetDict, ok := item.(*MShellDict)
+					if !ok {
+						return nil, fmt.Errorf("sheet %d must be a dictionary with 'name' and 'data', found %s", i+1, item.TypeName())
+					}
+					nameObj, ok := sheetDict.Items["name"]
+					if !ok {
+						return nil, fmt.Errorf("sheet %d is missing required key 'name'", i+1)
+					}
+					name, err := nameObj.CastString()
+					if err != nil {
+						return nil, fmt.Errorf("sheet %d key 'name' must be a string, found %s", i+1, nameObj.TypeName())
+					}
+					dataObj, ok := sheetDict.Items["data"]
+					if !ok {
+						return nil, fmt.Errorf("sheet %d is missing required key 'data'", i+1)
+					}
+					names = append(names, name)
+					data = append(data, dataObj)
+				}
+				break
+			}
+		}
+		names, data = []string{"Sheet1"}, []MShellObject{obj}
+	default:
+		names, data = []string{"Sheet1"}, []MShellObject{obj}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("a workbook needs at least one sheet")
+	}
+	seen := make(map[string]struct{}, len(names))
+	sheets := make([]excelSheet, len(names))
+	for i, name := range names {
+		if err := validateExcelSheetName(name); err != nil {
+			return nil, err
+		}
+		folded := strings.ToLower(name)
+		if _, dup := seen[folded]; dup {
+			return nil, fmt.Errorf("sheet name %q is used more than once", name)
+		}
+		seen[folded] = struct{}{}
+
+		sheet, err := excelSheetFromData(name, data[i], opts)
+		if err != nil {
+			return nil, err
+		}
+		sheets[i] = sheet
+	}
+	return sheets, nil
+}
+
+// validateExcelSheetName applies Excel's rules: 1 to 31 characters, none of
+// : \ / ? * [ ], and no leading or trailing apostrophe.
+func validateExcelSheetName(name string) error {
+	if name == "" || utf8.RuneCountInString(name) > 31 {
+		return fmt.Errorf("sheet name %q must be 1 to 31 characters", name)
+	}
+	if strings.ContainsAny(name, `:\/?*[]`) {
+		return fmt.Errorf("sheet name %q cannot contain any of : \\ / ? * [ ]", name)
+	}
+	if strings.HasPrefix(name, "'") || strings.HasSuffix(name, "'") {
+		return fmt.Errorf("sheet name %q cannot start or end with an apostrophe", name)
+	}
+	return nil
+}
+
+func excelSheetFromData(name string, obj MShellObject, opts excelWriteOptions) (excelSheet, error) {
+	sheet := excelSheet{name: name}
+	if src, ok := newGridWriteSource(obj); ok {
+		sheet.bold = !opts.hasHeader || opts.header
+		if sheet.bold {
+			header := make([]MShellObject, len(src.grid.Columns))
+			for j, col := range src.grid.Columns {
+				header[j] = MShellString{Content: col.Name}
+			}
+			sheet.rows = append(sheet.rows, header)
+		}
+		n := src.rowCount()
+		for i := 0; i < n; i++ {
+			row := src.sourceRow(i)
+			cells := make([]MShellObject, len(src.grid.Columns))
+			for j, col := range src.grid.Columns {
+				cells[j] = col.Get(row)
+			}
+			sheet.rows = append(sheet.rows, cells)
+		}
+		return sheet, nil
+	}
+
+	list, ok := obj.(*MShellList)
+	if !ok || !isExcelSheetData(list) {
+		return sheet, fmt.Errorf("sheet %q data must be a Grid, a GridView, or a list of rows, found %s", name, obj.TypeName())
+	}
+	sheet.bold = opts.header && len(list.Items) > 0
+	for _, item := range list.Items {
+		sheet.rows = append(sheet.rows, item.(*MShellList).Items)
+	}
+	return sheet, nil
+}
+
+// colIndexToRef converts a 0-based column index to its letters, the inverse
+// of colRefToIndex: 0 -> "A", 25 -> "Z", 26 -> "AA".
+func colIndexToRef(index int) string {
+	var letters []byte
+	for n := index + 1; n > 0; n = (n - 1) / 26 {
+		letters = append([]byte{byte('A' + (n-1)%26)}, letters...)
+	}
+	return string(letters)
+}
+
+// excelCellValue is a cell ready to write: its type attribute, style, and
+// either the <v> text or, for strings, the inline text.
+type excelCellValue struct {
+	kind  string // "n", "b", or "inlineStr"
+	style int
+	text  string
+	width int // display width in characters, for automatic column widths
+}
+
+// excelCell converts one MShell value to a cell. The bool result is false
+// for null cells (none and NaN), which are left empty.
+func excelCell(obj MShellObject) (excelCellValue, bool, error) {
+	switch typed := obj.(type) {
+	case nil, MShellNull:
+		return excelCellValue{}, false, nil
+	case *Maybe:
+		if typed.obj == nil {
+			return excelCellValue{}, false, nil
+		}
+		return excelCell(typed.obj)
+	case MShellInt:
+		text := strconv.Itoa(typed.Value)
+		return excelCellValue{kind: "n", text: text, width: len(text)}, true, nil
+	case MShellFloat:
+		if math.IsNaN(typed.Value) {
+			return excelCellValue{}, false, nil
+		}
+		if math.IsInf(typed.Value, 0) {
+			return excelCellValue{}, false, fmt.Errorf("Excel cannot store an infinite number")
+		}
+		text := strconv.FormatFloat(typed.Value, 'g', -1, 64)
+		return excelCellValue{kind: "n", text: text, width: min(len(text), 12)}, true, nil
+	case MShellBool:
+		text := "0"
+		if typed.Value {
+			text = "1"
+		}
+		return excelCellValue{kind: "b", text: text, width: 5}, true, nil
+	case *MShellDateTime:
+		text := strconv.FormatFloat(toOleDate(typed.Time), 'f', -1, 64)
+		hour, minute, sec := typed.Time.Clock()
+		if hour == 0 && minute == 0 && sec == 0 {
+			return excelCellValue{kind: "n", style: xlsxStyleDate, text: text, width: 10}, true, nil
+		}
+		return excelCellValue{kind: "n", style: xlsxStyleDateTime, text: text, width: 19}, true, nil
+	case *MShellList, *MShellDict, *MShellGrid, *MShellGridView, *MShellQuotation:
+		return excelCellValue{}, false, fmt.Errorf("cannot write a %s to an Excel cell", obj.TypeName())
+	}
+	text, err := obj.CastString()
+	if err != nil {
+		text = obj.ToString()
+	}
+	return excelCellValue{kind: "inlineStr", text: text, width: utf8.RuneCountInString(text)}, true, nil
+}
+
+func writeXMLText(sb *strings.Builder, text string) {
+	_ = xml.EscapeText(sb, []byte(text))
+}
+
+// excelWorksheetXML renders one sheet. widths, when given, override the
+// automatic width of the leading columns.
+func excelWorksheetXML(sheet excelSheet, widths []float64) (string, error) {
+	var data strings.Builder
+	var colWidths []int
+	for i, row := range sheet.rows {
+		rowRef := strconv.Itoa(i + 1)
+		fmt.Fprintf(&data, `<row r="%s">`, rowRef)
+		for j, obj := range row {
+			cell, ok, err := excelCell(obj)
+			if err != nil {
+				return "", fmt.Errorf("sheet %q cell %s%s: %s", sheet.name, colIndexToRef(j), rowRef, err.Error())
+			}
+			if !ok {
+				continue
+			}
+			for len(colWidths) <= j {
+				colWidths = append(colWidths, 0)
+			}
+			colWidths[j] = max(colWidths[j], cell.width)
+
+			if i == 0 && sheet.bold && cell.style == xlsxStyleDefault {
+				cell.style = xlsxStyleBold
+			}
+			fmt.Fprintf(&data, `<c r="%s%s"`, colIndexToRef(j), rowRef)
+			if cell.style != xlsxStyleDefault {
+				fmt.Fprintf(&data, ` s="%d"`, cell.style)
+			}
+			if cell.kind == "inlineStr" {
+				data.WriteString(` t="inlineStr"><is><t xml:space="preserve">`)
+				writeXMLText(&data, cell.text)
+				data.WriteString(`</t></is></c>`)
+			} else {
+				if cell.kind != "n" {
+					fmt.Fprintf(&data, ` t="%s"`, cell.kind)
+				}
+				fmt.Fprintf(&data, `><v>%s</v></c>`, cell.text)
+			}
+		}
+		data.WriteString(`</row>`)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(xlsxXMLHeader)
+	sb.WriteString(`<worksheet xmlns="` + xlsxNamespaceMain + `">`)
+	for len(colWidths) < len(widths) {
+		colWidths = append(colWidths, 0)
+	}
+	if len(colWidths) > 0 {
+		sb.WriteString(`<cols>`)
+		for j, chars := range colWidths {
+			width := float64(min(max(chars+2, xlsxMinColWidth), xlsxMaxColWidth))
+			if j < len(widths) {
+				width = widths[j]
+			}
+			fmt.Fprintf(&sb, `<col min="%d" max="%d" width="%s" customWidth="1"/>`, j+1, j+1, strconv.FormatFloat(width, 'f', -1, 64))
+		}
+		sb.WriteString(`</cols>`)
+	}
+	sb.WriteString(`<sheetData>`)
+	sb.WriteString(data.String())
+	sb.WriteString(`</sheetData></worksheet>`)
+	return sb.String(), nil
+}
+
+// writeExcel writes sheets as an .xlsx package: content types, package and
+// workbook relationships, the workbook, styles, and one part per sheet.
+func writeExcel(w io.Writer, sheets []excelSheet, opts excelWriteOptions) error {
+	parts := make([][2]string, 0, len(sheets)+5)
+
+	var types strings.Builder
+	types.WriteString(xlsxXMLHeader)
+	types.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	types.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	types.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	types.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	types.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range sheets {
+		fmt.Fprintf(&types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	types.WriteString(`</Types>`)
+	parts = append(parts, [2]string{"[Content_Types].xml", types.String()})
+
+	parts = append(parts, [2]string{"_rels/.rels", xlsxXMLHeader +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`})
+
+	var workbook, rels strings.Builder
+	workbook.WriteString(xlsxXMLHeader)
+	workbook.WriteString(`<workbook xmlns="` + xlsxNamespaceMain + `" xmlns:r="` + xlsxNamespaceRels + `"><sheets>`)
+	rels.WriteString(xlsxXMLHeader)
+	rels.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i, sheet := range sheets {
+		workbook.WriteString(`<sheet name="`)
+		writeXMLText(&workbook, sheet.name)
+		fmt.Fprintf(&workbook, `" sheetId="%d" r:id="rId%d"/>`, i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="%s" Target="worksheets/sheet%d.xml"/>`, i+1, xlsxRelTypeWorksheet, i+1)
+	}
+	workbook.WriteString(`</sheets></workbook>`)
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="%s" Target="styles.xml"/>`, len(sheets)+1, xlsxRelTypeStyles)
+	rels.WriteString(`</Relationships>`)
+	parts = append(parts,
+		[2]string{"xl/workbook.xml", workbook.String()},
+		[2]string{"xl/_rels/workbook.xml.rels", rels.String()},
+		[2]string{"xl/styles.xml", xlsxStylesXML})
+
+	for i, sheet := range sheets {
+		sheetXML, err := excelWorksheetXML(sheet, opts.widths)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, [2]string{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheetXML})
+	}
+
+	zw := zip.NewWriter(w)
+	for _, part := range parts {
+		fw, err := zw.Create(part[0])
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, part[1]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestColIndexToRef(t *testing.T) {
+	for _, tt := range []struct {
+		index int
+		ref   string
+	}{{0, "A"}, {25, "Z"}, {26, "AA"}, {51, "AZ"}, {701, "ZZ"}, {702, "AAA"}} {
+		if got := colIndexToRef(tt.index); got != tt.ref {
+			t.Errorf("colIndexToRef(%d) = %s, want %s", tt.index, got, tt.ref)
+		}
+		if back, err := colRefToIndex(tt.ref + "1"); err != nil || back != tt.index {
+			t.Errorf("colRefToIndex(%s1) = %d, %v, want %d", tt.ref, back, err, tt.index)
+		}
+	}
+}
+
+func TestWriteExcelRoundTrip(t *testing.T) {
+	grid := NewGrid()
+	grid.RowCount = 2
+	grid.AddColumn(&GridColumn{Name: "site", ColType: COL_STRING, StringData: []string{"A & B", "<C>"}})
+	grid.AddColumn(&GridColumn{Name: "kwh", ColType: COL_GENERIC, GenericData: []MShellObject{MShellInt{Value: 7}, &Maybe{obj: nil}}})
+	grid.AddColumn(&GridColumn{Name: "read", ColType: COL_DATETIME, DateTimeData: []time.Time{
+		time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC),
+	}})
+
+	sheetDict := NewDict()
+	sheetDict.Items["Data"] = grid
+	sheetDict.Items["Flags"] = &MShellList{Items: []MShellObject{
+		&MShellList{Items: []MShellObject{MShellBool{Value: true}, MShellFloat{Value: 1.5}}},
+	}}
+	sheets, err := excelSheetsFromObject(sheetDict, excelWriteOptions{})
+	if err != nil {
+		t.Fatalf("excelSheetsFromObject error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := writeExcel(&buf, sheets, excelWriteOptions{}); err != nil {
+		t.Fatalf("writeExcel error: %v", err)
+	}
+
+	parsed, err := parseExcelBytes(buf.Bytes(), excelReadOptions{})
+	if err != nil {
+		t.Fatalf("parseExcelBytes error: %v", err)
+	}
+	if len(parsed.Items) != 2 {
+		t.Fatalf("sheet count = %d, want 2", len(parsed.Items))
+	}
+	data := parsed.Items[0].(*MShellDict)
+	if name := data.Items["name"].(MShellString).Content; name != "Data" {
+		t.Fatalf("first sheet = %s, want Data", name)
+	}
+	rows := data.Items["data"].(*MShellList).Items
+	want := []string{
+		`["site" "kwh" "read"]`,
+		`["A & B" 7 45352]`,
+		`["<C>" "" 45352.5]`,
+	}
+	for i, row := range rows {
+		if got := row.DebugString(); got != want[i] {
+			t.Errorf("row %d = %s, want %s", i, got, want[i])
+		}
+	}
+	flags := parsed.Items[1].(*MShellDict).Items["data"].(*MShellList)
+	if got := flags.ToJson(); got != "[[true, 1.5]]" {
+		t.Errorf("Flags data = %s, want [[true, 1.5]]", got)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader error: %v", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		content, err := readZipFile(f)
+		if err != nil {
+			t.Fatalf("readZipFile error: %v", err)
+		}
+		sheetXML := string(content)
+		for _, fragment := range []string{`<c r="A1" s="1" t="inlineStr">`, `<c r="C2" s="2">`, `<c r="C3" s="3">`, `<col min="1" max="1" width="8"`} {
+			if !strings.Contains(sheetXML, fragment) {
+				t.Errorf("sheet1.xml is missing %s", fragment)
+			}
+		}
+	}
+}
+
+func TestExcelSheetsFromObjectErrors(t *testing.T) {
+	sheetList := &MShellList{Items: []MShellObject{}}
+	for _, name := range []string{"A", "a"} {
+		sheet := NewDict()
+		sheet.Items["name"] = MShellString{Content: name}
+		sheet.Items["data"] = &MShellList{}
+		sheetList.Items = append(sheetList.Items, sheet)
+	}
+	if _, err := excelSheetsFromObject(sheetList, excelWriteOptions{}); err == nil || !strings.Contains(err.Error(), "more than once") {
+		t.Errorf("duplicate sheet names: error = %v", err)
+	}
+
+	badCell := &MShellList{Items: []MShellObject{&MShellList{Items: []MShellObject{NewDict()}}}}
+	sheets, err := excelSheetsFromObject(badCell, excelWriteOptions{})
+	if err != nil {
+		t.Fatalf("excelSheetsFromObject error: %v", err)
+	}
+	if err := writeExcel(&bytes.Buffer{}, sheets, excelWriteOptions{}); err == nil || !strings.Contains(err.Error(), "A1") {
+		t.Errorf("dictionary cell: error = %v, want one naming A1", err)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"archive/zip"
 	"bytes"
+	"strings"
 	"testing"
 )
 
@@ -77,7 +78,7 @@ func buildMinimalXlsx(t *testing.T) []byte {
 
 func TestParseExcelBytes(t *testing.T) {
 	data := buildMinimalXlsx(t)
-	sheets, err := parseExcelBytes(data)
+	sheets, err := parseExcelBytes(data, excelReadOptions{})
 	if err != nil {
 		t.Fatalf("parseExcelBytes: %v", err)
 	}
@@ -161,3 +162,144 @@ func TestParseExcelBytes(t *testing.T) {
 		t.Errorf("Summary A1 formula-string: got %v", srow.Items[0])
 	}
 }
+
+func TestIsExcelDateFormatCode(t *testing.T) {
+	for code, want := range map[string]bool{
+		"yyyy-mm-dd":              true,
+		"[$-409]d-mmm-yy;@":       true,
+		"[h]:mm:ss":               true,
+		"h:mm AM/PM":              true,
+		"General":                 false,
+		"0.00":                    false,
+		`#,##0 "days"`:            false,
+		`0.0\h`:                   false,
+		"[Red]#,##0;[Blue]-#,##0": false,
+	} {
+		if got := isExcelDateFormatCode(code); got != want {
+			t.Errorf("isExcelDateFormatCode(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestShiftExcelFormula(t *testing.T) {
+	tests := []struct {
+		formula    string
+		dRow, dCol int
+		want       string
+	}{
+		{"A2*2", 1, 0, "A3*2"},
+		{"SUM($A$1:B2)+C$3", 2, 1, "SUM($A$1:C4)+D$3"},
+		{`LOG10(A1)&"B2"`, 1, 0, `LOG10(A2)&"B2"`},
+		{"'Q1 B2'!A1+Sheet2!B1", 3, 0, "'Q1 B2'!A4+Sheet2!B4"},
+	}
+	for _, tt := range tests {
+		if got := shiftExcelFormula(tt.formula, tt.dRow, tt.dCol); got != tt.want {
+			t.Errorf("shiftExcelFormula(%q, %d, %d) = %q, want %q", tt.formula, tt.dRow, tt.dCol, got, tt.want)
+		}
+	}
+}
+
+func buildStyledXlsx(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	writeZipFile(t, zw, "xl/workbook.xml",
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" `+
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`+
+			`<sheets><sheet name="Notes" sheetId="1" r:id="rId1"/><sheet name="Readings" sheetId="2" r:id="rId2"/></sheets></workbook>`)
+	writeZipFile(t, zw, "xl/_rels/workbook.xml.rels",
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>`+
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>`+
+			`</Relationships>`)
+	// Style 1 is the built-in short date; style 2 a custom date and time.
+	writeZipFile(t, zw, "xl/styles.xml",
+		`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+
+			`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm"/></numFmts>`+
+			`<cellXfs count="3"><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/></cellXfs>`+
+			`</styleSheet>`)
+	writeZipFile(t, zw, "xl/worksheets/sheet1.xml",
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`+
+			`<row r="1"><c r="A1" t="inlineStr"><is><t>skip me</t></is></c></row>`+
+			`</sheetData></worksheet>`)
+	// Readings: a merged title row, a header, and rows with a date, a
+	// number, and a shared formula filled down from C3.
+	writeZipFile(t, zw, "xl/worksheets/sheet2.xml",
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`+
+			`<row r="1"><c r="A1" t="inlineStr"><is><t>Meter</t></is></c></row>`+
+			`<row r="2"><c r="A2" t="inlineStr"><is><t>day</t></is></c><c r="B2" t="inlineStr"><is><t>kwh</t></is></c><c r="C2" t="inlineStr"><is><t>double</t></is></c></row>`+
+			`<row r="3"><c r="A3" s="1"><v>45352</v></c><c r="B3"><v>4</v></c><c r="C3"><f t="shared" ref="C3:C4" si="0">B3*2</f><v>8</v></c></row>`+
+			`<row r="4"><c r="A4" s="2"><v>45353.25</v></c><c r="B4"><v>5</v></c><c r="C4"><f t="shared" si="0"/><v>10</v></c></row>`+
+			`</sheetData><mergeCells count="1"><mergeCell ref="A1:C1"/></mergeCells></worksheet>`)
+
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseExcelBytesOptions(t *testing.T) {
+	data := buildStyledXlsx(t)
+	opts := excelReadOptions{
+		sheets:   []MShellObject{MShellString{Content: "readings"}},
+		dates:    true,
+		formulas: true,
+		merged:   true,
+	}
+	sheets, err := parseExcelBytes(data, opts)
+	if err != nil {
+		t.Fatalf("parseExcelBytes: %v", err)
+	}
+	if len(sheets.Items) != 1 {
+		t.Fatalf("expected only the selected sheet, got %d sheets", len(sheets.Items))
+	}
+	sheet := sheets.Items[0].(*MShellDict)
+	rows := sheet.Items["data"].(*MShellList)
+
+	if got := rows.Items[0].DebugString(); got != `["Meter" "Meter" "Meter"]` {
+		t.Errorf("merged title row = %s", got)
+	}
+	day, ok := rows.Items[2].(*MShellList).Items[0].(*MShellDateTime)
+	if !ok || day.Time.Format("2006-01-02 15:04") != "2024-03-01 00:00" {
+		t.Errorf("A3 built-in date: got %v", rows.Items[2].(*MShellList).Items[0])
+	}
+	stamp, ok := rows.Items[3].(*MShellList).Items[0].(*MShellDateTime)
+	if !ok || stamp.Time.Format("2006-01-02 15:04") != "2024-03-02 06:00" {
+		t.Errorf("A4 custom date: got %v", rows.Items[3].(*MShellList).Items[0])
+	}
+	if f, ok := rows.Items[2].(*MShellList).Items[1].(MShellFloat); !ok || f.Value != 4 {
+		t.Errorf("B3 unstyled number: got %v", rows.Items[2].(*MShellList).Items[1])
+	}
+
+	formulas := sheet.Items["formulas"].(*MShellList)
+	if got := formulas.Items[2].DebugString(); got != `["" "" "B3*2"]` {
+		t.Errorf("row 3 formulas = %s", got)
+	}
+	if got := formulas.Items[3].DebugString(); got != `["" "" "B4*2"]` {
+		t.Errorf("row 4 formulas = %s, want the shared formula shifted down", got)
+	}
+
+	if _, err := parseExcelBytes(data, excelReadOptions{sheets: []MShellObject{MShellInt{Value: 2}}}); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("sheet index 2: error = %v", err)
+	}
+}
+
+func TestReadExcelGrid(t *testing.T) {
+	data := buildStyledXlsx(t)
+	_, err := readExcelGrid(data, excelReadOptions{sheets: []MShellObject{MShellInt{Value: 1}}, header: true, dates: true, merged: true})
+	if err == nil || !strings.Contains(err.Error(), "duplicate column 'Meter'") {
+		t.Fatalf("merged title as header: error = %v", err)
+	}
+
+	grid, err := readExcelGrid(data, excelReadOptions{sheets: []MShellObject{MShellInt{Value: 1}}, dates: true})
+	if err != nil {
+		t.Fatalf("readExcelGrid: %v", err)
+	}
+	if grid.RowCount != 4 || len(grid.Columns) != 3 || grid.Columns[0].Name != "col1" {
+		t.Fatalf("grid without header = %s", grid.DebugString())
+	}
+	if kwh := grid.GetColumn("col2"); kwh.ColType != COL_GENERIC || !isNoneCell(kwh.Get(0)) {
+		t.Errorf("col2 should keep none for the empty title cell, got %s", kwh.Get(0).DebugString())
+	}
+}
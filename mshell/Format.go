@@ -0,0 +1,490 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// formatIndent is one level of indentation in formatted source.
+const formatIndent = "    "
+
+// formatLine is one line of source: its tokens, without whitespace, and
+// whether a blank line came before it.
+type formatLine struct {
+	tokens     []Token
+	spaced     []bool // spaced[i]: the source had whitespace before tokens[i]
+	blankAbove bool
+}
+
+// formatFrame is an open block while laying out lines. indent is the
+// indentation of the line that opened it; its contents go one level deeper.
+type formatFrame struct {
+	opener    TokenType
+	indent    int
+	inPattern bool // MATCH frames: the next tokens are an arm's pattern
+}
+
+// armFrame marks a match arm whose body starts on the line after its ':'.
+// The body is indented one level past the pattern and ends at the arm's ','.
+const armFrame TokenType = -1
+
+// FormatSource returns the canonical layout of an mshell program. Blocks
+// (if/else/end, match, def, prefix quotes, lists, quotes, dicts, and grids)
+// are indented four spaces per level, spacing inside brackets is normalized,
+// single-line match arms are aligned on their ':', and comments and blank
+// lines are kept. Formatting a formatted program leaves it unchanged.
+func FormatSource(source string) (string, error) {
+	if _, err := parseMShellInput(source, nil); err != nil {
+		return "", err
+	}
+
+	lexer := NewLexer(source, nil)
+	lexer.emitWhitespace = true
+	lexer.emitComments = true
+	tokens, err := lexer.Tokenize()
+	if err != nil {
+		return "", err
+	}
+
+	lines := splitFormatLines(tokens)
+	formatted := layoutFormatLines(lines)
+
+	if err := checkFormatPreservesTokens(source, formatted); err != nil {
+		return "", err
+	}
+	return formatted, nil
+}
+
+func splitFormatLines(tokens []Token) []formatLine {
+	lines := make([]formatLine, 0)
+	current := formatLine{}
+	spaced := false
+	blank := false
+
+	for _, t := range tokens {
+		switch t.Type {
+		case EOF:
+			if len(current.tokens) > 0 {
+				lines = append(lines, current)
+			}
+			return lines
+		case WHITESPACE:
+			newlines := strings.Count(t.Lexeme, "\n")
+			if newlines == 0 {
+				spaced = true
+				continue
+			}
+			if len(current.tokens) > 0 {
+				lines = append(lines, current)
+			}
+			// Blank lines at the top of the file are dropped; elsewhere runs
+			// of blank lines become one.
+			blank = newlines > 1 && len(lines) > 0
+			current = formatLine{blankAbove: blank}
+			spaced = false
+		default:
+			if t.Type == LINECOMMENT {
+				t.Lexeme = strings.TrimRight(t.Lexeme, " \t\r\v\f")
+			}
+			current.tokens = append(current.tokens, t)
+			current.spaced = append(current.spaced, spaced)
+			spaced = false
+		}
+	}
+	if len(current.tokens) > 0 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+func isFormatCloser(tokenType TokenType) bool {
+	switch tokenType {
+	case RIGHT_SQUARE_BRACKET, RIGHT_PAREN, RIGHT_CURLY, GRID_CLOSE, END, ELSE, ELSESTAR:
+		return true
+	}
+	return false
+}
+
+func isFormatOpener(tokenType TokenType) bool {
+	switch tokenType {
+	case LEFT_SQUARE_BRACKET, LEFT_PAREN, LEFT_CURLY, GRID_OPEN, IF, ELSE, STARIF, MATCH, DEF, PREFIXQUOTE:
+		return true
+	}
+	return false
+}
+
+// renderedLine is a laid-out line. When it is a single-line match arm,
+// match is the arm's block and separator the index of its ':' or ':>'.
+type renderedLine struct {
+	indent     int
+	parts      []string
+	blankAbove bool
+	match      *formatFrame
+	separator  int
+}
+
+func layoutFormatLines(lines []formatLine) string {
+	stack := make([]*formatFrame, 0)
+	top := func() *formatFrame {
+		if len(stack) == 0 {
+			return nil
+		}
+		return stack[len(stack)-1]
+	}
+	pop := func() *formatFrame {
+		frame := top()
+		if frame != nil {
+			stack = stack[:len(stack)-1]
+		}
+		return frame
+	}
+
+	rendered := make([]renderedLine, 0, len(lines))
+	var previous Token
+
+	for _, line := range lines {
+		indent := 0
+		if frame := top(); frame != nil {
+			indent = frame.indent + 1
+		}
+
+		var armMatch *formatFrame
+		if frame := top(); frame != nil && frame.opener == MATCH && frame.inPattern && line.tokens[0].Type != END {
+			armMatch = frame
+		}
+		out := renderedLine{blankAbove: line.blankAbove, separator: -1}
+
+		leading := true
+		for i, t := range line.tokens {
+			if leading && !isFormatCloser(t.Type) {
+				leading = false
+			}
+
+			switch t.Type {
+			case RIGHT_SQUARE_BRACKET, RIGHT_PAREN, RIGHT_CURLY, GRID_CLOSE, END, ELSE, ELSESTAR:
+				if t.Type == END {
+					for top() != nil && top().opener == armFrame {
+						pop()
+					}
+				}
+				if frame := pop(); frame != nil && leading {
+					indent = frame.indent
+				}
+				if t.Type == ELSE || t.Type == ELSESTAR {
+					leading = false
+				}
+			case COLON, MATCHARMDUP:
+				if frame := top(); frame != nil && frame.opener == MATCH && frame.inPattern {
+					frame.inPattern = false
+					if i == len(line.tokens)-1 {
+						stack = append(stack, &formatFrame{opener: armFrame, indent: indent})
+					} else if frame == armMatch && out.separator < 0 {
+						out.match = frame
+						out.separator = i
+					}
+				}
+			case COMMA:
+				// A comma after a variable store belongs to the store list,
+				// as in the parser, and does not end the arm.
+				if previous.Type != VARSTORE {
+					if frame := top(); frame != nil && frame.opener == armFrame {
+						pop()
+					}
+					if frame := top(); frame != nil && frame.opener == MATCH {
+						frame.inPattern = true
+					}
+				}
+			}
+
+			if isFormatOpener(t.Type) {
+				frame := &formatFrame{opener: t.Type, indent: indent}
+				if t.Type == MATCH {
+					frame.inPattern = true
+				}
+				stack = append(stack, frame)
+			}
+			previous = t
+		}
+
+		out.indent = indent
+		out.parts = renderFormatTokens(line, out.separator)
+		rendered = append(rendered, out)
+	}
+
+	alignMatchArms(rendered)
+
+	var b strings.Builder
+	for i, line := range rendered {
+		if line.blankAbove && i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(strings.Repeat(formatIndent, line.indent))
+		for _, part := range line.parts {
+			b.WriteString(part)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// renderFormatTokens joins a line's tokens with canonical spacing. Each part
+// is a token's text with the spacing that precedes it.
+func renderFormatTokens(line formatLine, separator int) []string {
+	parts := make([]string, len(line.tokens))
+	for i, t := range line.tokens {
+		if i == 0 {
+			parts[i] = t.Lexeme
+			continue
+		}
+		var gap string
+		if i == separator || i == separator+1 {
+			gap = " "
+		} else {
+			gap = formatGap(line.tokens[i-1], t, line.spaced[i])
+		}
+		parts[i] = gap + t.Lexeme
+	}
+	return parts
+}
+
+// formatGap is the spacing between two tokens on a line: none just inside
+// '[', '(', and '[|' or before ',', one space just inside '{ }' and after
+// ',', and otherwise one space where the source had any.
+func formatGap(a, b Token, spaced bool) string {
+	gap := ""
+	switch {
+	case a.Type == LEFT_CURLY && b.Type == RIGHT_CURLY:
+	case a.Type == LEFT_PAREN && b.Type == DOUBLEDASH, a.Type == DOUBLEDASH && b.Type == RIGHT_PAREN:
+		// Signatures keep their spacing: '( -- int)', '(int -- )'.
+		gap = " "
+	case b.Type == RIGHT_SQUARE_BRACKET || b.Type == RIGHT_PAREN || b.Type == GRID_CLOSE:
+	case a.Type == LEFT_SQUARE_BRACKET || a.Type == LEFT_PAREN || a.Type == GRID_OPEN:
+	case a.Type == LEFT_CURLY || b.Type == RIGHT_CURLY:
+		gap = " "
+	case b.Type == COMMA:
+	case a.Type == COMMA:
+		gap = " "
+	case spaced:
+		gap = " "
+	}
+
+	// Only join tokens that still lex apart, so '[ |' never becomes '[|'.
+	if gap == "" && spaced && !tokensLexApart(a, b) {
+		gap = " "
+	}
+	return gap
+}
+
+func tokensLexApart(a, b Token) bool {
+	tokens, err := NewLexer(a.Lexeme+b.Lexeme, nil).Tokenize()
+	if err != nil || len(tokens) != 3 {
+		return false
+	}
+	return tokens[0].Type == a.Type && tokens[0].Lexeme == a.Lexeme &&
+		tokens[1].Type == b.Type && tokens[1].Lexeme == b.Lexeme
+}
+
+// alignMatchArms pads consecutive single-line arms of the same match block
+// so their ':' separators line up.
+func alignMatchArms(lines []renderedLine) {
+	for start := 0; start < len(lines); {
+		match := lines[start].match
+		end := start + 1
+		if match != nil {
+			for end < len(lines) && lines[end].match == match && !lines[end].blankAbove {
+				end++
+			}
+		}
+
+		if end-start > 1 {
+			width := 0
+			for _, line := range lines[start:end] {
+				width = max(width, armPatternWidth(line))
+			}
+			for _, line := range lines[start:end] {
+				pad := width - armPatternWidth(line)
+				line.parts[line.separator] = strings.Repeat(" ", pad) + line.parts[line.separator]
+			}
+		}
+		start = end
+	}
+}
+
+func armPatternWidth(line renderedLine) int {
+	width := 0
+	for _, part := range line.parts[:line.separator] {
+		width += utf8.RuneCountInString(part)
+	}
+	return width
+}
+
+// checkFormatPreservesTokens guards against a layout change that would
+// alter the program: the formatted source must lex to the same tokens.
+func checkFormatPreservesTokens(source, formatted string) error {
+	significant := func(input string) ([]Token, error) {
+		lexer := NewLexer(input, nil)
+		lexer.emitComments = true
+		tokens, err := lexer.Tokenize()
+		if err != nil {
+			return nil, err
+		}
+		for i := range tokens {
+			if tokens[i].Type == LINECOMMENT {
+				tokens[i].Lexeme = strings.TrimRight(tokens[i].Lexeme, " \t\r\v\f")
+			}
+		}
+		return tokens, nil
+	}
+
+	before, err := significant(source)
+	if err != nil {
+		return err
+	}
+	after, err := significant(formatted)
+	if err != nil {
+		return fmt.Errorf("formatting produced source that does not lex: %w", err)
+	}
+	if len(before) != len(after) {
+		return errors.New("formatting changed the program's tokens")
+	}
+	for i := range before {
+		if before[i].Type != after[i].Type || before[i].Lexeme != after[i].Lexeme {
+			return fmt.Errorf("%d:%d: formatting changed the token '%s'", before[i].Line, before[i].Column, before[i].Lexeme)
+		}
+	}
+	return nil
+}
+
+// discoverFormatFiles expands the paths given to 'msh fmt': directories are
+// searched for '*.msh' files, and files are used as given.
+func discoverFormatFiles(paths []string) ([]string, error) {
+	files := make([]string, 0)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		found := make([]string, 0)
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(d.Name(), ".msh") {
+				found = append(found, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// runFmtCommand implements 'msh fmt'. Files are rewritten in place; with
+// --check, the files that are not formatted are listed instead and the
+// exit code is 1. With no paths, stdin is formatted to stdout.
+func runFmtCommand(args []string) int {
+	check := false
+	paths := make([]string, 0)
+	for _, arg := range args {
+		switch arg {
+		case "--check":
+			check = true
+		case "-h", "--help":
+			fmt.Println("Usage: msh fmt [--check] [PATH]..")
+			fmt.Println("")
+			fmt.Println("Formats mshell source files in place. Directories are searched for")
+			fmt.Println("'*.msh' files. With no paths, formats stdin to stdout.")
+			fmt.Println("")
+			fmt.Println("Options:")
+			fmt.Println("  --check  List the files that are not formatted and exit 1, without writing")
+			return 0
+		default:
+			if strings.HasPrefix(arg, "-") {
+				fmt.Fprintf(os.Stderr, "Unknown option for msh fmt: %s\n", arg)
+				return 1
+			}
+			paths = append(paths, arg)
+		}
+	}
+
+	if len(paths) == 0 {
+		source, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading stdin: %s\n", err)
+			return 1
+		}
+		formatted, err := FormatSource(string(source))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "<stdin>: %s\n", err)
+			return 1
+		}
+		if check {
+			if formatted != string(source) {
+				fmt.Println("<stdin>")
+				return 1
+			}
+			return 0
+		}
+		fmt.Print(formatted)
+		return 0
+	}
+
+	files, err := discoverFormatFiles(paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	exitCode := 0
+	for _, path := range files {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			exitCode = 1
+			continue
+		}
+		formatted, err := FormatSource(string(source))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			exitCode = 1
+			continue
+		}
+		if bytes.Equal(source, []byte(formatted)) {
+			continue
+		}
+		if check {
+			fmt.Println(path)
+			exitCode = 1
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			exitCode = 1
+			continue
+		}
+		if err := os.WriteFile(path, []byte(formatted), info.Mode().Perm()); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{
+			name:   "reindents blocks",
+			input:  "def f (int -- int)\n  dup 0 > if\n1 +\n      else\n  1 -\n end\nend\n",
+			expect: "def f (int -- int)\n    dup 0 > if\n        1 +\n    else\n        1 -\n    end\nend\n",
+		},
+		{
+			name:   "def signatures",
+			input:  "def f (  -- int)\n1\nend\ndef g (int str --   )\nend\ndef h (--)\nend\n",
+			expect: "def f ( -- int)\n    1\nend\ndef g (int str -- )\nend\ndef h ( -- )\nend\n",
+		},
+		{
+			name:   "else-if chains",
+			input:  "@x 1 = if\n'one'\nelse* @x 2 = *if\n'two'\nelse\n'many'\nend\n",
+			expect: "@x 1 = if\n    'one'\nelse* @x 2 = *if\n    'two'\nelse\n    'many'\nend\n",
+		},
+		{
+			name:   "bracket spacing",
+			input:  "[ 1  2 ] ( dup ) map {\"a\": 1 ,\"b\": 2} {} [|a, b; 1, 2|]\n",
+			expect: "[1 2] (dup) map { \"a\": 1, \"b\": 2 } {} [|a, b; 1, 2|]\n",
+		},
+		{
+			name:   "multi-line lists and quotes",
+			input:  "[\n'a'\n  'b'\n] (\nwl\n) each\n",
+			expect: "[\n    'a'\n    'b'\n] (\n    wl\n) each\n",
+		},
+		{
+			name:   "openers on one line indent once",
+			input:  "(x! @x match\n1 : 'one',\nend) map\n",
+			expect: "(x! @x match\n    1 : 'one',\nend) map\n",
+		},
+		{
+			name:   "aligns match arms",
+			input:  "@x match\n'a' : 1,\n'long' : 2,\n_ : 3,\nend\n",
+			expect: "@x match\n    'a'    : 1,\n    'long' : 2,\n    _      : 3,\nend\n",
+		},
+		{
+			name:   "multi-line arm bodies",
+			input:  "@x match\nlist l :\n@l len,\n_ : 0,\nend\n",
+			expect: "@x match\n    list l :\n        @l len,\n    _ : 0,\nend\n",
+		},
+		{
+			name:   "comments and blank lines",
+			input:  "\n\n# header   \ndef f ( -- )\n# inside\n1 wl   # trailing\n\n\n\n2 wl\nend\n\n\n",
+			expect: "# header\ndef f ( -- )\n    # inside\n    1 wl # trailing\n\n    2 wl\nend\n",
+		},
+		{
+			name:   "prefix quotes",
+			input:  "map. map. toCsvCell end \",\" join end\nfilter.\n@x 1 >\nend\n",
+			expect: "map. map. toCsvCell end \",\" join end\nfilter.\n    @x 1 >\nend\n",
+		},
+		{
+			name:   "multi-line strings are untouched",
+			input:  "def f ( -- )\n\"line one\n  line two\" wl\nend\n",
+			expect: "def f ( -- )\n    \"line one\n  line two\" wl\nend\n",
+		},
+		{
+			name:   "keeps tokens that would merge apart",
+			input:  "[ |ls| ]\n",
+			expect: "[ |ls| ]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatSource(tt.input)
+			if err != nil {
+				t.Fatalf("FormatSource error: %v", err)
+			}
+			if got != tt.expect {
+				t.Fatalf("FormatSource =\n%s\nwant\n%s", got, tt.expect)
+			}
+			again, err := FormatSource(got)
+			if err != nil {
+				t.Fatalf("FormatSource on formatted source error: %v", err)
+			}
+			if again != got {
+				t.Fatalf("formatting is not idempotent:\n%s\nthen\n%s", got, again)
+			}
+		})
+	}
+}
+
+func TestFormatSourceRejectsInvalidPrograms(t *testing.T) {
+	_, err := FormatSource("def f ( -- )\n[1 2\n")
+	if err == nil {
+		t.Fatal("expected an error for an unterminated list")
+	}
+	if strings.Contains(err.Error(), "formatting") {
+		t.Fatalf("expected the parse error, got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// gridCellIsNull reports whether a cell is missing: none, or a NaN float.
+func gridCellIsNull(col *GridColumn, idx int) bool {
+	switch col.ColType {
+	case COL_GENERIC:
+		cell := col.GenericData[idx]
+		if isNoneCell(cell) {
+			return true
+		}
+		f, ok := unwrapMaybeCell(cell).(MShellFloat)
+		return ok && math.IsNaN(f.Value)
+	case COL_FLOAT:
+		return math.IsNaN(col.FloatData[idx])
+	default:
+		return col.Nulls.has(idx)
+	}
+}
+
+// gridNullFill is a parsed fillNull options dictionary. Exactly one of value
+// and strategy is set.
+type gridNullFill struct {
+	value    MShellObject
+	strategy string
+	limit    int
+}
+
+// parseGridNullFill reads the fillNull options: 'value' for a constant, or
+// 'strategy' of forward, backward or linear, with an optional 'limit' on how
+// many consecutive nulls forward and backward fill.
+func parseGridNullFill(dict *MShellDict) (gridNullFill, error) {
+	var fill gridNullFill
+	for key := range dict.Items {
+		switch key {
+		case "value", "strategy", "limit":
+		default:
+			return fill, fmt.Errorf("fillNull has unknown option '%s'", key)
+		}
+	}
+
+	value, hasValue := dict.Items["value"]
+	strategy, hasStrategy, err := stringOption(dict, "strategy")
+	if err != nil {
+		return fill, err
+	}
+	if hasValue == hasStrategy {
+		return fill, fmt.Errorf("fillNull requires exactly one of the options 'value' and 'strategy'")
+	}
+
+	if hasValue {
+		if isNoneCell(value) || isContainerType(value) {
+			return fill, fmt.Errorf("fillNull value must be a non-container value, got %s", value.TypeName())
+		}
+		fill.value = value
+	} else {
+		switch strategy {
+		case "forward", "backward", "linear":
+			fill.strategy = strategy
+		default:
+			return fill, fmt.Errorf("fillNull strategy must be 'forward', 'backward', or 'linear', got '%s'", strategy)
+		}
+	}
+
+	limit, hasLimit, err := intOption(dict, "limit")
+	if err != nil {
+		return fill, err
+	}
+	if hasLimit {
+		if fill.strategy != "forward" && fill.strategy != "backward" {
+			return fill, fmt.Errorf("fillNull option 'limit' only applies to the forward and backward strategies")
+		}
+		if limit < 1 {
+			return fill, fmt.Errorf("Option 'limit' must be at least 1, got %d", limit)
+		}
+		fill.limit = limit
+	}
+	return fill, nil
+}
+
+// setGridCell writes value into row idx of a column, widening a typed
+// column when value does not fit it. Float columns also take ints.
+func setGridCell(col *GridColumn, idx int, value MShellObject) {
+	if n, ok := value.(MShellInt); ok && col.ColType == COL_FLOAT {
+		value = MShellFloat{Value: float64(n.Value)}
+	}
+	if col.ColType != COL_GENERIC && !gridValueFitsColumn(col.ColType, value) {
+		widenColumnToGeneric(col)
+	}
+	col.Set(idx, value)
+}
+
+// gridValueFitsColumn reports whether value can be stored in a column of
+// the given typed kind.
+func gridValueFitsColumn(kind ColumnType, value MShellObject) bool {
+	switch value.(type) {
+	case MShellInt:
+		return kind == COL_INT
+	case MShellFloat:
+		return kind == COL_FLOAT
+	case MShellString:
+		return kind == COL_STRING
+	case *MShellDateTime:
+		return kind == COL_DATETIME
+	}
+	return false
+}
+
+// fillGridColumnNulls fills the null cells of col in place. Generic columns
+// are re-typed afterwards, so a column whose gaps were its only non-uniform
+// cells regains typed storage.
+func fillGridColumnNulls(col *GridColumn, fill gridNullFill) error {
+	n := col.Len()
+	isNull := make([]bool, n)
+	anyNull := false
+	for i := range isNull {
+		isNull[i] = gridCellIsNull(col, i)
+		anyNull = anyNull || isNull[i]
+	}
+	if !anyNull {
+		return nil
+	}
+
+	switch fill.strategy {
+	case "":
+		for i := range isNull {
+			if isNull[i] {
+				setGridCell(col, i, fill.value)
+			}
+		}
+	case "forward", "backward":
+		start, end, step := 0, n, 1
+		if fill.strategy == "backward" {
+			start, end, step = n-1, -1, -1
+		}
+		last := -1
+		run := 0
+		for i := start; i != end; i += step {
+			if !isNull[i] {
+				last = i
+				run = 0
+				continue
+			}
+			run++
+			if last >= 0 && (fill.limit == 0 || run <= fill.limit) {
+				setGridCell(col, i, col.Get(last))
+			}
+		}
+	case "linear":
+		rows := make([]int, n)
+		for i := range rows {
+			rows[i] = i
+		}
+		values, err := gridColumnFloats(col, rows)
+		if err != nil {
+			return fmt.Errorf("fillNull linear strategy: %s", err.Error())
+		}
+		prev := -1
+		for i := 0; i < n; i++ {
+			if isNull[i] {
+				continue
+			}
+			if prev >= 0 && i-prev > 1 {
+				slope := (values[i] - values[prev]) / float64(i-prev)
+				for j := prev + 1; j < i; j++ {
+					setGridCell(col, j, MShellFloat{Value: values[prev] + slope*float64(j-prev)})
+				}
+			}
+			prev = i
+		}
+	}
+
+	optimizeColumnStorage(col)
+	return nil
+}
+
+// fillGridNulls materializes the rows of sourceGrid at sourceIndices and fills
+// the nulls of the named columns.
+func fillGridNulls(sourceGrid *MShellGrid, sourceIndices []int, colNames []string, fill gridNullFill) (*MShellGrid, error) {
+	newGrid := projectGridAllColumns(sourceGrid, sourceIndices)
+	for _, colName := range colNames {
+		if err := fillGridColumnNulls(newGrid.GetColumn(colName), fill); err != nil {
+			return nil, err
+		}
+	}
+	return newGrid, nil
+}
+
+// dropGridNullRows returns the source indices whose cells in cols are all
+// present.
+func dropGridNullRows(sourceGrid *MShellGrid, sourceIndices []int, colNames []string) []int {
+	cols := make([]*GridColumn, len(colNames))
+	for i, colName := range colNames {
+		cols[i] = sourceGrid.GetColumn(colName)
+	}
+
+	kept := make([]int, 0, len(sourceIndices))
+	for _, srcIdx := range sourceIndices {
+		keep := true
+		for _, col := range cols {
+			if gridCellIsNull(col, srcIdx) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			kept = append(kept, srcIdx)
+		}
+	}
+	return kept
+}
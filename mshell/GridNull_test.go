@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func gridNullTestColumn() *GridColumn {
+	none := &Maybe{obj: nil}
+	return &GridColumn{Name: "v", ColType: COL_GENERIC, GenericData: []MShellObject{none, MShellInt{Value: 1}, none, none, MShellInt{Value: 7}, none}}
+}
+
+func gridNullCells(col *GridColumn) []string {
+	cells := make([]string, col.Len())
+	for i := range cells {
+		if gridCellIsNull(col, i) {
+			cells[i] = "none"
+		} else {
+			cells[i] = col.Get(i).DebugString()
+		}
+	}
+	return cells
+}
+
+func TestFillGridColumnNulls(t *testing.T) {
+	tests := []struct {
+		name     string
+		fill     gridNullFill
+		want     []string
+		wantType ColumnType
+	}{
+		{"constant fill restores int storage", gridNullFill{value: MShellInt{Value: 0}}, []string{"0", "1", "0", "0", "7", "0"}, COL_INT},
+		{"forward fill leaves leading nulls", gridNullFill{strategy: "forward"}, []string{"none", "1", "1", "1", "7", "7"}, COL_GENERIC},
+		{"backward fill with a limit", gridNullFill{strategy: "backward", limit: 1}, []string{"1", "1", "none", "7", "7", "none"}, COL_GENERIC},
+		{"linear fill between known values", gridNullFill{strategy: "linear"}, []string{"none", "1", "3", "5", "7", "none"}, COL_GENERIC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			col := gridNullTestColumn()
+			if err := fillGridColumnNulls(col, tt.fill); err != nil {
+				t.Fatalf("fillGridColumnNulls error: %v", err)
+			}
+			if got := gridNullCells(col); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("cells = %v, want %v", got, tt.want)
+			}
+			if col.ColType != tt.wantType {
+				t.Fatalf("column type = %d, want %d", col.ColType, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestFillGridColumnNullsKeepsFloatStorage(t *testing.T) {
+	col := &GridColumn{Name: "f", ColType: COL_FLOAT, FloatData: []float64{1, math.NaN(), 3}}
+	if err := fillGridColumnNulls(col, gridNullFill{strategy: "linear"}); err != nil {
+		t.Fatalf("fillGridColumnNulls error: %v", err)
+	}
+	if col.ColType != COL_FLOAT || !reflect.DeepEqual(col.FloatData, []float64{1, 2, 3}) {
+		t.Fatalf("column = %d %v, want float [1 2 3]", col.ColType, col.FloatData)
+	}
+
+	col = &GridColumn{Name: "f", ColType: COL_FLOAT, FloatData: []float64{1, math.NaN()}}
+	if err := fillGridColumnNulls(col, gridNullFill{value: MShellString{Content: "n/a"}}); err != nil {
+		t.Fatalf("fillGridColumnNulls error: %v", err)
+	}
+	if col.ColType != COL_GENERIC || !reflect.DeepEqual(gridNullCells(col), []string{"1", "\"n/a\""}) {
+		t.Fatalf("column = %d %v, want a generic column [1 \"n/a\"]", col.ColType, gridNullCells(col))
+	}
+}
+
+func TestParseGridNullFillErrors(t *testing.T) {
+	tests := []map[string]MShellObject{
+		{},
+		{"value": MShellInt{Value: 0}, "strategy": MShellString{Content: "forward"}},
+		{"strategy": MShellString{Content: "mean"}},
+		{"strategy": MShellString{Content: "linear"}, "limit": MShellInt{Value: 1}},
+		{"strategy": MShellString{Content: "forward"}, "limit": MShellInt{Value: 0}},
+		{"value": NewList(0)},
+		{"fill": MShellInt{Value: 0}},
+	}
+	for _, items := range tests {
+		dict := NewDict()
+		for k, v := range items {
+			dict.Items[k] = v
+		}
+		if _, err := parseGridNullFill(dict); err == nil {
+			t.Errorf("expected an error for options %s", dict.DebugString())
+		}
+	}
+}
+
+func TestDropGridNullRows(t *testing.T) {
+	grid := NewGrid()
+	grid.RowCount = 6
+	grid.AddColumn(gridNullTestColumn())
+	grid.AddColumn(&GridColumn{Name: "w", ColType: COL_FLOAT, FloatData: []float64{0, 1, 2, math.NaN(), 4, 5}})
+	rows := []int{5, 4, 3, 2, 1, 0}
+	if got := dropGridNullRows(grid, rows, []string{"w"}); !reflect.DeepEqual(got, []int{5, 4, 2, 1, 0}) {
+		t.Errorf("rows without NaN in w = %v", got)
+	}
+	if got := dropGridNullRows(grid, rows, []string{"v", "w"}); !reflect.DeepEqual(got, []int{4, 1}) {
+		t.Errorf("rows without nulls = %v, want [4 1]", got)
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// timeInterval is a bucket width such as 15min, 1h, or 1mo.
+type timeInterval struct {
+	n    int
+	unit string
+}
+
+var timeIntervalRegex = regexp.MustCompile(`^(\d*)(s|min|h|d|w|mo|q|y)$`)
+
+var timeIntervalUnits = map[string]time.Duration{
+	"s":   time.Second,
+	"min": time.Minute,
+	"h":   time.Hour,
+}
+
+// parseTimeInterval parses an interval of an optional count and a unit: s,
+// min, h, d, w (weeks starting Monday), mo, q (quarters), or y.
+func parseTimeInterval(text string) (timeInterval, error) {
+	match := timeIntervalRegex.FindStringSubmatch(text)
+	if match == nil {
+		return timeInterval{}, fmt.Errorf("Invalid interval '%s', expected a count and a unit of s, min, h, d, w, mo, q, or y", text)
+	}
+	iv := timeInterval{n: 1, unit: match[2]}
+	if match[1] != "" {
+		n, err := strconv.Atoi(match[1])
+		if err != nil || n < 1 {
+			return timeInterval{}, fmt.Errorf("Invalid interval '%s', the count must be at least 1", text)
+		}
+		iv.n = n
+	}
+	if iv.unit == "q" {
+		iv.n *= 3
+		iv.unit = "mo"
+	}
+	return iv, nil
+}
+
+// floorMod is the modulo that rounds toward negative infinity.
+func floorMod(a, n int64) int64 {
+	return ((a % n) + n) % n
+}
+
+// truncate returns the start of the bucket containing t, in t's location.
+// Sub-day buckets count from midnight, day and week buckets from the Unix
+// epoch (weeks from Monday 1970-01-05), and month and year buckets from year
+// zero, so 3mo buckets are calendar quarters.
+func (iv timeInterval) truncate(t time.Time) time.Time {
+	loc := t.Location()
+	year, month, day := t.Date()
+	n := int64(iv.n)
+
+	switch iv.unit {
+	case "s", "min", "h":
+		midnight := time.Date(year, month, day, 0, 0, 0, 0, loc)
+		width := time.Duration(n) * timeIntervalUnits[iv.unit]
+		elapsed := t.Sub(midnight)
+		return midnight.Add(elapsed - elapsed%width)
+	case "d", "w":
+		days := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / 86400
+		if iv.unit == "d" {
+			days -= floorMod(days, n)
+		} else {
+			// 1970-01-05 was a Monday, four days after the epoch.
+			days -= floorMod(days-4, 7*n)
+		}
+		start := time.Unix(days*86400, 0).UTC()
+		return time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	case "mo":
+		months := int64(year)*12 + int64(month) - 1
+		months -= floorMod(months, n)
+		return time.Date(int(months/12), time.Month(months%12+1), 1, 0, 0, 0, 0, loc)
+	default:
+		y := int64(year)
+		y -= floorMod(y, n)
+		return time.Date(int(y), time.January, 1, 0, 0, 0, 0, loc)
+	}
+}
+
+// next returns the start of the bucket after the one starting at start.
+func (iv timeInterval) next(start time.Time) time.Time {
+	switch iv.unit {
+	case "s", "min", "h":
+		return iv.truncate(start.Add(time.Duration(iv.n) * timeIntervalUnits[iv.unit]))
+	case "d":
+		return start.AddDate(0, 0, iv.n)
+	case "w":
+		return start.AddDate(0, 0, 7*iv.n)
+	case "mo":
+		return start.AddDate(0, iv.n, 0)
+	default:
+		return start.AddDate(iv.n, 0, 0)
+	}
+}
+
+// gridResampleAgg is one output column of resample: agg applied to col.
+type gridResampleAgg struct {
+	col string
+	agg string
+}
+
+// gridAggNames are the named aggregations accepted by resample and groupBy.
+var gridAggNames = map[string]struct{}{
+	"sum":   {},
+	"mean":  {},
+	"min":   {},
+	"max":   {},
+	"count": {},
+	"first": {},
+	"last":  {},
+}
+
+// parseGridResampleAggs reads the resample aggregation dictionary, which maps
+// each source column to an aggregation name. Output columns keep the source
+// names and are returned sorted by name.
+func parseGridResampleAggs(aggDict *MShellDict, grid *MShellGrid, dtCol string) ([]gridResampleAgg, error) {
+	aggs := make([]gridResampleAgg, 0, len(aggDict.Items))
+	for colName, aggObj := range aggDict.Items {
+		if colName == dtCol {
+			return nil, fmt.Errorf("resample cannot aggregate the datetime column '%s'", dtCol)
+		}
+		if grid.GetColumn(colName) == nil {
+			return nil, fmt.Errorf("Column '%s' not found in grid", colName)
+		}
+		aggStr, ok := aggObj.(MShellString)
+		if !ok {
+			return nil, fmt.Errorf("resample aggregation for '%s' must be a string, got %s", colName, aggObj.TypeName())
+		}
+		if _, ok := gridAggNames[aggStr.Content]; !ok {
+			return nil, fmt.Errorf("resample aggregation for '%s' must be sum, mean, min, max, count, first, or last, got '%s'", colName, aggStr.Content)
+		}
+		aggs = append(aggs, gridResampleAgg{col: colName, agg: aggStr.Content})
+	}
+	sort.Slice(aggs, func(i, j int) bool { return aggs[i].col < aggs[j].col })
+	return aggs, nil
+}
+
+// gridDateTimeAt returns the datetime in row idx of col, or false for a null.
+func gridDateTimeAt(col *GridColumn, idx int) (time.Time, bool, error) {
+	if col.ColType == COL_DATETIME {
+		return col.DateTimeData[idx], !col.Nulls.has(idx), nil
+	}
+	if col.ColType != COL_GENERIC {
+		return time.Time{}, false, fmt.Errorf("Column '%s' is not a datetime column, it holds %s values", col.Name, gridColumnTypeLabel(col))
+	}
+	cell := col.GenericData[idx]
+	if isNoneCell(cell) {
+		return time.Time{}, false, nil
+	}
+	dt, ok := unwrapMaybeCell(cell).(*MShellDateTime)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("Column '%s' is not a datetime column, found %s", col.Name, unwrapMaybeCell(cell).TypeName())
+	}
+	return dt.Time, true, nil
+}
+
+// resampleGrid buckets rows by the datetime column and aggregates each
+// bucket. Rows with a null datetime are dropped. With fillGaps, buckets with
+// no rows between the first and last are emitted with a count of 0 and none
+// for the other aggregations.
+func resampleGrid(sourceGrid *MShellGrid, sourceIndices []int, dtCol string, iv timeInterval, aggs []gridResampleAgg, fillGaps bool) (*MShellGrid, error) {
+	timeCol := sourceGrid.GetColumn(dtCol)
+	if timeCol == nil {
+		return nil, fmt.Errorf("Column '%s' not found in grid", dtCol)
+	}
+
+	type timedRow struct {
+		t   time.Time
+		idx int
+	}
+	var rows []timedRow
+	for _, srcIdx := range sourceIndices {
+		t, ok, err := gridDateTimeAt(timeCol, srcIdx)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			rows = append(rows, timedRow{t: t, idx: srcIdx})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].t.Before(rows[j].t) })
+
+	var starts []time.Time
+	var buckets [][]int
+	for _, row := range rows {
+		start := iv.truncate(row.t)
+		if len(starts) > 0 && start.Equal(starts[len(starts)-1]) {
+			buckets[len(buckets)-1] = append(buckets[len(buckets)-1], row.idx)
+			continue
+		}
+		if fillGaps && len(starts) > 0 {
+			for gap := iv.next(starts[len(starts)-1]); gap.Before(start); gap = iv.next(gap) {
+				starts = append(starts, gap)
+				buckets = append(buckets, nil)
+			}
+		}
+		starts = append(starts, start)
+		buckets = append(buckets, []int{row.idx})
+	}
+
+	newGrid := NewGrid()
+	newGrid.Meta = sourceGrid.Meta
+	newGrid.RowCount = len(starts)
+	newGrid.AddColumn(&GridColumn{Name: dtCol, Meta: timeCol.Meta, ColType: COL_DATETIME, DateTimeData: starts})
+
+	for _, agg := range aggs {
+		srcCol := sourceGrid.GetColumn(agg.col)
+		newCol := NewGridColumn(agg.col, len(starts))
+		newCol.Meta = srcCol.Meta
+		for b, bucket := range buckets {
+			value, err := aggregateGridBucket(srcCol, bucket, agg.agg)
+			if err != nil {
+				return nil, err
+			}
+			newCol.GenericData[b] = value
+		}
+		optimizeColumnStorage(newCol)
+		newGrid.AddColumn(newCol)
+	}
+
+	return newGrid, nil
+}
+
+// aggregateGridBucket applies a resample aggregation to the rows of one
+// bucket, in time order. Aggregations other than count give none when the
+// bucket has no values. Sums, minimums and maximums of int columns stay ints.
+func aggregateGridBucket(col *GridColumn, rows []int, agg string) (MShellObject, error) {
+	switch agg {
+	case "count":
+		count := 0
+		for _, row := range rows {
+			if !gridCellIsNull(col, row) {
+				count++
+			}
+		}
+		return MShellInt{Value: count}, nil
+	case "first", "last":
+		for i := range rows {
+			row := rows[i]
+			if agg == "last" {
+				row = rows[len(rows)-1-i]
+			}
+			if !gridCellIsNull(col, row) {
+				return col.Get(row), nil
+			}
+		}
+		return &Maybe{obj: nil}, nil
+	}
+
+	if col.ColType == COL_INT && agg != "mean" {
+		var result int64
+		seen := false
+		for _, row := range rows {
+			if col.Nulls.has(row) {
+				continue
+			}
+			v := col.IntData[row]
+			if !seen {
+				result, seen = v, true
+				continue
+			}
+			switch agg {
+			case "sum":
+				result += v
+			case "min":
+				result = min(result, v)
+			case "max":
+				result = max(result, v)
+			}
+		}
+		if !seen {
+			return &Maybe{obj: nil}, nil
+		}
+		return MShellInt{Value: int(result)}, nil
+	}
+
+	values, err := gridColumnFloats(col, rows)
+	if err != nil {
+		return nil, err
+	}
+	values, _ = presentFloats(values)
+	if len(values) == 0 {
+		return &Maybe{obj: nil}, nil
+	}
+
+	result := values[0]
+	switch agg {
+	case "sum", "mean":
+		result = 0
+		for _, v := range values {
+			result += v
+		}
+		if agg == "mean" {
+			return MShellFloat{Value: result / float64(len(values))}, nil
+		}
+	case "min":
+		for _, v := range values[1:] {
+			result = math.Min(result, v)
+		}
+	case "max":
+		for _, v := range values[1:] {
+			result = math.Max(result, v)
+		}
+	}
+	return MShellFloat{Value: result}, nil
+}
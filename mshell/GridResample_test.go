@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeIntervalTruncate(t *testing.T) {
+	at := time.Date(2024, time.August, 14, 13, 47, 30, 0, time.UTC)
+	tests := []struct {
+		interval string
+		expect   string
+	}{
+		{"30s", "2024-08-14 13:47:30"},
+		{"15min", "2024-08-14 13:45:00"},
+		{"h", "2024-08-14 13:00:00"},
+		{"6h", "2024-08-14 12:00:00"},
+		{"1d", "2024-08-14 00:00:00"},
+		{"1w", "2024-08-12 00:00:00"},
+		{"1mo", "2024-08-01 00:00:00"},
+		{"q", "2024-07-01 00:00:00"},
+		{"2y", "2024-01-01 00:00:00"},
+	}
+	for _, tt := range tests {
+		iv, err := parseTimeInterval(tt.interval)
+		if err != nil {
+			t.Fatalf("parseTimeInterval(%q) error: %v", tt.interval, err)
+		}
+		got := iv.truncate(at).Format("2006-01-02 15:04:05")
+		if got != tt.expect {
+			t.Errorf("truncate %s = %s, want %s", tt.interval, got, tt.expect)
+		}
+	}
+}
+
+func TestTimeIntervalNextMonth(t *testing.T) {
+	iv, err := parseTimeInterval("1mo")
+	if err != nil {
+		t.Fatalf("parseTimeInterval error: %v", err)
+	}
+	start := iv.truncate(time.Date(2024, time.January, 31, 8, 0, 0, 0, time.UTC))
+	var got []string
+	for i := 0; i < 3; i++ {
+		start = iv.next(start)
+		got = append(got, start.Format("2006-01-02"))
+	}
+	if strings.Join(got, " ") != "2024-02-01 2024-03-01 2024-04-01" {
+		t.Fatalf("month starts = %v", got)
+	}
+}
+
+func TestParseTimeIntervalErrors(t *testing.T) {
+	for _, text := range []string{"", "5", "1m", "0h", "1.5h", "-1d"} {
+		if _, err := parseTimeInterval(text); err == nil {
+			t.Errorf("parseTimeInterval(%q) succeeded, want an error", text)
+		}
+	}
+}
+
+func TestResampleGridFillGaps(t *testing.T) {
+	base := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	grid := NewGrid()
+	grid.RowCount = 4
+	grid.AddColumn(&GridColumn{Name: "ts", ColType: COL_DATETIME, DateTimeData: []time.Time{
+		base.Add(70 * time.Minute),
+		base.Add(10 * time.Minute),
+		base.Add(20 * time.Minute),
+		base.Add(190 * time.Minute),
+	}})
+	grid.AddColumn(&GridColumn{Name: "kwh", ColType: COL_INT, IntData: []int64{4, 1, 2, 8}})
+
+	iv, err := parseTimeInterval("1h")
+	if err != nil {
+		t.Fatalf("parseTimeInterval error: %v", err)
+	}
+	aggDict := NewDict()
+	aggDict.Items["kwh"] = MShellString{Content: "sum"}
+	aggs, err := parseGridResampleAggs(aggDict, grid, "ts")
+	if err != nil {
+		t.Fatalf("parseGridResampleAggs error: %v", err)
+	}
+	_, indices, _ := getGridSourceAndIndices(grid)
+	result, err := resampleGrid(grid, indices, "ts", iv, aggs, true)
+	if err != nil {
+		t.Fatalf("resampleGrid error: %v", err)
+	}
+
+	if result.RowCount != 4 {
+		t.Fatalf("RowCount = %d, want 4", result.RowCount)
+	}
+	var hours, sums []string
+	kwh := result.GetColumn("kwh")
+	for i := 0; i < result.RowCount; i++ {
+		hours = append(hours, result.GetColumn("ts").DateTimeData[i].Format("15:04"))
+		if isNoneCell(kwh.Get(i)) {
+			sums = append(sums, "none")
+		} else {
+			sums = append(sums, kwh.Get(i).DebugString())
+		}
+	}
+	if got := strings.Join(hours, " "); got != "00:00 01:00 02:00 03:00" {
+		t.Errorf("bucket starts = %s", got)
+	}
+	if got := strings.Join(sums, " "); got != "3 4 none 8" {
+		t.Errorf("sums = %s", got)
+	}
+	if _, ok := kwh.Get(0).(MShellInt); !ok {
+		t.Errorf("int sum became %s", kwh.Get(0).TypeName())
+	}
+}
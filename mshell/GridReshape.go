@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+)
+
+// unpivotGrid turns every column not in idCols into rows: one output row per
+// (value column, source row), value column by value column. nameCol holds
+// the source column name and valueCol its cell. Value columns of one storage
+// type keep it; mixed types widen valueCol to generic, as with '+'.
+func unpivotGrid(sourceGrid *MShellGrid, sourceIndices []int, idCols []string, nameCol string, valueCol string) (*MShellGrid, error) {
+	isIdCol := make(map[string]struct{}, len(idCols))
+	for _, colName := range idCols {
+		if _, exists := isIdCol[colName]; exists {
+			return nil, fmt.Errorf("unpivot id column '%s' was requested more than once", colName)
+		}
+		if sourceGrid.GetColumn(colName) == nil {
+			return nil, fmt.Errorf("Column '%s' not found in grid", colName)
+		}
+		isIdCol[colName] = struct{}{}
+	}
+
+	if nameCol == valueCol {
+		return nil, fmt.Errorf("unpivot name and value columns must differ, both are '%s'", nameCol)
+	}
+	for _, outName := range []string{nameCol, valueCol} {
+		if _, exists := isIdCol[outName]; exists {
+			return nil, fmt.Errorf("unpivot output column '%s' conflicts with an id column", outName)
+		}
+	}
+
+	var valueCols []*GridColumn
+	for _, col := range sourceGrid.Columns {
+		if _, exists := isIdCol[col.Name]; !exists {
+			valueCols = append(valueCols, col)
+		}
+	}
+	if len(valueCols) == 0 {
+		return nil, fmt.Errorf("unpivot requires at least one column besides the id columns")
+	}
+
+	newGrid := NewGrid()
+	newGrid.Meta = sourceGrid.Meta
+	newGrid.RowCount = len(valueCols) * len(sourceIndices)
+
+	for _, colName := range idCols {
+		srcCol := sourceGrid.GetColumn(colName)
+		newCol := &GridColumn{Name: colName, Meta: srcCol.Meta, ColType: srcCol.ColType}
+		for range valueCols {
+			appendColumnRows(newCol, srcCol, sourceIndices)
+		}
+		newGrid.AddColumn(newCol)
+	}
+
+	names := &GridColumn{Name: nameCol, ColType: COL_STRING, StringData: make([]string, 0, newGrid.RowCount)}
+	values := &GridColumn{Name: valueCol, ColType: valueCols[0].ColType}
+	for _, srcCol := range valueCols {
+		for range sourceIndices {
+			names.StringData = append(names.StringData, srcCol.Name)
+		}
+		if resolveColType(values.ColType, srcCol.ColType) != values.ColType {
+			widenColumnToGeneric(values)
+		}
+		appendColumnRows(values, srcCol, sourceIndices)
+	}
+	newGrid.AddColumn(names)
+	newGrid.AddColumn(values)
+
+	return newGrid, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnpivotGrid(t *testing.T) {
+	grid, err := readCsvGrid(strings.NewReader("site,a,b,note\nX,1,2,hi\nY,3,4,yo\n"), defaultCsvGridOptions())
+	if err != nil {
+		t.Fatalf("readCsvGrid error: %v", err)
+	}
+
+	long, err := unpivotGrid(grid, []int{1, 0}, []string{"site", "note"}, "var", "val")
+	if err != nil {
+		t.Fatalf("unpivotGrid error: %v", err)
+	}
+	if got, want := long.GetColumn("site").StringData, []string{"Y", "X", "Y", "X"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("site = %q, want %q", got, want)
+	}
+	if got, want := long.GetColumn("var").StringData, []string{"a", "a", "b", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("var = %q, want %q", got, want)
+	}
+	val := long.GetColumn("val")
+	if val.ColType != COL_INT || !reflect.DeepEqual(val.IntData, []int64{3, 1, 4, 2}) {
+		t.Errorf("val = %d %v, want an int column [3 1 4 2]", val.ColType, val.IntData)
+	}
+
+	mixed, err := unpivotGrid(grid, []int{0, 1}, []string{"site"}, "var", "val")
+	if err != nil {
+		t.Fatalf("unpivotGrid error: %v", err)
+	}
+	val = mixed.GetColumn("val")
+	if val.ColType != COL_GENERIC || mixed.RowCount != 6 {
+		t.Fatalf("val type = %d with %d rows, want a generic column with 6 rows", val.ColType, mixed.RowCount)
+	}
+	if s, ok := val.Get(4).(MShellString); !ok || s.Content != "hi" {
+		t.Errorf("val[4] = %#v, want str hi", val.Get(4))
+	}
+	if i, ok := val.Get(1).(MShellInt); !ok || i.Value != 3 {
+		t.Errorf("val[1] = %#v, want int 3", val.Get(1))
+	}
+
+	if _, err := unpivotGrid(grid, []int{0}, []string{"site", "a", "b", "note"}, "var", "val"); err == nil {
+		t.Error("expected an error when no value columns remain")
+	}
+	if _, err := unpivotGrid(grid, []int{0}, []string{"site"}, "v", "v"); err == nil {
+		t.Error("expected an error for matching name and value columns")
+	}
+}
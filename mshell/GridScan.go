@@ -0,0 +1,527 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// gridScanBatchRows is how many data rows a scan parses before running its
+// filters and handing the survivors on.
+const gridScanBatchRows = 4096
+
+// errGridScanStopped ends a scan early without an error of its own, such as
+// when a filter quotation returns or exits.
+var errGridScanStopped = errors.New("grid scan stopped")
+
+// MShellGridScan is a lazy CSV scan: a file, the schema read from its header
+// and inference sample, and a plan of projections and filters. No data past
+// the sample is read until collect or groupBy runs the plan in one streaming
+// pass.
+type MShellGridScan struct {
+	Path     string
+	options  csvGridOptions
+	colNames []string
+	kinds    []ColumnType
+	declared []bool
+	steps    []gridScanStep
+}
+
+// gridScanStep is one plan step: a projection to cols, or a filter when
+// quote is set.
+type gridScanStep struct {
+	cols  []string
+	quote *MShellQuotation
+}
+
+// gridScanPredicate evaluates a filter quotation against one row.
+type gridScanPredicate func(quote *MShellQuotation, row *MShellGridRow) (bool, error)
+
+// newGridScan reads the header and inference sample of the CSV file at path
+// to fix the scan's column names and types.
+func newGridScan(path string, options csvGridOptions) (*MShellGridScan, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	colNames, sample, err := readCsvSchema(newCsvRecordReader(file, options), options)
+	if err != nil {
+		return nil, err
+	}
+	if colNames == nil {
+		return nil, fmt.Errorf("CSV file %s is empty", path)
+	}
+	kinds, declared := csvColumnKinds(colNames, sample, options)
+	return &MShellGridScan{Path: path, options: options, colNames: colNames, kinds: kinds, declared: declared}, nil
+}
+
+// Columns returns the columns available after the plan's projections.
+func (s *MShellGridScan) Columns() []string {
+	cols := s.colNames
+	for _, step := range s.steps {
+		if step.quote == nil {
+			cols = step.cols
+		}
+	}
+	return cols
+}
+
+// HasColumn reports whether name is available after the plan's projections.
+func (s *MShellGridScan) HasColumn(name string) bool {
+	for _, col := range s.Columns() {
+		if col == name {
+			return true
+		}
+	}
+	return false
+}
+
+// withStep returns a copy of the scan with step added to its plan, leaving
+// the receiver unchanged.
+func (s *MShellGridScan) withStep(step gridScanStep) *MShellGridScan {
+	next := *s
+	next.steps = append(append([]gridScanStep(nil), s.steps...), step)
+	return &next
+}
+
+// gridColumnSubset returns a grid sharing grid's column storage for cols.
+func gridColumnSubset(grid *MShellGrid, cols []string) *MShellGrid {
+	subset := NewGrid()
+	subset.RowCount = grid.RowCount
+	for _, name := range cols {
+		subset.AddColumn(grid.GetColumn(name))
+	}
+	return subset
+}
+
+// run executes the plan in one pass over the file, batch by batch. Only the
+// columns a filter can see or outCols names are parsed. emit gets each batch
+// as a grid of outCols along with the rows that passed every filter.
+func (s *MShellGridScan) run(outCols []string, keep gridScanPredicate, emit func(batch *MShellGrid, rows []int) error) error {
+	needed := make(map[string]struct{}, len(s.colNames))
+	available := s.colNames
+	for _, step := range s.steps {
+		if step.quote == nil {
+			available = step.cols
+			continue
+		}
+		for _, name := range available {
+			needed[name] = struct{}{}
+		}
+	}
+	for _, name := range outCols {
+		needed[name] = struct{}{}
+	}
+	var parseIdx []int
+	for i, name := range s.colNames {
+		if _, ok := needed[name]; ok {
+			parseIdx = append(parseIdx, i)
+		}
+	}
+
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := newCsvRecordReader(file, s.options)
+	colNames, pending, err := readCsvSchema(reader, s.options)
+	if err != nil {
+		return err
+	}
+	if strings.Join(colNames, "\x1f") != strings.Join(s.colNames, "\x1f") {
+		return fmt.Errorf("the columns of %s changed after scanCsv read its header", s.Path)
+	}
+
+	var builders []*csvColumnBuilder
+	newBatch := func() {
+		builders = make([]*csvColumnBuilder, len(parseIdx))
+		for j, i := range parseIdx {
+			builders[j] = newCsvColumnBuilder(s.colNames[i], s.kinds[i], s.declared[i])
+		}
+	}
+	newBatch()
+
+	rows, batchRows := 0, 0
+	flush := func() error {
+		batch := NewGrid()
+		batch.RowCount = batchRows
+		for _, b := range builders {
+			batch.AddColumn(b.col)
+		}
+		indices := make([]int, batchRows)
+		for i := range indices {
+			indices[i] = i
+		}
+
+		available := s.colNames
+		for _, step := range s.steps {
+			if step.quote == nil {
+				available = step.cols
+				continue
+			}
+			visible := gridColumnSubset(batch, available)
+			kept := indices[:0]
+			for _, idx := range indices {
+				ok, err := keep(step.quote, &MShellGridRow{Grid: visible, RowIndex: idx})
+				if err != nil {
+					return err
+				}
+				if ok {
+					kept = append(kept, idx)
+				}
+			}
+			indices = kept
+		}
+
+		newBatch()
+		batchRows = 0
+		if len(indices) == 0 {
+			return nil
+		}
+		return emit(gridColumnSubset(batch, outCols), indices)
+	}
+
+	addRecord := func(record []string) error {
+		for j, i := range parseIdx {
+			_, isNull := s.options.nulls[record[i]]
+			if err := builders[j].add(record[i], isNull); err != nil {
+				return fmt.Errorf("data row %d: %s", rows+1, err.Error())
+			}
+		}
+		rows++
+		batchRows++
+		if batchRows == gridScanBatchRows {
+			return flush()
+		}
+		return nil
+	}
+
+	for _, record := range pending {
+		if err := addRecord(record); err != nil {
+			return err
+		}
+	}
+	pending = nil
+
+	for !csvRowLimitReached(s.options, rows) {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if len(record) != len(s.colNames) {
+			return reader.errorf("record has %d fields but the CSV has %d columns", len(record), len(s.colNames))
+		}
+		if err := addRecord(record); err != nil {
+			return err
+		}
+	}
+
+	if batchRows > 0 {
+		return flush()
+	}
+	return nil
+}
+
+// collectGridScan runs the scan and gathers the surviving rows into a Grid.
+func collectGridScan(s *MShellGridScan, keep gridScanPredicate) (*MShellGrid, error) {
+	outCols := s.Columns()
+	grid := NewGrid()
+	for _, name := range outCols {
+		for i, colName := range s.colNames {
+			if colName == name {
+				grid.AddColumn(&GridColumn{Name: name, ColType: s.kinds[i]})
+			}
+		}
+	}
+
+	err := s.run(outCols, keep, func(batch *MShellGrid, rows []int) error {
+		for i, src := range batch.Columns {
+			dst := grid.Columns[i]
+			if resolveColType(dst.ColType, src.ColType) != dst.ColType {
+				widenColumnToGeneric(dst)
+			}
+			appendColumnRows(dst, src, rows)
+		}
+		grid.RowCount += len(rows)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return grid, nil
+}
+
+// groupByGridScan runs the scan and folds the surviving rows into one row per
+// distinct key. Only named aggregations are supported, so memory grows with
+// the number of groups rather than the number of rows.
+func groupByGridScan(s *MShellGridScan, keyCols []string, specs []gridGroupByAggSpec, keep gridScanPredicate) (*MShellGrid, error) {
+	outCols := append([]string(nil), keyCols...)
+	seenCols := make(map[string]struct{}, len(keyCols)+len(specs))
+	for _, keyCol := range keyCols {
+		seenCols[keyCol] = struct{}{}
+	}
+	for _, spec := range specs {
+		if _, seen := seenCols[spec.Col]; spec.Col != "" && !seen {
+			seenCols[spec.Col] = struct{}{}
+			outCols = append(outCols, spec.Col)
+		}
+	}
+
+	type scanGroup struct {
+		keys []MShellObject
+		accs []*gridAggAccumulator
+		rows int
+	}
+	var groups []*scanGroup
+	groupMap := make(map[string]*scanGroup)
+
+	err := s.run(outCols, keep, func(batch *MShellGrid, rows []int) error {
+		for _, row := range rows {
+			key, err := typedGridGroupKey(batch, row, keyCols)
+			if err != nil {
+				return err
+			}
+			group, exists := groupMap[key]
+			if !exists {
+				group = &scanGroup{keys: make([]MShellObject, len(keyCols)), accs: make([]*gridAggAccumulator, len(specs))}
+				for i, keyCol := range keyCols {
+					group.keys[i] = batch.GetColumn(keyCol).Get(row)
+				}
+				for i, spec := range specs {
+					group.accs[i] = newGridAggAccumulator(spec.Agg)
+				}
+				groupMap[key] = group
+				groups = append(groups, group)
+			}
+			group.rows++
+			for i, spec := range specs {
+				if spec.Col == "" {
+					continue
+				}
+				if err := group.accs[i].add(batch.GetColumn(spec.Col), row); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	newGrid := NewGrid()
+	newGrid.RowCount = len(groups)
+	for i, keyCol := range keyCols {
+		col := NewGridColumn(keyCol, len(groups))
+		for g, group := range groups {
+			col.GenericData[g] = group.keys[i]
+		}
+		newGrid.AddColumn(col)
+	}
+	for i, spec := range specs {
+		col := NewGridColumn(spec.Name, len(groups))
+		col.Meta = spec.Meta
+		for g, group := range groups {
+			if spec.Col == "" {
+				col.GenericData[g] = MShellInt{Value: group.rows}
+			} else {
+				col.GenericData[g] = group.accs[i].result()
+			}
+		}
+		newGrid.AddColumn(col)
+	}
+	for _, col := range newGrid.Columns {
+		optimizeColumnStorage(col)
+	}
+	return newGrid, nil
+}
+
+// MShellGridScan MShellObject interface {{{
+
+func (s *MShellGridScan) TypeName() string {
+	return "GridScan"
+}
+
+func (s *MShellGridScan) IsCommandLineable() bool {
+	return false
+}
+
+func (s *MShellGridScan) IsNumeric() bool {
+	return false
+}
+
+func (s *MShellGridScan) FloatNumeric() float64 {
+	return 0
+}
+
+func (s *MShellGridScan) CommandLine() string {
+	return ""
+}
+
+func (s *MShellGridScan) DebugString() string {
+	steps := make([]string, len(s.steps))
+	for i, step := range s.steps {
+		if step.quote == nil {
+			steps[i] = "select [" + strings.Join(step.cols, ", ") + "]"
+		} else {
+			steps[i] = "filter"
+		}
+	}
+	return fmt.Sprintf("GridScan{path: %s, cols: [%s], plan: [%s]}", s.Path, strings.Join(s.Columns(), ", "), strings.Join(steps, ", "))
+}
+
+func (s *MShellGridScan) Index(index int) (MShellObject, error) {
+	return nil, fmt.Errorf("Cannot index a GridScan. Use collect first.\n")
+}
+
+func (s *MShellGridScan) SliceStart(startInclusive int) (MShellObject, error) {
+	return nil, fmt.Errorf("Cannot slice a GridScan. Use collect first.\n")
+}
+
+func (s *MShellGridScan) SliceEnd(end int) (MShellObject, error) {
+	return nil, fmt.Errorf("Cannot slice a GridScan. Use collect first.\n")
+}
+
+func (s *MShellGridScan) Slice(startInc int, endExc int) (MShellObject, error) {
+	return nil, fmt.Errorf("Cannot slice a GridScan. Use collect first.\n")
+}
+
+func (s *MShellGridScan) ToJson() string {
+	encoded, _ := json.Marshal(s.Path)
+	return "{\"path\": " + string(encoded) + "}"
+}
+
+func (s *MShellGridScan) ToString() string {
+	return s.DebugString()
+}
+
+func (s *MShellGridScan) IndexErrStr() string {
+	return " GridScan"
+}
+
+func (s *MShellGridScan) Concat(other MShellObject) (MShellObject, error) {
+	return nil, fmt.Errorf("Cannot concatenate a GridScan. Use collect first.\n")
+}
+
+func (s *MShellGridScan) Equals(other MShellObject) (bool, error) {
+	return false, fmt.Errorf("Equality currently not defined for grid scans.\n")
+}
+
+func (s *MShellGridScan) CastString() (string, error) {
+	return "", fmt.Errorf("Cannot cast a GridScan to a string.\n")
+}
+
+// }}}
+
+// gridAggAccumulator folds the cells of one column into a named aggregation
+// a row at a time, so a scan groupBy never holds a group's rows.
+// Nulls are skipped, and sums, minimums and maximums stay ints while every
+// value is an int.
+type gridAggAccumulator struct {
+	agg    string
+	count  int
+	allInt bool
+	intAcc int64
+	acc    float64
+	value  MShellObject
+}
+
+func newGridAggAccumulator(agg string) *gridAggAccumulator {
+	return &gridAggAccumulator{agg: agg, allInt: true}
+}
+
+// add folds in row row of col.
+func (a *gridAggAccumulator) add(col *GridColumn, row int) error {
+	if gridCellIsNull(col, row) {
+		return nil
+	}
+	switch a.agg {
+	case "count":
+		a.count++
+		return nil
+	case "first", "last":
+		if a.count == 0 || a.agg == "last" {
+			a.value = col.Get(row)
+		}
+		a.count++
+		return nil
+	}
+
+	var i int64
+	var f float64
+	isInt := false
+	switch col.ColType {
+	case COL_INT:
+		i, f, isInt = col.IntData[row], float64(col.IntData[row]), true
+	case COL_FLOAT:
+		f = col.FloatData[row]
+	case COL_GENERIC:
+		switch num := unwrapMaybeCell(col.GenericData[row]).(type) {
+		case MShellInt:
+			i, f, isInt = int64(num.Value), float64(num.Value), true
+		case MShellFloat:
+			f = num.Value
+		default:
+			return fmt.Errorf("Column '%s' is not numeric, found %s", col.Name, num.TypeName())
+		}
+	default:
+		return fmt.Errorf("Column '%s' is not numeric, it holds %s values", col.Name, gridColumnTypeLabel(col))
+	}
+
+	first := a.count == 0
+	a.count++
+	a.allInt = a.allInt && isInt
+	switch a.agg {
+	case "sum", "mean":
+		a.intAcc += i
+		a.acc += f
+	case "min":
+		if first || f < a.acc {
+			a.acc = f
+		}
+		if first || i < a.intAcc {
+			a.intAcc = i
+		}
+	case "max":
+		if first || f > a.acc {
+			a.acc = f
+		}
+		if first || i > a.intAcc {
+			a.intAcc = i
+		}
+	}
+	return nil
+}
+
+// result returns the aggregation of the cells added so far. Aggregations
+// other than count give none when no value was added.
+func (a *gridAggAccumulator) result() MShellObject {
+	switch a.agg {
+	case "count":
+		return MShellInt{Value: a.count}
+	case "first", "last":
+		if a.value == nil {
+			return &Maybe{obj: nil}
+		}
+		return a.value
+	}
+	if a.count == 0 {
+		return &Maybe{obj: nil}
+	}
+	if a.agg == "mean" {
+		return MShellFloat{Value: a.acc / float64(a.count)}
+	}
+	if a.allInt {
+		return MShellInt{Value: int(a.intAcc)}
+	}
+	return MShellFloat{Value: a.acc}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeGridScanTestFile(t *testing.T, rows int) string {
+	t.Helper()
+	var sb strings.Builder
+	sb.WriteString("id,site,kwh,junk\n")
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&sb, "%d,%c,%d,x\n", i, 'A'+rune(i%3), i%10)
+	}
+	path := filepath.Join(t.TempDir(), "scan.csv")
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	return path
+}
+
+func TestGridScanSkipsUnselectedColumns(t *testing.T) {
+	path := writeGridScanTestFile(t, 10)
+	options := defaultCsvGridOptions()
+	// Every junk value fails its declared type, so parsing it would error.
+	options.types = map[string]ColumnType{"junk": COL_INT}
+	scan, err := newGridScan(path, options)
+	if err != nil {
+		t.Fatalf("newGridScan error: %v", err)
+	}
+
+	if _, err := collectGridScan(scan, nil); err == nil || !strings.Contains(err.Error(), "junk") {
+		t.Fatalf("collect of every column: error = %v, want a junk parse error", err)
+	}
+	grid, err := collectGridScan(scan.withStep(gridScanStep{cols: []string{"kwh", "id"}}), nil)
+	if err != nil {
+		t.Fatalf("collect after select error: %v", err)
+	}
+	if len(grid.Columns) != 2 || grid.Columns[0].Name != "kwh" || grid.RowCount != 10 {
+		t.Fatalf("collect after select = %s", grid.DebugString())
+	}
+	if grid.GetColumn("id").ColType != COL_INT {
+		t.Errorf("id column type = %d, want int", grid.GetColumn("id").ColType)
+	}
+}
+
+func TestGridScanGroupByAcrossBatches(t *testing.T) {
+	rows := 2*gridScanBatchRows + 17
+	path := writeGridScanTestFile(t, rows)
+	scan, err := newGridScan(path, defaultCsvGridOptions())
+	if err != nil {
+		t.Fatalf("newGridScan error: %v", err)
+	}
+
+	// Keep rows with an even kwh.
+	keep := func(quote *MShellQuotation, row *MShellGridRow) (bool, error) {
+		kwh, _ := row.Get("kwh")
+		return kwh.(MShellInt).Value%2 == 0, nil
+	}
+	filtered := scan.withStep(gridScanStep{quote: &MShellQuotation{}})
+	specs := []gridGroupByAggSpec{
+		{Name: "n", Agg: "count"},
+		{Name: "total", Agg: "sum", Col: "kwh"},
+	}
+	grid, err := groupByGridScan(filtered, []string{"site"}, specs, keep)
+	if err != nil {
+		t.Fatalf("groupByGridScan error: %v", err)
+	}
+
+	wantN := map[string]int{}
+	wantTotal := map[string]int{}
+	for i := 0; i < rows; i++ {
+		if kwh := i % 10; kwh%2 == 0 {
+			site := string(rune('A' + i%3))
+			wantN[site]++
+			wantTotal[site] += kwh
+		}
+	}
+	if grid.RowCount != 3 {
+		t.Fatalf("RowCount = %d, want 3", grid.RowCount)
+	}
+	for i := 0; i < grid.RowCount; i++ {
+		site := grid.GetColumn("site").Get(i).(MShellString).Content
+		n := grid.GetColumn("n").Get(i).(MShellInt).Value
+		total := grid.GetColumn("total").Get(i).(MShellInt).Value
+		if n != wantN[site] || total != wantTotal[site] {
+			t.Errorf("site %s: n = %d, total = %d, want %d and %d", site, n, total, wantN[site], wantTotal[site])
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math"
+	"strings"
+)
+
+// gridColumnNames returns the names of grid's columns in order.
+func gridColumnNames(grid *MShellGrid) []string {
+	names := make([]string, len(grid.Columns))
+	for i, col := range grid.Columns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// distinctGridRows returns the first of sourceIndices for each distinct
+// combination of values in cols. Nulls compare equal to each other here, as
+// in SQL DISTINCT, and an int equals a float with the same value.
+func distinctGridRows(sourceGrid *MShellGrid, sourceIndices []int, cols []string) ([]int, error) {
+	seen := make(map[string]struct{}, len(sourceIndices))
+	kept := make([]int, 0, len(sourceIndices))
+	for _, srcIdx := range sourceIndices {
+		key, err := gridSetRowKey(sourceGrid, srcIdx, cols)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		kept = append(kept, srcIdx)
+	}
+	return kept, nil
+}
+
+// gridSetOperation compares whole rows of two grids with the same column
+// names, matched by name. It returns the distinct left rows found in the
+// right grid when intersect is set, or missing from it otherwise, keeping
+// the first occurrence of each in left order. Numbers compare by value, so
+// an int column matches a float column holding the same numbers.
+func gridSetOperation(leftGrid *MShellGrid, leftIndices []int, rightGrid *MShellGrid, rightIndices []int, intersect bool) ([]int, error) {
+	cols := gridColumnNames(leftGrid)
+	rightKeys := make(map[string]struct{}, len(rightIndices))
+	for _, srcIdx := range rightIndices {
+		key, err := gridSetRowKey(rightGrid, srcIdx, cols)
+		if err != nil {
+			return nil, err
+		}
+		rightKeys[key] = struct{}{}
+	}
+
+	seen := make(map[string]struct{}, len(leftIndices))
+	kept := make([]int, 0, len(leftIndices))
+	for _, srcIdx := range leftIndices {
+		key, err := gridSetRowKey(leftGrid, srcIdx, cols)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		if _, found := rightKeys[key]; found == intersect {
+			kept = append(kept, srcIdx)
+		}
+	}
+	return kept, nil
+}
+
+// gridSetRowKey is typedGridGroupKey with a whole float keyed as the int of
+// the same value, so 1 and 1.0 give the same key.
+func gridSetRowKey(grid *MShellGrid, rowIdx int, cols []string) (string, error) {
+	parts := make([]string, len(cols))
+	for i, name := range cols {
+		cell := grid.GetColumn(name).Get(rowIdx)
+		if f, ok := cell.(MShellFloat); ok && f.Value == math.Trunc(f.Value) && math.Abs(f.Value) < math.MaxInt64 {
+			cell = MShellInt{Value: int(f.Value)}
+		}
+		part, err := typedGridGroupKeyPart(cell)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, "\x1f"), nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDistinctGridRowsTreatsNullsAsEqual(t *testing.T) {
+	grid := NewGrid()
+	grid.RowCount = 4
+	grid.AddColumn(&GridColumn{Name: "site", ColType: COL_GENERIC, GenericData: []MShellObject{
+		MShellString{Content: "A"},
+		&Maybe{obj: nil},
+		MShellString{Content: "A"},
+		&Maybe{obj: nil},
+	}})
+	grid.AddColumn(&GridColumn{Name: "kwh", ColType: COL_INT, IntData: []int64{1, 2, 3, 4}})
+
+	kept, err := distinctGridRows(grid, []int{0, 1, 2, 3}, []string{"site"})
+	if err != nil {
+		t.Fatalf("distinctGridRows error: %v", err)
+	}
+	if fmt.Sprint(kept) != "[0 1]" {
+		t.Errorf("distinct on site = %v, want [0 1]", kept)
+	}
+}
+
+func TestGridSetOperationMatchesColumnsByName(t *testing.T) {
+	left := NewGrid()
+	left.RowCount = 4
+	left.AddColumn(&GridColumn{Name: "id", ColType: COL_INT, IntData: []int64{1, 2, 2, 3}})
+	left.AddColumn(&GridColumn{Name: "kwh", ColType: COL_FLOAT, FloatData: []float64{1.5, 2.5, 2.5, 3.5}})
+
+	// Same columns in the other order, with an int id stored generically.
+	right := NewGrid()
+	right.RowCount = 2
+	right.AddColumn(&GridColumn{Name: "kwh", ColType: COL_FLOAT, FloatData: []float64{2.5, 3.5}})
+	right.AddColumn(&GridColumn{Name: "id", ColType: COL_GENERIC, GenericData: []MShellObject{MShellInt{Value: 2}, MShellInt{Value: 4}}})
+
+	leftIndices := []int{0, 1, 2, 3}
+	rightIndices := []int{0, 1}
+	except, err := gridSetOperation(left, leftIndices, right, rightIndices, false)
+	if err != nil {
+		t.Fatalf("except error: %v", err)
+	}
+	if fmt.Sprint(except) != "[0 3]" {
+		t.Errorf("except = %v, want [0 3]", except)
+	}
+	intersect, err := gridSetOperation(left, leftIndices, right, rightIndices, true)
+	if err != nil {
+		t.Fatalf("intersect error: %v", err)
+	}
+	if fmt.Sprint(intersect) != "[1]" {
+		t.Errorf("intersect = %v, want [1]", intersect)
+	}
+}
+
+func TestGridSetOperationComparesNumbersByValue(t *testing.T) {
+	left := NewGrid()
+	left.RowCount = 2
+	left.AddColumn(&GridColumn{Name: "a", ColType: COL_INT, IntData: []int64{1, 2}})
+	left.AddColumn(&GridColumn{Name: "b", ColType: COL_STRING, StringData: []string{"x", "y"}})
+
+	right := NewGrid()
+	right.RowCount = 2
+	right.AddColumn(&GridColumn{Name: "a", ColType: COL_FLOAT, FloatData: []float64{1, 2.5}})
+	right.AddColumn(&GridColumn{Name: "b", ColType: COL_STRING, StringData: []string{"x", "y"}})
+
+	except, err := gridSetOperation(left, []int{0, 1}, right, []int{0, 1}, false)
+	if err != nil {
+		t.Fatalf("except error: %v", err)
+	}
+	if fmt.Sprint(except) != "[1]" {
+		t.Errorf("except = %v, want [1]", except)
+	}
+	intersect, err := gridSetOperation(left, []int{0, 1}, right, []int{0, 1}, true)
+	if err != nil {
+		t.Fatalf("intersect error: %v", err)
+	}
+	if fmt.Sprint(intersect) != "[0]" {
+		t.Errorf("intersect = %v, want [0]", intersect)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+)
+
+// gridSortKey is one parsed gridSort spec.
+type gridSortKey struct {
+	col        *GridColumn
+	desc       bool
+	nullsFirst bool
+}
+
+// parseGridSortKeys reads a gridSort spec list. Each item is a column name,
+// sorted ascending with nulls last, or a {col, desc, nulls} dictionary.
+func parseGridSortKeys(specList *MShellList, grid *MShellGrid) ([]gridSortKey, error) {
+	if len(specList.Items) == 0 {
+		return nil, fmt.Errorf("gridSort requires at least one sort key")
+	}
+
+	allowedKeys := map[string]struct{}{
+		"col":   {},
+		"desc":  {},
+		"nulls": {},
+	}
+
+	keys := make([]gridSortKey, len(specList.Items))
+	for i, item := range specList.Items {
+		var colName string
+		switch spec := item.(type) {
+		case MShellString:
+			colName = spec.Content
+		case *MShellDict:
+			for key := range spec.Items {
+				if _, ok := allowedKeys[key]; !ok {
+					return nil, fmt.Errorf("gridSort spec %d has unknown key '%s'", i+1, key)
+				}
+			}
+
+			colObj, ok := spec.Items["col"]
+			if !ok {
+				return nil, fmt.Errorf("gridSort spec %d is missing required key 'col'", i+1)
+			}
+			colStr, ok := colObj.(MShellString)
+			if !ok {
+				return nil, fmt.Errorf("gridSort spec %d key 'col' must be a string, got %s", i+1, colObj.TypeName())
+			}
+			colName = colStr.Content
+
+			desc, _, err := boolOption(spec, "desc")
+			if err != nil {
+				return nil, fmt.Errorf("gridSort spec %d: %s", i+1, err.Error())
+			}
+			keys[i].desc = desc
+
+			nulls, hasNulls, err := stringOption(spec, "nulls")
+			if err != nil {
+				return nil, fmt.Errorf("gridSort spec %d: %s", i+1, err.Error())
+			}
+			if hasNulls {
+				switch nulls {
+				case "first":
+					keys[i].nullsFirst = true
+				case "last":
+				default:
+					return nil, fmt.Errorf("gridSort spec %d key 'nulls' must be 'first' or 'last', got '%s'", i+1, nulls)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("gridSort spec %d must be a column name or a dictionary, got %s", i+1, item.TypeName())
+		}
+
+		keys[i].col = grid.GetColumn(colName)
+		if keys[i].col == nil {
+			return nil, fmt.Errorf("Column '%s' not found in grid", colName)
+		}
+	}
+
+	return keys, nil
+}
+
+// compareGridRowsByKeys compares two source rows under the sort keys. Nulls are
+// placed before or after everything else regardless of direction; other
+// values use the typed comparisons in compareGridCellsForSort.
+func compareGridRowsByKeys(keys []gridSortKey, idxA, idxB int) (int, error) {
+	for _, key := range keys {
+		aNull := gridCellIsNull(key.col, idxA)
+		bNull := gridCellIsNull(key.col, idxB)
+		if aNull || bNull {
+			if aNull && bNull {
+				continue
+			}
+			if aNull == key.nullsFirst {
+				return -1, nil
+			}
+			return 1, nil
+		}
+
+		cmp, err := compareGridCellsForSort(key.col, idxA, idxB)
+		if err != nil {
+			return 0, err
+		}
+		if key.desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp, nil
+		}
+	}
+	return 0, nil
+}
+
+// mergeSortGridIndices stably sorts source row indices with a bottom-up merge
+// sort, stopping at the first comparison error. The returned slice may be the
+// input or a fresh buffer.
+func mergeSortGridIndices(indices []int, cmp func(int, int) (int, error)) ([]int, error) {
+	n := len(indices)
+	sorted := indices
+	work := make([]int, n)
+
+	for width := 1; width < n; width = 2 * width {
+		for i := 0; i < n; i = i + 2*width {
+			leftIndex := i
+			rightStart := min(i+width, n)
+			rightIndex := rightStart
+			end := min(i+2*width, n)
+
+			for k := i; k < end; k++ {
+				takeLeft := leftIndex < rightStart
+				if takeLeft && rightIndex < end {
+					c, err := cmp(sorted[leftIndex], sorted[rightIndex])
+					if err != nil {
+						return nil, err
+					}
+					takeLeft = c <= 0
+				}
+				if takeLeft {
+					work[k] = sorted[leftIndex]
+					leftIndex++
+				} else {
+					work[k] = sorted[rightIndex]
+					rightIndex++
+				}
+			}
+		}
+		sorted, work = work, sorted
+	}
+
+	return sorted, nil
+}
+
+// sortGridByKeys returns a Grid with the rows of sourceGrid at sourceIndices
+// ordered by keys.
+func sortGridByKeys(sourceGrid *MShellGrid, sourceIndices []int, keys []gridSortKey) (*MShellGrid, error) {
+	permutation := make([]int, len(sourceIndices))
+	copy(permutation, sourceIndices)
+
+	permutation, err := mergeSortGridIndices(permutation, func(a, b int) (int, error) {
+		return compareGridRowsByKeys(keys, a, b)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return projectGridAllColumns(sourceGrid, permutation), nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeSortGridIndicesIsStable(t *testing.T) {
+	keys := []int{3, 1, 2, 1, 3, 2}
+	indices := []int{0, 1, 2, 3, 4, 5}
+	got, err := mergeSortGridIndices(indices, func(a, b int) (int, error) {
+		return keys[a] - keys[b], nil
+	})
+	if err != nil {
+		t.Fatalf("mergeSortGridIndices error: %v", err)
+	}
+	if want := []int{1, 3, 2, 5, 0, 4}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+}
+
+func TestSortGridByKeys(t *testing.T) {
+	grid, err := readCsvGrid(strings.NewReader("id,kwh,site\n1,2.5,A\n2,,B\n3,1.5,A\n4,2.5,B\n5,,A\n"), defaultCsvGridOptions())
+	if err != nil {
+		t.Fatalf("readCsvGrid error: %v", err)
+	}
+	spec := func(col string, desc bool, nulls string) MShellObject {
+		dict := NewDict()
+		dict.Items["col"] = MShellString{Content: col}
+		dict.Items["desc"] = MShellBool{Value: desc}
+		if nulls != "" {
+			dict.Items["nulls"] = MShellString{Content: nulls}
+		}
+		return dict
+	}
+	tests := []struct {
+		name  string
+		specs []MShellObject
+		want  []int
+	}{
+		{"ascending keeps nulls last", []MShellObject{MShellString{Content: "kwh"}}, []int{3, 1, 4, 2, 5}},
+		{"descending keeps nulls last", []MShellObject{spec("kwh", true, "")}, []int{1, 4, 3, 2, 5}},
+		{"nulls first", []MShellObject{spec("kwh", true, "first")}, []int{2, 5, 1, 4, 3}},
+		{"later keys break ties", []MShellObject{spec("kwh", false, ""), spec("site", true, "")}, []int{3, 4, 1, 2, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specList := NewList(0)
+			specList.Items = tt.specs
+			keys, err := parseGridSortKeys(specList, grid)
+			if err != nil {
+				t.Fatalf("parseGridSortKeys error: %v", err)
+			}
+			_, indices, _ := getGridSourceAndIndices(grid)
+			sorted, err := sortGridByKeys(grid, indices, keys)
+			if err != nil {
+				t.Fatalf("sortGridByKeys error: %v", err)
+			}
+			ids := sorted.GetColumn("id").IntData
+			got := make([]int, len(ids))
+			for i, id := range ids {
+				got[i] = int(id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGridSortTreatsNaNAsNull(t *testing.T) {
+	col := &GridColumn{Name: "v", ColType: COL_FLOAT, FloatData: []float64{math.NaN(), 1}}
+	keys := []gridSortKey{{col: col}}
+	if cmp, err := compareGridRowsByKeys(keys, 0, 1); err != nil || cmp != 1 {
+		t.Fatalf("NaN vs 1 = %d, %v; want NaN after 1", cmp, err)
+	}
+	keys[0].nullsFirst = true
+	keys[0].desc = true
+	if cmp, err := compareGridRowsByKeys(keys, 0, 1); err != nil || cmp != -1 {
+		t.Fatalf("NaN vs 1 = %d, %v; want NaN first", cmp, err)
+	}
+}
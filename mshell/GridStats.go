@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// gridDescribePercentiles are the percentile columns of describe.
+var gridDescribePercentiles = []struct {
+	name string
+	q    float64
+}{
+	{"p25", 0.25},
+	{"p50", 0.5},
+	{"p75", 0.75},
+}
+
+// gridColumnFloats reads col at rows straight from its typed storage, one
+// float per row, with NaN standing in for none. Generic columns are accepted
+// when every value is an int or float.
+func gridColumnFloats(col *GridColumn, rows []int) ([]float64, error) {
+	values := make([]float64, len(rows))
+	switch col.ColType {
+	case COL_INT:
+		for i, row := range rows {
+			if col.Nulls.has(row) {
+				values[i] = math.NaN()
+			} else {
+				values[i] = float64(col.IntData[row])
+			}
+		}
+	case COL_FLOAT:
+		for i, row := range rows {
+			values[i] = col.FloatData[row]
+		}
+	case COL_GENERIC:
+		for i, row := range rows {
+			cell := col.GenericData[row]
+			if isNoneCell(cell) {
+				values[i] = math.NaN()
+				continue
+			}
+			switch num := unwrapMaybeCell(cell).(type) {
+			case MShellInt:
+				values[i] = float64(num.Value)
+			case MShellFloat:
+				values[i] = num.Value
+			default:
+				return nil, fmt.Errorf("Column '%s' is not numeric, found %s", col.Name, num.TypeName())
+			}
+		}
+	default:
+		return nil, fmt.Errorf("Column '%s' is not numeric, it holds %s values", col.Name, gridColumnTypeLabel(col))
+	}
+	return values, nil
+}
+
+// presentFloats drops the NaN entries from values in place and returns the
+// remaining values with the number dropped.
+func presentFloats(values []float64) ([]float64, int) {
+	present := values[:0]
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			present = append(present, v)
+		}
+	}
+	return present, len(values) - len(present)
+}
+
+// sortedQuantile returns the q quantile of sorted values, interpolating
+// linearly between the two nearest ranks.
+func sortedQuantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
+
+// gridQuantile returns the q quantile of a numeric column.
+func gridQuantile(col *GridColumn, rows []int, q float64) (float64, error) {
+	if q < 0 || q > 1 || math.IsNaN(q) {
+		return 0, fmt.Errorf("quantile must be between 0 and 1, got %g", q)
+	}
+	values, err := gridColumnFloats(col, rows)
+	if err != nil {
+		return 0, err
+	}
+	values, _ = presentFloats(values)
+	if len(values) == 0 {
+		return 0, fmt.Errorf("Column '%s' has no values to take a quantile of", col.Name)
+	}
+	sort.Float64s(values)
+	return sortedQuantile(values, q), nil
+}
+
+// gridCorr returns the Pearson correlation of two numeric columns over the
+// rows where both have a value. A column with no variance gives NaN.
+func gridCorr(colA, colB *GridColumn, rows []int) (float64, error) {
+	colX, err := gridColumnFloats(colA, rows)
+	if err != nil {
+		return 0, err
+	}
+	colY, err := gridColumnFloats(colB, rows)
+	if err != nil {
+		return 0, err
+	}
+	var xs, ys []float64
+	for i := range rows {
+		if !math.IsNaN(colX[i]) && !math.IsNaN(colY[i]) {
+			xs = append(xs, colX[i])
+			ys = append(ys, colY[i])
+		}
+	}
+	if len(xs) < 2 {
+		return 0, fmt.Errorf("corr needs at least two rows with values in both '%s' and '%s', found %d", colA.Name, colB.Name, len(xs))
+	}
+
+	n := float64(len(xs))
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	var cov, varX, varY float64
+	for i := range xs {
+		dx := xs[i] - meanX
+		dy := ys[i] - meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return math.NaN(), nil
+	}
+	return cov / math.Sqrt(varX*varY), nil
+}
+
+// gridHistogram counts the numeric values of col into bins given by
+// ascending edges. Bins are half open except the last, which includes its
+// upper edge; values outside the edges are not counted.
+func gridHistogram(col *GridColumn, rows []int, edges []float64) (*MShellGrid, error) {
+	if len(edges) < 2 {
+		return nil, fmt.Errorf("histogram needs at least two bin edges, got %d", len(edges))
+	}
+	for i := 1; i < len(edges); i++ {
+		if !(edges[i] > edges[i-1]) {
+			return nil, fmt.Errorf("histogram bin edges must be strictly increasing")
+		}
+	}
+	values, err := gridColumnFloats(col, rows)
+	if err != nil {
+		return nil, err
+	}
+	values, _ = presentFloats(values)
+
+	bins := len(edges) - 1
+	counts := make([]int64, bins)
+	for _, v := range values {
+		if v < edges[0] || v > edges[bins] {
+			continue
+		}
+		// The first edge above v closes its bin.
+		bin := sort.Search(bins, func(i int) bool { return v < edges[i+1] })
+		if bin == bins {
+			bin = bins - 1
+		}
+		counts[bin]++
+	}
+
+	grid := NewGrid()
+	grid.RowCount = bins
+	grid.AddColumn(&GridColumn{Name: "lower", ColType: COL_FLOAT, FloatData: append([]float64(nil), edges[:bins]...)})
+	grid.AddColumn(&GridColumn{Name: "upper", ColType: COL_FLOAT, FloatData: append([]float64(nil), edges[1:]...)})
+	grid.AddColumn(&GridColumn{Name: "count", ColType: COL_INT, IntData: counts})
+	return grid, nil
+}
+
+// gridHistogramEdges returns bins+1 equal width edges spanning the values of
+// col. When every value is the same, the bins span half a unit either side.
+func gridHistogramEdges(col *GridColumn, rows []int, bins int) ([]float64, error) {
+	if bins < 1 {
+		return nil, fmt.Errorf("histogram needs at least one bin, got %d", bins)
+	}
+	values, err := gridColumnFloats(col, rows)
+	if err != nil {
+		return nil, err
+	}
+	values, _ = presentFloats(values)
+	if len(values) == 0 {
+		return nil, fmt.Errorf("Column '%s' has no values to bin", col.Name)
+	}
+
+	lo, hi := values[0], values[0]
+	for _, v := range values[1:] {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	if lo == hi {
+		lo -= 0.5
+		hi += 0.5
+	}
+
+	edges := make([]float64, bins+1)
+	width := (hi - lo) / float64(bins)
+	for i := range edges {
+		edges[i] = lo + width*float64(i)
+	}
+	edges[bins] = hi
+	return edges, nil
+}
+
+// describeGrid summarizes each column of a grid as one row. Numeric columns
+// get count, nulls, mean, sample std, min, percentiles and max; string
+// columns get count, nulls and a distinct count. Statistics that do not
+// apply, or need more values than there are, are none.
+func describeGrid(sourceGrid *MShellGrid, sourceIndices []int) *MShellGrid {
+	statNames := []string{"count", "nulls", "mean", "std", "min"}
+	for _, p := range gridDescribePercentiles {
+		statNames = append(statNames, p.name)
+	}
+	statNames = append(statNames, "max", "distinct")
+
+	nCols := len(sourceGrid.Columns)
+	result := NewGrid()
+	result.RowCount = nCols
+
+	nameCol := &GridColumn{Name: "column", ColType: COL_STRING, StringData: make([]string, nCols)}
+	typeCol := &GridColumn{Name: "type", ColType: COL_STRING, StringData: make([]string, nCols)}
+	result.AddColumn(nameCol)
+	result.AddColumn(typeCol)
+	stats := make(map[string]*GridColumn, len(statNames))
+	for _, name := range statNames {
+		stats[name] = NewGridColumn(name, nCols)
+		result.AddColumn(stats[name])
+	}
+
+	for i, col := range sourceGrid.Columns {
+		nameCol.StringData[i] = col.Name
+		typeCol.StringData[i] = gridColumnTypeLabel(col)
+		for _, name := range statNames {
+			stats[name].GenericData[i] = &Maybe{obj: nil}
+		}
+
+		if values, err := gridColumnFloats(col, sourceIndices); err == nil {
+			values, nulls := presentFloats(values)
+			stats["count"].GenericData[i] = MShellInt{Value: len(values)}
+			stats["nulls"].GenericData[i] = MShellInt{Value: nulls}
+			if len(values) == 0 {
+				continue
+			}
+
+			sort.Float64s(values)
+			sum := 0.0
+			for _, v := range values {
+				sum += v
+			}
+			mean := sum / float64(len(values))
+			stats["mean"].GenericData[i] = MShellFloat{Value: mean}
+			if len(values) > 1 {
+				sq := 0.0
+				for _, v := range values {
+					sq += (v - mean) * (v - mean)
+				}
+				stats["std"].GenericData[i] = MShellFloat{Value: math.Sqrt(sq / float64(len(values)-1))}
+			}
+			stats["min"].GenericData[i] = MShellFloat{Value: values[0]}
+			for _, p := range gridDescribePercentiles {
+				stats[p.name].GenericData[i] = MShellFloat{Value: sortedQuantile(values, p.q)}
+			}
+			stats["max"].GenericData[i] = MShellFloat{Value: values[len(values)-1]}
+			continue
+		}
+
+		count, nulls := 0, 0
+		distinct := make(map[string]struct{})
+		isText := true
+		for _, row := range sourceIndices {
+			cell := col.Get(row)
+			if isNoneCell(cell) {
+				nulls++
+				continue
+			}
+			count++
+			if s, ok := unwrapMaybeCell(cell).(MShellString); ok {
+				distinct[s.Content] = struct{}{}
+			} else {
+				isText = false
+			}
+		}
+		stats["count"].GenericData[i] = MShellInt{Value: count}
+		stats["nulls"].GenericData[i] = MShellInt{Value: nulls}
+		if isText {
+			stats["distinct"].GenericData[i] = MShellInt{Value: len(distinct)}
+		}
+	}
+
+	for _, name := range statNames {
+		optimizeColumnStorage(stats[name])
+	}
+	return result
+}
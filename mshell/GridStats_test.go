@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func gridStatsTestGrid(t *testing.T) (*MShellGrid, []int) {
+	t.Helper()
+	grid, err := readCsvGrid(strings.NewReader("n,x,y,label\n1,1.0,2,a\n2,,4,b\n3,3.0,6,a\n4,4.0,7,\n"), defaultCsvGridOptions())
+	if err != nil {
+		t.Fatalf("readCsvGrid error: %v", err)
+	}
+	_, rows, _ := getGridSourceAndIndices(grid)
+	return grid, rows
+}
+
+func TestGridColumnFloatsMarksNullsWithNaN(t *testing.T) {
+	grid, rows := gridStatsTestGrid(t)
+	values, err := gridColumnFloats(grid.GetColumn("x"), rows)
+	if err != nil {
+		t.Fatalf("gridColumnFloats error: %v", err)
+	}
+	if len(values) != 4 || values[0] != 1 || !math.IsNaN(values[1]) || values[3] != 4 {
+		t.Fatalf("values = %v, want [1 NaN 3 4]", values)
+	}
+	if _, err := gridColumnFloats(grid.GetColumn("label"), rows); err == nil {
+		t.Fatal("expected an error for a string column")
+	}
+}
+
+func TestGridQuantile(t *testing.T) {
+	grid, rows := gridStatsTestGrid(t)
+	tests := []struct {
+		col  string
+		q    float64
+		want float64
+	}{
+		{"n", 0, 1},
+		{"n", 0.5, 2.5},
+		{"n", 1, 4},
+		{"x", 0.5, 3},
+		{"y", 0.25, 3.5},
+	}
+	for _, tt := range tests {
+		got, err := gridQuantile(grid.GetColumn(tt.col), rows, tt.q)
+		if err != nil || got != tt.want {
+			t.Errorf("quantile(%s, %g) = %g, %v; want %g", tt.col, tt.q, got, err, tt.want)
+		}
+	}
+	if _, err := gridQuantile(grid.GetColumn("n"), rows, 1.5); err == nil {
+		t.Error("expected an error for q outside [0, 1]")
+	}
+	if _, err := gridQuantile(grid.GetColumn("x"), []int{1}, 0.5); err == nil {
+		t.Error("expected an error when every value is none")
+	}
+}
+
+func TestGridCorr(t *testing.T) {
+	grid, rows := gridStatsTestGrid(t)
+	got, err := gridCorr(grid.GetColumn("n"), grid.GetColumn("x"), rows)
+	if err != nil || math.Abs(got-1) > 1e-12 {
+		t.Fatalf("corr(n, x) = %g, %v; want 1", got, err)
+	}
+	constant := &GridColumn{Name: "c", ColType: COL_INT, IntData: []int64{5, 5, 5, 5}}
+	if got, err := gridCorr(grid.GetColumn("n"), constant, rows); err != nil || !math.IsNaN(got) {
+		t.Fatalf("corr with a constant column = %g, %v; want NaN", got, err)
+	}
+	if _, err := gridCorr(grid.GetColumn("n"), grid.GetColumn("x"), []int{0, 1}); err == nil {
+		t.Fatal("expected an error with fewer than two complete rows")
+	}
+}
+
+func TestGridHistogram(t *testing.T) {
+	grid, rows := gridStatsTestGrid(t)
+	edges, err := gridHistogramEdges(grid.GetColumn("y"), rows, 5)
+	if err != nil {
+		t.Fatalf("gridHistogramEdges error: %v", err)
+	}
+	if want := []float64{2, 3, 4, 5, 6, 7}; !reflect.DeepEqual(edges, want) {
+		t.Fatalf("edges = %v, want %v", edges, want)
+	}
+	hist, err := gridHistogram(grid.GetColumn("y"), rows, edges)
+	if err != nil {
+		t.Fatalf("gridHistogram error: %v", err)
+	}
+	if got, want := hist.GetColumn("count").IntData, []int64{1, 0, 1, 0, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("counts = %v, want %v", got, want)
+	}
+
+	edges, err = gridHistogramEdges(&GridColumn{Name: "c", ColType: COL_INT, IntData: []int64{3, 3}}, []int{0, 1}, 2)
+	if err != nil || !reflect.DeepEqual(edges, []float64{2.5, 3, 3.5}) {
+		t.Fatalf("edges for a constant column = %v, %v; want [2.5 3 3.5]", edges, err)
+	}
+	if _, err := gridHistogram(grid.GetColumn("y"), rows, []float64{1, 1}); err == nil {
+		t.Fatal("expected an error for edges that do not increase")
+	}
+}
+
+func TestDescribeGrid(t *testing.T) {
+	grid, rows := gridStatsTestGrid(t)
+	summary := describeGrid(grid, rows)
+	if got, want := summary.GetColumn("column").StringData, []string{"n", "x", "y", "label"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("columns = %q, want %q", got, want)
+	}
+	if got, want := summary.GetColumn("nulls").IntData, []int64{0, 1, 0, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("nulls = %v, want %v", got, want)
+	}
+	if f, ok := summary.GetColumn("mean").Get(0).(MShellFloat); !ok || f.Value != 2.5 {
+		t.Errorf("mean of n = %#v, want 2.5", summary.GetColumn("mean").Get(0))
+	}
+	if f, ok := summary.GetColumn("std").Get(0).(MShellFloat); !ok || math.Abs(f.Value-math.Sqrt(5.0/3.0)) > 1e-12 {
+		t.Errorf("std of n = %#v, want the sample standard deviation", summary.GetColumn("std").Get(0))
+	}
+	if !isNoneCell(summary.GetColumn("mean").Get(3)) {
+		t.Errorf("mean of label = %#v, want none", summary.GetColumn("mean").Get(3))
+	}
+	if i, ok := summary.GetColumn("distinct").Get(3).(MShellInt); !ok || i.Value != 3 {
+		t.Errorf("distinct of label = %#v, want 3", summary.GetColumn("distinct").Get(3))
+	}
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// Box-drawn table rendering for grids, used when a grid is written with
+// w/wl, when a grid lands on the interactive stack, and by the gridPager
+// builtin.
+
+const (
+	// gridTableMaxCellWidth caps a column's width; longer cells are
+	// shortened with TruncateMiddle.
+	gridTableMaxCellWidth = 40
+	// gridTableWidthSample bounds how many rows are scanned to size the
+	// columns, so very large grids open quickly.
+	gridTableWidthSample = 10000
+	// gridTableTerminalRows is how many rows a terminal preview shows before
+	// eliding the middle of the grid.
+	gridTableTerminalRows = 20
+)
+
+// gridTableOptions controls the size of a rendered table. Zero values mean
+// no limit.
+type gridTableOptions struct {
+	maxWidth int // total width in columns, including borders
+	maxRows  int // data rows shown; the middle rows are elided past this
+}
+
+// gridTableLayout holds the column headers, types, widths, and alignment
+// for a grid so the static renderer and the pager lay out cells the same way.
+type gridTableLayout struct {
+	src    gridWriteSource
+	cells  gridWriteOptions
+	names  []string
+	types  []string
+	widths []int
+	right  []bool
+}
+
+func newGridTableLayout(src gridWriteSource) *gridTableLayout {
+	cols := src.grid.Columns
+	layout := &gridTableLayout{
+		src:    src,
+		cells:  defaultGridWriteOptions(','),
+		names:  make([]string, len(cols)),
+		types:  make([]string, len(cols)),
+		widths: make([]int, len(cols)),
+		right:  make([]bool, len(cols)),
+	}
+	layout.cells.null = "none"
+
+	sample := min(src.rowCount(), gridTableWidthSample)
+	for j, col := range cols {
+		layout.names[j] = gridTableText(col.Name)
+		layout.types[j] = gridColumnTypeLabel(col)
+		layout.right[j] = isNumericGridColumn(col, src)
+		width := max(utf8.RuneCountInString(layout.names[j]), utf8.RuneCountInString(layout.types[j]))
+		for i := 0; i < sample; i++ {
+			width = max(width, utf8.RuneCountInString(layout.cell(j, i)))
+		}
+		layout.widths[j] = min(width, gridTableMaxCellWidth)
+	}
+	return layout
+}
+
+// cell returns the display text for column j at view row i.
+func (layout *gridTableLayout) cell(j int, i int) string {
+	text, ok := layout.cells.formatCell(layout.src.grid.Columns[j], layout.src.sourceRow(i))
+	if !ok {
+		text = layout.cells.null
+	}
+	return gridTableText(text)
+}
+
+// pad truncates or pads text to the width of column j, honoring alignment.
+func (layout *gridTableLayout) pad(j int, text string) string {
+	width := layout.widths[j]
+	text = TruncateMiddle(text, width)
+	if n := utf8.RuneCountInString(text); n > width {
+		text = string([]rune(text)[:width])
+	} else if n < width {
+		if layout.right[j] {
+			return strings.Repeat(" ", width-n) + text
+		}
+		return text + strings.Repeat(" ", width-n)
+	}
+	return text
+}
+
+// gridTableText keeps a cell on one line.
+var gridTableText = strings.NewReplacer("\r\n", "↵", "\n", "↵", "\r", "↵", "\t", " ").Replace
+
+func gridColumnTypeLabel(col *GridColumn) string {
+	switch col.ColType {
+	case COL_INT:
+		return "int"
+	case COL_FLOAT:
+		return "float"
+	case COL_STRING:
+		return "str"
+	case COL_DATETIME:
+		return "datetime"
+	}
+	return "any"
+}
+
+// visibleColumns picks the columns that fit in maxWidth, keeping columns
+// from both ends. elideAfter is the index in the result after which a "…"
+// column is drawn, or -1 when every column fits.
+func (layout *gridTableLayout) visibleColumns(maxWidth int) ([]int, int) {
+	n := len(layout.widths)
+	all := make([]int, n)
+	total := 1
+	for j := range all {
+		all[j] = j
+		total += layout.widths[j] + 3
+	}
+	if maxWidth <= 0 || total <= maxWidth || n <= 1 {
+		return all, -1
+	}
+
+	// Reserve room for the left border and the elision column.
+	used := 1 + 4
+	var left, right []int
+	l, r := 0, n-1
+	for l <= r {
+		takeLeft := len(left) <= len(right)
+		j := r
+		if takeLeft {
+			j = l
+		}
+		if used+layout.widths[j]+3 > maxWidth {
+			break
+		}
+		used += layout.widths[j] + 3
+		if takeLeft {
+			left = append(left, j)
+			l++
+		} else {
+			right = append(right, j)
+			r--
+		}
+	}
+	if len(left) == 0 {
+		// Always show the first column, shrunk to fit.
+		layout.widths[0] = max(1, min(layout.widths[0], maxWidth-1-4-3))
+		left = []int{0}
+		right = nil
+	}
+	visible := append([]int{}, left...)
+	for k := len(right) - 1; k >= 0; k-- {
+		visible = append(visible, right[k])
+	}
+	return visible, len(left) - 1
+}
+
+// renderGridTable writes the whole grid, or its ends when it exceeds the
+// row or width limits, as a box-drawn table followed by a size footer.
+func renderGridTable(w io.Writer, obj MShellObject, opts gridTableOptions) error {
+	src, ok := newGridWriteSource(obj)
+	if !ok {
+		return fmt.Errorf("Cannot render a %s as a table", obj.TypeName())
+	}
+	layout := newGridTableLayout(src)
+	visible, elideAfter := layout.visibleColumns(opts.maxWidth)
+
+	var buf bytes.Buffer
+	border := func(leftCh, midCh, rightCh string) {
+		buf.WriteString(leftCh)
+		for k, j := range visible {
+			if k > 0 {
+				buf.WriteString(midCh)
+			}
+			buf.WriteString(strings.Repeat("─", layout.widths[j]+2))
+			if k == elideAfter {
+				buf.WriteString(midCh + "───")
+			}
+		}
+		buf.WriteString(rightCh + "\n")
+	}
+	row := func(text func(j int) string, elided string) {
+		buf.WriteString("│")
+		for k, j := range visible {
+			buf.WriteString(" " + layout.pad(j, text(j)) + " │")
+			if k == elideAfter {
+				buf.WriteString(" " + elided + " │")
+			}
+		}
+		buf.WriteString("\n")
+	}
+
+	border("┌", "┬", "┐")
+	row(func(j int) string { return layout.names[j] }, "…")
+	row(func(j int) string { return layout.types[j] }, " ")
+	border("├", "┼", "┤")
+
+	n := src.rowCount()
+	head, tail := n, 0
+	if opts.maxRows > 0 && n > opts.maxRows {
+		head = (opts.maxRows + 1) / 2
+		tail = opts.maxRows - head
+	}
+	for i := 0; i < head; i++ {
+		row(func(j int) string { return layout.cell(j, i) }, "…")
+	}
+	if head < n {
+		row(func(j int) string { return "…" }, "…")
+		for i := n - tail; i < n; i++ {
+			row(func(j int) string { return layout.cell(j, i) }, "…")
+		}
+	}
+	border("└", "┴", "┘")
+
+	rowNoun := "rows"
+	if n == 1 {
+		rowNoun = "row"
+	}
+	colNoun := "columns"
+	if len(layout.widths) == 1 {
+		colNoun = "column"
+	}
+	fmt.Fprintf(&buf, "%s: %d %s × %d %s\n", obj.TypeName(), n, rowNoun, len(layout.widths), colNoun)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// terminalTableOptions sizes a table for w when it is a terminal. Other
+// writers (files, pipes) get the whole table.
+func terminalTableOptions(w io.Writer) gridTableOptions {
+	file, ok := w.(*os.File)
+	if !ok || !term.IsTerminal(int(file.Fd())) {
+		return gridTableOptions{}
+	}
+	width, _, err := term.GetSize(int(file.Fd()))
+	if err != nil {
+		return gridTableOptions{maxRows: gridTableTerminalRows}
+	}
+	return gridTableOptions{maxWidth: width, maxRows: gridTableTerminalRows}
+}
+
+// topGridObject returns the Grid or GridView on top of the stack, or nil.
+// The interactive loop compares it before and after a command to preview
+// grids that land on the stack.
+func topGridObject(stack MShellStack) MShellObject {
+	if len(stack) == 0 {
+		return nil
+	}
+	switch top := stack[len(stack)-1].(type) {
+	case *MShellGrid, *MShellGridView:
+		return top
+	}
+	return nil
+}
+
+// GridPager is a full-screen, scrollable view of a grid. Frozen columns stay
+// on the left while the rest scroll horizontally.
+type GridPager struct {
+	layout     *gridTableLayout
+	title      string
+	rows, cols int
+	stdInFd    int
+	out        *os.File
+
+	rowOffset int
+	colOffset int // first scrolling column
+	frozen    int
+}
+
+// RunGridPager opens the pager on the terminal. The terminal is in cooked
+// mode during evaluation, so this handles MakeRaw/Restore itself, like
+// RunFileManagerBuiltin.
+func RunGridPager(obj MShellObject) error {
+	src, ok := newGridWriteSource(obj)
+	if !ok {
+		return fmt.Errorf("Cannot page a %s", obj.TypeName())
+	}
+	p := &GridPager{
+		layout:  newGridTableLayout(src),
+		title:   obj.TypeName(),
+		stdInFd: int(os.Stdin.Fd()),
+		out:     os.Stdout,
+	}
+
+	cols, rows, err := term.GetSize(int(p.out.Fd()))
+	if err != nil {
+		return fmt.Errorf("Error getting terminal size: %s", err)
+	}
+	p.rows = rows
+	p.cols = cols
+
+	oldState, err := term.MakeRaw(p.stdInFd)
+	if err != nil {
+		return fmt.Errorf("Error entering raw mode: %s", err)
+	}
+	p.out.WriteString("\033[?1049h\033[?25l")
+	defer func() {
+		p.out.WriteString("\033[?25h\033[?1049l")
+		term.Restore(p.stdInFd, oldState)
+	}()
+
+	buf := make([]byte, 16)
+	for {
+		p.out.Write(p.render())
+		n, err := os.Stdin.Read(buf)
+		if err != nil || n == 0 {
+			return nil
+		}
+		if p.handleKey(buf[:n]) {
+			return nil
+		}
+	}
+}
+
+// bodyRows is how many data rows fit between the header and status lines.
+func (p *GridPager) bodyRows() int {
+	return max(1, p.rows-4)
+}
+
+func (p *GridPager) scrollColumns() int {
+	return len(p.layout.widths) - p.frozen
+}
+
+// handleKey applies one key press and reports whether the pager should exit.
+func (p *GridPager) handleKey(key []byte) bool {
+	total := p.layout.src.rowCount()
+	page := p.bodyRows()
+	if len(key) >= 3 && key[0] == 0x1b && key[1] == '[' {
+		switch key[2] {
+		case 'A':
+			p.rowOffset--
+		case 'B':
+			p.rowOffset++
+		case 'C':
+			p.colOffset++
+		case 'D':
+			p.colOffset--
+		case '5':
+			p.rowOffset -= page
+		case '6':
+			p.rowOffset += page
+		}
+	} else {
+		switch key[0] {
+		case 'q', 0x1b, 3:
+			return true
+		case 'j':
+			p.rowOffset++
+		case 'k':
+			p.rowOffset--
+		case 'l':
+			p.colOffset++
+		case 'h':
+			p.colOffset--
+		case ' ', 'f' & 0x1f:
+			p.rowOffset += page
+		case 'b', 'b' & 0x1f:
+			p.rowOffset -= page
+		case 'g':
+			p.rowOffset = 0
+		case 'G':
+			p.rowOffset = total
+		case '0':
+			p.colOffset = 0
+		case 'f':
+			if p.frozen < len(p.layout.widths)-1 {
+				p.frozen++
+			}
+		case 'F':
+			if p.frozen > 0 {
+				p.frozen--
+			}
+		}
+	}
+	p.rowOffset = max(0, min(p.rowOffset, total-page))
+	p.colOffset = max(0, min(p.colOffset, p.scrollColumns()-1))
+	return false
+}
+
+// visiblePagerColumns returns the frozen columns followed by the scrolling
+// columns that fit from colOffset.
+func (p *GridPager) visiblePagerColumns() []int {
+	var visible []int
+	used := 1
+	add := func(j int) bool {
+		if used+p.layout.widths[j]+3 > p.cols && len(visible) > 0 {
+			return false
+		}
+		used += p.layout.widths[j] + 3
+		visible = append(visible, j)
+		return true
+	}
+	for j := 0; j < p.frozen; j++ {
+		if !add(j) {
+			return visible
+		}
+	}
+	for j := p.frozen + p.colOffset; j < len(p.layout.widths); j++ {
+		if !add(j) {
+			break
+		}
+	}
+	return visible
+}
+
+func (p *GridPager) render() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\033[H\033[2J")
+	visible := p.visiblePagerColumns()
+
+	line := func(text func(j int) string) {
+		var sb strings.Builder
+		sb.WriteString("│")
+		for _, j := range visible {
+			sb.WriteString(" " + p.layout.pad(j, text(j)) + " ")
+			if p.frozen > 0 && j == p.frozen-1 {
+				sb.WriteString("┃")
+			} else {
+				sb.WriteString("│")
+			}
+		}
+		rendered := sb.String()
+		if utf8.RuneCountInString(rendered) > p.cols {
+			rendered = string([]rune(rendered)[:p.cols])
+		}
+		buf.WriteString(rendered)
+		buf.WriteString("\r\n")
+	}
+
+	buf.WriteString("\033[1m")
+	line(func(j int) string { return p.layout.names[j] })
+	buf.WriteString("\033[0m\033[2m")
+	line(func(j int) string { return p.layout.types[j] })
+	buf.WriteString("\033[0m")
+	line(func(j int) string { return strings.Repeat("─", p.layout.widths[j]) })
+
+	total := p.layout.src.rowCount()
+	end := min(total, p.rowOffset+p.bodyRows())
+	for i := p.rowOffset; i < end; i++ {
+		line(func(j int) string { return p.layout.cell(j, i) })
+	}
+	for i := end - p.rowOffset; i < p.bodyRows(); i++ {
+		buf.WriteString("\r\n")
+	}
+
+	firstCol := p.frozen + p.colOffset + 1
+	status := fmt.Sprintf(" %s  rows %d-%d of %d  columns %d+ of %d  frozen %d  (hjkl scroll, f/F freeze, q quit)",
+		p.title, min(p.rowOffset+1, total), end, total, firstCol, len(p.layout.widths), p.frozen)
+	status = truncateMiddle(status, p.cols)
+	buf.WriteString("\033[7m" + status + strings.Repeat(" ", max(0, p.cols-utf8.RuneCountInString(status))) + "\033[0m")
+	return buf.Bytes()
+}
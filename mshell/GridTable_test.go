@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func gridTableTestGrid(t *testing.T, rows int) *MShellGrid {
+	t.Helper()
+	var sb strings.Builder
+	sb.WriteString("id,alpha,beta,gamma,delta,omega\n")
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&sb, "%d,aaaaaaaa,bbbbbbbb,cccccccc,dddddddd,z%d\n", i, i)
+	}
+	grid, err := readCsvGrid(strings.NewReader(sb.String()), defaultCsvGridOptions())
+	if err != nil {
+		t.Fatalf("readCsvGrid error: %v", err)
+	}
+	return grid
+}
+
+func TestRenderGridTableElidesRows(t *testing.T) {
+	grid := gridTableTestGrid(t, 10)
+	var sb strings.Builder
+	if err := renderGridTable(&sb, grid, gridTableOptions{maxRows: 4}); err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	out := sb.String()
+	for _, want := range []string{"│   0 │", "│   1 │", "│   8 │", "│   9 │", "│   … │", "Grid: 10 rows × 6 columns"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("table is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "│   5 │") {
+		t.Errorf("expected row 5 to be elided:\n%s", out)
+	}
+}
+
+func TestRenderGridTableElidesColumnsToWidth(t *testing.T) {
+	grid := gridTableTestGrid(t, 2)
+	var sb strings.Builder
+	if err := renderGridTable(&sb, grid, gridTableOptions{maxWidth: 40}); err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	for _, line := range lines[:len(lines)-1] {
+		if n := utf8.RuneCountInString(line); n > 40 {
+			t.Errorf("line is %d wide, want at most 40: %q", n, line)
+		}
+	}
+	header := lines[1]
+	if !strings.Contains(header, "id") || !strings.Contains(header, "omega") || !strings.Contains(header, "…") {
+		t.Errorf("expected the first and last columns around an elision, got %q", header)
+	}
+	if strings.Contains(header, "gamma") {
+		t.Errorf("expected middle columns to be elided, got %q", header)
+	}
+}
+
+func TestTruncateMiddleCountsRunes(t *testing.T) {
+	if got := TruncateMiddle("ééééééééé", 7); got != "éé...éé" {
+		t.Fatalf("TruncateMiddle = %q, want %q", got, "éé...éé")
+	}
+	if got := TruncateMiddle("short", 10); got != "short" {
+		t.Fatalf("TruncateMiddle = %q, want the input unchanged", got)
+	}
+}
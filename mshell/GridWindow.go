@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// gridWindowSpec is one output column of the window builtin. col and n are
+// only meaningful for the functions that read a source column.
+type gridWindowSpec struct {
+	name string
+	fn   string
+	col  string
+	n    int
+	meta *MShellDict
+}
+
+// gridWindowFns maps each window function to whether it reads a source column.
+var gridWindowFns = map[string]bool{
+	"rowNumber":   false,
+	"rank":        false,
+	"lag":         true,
+	"lead":        true,
+	"cumSum":      true,
+	"rollingMean": true,
+	"first":       true,
+	"last":        true,
+}
+
+// parseGridWindowSpecs reads the window spec dictionary. Each value is either
+// a function name or a dictionary with 'fn' and, as needed, 'col', 'n' and
+// 'meta'. Specs come back sorted by output column name so the appended
+// column order does not depend on dictionary iteration.
+func parseGridWindowSpecs(specDict *MShellDict, grid *MShellGrid) ([]gridWindowSpec, error) {
+	names := make([]string, 0, len(specDict.Items))
+	for name := range specDict.Items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	allowedKeys := map[string]struct{}{
+		"fn":   {},
+		"col":  {},
+		"n":    {},
+		"meta": {},
+	}
+
+	specs := make([]gridWindowSpec, len(names))
+	for i, name := range names {
+		if grid.GetColumn(name) != nil {
+			return nil, fmt.Errorf("Column '%s' already exists in grid", name)
+		}
+
+		spec := gridWindowSpec{name: name, n: 1, meta: NewDict()}
+		switch value := specDict.Items[name].(type) {
+		case MShellString:
+			spec.fn = value.Content
+		case *MShellDict:
+			for key := range value.Items {
+				if _, ok := allowedKeys[key]; !ok {
+					return nil, fmt.Errorf("window spec '%s' has unknown key '%s'", name, key)
+				}
+			}
+
+			fnObj, ok := value.Items["fn"]
+			if !ok {
+				return nil, fmt.Errorf("window spec '%s' is missing required key 'fn'", name)
+			}
+			fnStr, ok := fnObj.(MShellString)
+			if !ok {
+				return nil, fmt.Errorf("window spec '%s' key 'fn' must be a string, got %s", name, fnObj.TypeName())
+			}
+			spec.fn = fnStr.Content
+
+			if colObj, ok := value.Items["col"]; ok {
+				colStr, ok := colObj.(MShellString)
+				if !ok {
+					return nil, fmt.Errorf("window spec '%s' key 'col' must be a string, got %s", name, colObj.TypeName())
+				}
+				spec.col = colStr.Content
+			}
+
+			nObj, hasN := value.Items["n"]
+			if hasN {
+				nInt, ok := nObj.(MShellInt)
+				if !ok {
+					return nil, fmt.Errorf("window spec '%s' key 'n' must be an integer, got %s", name, nObj.TypeName())
+				}
+				if nInt.Value < 1 {
+					return nil, fmt.Errorf("window spec '%s' key 'n' must be at least 1, got %d", name, nInt.Value)
+				}
+				spec.n = nInt.Value
+			} else if spec.fn == "rollingMean" {
+				return nil, fmt.Errorf("window spec '%s' function 'rollingMean' requires key 'n'", name)
+			}
+
+			if metaObj, ok := value.Items["meta"]; ok {
+				metaDict, ok := metaObj.(*MShellDict)
+				if !ok {
+					return nil, fmt.Errorf("window spec '%s' key 'meta' must be a dictionary, got %s", name, metaObj.TypeName())
+				}
+				spec.meta = metaDict
+			}
+		default:
+			return nil, fmt.Errorf("window spec '%s' must be a function name or a dictionary, got %s", name, value.TypeName())
+		}
+
+		readsCol, ok := gridWindowFns[spec.fn]
+		if !ok {
+			return nil, fmt.Errorf("window spec '%s' has unknown function '%s'", name, spec.fn)
+		}
+		if readsCol {
+			if spec.col == "" {
+				return nil, fmt.Errorf("window spec '%s' function '%s' requires key 'col'", name, spec.fn)
+			}
+			if grid.GetColumn(spec.col) == nil {
+				return nil, fmt.Errorf("Column '%s' not found in grid", spec.col)
+			}
+		}
+		specs[i] = spec
+	}
+
+	return specs, nil
+}
+
+// computeGridWindow materializes the rows of sourceGrid at sourceIndices and
+// appends one column per spec. Rows keep their input order; partitioning and
+// the sort keys only decide what each row's window looks like.
+func computeGridWindow(sourceGrid *MShellGrid, sourceIndices []int, partCols []string, sortCols []string, specs []gridWindowSpec) (*MShellGrid, error) {
+	partitions := make([][]int, 0)
+	partitionMap := make(map[string]int)
+	for pos, srcIdx := range sourceIndices {
+		key, err := typedGridGroupKey(sourceGrid, srcIdx, partCols)
+		if err != nil {
+			return nil, err
+		}
+		partIdx, exists := partitionMap[key]
+		if !exists {
+			partIdx = len(partitions)
+			partitionMap[key] = partIdx
+			partitions = append(partitions, []int{})
+		}
+		partitions[partIdx] = append(partitions[partIdx], pos)
+	}
+
+	sortColumns := make([]*GridColumn, len(sortCols))
+	for i, colName := range sortCols {
+		sortColumns[i] = sourceGrid.GetColumn(colName)
+	}
+	compareRows := func(posA, posB int) (int, error) {
+		for _, col := range sortColumns {
+			cmp, err := compareGridCellsForSort(col, sourceIndices[posA], sourceIndices[posB])
+			if err != nil || cmp != 0 {
+				return cmp, err
+			}
+		}
+		return 0, nil
+	}
+
+	var sortErr error
+	for _, partition := range partitions {
+		sort.SliceStable(partition, func(i, j int) bool {
+			cmp, err := compareRows(partition[i], partition[j])
+			if err != nil && sortErr == nil {
+				sortErr = err
+			}
+			return cmp < 0
+		})
+	}
+	if sortErr != nil {
+		return nil, sortErr
+	}
+
+	newGrid := projectGridAllColumns(sourceGrid, sourceIndices)
+	for _, spec := range specs {
+		newCol := NewGridColumn(spec.name, newGrid.RowCount)
+		newCol.Meta = spec.meta
+		var srcCol *GridColumn
+		if spec.col != "" {
+			srcCol = sourceGrid.GetColumn(spec.col)
+		}
+
+		for _, partition := range partitions {
+			if err := fillGridWindowPartition(newCol.GenericData, spec, srcCol, sourceIndices, partition, compareRows); err != nil {
+				return nil, err
+			}
+		}
+
+		optimizeColumnStorage(newCol)
+		newGrid.AddColumn(newCol)
+	}
+
+	return newGrid, nil
+}
+
+// fillGridWindowPartition writes spec's values for one sorted partition into
+// out, which is indexed by output row position.
+func fillGridWindowPartition(out []MShellObject, spec gridWindowSpec, srcCol *GridColumn, sourceIndices []int, partition []int, compareRows func(int, int) (int, error)) error {
+	value := func(k int) MShellObject {
+		return srcCol.Get(sourceIndices[partition[k]])
+	}
+
+	switch spec.fn {
+	case "rowNumber":
+		for k, pos := range partition {
+			out[pos] = MShellInt{Value: k + 1}
+		}
+	case "rank":
+		rank := 1
+		for k, pos := range partition {
+			if k > 0 {
+				cmp, err := compareRows(partition[k-1], pos)
+				if err != nil {
+					return err
+				}
+				if cmp != 0 {
+					rank = k + 1
+				}
+			}
+			out[pos] = MShellInt{Value: rank}
+		}
+	case "lag", "lead":
+		offset := -spec.n
+		if spec.fn == "lead" {
+			offset = spec.n
+		}
+		for k, pos := range partition {
+			if k+offset < 0 || k+offset >= len(partition) {
+				out[pos] = &Maybe{obj: nil}
+			} else {
+				out[pos] = value(k + offset)
+			}
+		}
+	case "cumSum":
+		var intSum int
+		var floatSum float64
+		isFloat := false
+		for k, pos := range partition {
+			num, err := gridWindowNumber(value(k), spec)
+			if err != nil {
+				return err
+			}
+			switch num := num.(type) {
+			case MShellInt:
+				intSum += num.Value
+			case MShellFloat:
+				isFloat = true
+				floatSum += num.Value
+			}
+			if isFloat {
+				out[pos] = MShellFloat{Value: floatSum + float64(intSum)}
+			} else {
+				out[pos] = MShellInt{Value: intSum}
+			}
+		}
+	case "rollingMean":
+		for k, pos := range partition {
+			out[pos] = &Maybe{obj: nil}
+			if k+1 < spec.n {
+				continue
+			}
+			sum := 0.0
+			count := 0
+			for w := k + 1 - spec.n; w <= k; w++ {
+				num, err := gridWindowNumber(value(w), spec)
+				if err != nil {
+					return err
+				}
+				switch num := num.(type) {
+				case MShellInt:
+					sum += float64(num.Value)
+					count++
+				case MShellFloat:
+					sum += num.Value
+					count++
+				}
+			}
+			if count > 0 {
+				out[pos] = MShellFloat{Value: sum / float64(count)}
+			}
+		}
+	case "first", "last":
+		k := 0
+		if spec.fn == "last" {
+			k = len(partition) - 1
+		}
+		edge := value(k)
+		for _, pos := range partition {
+			out[pos] = edge
+		}
+	}
+	return nil
+}
+
+// gridWindowNumber returns the numeric value of a cell for the summing window
+// functions, or nil when the cell is none or NaN and should be skipped.
+func gridWindowNumber(cell MShellObject, spec gridWindowSpec) (MShellObject, error) {
+	if isNoneCell(cell) {
+		return nil, nil
+	}
+	switch num := unwrapMaybeCell(cell).(type) {
+	case MShellInt:
+		return num, nil
+	case MShellFloat:
+		if math.IsNaN(num.Value) {
+			return nil, nil
+		}
+		return num, nil
+	default:
+		return nil, fmt.Errorf("window %s requires numeric values in column '%s', got %s", spec.fn, spec.col, num.TypeName())
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func gridWindowTestColumn(t *testing.T, input string, partCols []string, sortCols []string, spec *MShellDict, name string) []string {
+	t.Helper()
+	grid, err := readCsvGrid(strings.NewReader(input), defaultCsvGridOptions())
+	if err != nil {
+		t.Fatalf("readCsvGrid error: %v", err)
+	}
+	specDict := NewDict()
+	specDict.Items[name] = spec
+	specs, err := parseGridWindowSpecs(specDict, grid)
+	if err != nil {
+		t.Fatalf("parseGridWindowSpecs error: %v", err)
+	}
+	_, indices, _ := getGridSourceAndIndices(grid)
+	result, err := computeGridWindow(grid, indices, partCols, sortCols, specs)
+	if err != nil {
+		t.Fatalf("computeGridWindow error: %v", err)
+	}
+	col := result.GetColumn(name)
+	values := make([]string, result.RowCount)
+	for i := range values {
+		cell := col.Get(i)
+		if isNoneCell(cell) {
+			values[i] = "none"
+		} else {
+			values[i] = unwrapMaybeCell(cell).DebugString()
+		}
+	}
+	return values
+}
+
+func gridWindowSpecDict(fn string, col string, n int) *MShellDict {
+	dict := NewDict()
+	dict.Items["fn"] = MShellString{Content: fn}
+	if col != "" {
+		dict.Items["col"] = MShellString{Content: col}
+	}
+	if n > 0 {
+		dict.Items["n"] = MShellInt{Value: n}
+	}
+	return dict
+}
+
+func TestComputeGridWindow(t *testing.T) {
+	input := "site,day,v\nA,3,30\nB,1,5\nA,1,10\nA,2,\nB,2,7\nA,4,30\n"
+	tests := []struct {
+		name     string
+		partCols []string
+		sortCols []string
+		spec     *MShellDict
+		expect   string
+	}{
+		{"row numbers follow the sort within each partition", []string{"site"}, []string{"day"}, gridWindowSpecDict("rowNumber", "", 0), "3 1 1 2 2 4"},
+		{"rank leaves gaps after ties", []string{"site"}, []string{"v"}, gridWindowSpecDict("rank", "", 0), "2 1 1 4 2 2"},
+		{"lag starts each partition with none", []string{"site"}, []string{"day"}, gridWindowSpecDict("lag", "v", 1), "none none none 10 5 30"},
+		{"lead stops at the partition end", []string{"site"}, []string{"day"}, gridWindowSpecDict("lead", "v", 0), "30 7 none 30 none none"},
+		{"cumSum skips none", []string{"site"}, []string{"day"}, gridWindowSpecDict("cumSum", "v", 0), "40 5 10 10 12 70"},
+		{"rollingMean waits for a full window", []string{"site"}, []string{"day"}, gridWindowSpecDict("rollingMean", "v", 2), "30 none none 10 6 30"},
+		{"last uses the end of the sorted partition", []string{"site"}, []string{"day"}, gridWindowSpecDict("last", "day", 0), "4 2 4 4 2 4"},
+		{"no partition keys means one partition", nil, []string{"day"}, gridWindowSpecDict("first", "site", 0), `"B" "B" "B" "B" "B" "B"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.Join(gridWindowTestColumn(t, input, tt.partCols, tt.sortCols, tt.spec, "out"), " ")
+			if got != tt.expect {
+				t.Fatalf("window column = %q, want %q", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestParseGridWindowSpecsErrors(t *testing.T) {
+	grid, err := readCsvGrid(strings.NewReader("a,b\n1,x\n"), defaultCsvGridOptions())
+	if err != nil {
+		t.Fatalf("readCsvGrid error: %v", err)
+	}
+	tests := []struct {
+		name string
+		spec MShellObject
+		want string
+	}{
+		{"b", MShellString{Content: "rank"}, "already exists"},
+		{"out", MShellString{Content: "median"}, "unknown function 'median'"},
+		{"out", gridWindowSpecDict("lag", "", 0), "requires key 'col'"},
+		{"out", gridWindowSpecDict("lag", "missing", 0), "Column 'missing' not found"},
+		{"out", gridWindowSpecDict("rollingMean", "a", 0), "requires key 'n'"},
+		{"out", MShellInt{Value: 1}, "function name or a dictionary"},
+	}
+	for _, tt := range tests {
+		specDict := NewDict()
+		specDict.Items[tt.name] = tt.spec
+		if _, err := parseGridWindowSpecs(specDict, grid); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("spec %s = %s: error = %v, want %q", tt.name, tt.spec.DebugString(), err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Grid writers: toCsv, toTsv, toJsonLines, and toMarkdownTable. Each writer
+// renders a Grid or GridView into an io.Writer, so the same code builds a
+// string result or streams to a file or standard output.
+
+// gridWriteOptions holds the parsed options dictionary shared by the grid
+// writers. Not every writer uses every field.
+type gridWriteOptions struct {
+	header    bool
+	delimiter rune
+	quoteAll  bool
+	newline   string
+	null      string
+	dateFmt   string
+	numFmt    *numFmtSpec // nil writes floats in their shortest form
+}
+
+func defaultGridWriteOptions(delimiter rune) gridWriteOptions {
+	return gridWriteOptions{
+		header:    true,
+		delimiter: delimiter,
+		newline:   "\n",
+		dateFmt:   "2006-01-02T15:04:05",
+	}
+}
+
+// parseGridWriteOptions reads a writer's options dictionary over its
+// defaults.
+func parseGridWriteOptions(dict *MShellDict, opts gridWriteOptions) (gridWriteOptions, error) {
+	if header, ok, err := boolOption(dict, "header"); err != nil {
+		return opts, err
+	} else if ok {
+		opts.header = header
+	}
+	if quoteAll, ok, err := boolOption(dict, "quoteAll"); err != nil {
+		return opts, err
+	} else if ok {
+		opts.quoteAll = quoteAll
+	}
+	if delimiter, ok, err := stringOption(dict, "delimiter"); err != nil {
+		return opts, err
+	} else if ok {
+		if utf8.RuneCountInString(delimiter) != 1 {
+			return opts, fmt.Errorf("Option 'delimiter' must be a single character, found %q", delimiter)
+		}
+		opts.delimiter, _ = utf8.DecodeRuneInString(delimiter)
+		if opts.delimiter == '"' || opts.delimiter == '\n' || opts.delimiter == '\r' {
+			return opts, fmt.Errorf("Option 'delimiter' cannot be %q", delimiter)
+		}
+	}
+	if lineEnding, ok, err := stringOption(dict, "lineEnding"); err != nil {
+		return opts, err
+	} else if ok {
+		if lineEnding != "\n" && lineEnding != "\r\n" {
+			return opts, fmt.Errorf("Option 'lineEnding' must be \"\\n\" or \"\\r\\n\", found %q", lineEnding)
+		}
+		opts.newline = lineEnding
+	}
+	if null, ok, err := stringOption(dict, "null"); err != nil {
+		return opts, err
+	} else if ok {
+		opts.null = null
+	}
+	if dateFmt, ok, err := stringOption(dict, "dateFmt"); err != nil {
+		return opts, err
+	} else if ok {
+		opts.dateFmt = dateFmt
+	}
+	if obj, ok := dict.Items["numFmt"]; ok {
+		numDict, ok := obj.(*MShellDict)
+		if !ok {
+			return opts, fmt.Errorf("Option 'numFmt' must be a dictionary of 'numFmt' options, found %s", obj.TypeName())
+		}
+		spec, err := parseNumFmtSpec(numDict)
+		if err != nil {
+			return opts, err
+		}
+		opts.numFmt = &spec
+	}
+	return opts, nil
+}
+
+// gridWriteSource is the grid and row order a writer walks. rows is nil for
+// a whole grid.
+type gridWriteSource struct {
+	grid *MShellGrid
+	rows []int
+}
+
+func newGridWriteSource(obj MShellObject) (gridWriteSource, bool) {
+	switch typed := obj.(type) {
+	case *MShellGrid:
+		return gridWriteSource{grid: typed}, true
+	case *MShellGridView:
+		return gridWriteSource{grid: typed.Source, rows: typed.Indices}, true
+	}
+	return gridWriteSource{}, false
+}
+
+func (src gridWriteSource) rowCount() int {
+	if src.rows == nil {
+		return src.grid.RowCount
+	}
+	return len(src.rows)
+}
+
+func (src gridWriteSource) sourceRow(i int) int {
+	if src.rows == nil {
+		return i
+	}
+	return src.rows[i]
+}
+
+// formatCell renders one cell as text. The bool result is false for null
+// cells (none and JSON null), which the caller renders with the null option.
+func (opts *gridWriteOptions) formatCell(col *GridColumn, row int) (string, bool) {
+	if col.Nulls.has(row) {
+		return "", false
+	}
+	switch col.ColType {
+	case COL_INT:
+		return strconv.FormatInt(col.IntData[row], 10), true
+	case COL_FLOAT:
+		return opts.formatFloat(col.FloatData[row]), true
+	case COL_STRING:
+		return col.StringData[row], true
+	case COL_DATETIME:
+		return col.DateTimeData[row].Format(opts.dateFmt), true
+	}
+	return opts.formatObject(col.GenericData[row])
+}
+
+func (opts *gridWriteOptions) formatFloat(f float64) string {
+	if opts.numFmt != nil {
+		return opts.numFmt.Format(MShellFloat{Value: f})
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func (opts *gridWriteOptions) formatObject(obj MShellObject) (string, bool) {
+	switch typed := obj.(type) {
+	case nil, MShellNull:
+		return "", false
+	case *Maybe:
+		if typed.obj == nil {
+			return "", false
+		}
+		return opts.formatObject(typed.obj)
+	case Maybe:
+		if typed.obj == nil {
+			return "", false
+		}
+		return opts.formatObject(typed.obj)
+	case MShellInt:
+		return strconv.Itoa(typed.Value), true
+	case MShellFloat:
+		return opts.formatFloat(typed.Value), true
+	case MShellString:
+		return typed.Content, true
+	case MShellLiteral:
+		return typed.LiteralText, true
+	case MShellPath:
+		return typed.Path, true
+	case *MShellDateTime:
+		return typed.Time.Format(opts.dateFmt), true
+	case MShellBool:
+		return strconv.FormatBool(typed.Value), true
+	case *MShellList, *MShellDict:
+		return obj.ToJson(), true
+	}
+	return obj.ToString(), true
+}
+
+// writeGridDelimited writes a Grid as CSV, or TSV when the delimiter is a tab.
+func writeGridDelimited(w io.Writer, src gridWriteSource, opts gridWriteOptions) error {
+	bw := bufio.NewWriterSize(w, 64*1024)
+	cols := src.grid.Columns
+	if opts.header {
+		for i, col := range cols {
+			if i > 0 {
+				bw.WriteRune(opts.delimiter)
+			}
+			writeDelimitedField(bw, col.Name, &opts)
+		}
+		bw.WriteString(opts.newline)
+	}
+	n := src.rowCount()
+	for i := 0; i < n; i++ {
+		row := src.sourceRow(i)
+		for j, col := range cols {
+			if j > 0 {
+				bw.WriteRune(opts.delimiter)
+			}
+			text, ok := opts.formatCell(col, row)
+			if !ok {
+				text = opts.null
+			}
+			writeDelimitedField(bw, text, &opts)
+		}
+		if _, err := bw.WriteString(opts.newline); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// writeDelimitedField quotes a field when it contains the delimiter, a double
+// quote, or a line break, doubling embedded quotes (RFC 4180).
+func writeDelimitedField(bw *bufio.Writer, field string, opts *gridWriteOptions) {
+	needsQuotes := opts.quoteAll || strings.ContainsAny(field, "\"\r\n") || strings.ContainsRune(field, opts.delimiter)
+	if !needsQuotes {
+		bw.WriteString(field)
+		return
+	}
+	bw.WriteByte('"')
+	bw.WriteString(strings.ReplaceAll(field, `"`, `""`))
+	bw.WriteByte('"')
+}
+
+// writeGridJsonLines writes one JSON object per row. Ints, floats, strings,
+// and booleans keep their JSON types, datetimes are formatted with dateFmt,
+// and null cells become JSON null.
+func writeGridJsonLines(w io.Writer, src gridWriteSource, opts gridWriteOptions) error {
+	bw := bufio.NewWriterSize(w, 64*1024)
+	cols := src.grid.Columns
+	keys := make([][]byte, len(cols))
+	for i, col := range cols {
+		keys[i], _ = json.Marshal(col.Name)
+	}
+	n := src.rowCount()
+	for i := 0; i < n; i++ {
+		row := src.sourceRow(i)
+		bw.WriteByte('{')
+		for j, col := range cols {
+			if j > 0 {
+				bw.WriteByte(',')
+			}
+			bw.Write(keys[j])
+			bw.WriteByte(':')
+			bw.WriteString(opts.jsonCell(col, row))
+		}
+		if _, err := bw.WriteString("}\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (opts *gridWriteOptions) jsonCell(col *GridColumn, row int) string {
+	if col.Nulls.has(row) {
+		return "null"
+	}
+	switch col.ColType {
+	case COL_INT:
+		return strconv.FormatInt(col.IntData[row], 10)
+	case COL_FLOAT:
+		return MShellFloat{Value: col.FloatData[row]}.ToJson()
+	case COL_STRING:
+		return MShellString{Content: col.StringData[row]}.ToJson()
+	case COL_DATETIME:
+		return jsonString(col.DateTimeData[row].Format(opts.dateFmt))
+	}
+	return opts.jsonObject(col.GenericData[row])
+}
+
+func (opts *gridWriteOptions) jsonObject(obj MShellObject) string {
+	switch typed := obj.(type) {
+	case nil:
+		return "null"
+	case *Maybe:
+		if typed.obj == nil {
+			return "null"
+		}
+		return opts.jsonObject(typed.obj)
+	case Maybe:
+		if typed.obj == nil {
+			return "null"
+		}
+		return opts.jsonObject(typed.obj)
+	case *MShellDateTime:
+		return jsonString(typed.Time.Format(opts.dateFmt))
+	}
+	return compactJson(obj.ToJson())
+}
+
+func jsonString(s string) string {
+	encoded, _ := json.Marshal(s)
+	return string(encoded)
+}
+
+// compactJson strips the spacing ToJson puts after ':' and ',', so a JSON
+// Lines record has no insignificant whitespace. Text that does not parse is
+// returned as is.
+func compactJson(s string) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(s)); err != nil {
+		return s
+	}
+	return buf.String()
+}
+
+// writeGridMarkdownTable writes a GitHub-flavored Markdown table. Columns
+// are padded to a common width, and int and float columns are right aligned.
+func writeGridMarkdownTable(w io.Writer, src gridWriteSource, opts gridWriteOptions) error {
+	cols := src.grid.Columns
+	if len(cols) == 0 {
+		return nil
+	}
+	n := src.rowCount()
+	cells := make([][]string, n)
+	widths := make([]int, len(cols))
+	for j, col := range cols {
+		widths[j] = max(3, utf8.RuneCountInString(markdownCell(col.Name)))
+	}
+	for i := 0; i < n; i++ {
+		row := src.sourceRow(i)
+		cells[i] = make([]string, len(cols))
+		for j, col := range cols {
+			text, ok := opts.formatCell(col, row)
+			if !ok {
+				text = opts.null
+			}
+			text = markdownCell(text)
+			cells[i][j] = text
+			widths[j] = max(widths[j], utf8.RuneCountInString(text))
+		}
+	}
+	rightAlign := make([]bool, len(cols))
+	for j, col := range cols {
+		rightAlign[j] = isNumericGridColumn(col, src)
+	}
+
+	bw := bufio.NewWriter(w)
+	writeRow := func(row []string) {
+		bw.WriteString("|")
+		for j, text := range row {
+			pad := strings.Repeat(" ", widths[j]-utf8.RuneCountInString(text))
+			bw.WriteByte(' ')
+			if rightAlign[j] {
+				bw.WriteString(pad + text)
+			} else {
+				bw.WriteString(text + pad)
+			}
+			bw.WriteString(" |")
+		}
+		bw.WriteString("\n")
+	}
+
+	names := make([]string, len(cols))
+	for j, col := range cols {
+		names[j] = markdownCell(col.Name)
+	}
+	writeRow(names)
+	bw.WriteString("|")
+	for j := range cols {
+		if rightAlign[j] {
+			bw.WriteString(" " + strings.Repeat("-", widths[j]-1) + ": |")
+		} else {
+			bw.WriteString(" " + strings.Repeat("-", widths[j]) + " |")
+		}
+	}
+	bw.WriteString("\n")
+	for _, row := range cells {
+		writeRow(row)
+	}
+	return bw.Flush()
+}
+
+// isNumericGridColumn reports whether every non-null cell of the column is
+// an int or a float, so a float column with missing values still aligns
+// like a number column.
+func isNumericGridColumn(col *GridColumn, src gridWriteSource) bool {
+	switch col.ColType {
+	case COL_INT, COL_FLOAT:
+		return true
+	case COL_GENERIC:
+	default:
+		return false
+	}
+	sawNumber := false
+	n := src.rowCount()
+	for i := 0; i < n; i++ {
+		obj := col.GenericData[src.sourceRow(i)]
+		if m, ok := obj.(*Maybe); ok {
+			obj = m.obj
+		} else if m, ok := obj.(Maybe); ok {
+			obj = m.obj
+		}
+		switch obj.(type) {
+		case nil, MShellNull:
+		case MShellInt, MShellFloat:
+			sawNumber = true
+		default:
+			return false
+		}
+	}
+	return sawNumber
+}
+
+// markdownCell escapes pipes and turns line breaks into <br> so a value
+// stays inside its table cell.
+func markdownCell(text string) string {
+	if !strings.ContainsAny(text, "|\r\n") {
+		return text
+	}
+	text = strings.ReplaceAll(text, "|", `\|`)
+	text = strings.ReplaceAll(text, "\r\n", "<br>")
+	return strings.NewReplacer("\n", "<br>", "\r", "<br>").Replace(text)
+}
+
+// gridWriters maps each writer name to its default options and render
+// function.
+var gridWriters = map[string]struct {
+	defaults func() gridWriteOptions
+	write    func(io.Writer, gridWriteSource, gridWriteOptions) error
+}{
+	"toCsv":           {func() gridWriteOptions { return defaultGridWriteOptions(',') }, writeGridDelimited},
+	"toTsv":           {func() gridWriteOptions { return defaultGridWriteOptions('\t') }, writeGridDelimited},
+	"toJsonLines":     {func() gridWriteOptions { return defaultGridWriteOptions(',') }, writeGridJsonLines},
+	"toMarkdownTable": {func() gridWriteOptions { return defaultGridWriteOptions(',') }, writeGridMarkdownTable},
+}
+
+// writeStringRowsCsv is toCsv for a list of string rows: each cell escaped
+// like toCsvCell, joined with commas, and every row ended with a newline.
+func writeStringRowsCsv(list *MShellList) (string, error) {
+	var sb strings.Builder
+	for i, rowObj := range list.Items {
+		row, ok := rowObj.(*MShellList)
+		if !ok {
+			return "", fmt.Errorf("row %d is a %s, not a list", i, rowObj.TypeName())
+		}
+		for j, cellObj := range row.Items {
+			if j > 0 {
+				sb.WriteByte(',')
+			}
+			cell, err := cellObj.CastString()
+			if err != nil {
+				return "", fmt.Errorf("row %d, cell %d is a %s, not a string", i, j, cellObj.TypeName())
+			}
+			if strings.ContainsAny(cell, ",\"\n") {
+				sb.WriteByte('"')
+				sb.WriteString(strings.ReplaceAll(cell, `"`, `""`))
+				sb.WriteByte('"')
+			} else {
+				sb.WriteString(cell)
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
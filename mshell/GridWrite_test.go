@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func gridWriterTestGrid(t *testing.T) *MShellGrid {
+	t.Helper()
+	grid, err := readCsvGrid(strings.NewReader("id,kwh,note\n1,1.5,\"a,b\"\n2,,\"x\ny\"\n"), defaultCsvGridOptions())
+	if err != nil {
+		t.Fatalf("readCsvGrid error: %v", err)
+	}
+	return grid
+}
+
+func TestWriteGridDelimited(t *testing.T) {
+	grid := gridWriterTestGrid(t)
+	tests := []struct {
+		name   string
+		opts   gridWriteOptions
+		modify func(*gridWriteOptions)
+		expect string
+	}{
+		{
+			name:   "csv quotes delimiters and line breaks",
+			opts:   defaultGridWriteOptions(','),
+			expect: "id,kwh,note\n1,1.5,\"a,b\"\n2,,\"x\ny\"\n",
+		},
+		{
+			name:   "tsv only quotes what it must",
+			opts:   defaultGridWriteOptions('\t'),
+			expect: "id\tkwh\tnote\n1\t1.5\ta,b\n2\t\t\"x\ny\"\n",
+		},
+		{
+			name: "null text, no header, and crlf",
+			opts: defaultGridWriteOptions(','),
+			modify: func(o *gridWriteOptions) {
+				o.header = false
+				o.null = "NA"
+				o.newline = "\r\n"
+			},
+			expect: "1,1.5,\"a,b\"\r\n2,NA,\"x\ny\"\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			if tt.modify != nil {
+				tt.modify(&opts)
+			}
+			var sb strings.Builder
+			if err := writeGridDelimited(&sb, gridWriteSource{grid: grid}, opts); err != nil {
+				t.Fatalf("write error: %v", err)
+			}
+			if sb.String() != tt.expect {
+				t.Fatalf("output = %q, want %q", sb.String(), tt.expect)
+			}
+		})
+	}
+}
+
+func TestWriteGridJsonLinesFollowsViewRows(t *testing.T) {
+	grid := gridWriterTestGrid(t)
+	var sb strings.Builder
+	src := gridWriteSource{grid: grid, rows: []int{1, 0}}
+	if err := writeGridJsonLines(&sb, src, defaultGridWriteOptions(',')); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	want := "{\"id\":2,\"kwh\":null,\"note\":\"x\\ny\"}\n{\"id\":1,\"kwh\":1.5,\"note\":\"a,b\"}\n"
+	if sb.String() != want {
+		t.Fatalf("output = %q, want %q", sb.String(), want)
+	}
+}
+
+func TestWriteGridMarkdownTable(t *testing.T) {
+	grid := gridWriterTestGrid(t)
+	var sb strings.Builder
+	if err := writeGridMarkdownTable(&sb, gridWriteSource{grid: grid}, defaultGridWriteOptions(',')); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	want := strings.Join([]string{
+		"|  id | kwh | note   |",
+		"| --: | --: | ------ |",
+		"|   1 | 1.5 | a,b    |",
+		"|   2 |     | x<br>y |",
+		"",
+	}, "\n")
+	if sb.String() != want {
+		t.Fatalf("output =\n%s\nwant\n%s", sb.String(), want)
+	}
+}
+
+func TestParseGridWriteOptionsErrors(t *testing.T) {
+	tests := []struct {
+		key   string
+		value MShellObject
+	}{
+		{"delimiter", MShellString{Content: ",,"}},
+		{"lineEnding", MShellString{Content: "\r"}},
+		{"header", MShellString{Content: "yes"}},
+		{"numFmt", MShellInt{Value: 2}},
+	}
+	for _, tt := range tests {
+		dict := NewDict()
+		dict.Items[tt.key] = tt.value
+		if _, err := parseGridWriteOptions(dict, defaultGridWriteOptions(',')); err == nil {
+			t.Errorf("expected an error for option %s = %s", tt.key, tt.value.DebugString())
+		}
+	}
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Modules are single .msh files pinned by the sha256 of their bytes:
+//
+//	import "lib/strings.msh" sha256:<hash>
+//	import "gh:owner/repo" sha256:<hash> as repo
+//
+// A module may only contain definitions and imports. Without 'as', the
+// module's definitions (and the ones it re-exports from its own plain
+// imports) are added under their own names. With 'as ns', they are
+// renamed to 'ns.name', including the calls between them.
+//
+// Local sources are relative to the importing file. Remote modules
+// ('gh:' and http(s) URLs) are cached by hash under <data dir>/modules.
+
+// ModuleLoader resolves the imports of a file, recursively.
+type ModuleLoader struct {
+	Definitions []MShellDefinition
+	Locations   []string // Locations[i] is the file or URL Definitions[i] came from
+
+	loading map[string]bool     // hashes currently being loaded, for cycles
+	emitted map[string]bool     // hash + prefix pairs already added
+	exports map[string][]string // unprefixed exported names by hash
+}
+
+func NewModuleLoader() *ModuleLoader {
+	return &ModuleLoader{
+		loading: map[string]bool{},
+		emitted: map[string]bool{},
+		exports: map[string][]string{},
+	}
+}
+
+// ResolveImports loads every import of file and returns the definitions they
+// provide. importerPath is the path of the importing file; when empty, local
+// sources are relative to the current directory.
+func ResolveImports(file *MShellFile, importerPath string) ([]MShellDefinition, error) {
+	if len(file.Imports) == 0 {
+		return nil, nil
+	}
+	loader, err := loadImports(file, importerPath)
+	if err != nil {
+		return nil, err
+	}
+	return loader.Definitions, nil
+}
+
+// loadImports loads every module file imports, returning the loader so
+// callers can see where each definition came from.
+func loadImports(file *MShellFile, importerPath string) (*ModuleLoader, error) {
+	loader := NewModuleLoader()
+	for _, imp := range file.Imports {
+		prefix := ""
+		if imp.Alias != "" {
+			prefix = imp.Alias + "."
+		}
+		if _, err := loader.load(imp, importerPath, prefix); err != nil {
+			return nil, err
+		}
+	}
+	return loader, nil
+}
+
+// load adds the definitions of the imported module, named with prefix, and
+// returns the names the module exports, without the prefix.
+func (loader *ModuleLoader) load(imp MShellImport, base string, prefix string) ([]string, error) {
+	if loader.emitted[imp.Hash+"\x00"+prefix] {
+		return loader.exports[imp.Hash], nil
+	}
+	if loader.loading[imp.Hash] {
+		return nil, importError(imp, base, fmt.Sprintf("Import cycle through %s", imp.Source))
+	}
+
+	location, err := ResolveModuleLocation(imp.Source, base)
+	if err != nil {
+		return nil, importError(imp, base, err.Error())
+	}
+
+	content, err := FetchPinnedModule(location, imp.Hash)
+	if err != nil {
+		return nil, importError(imp, base, err.Error())
+	}
+
+	loader.loading[imp.Hash] = true
+	defer delete(loader.loading, imp.Hash)
+
+	module, err := parseModule(content, location, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Names visible inside the module, mapped to their final names.
+	rename := map[string]string{}
+	var exports []string
+
+	for _, sub := range module.Imports {
+		subPrefix := prefix
+		if sub.Alias != "" {
+			subPrefix = prefix + sub.Alias + "."
+		}
+		subExports, err := loader.load(sub, location, subPrefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range subExports {
+			if sub.Alias != "" {
+				rename[sub.Alias+"."+name] = prefix + sub.Alias + "." + name
+			} else {
+				rename[name] = prefix + name
+				exports = append(exports, name)
+			}
+		}
+	}
+
+	for _, def := range module.Definitions {
+		rename[def.Name] = prefix + def.Name
+		exports = append(exports, def.Name)
+	}
+
+	if prefix != "" {
+		module, err = parseModule(content, location, rename)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	loader.Definitions = append(loader.Definitions, module.Definitions...)
+	for range module.Definitions {
+		loader.Locations = append(loader.Locations, location)
+	}
+	loader.emitted[imp.Hash+"\x00"+prefix] = true
+	loader.exports[imp.Hash] = exports
+	return exports, nil
+}
+
+// importError positions an error at the import token in base, the importing
+// file, as the failing import may be in a nested module.
+func importError(imp MShellImport, base string, message string) error {
+	t := imp.ImportToken
+	if base == "" {
+		return fmt.Errorf("%d:%d: %s", t.Line, t.Column, message)
+	}
+	return fmt.Errorf("%s:%d:%d: %s", base, t.Line, t.Column, message)
+}
+
+func parseModule(content []byte, location string, rename map[string]string) (*MShellFile, error) {
+	lexer := NewLexer(string(content), &TokenFile{location})
+	parser := MShellParser{lexer: lexer, rename: rename}
+	parser.NextToken()
+	module, err := parser.ParseFile()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", location, err)
+	}
+	if len(module.Items) > 0 {
+		start := module.Items[0].GetStartToken()
+		return nil, fmt.Errorf("%s:%d:%d: A module may only contain definitions and imports", location, start.Line, start.Column)
+	}
+	return module, nil
+}
+
+func isRemoteLocation(location string) bool {
+	return strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://")
+}
+
+// ResolveModuleLocation turns an import source into a file path or URL.
+// 'gh:owner/repo[@ref][/path]' maps to the raw file on GitHub, defaulting to
+// mod.msh at HEAD. Relative sources resolve against base, the location of
+// the importing file, whether that is a path or a URL.
+func ResolveModuleLocation(source string, base string) (string, error) {
+	if strings.HasPrefix(source, "gh:") {
+		parts := strings.SplitN(strings.TrimPrefix(source, "gh:"), "/", 3)
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			return "", fmt.Errorf("Invalid GitHub import source '%s', expected gh:owner/repo[/path]", source)
+		}
+		owner, repo, path := parts[0], parts[1], "mod.msh"
+		if len(parts) == 3 && parts[2] != "" {
+			path = parts[2]
+		}
+		ref := "HEAD"
+		if at := strings.IndexByte(repo, '@'); at >= 0 {
+			repo, ref = repo[:at], repo[at+1:]
+		}
+		return fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s/%s", owner, repo, ref, path), nil
+	}
+
+	if isRemoteLocation(source) {
+		return source, nil
+	}
+
+	if isRemoteLocation(base) {
+		baseUrl, err := url.Parse(base)
+		if err != nil {
+			return "", err
+		}
+		ref, err := url.Parse(source)
+		if err != nil {
+			return "", err
+		}
+		return baseUrl.ResolveReference(ref).String(), nil
+	}
+
+	if filepath.IsAbs(source) {
+		return source, nil
+	}
+	return filepath.Join(filepath.Dir(base), source), nil
+}
+
+// ContentHash is the hex sha256 digest used to pin modules.
+func ContentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// FetchModule reads a module without checking its hash.
+func FetchModule(location string) ([]byte, error) {
+	if !isRemoteLocation(location) {
+		return os.ReadFile(location)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Fetching %s returned %s", location, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func moduleCachePath(hash string) (string, error) {
+	dataDir, err := getStartupDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "modules", hash+".msh"), nil
+}
+
+// CacheModule stores a remote module's content under its hash.
+func CacheModule(content []byte) error {
+	path, err := moduleCachePath(ContentHash(content))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".module-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// FetchPinnedModule returns the content of the module at location, failing
+// if it does not hash to hash. Remote modules are served from the cache when
+// present and added to it after a verified fetch.
+func FetchPinnedModule(location string, hash string) ([]byte, error) {
+	remote := isRemoteLocation(location)
+	if remote {
+		if path, err := moduleCachePath(hash); err == nil {
+			if content, err := os.ReadFile(path); err == nil && ContentHash(content) == hash {
+				return content, nil
+			}
+		}
+	}
+
+	content, err := FetchModule(location)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read module %s: %s", location, err)
+	}
+
+	actual := ContentHash(content)
+	if actual != hash {
+		return nil, fmt.Errorf("Hash mismatch for module %s: expected sha256:%s, got sha256:%s", location, hash, actual)
+	}
+
+	if remote {
+		if err := CacheModule(content); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not cache module %s: %s\n", location, err)
+		}
+	}
+	return content, nil
+}
+
+// ImportLine formats the pinned import line for a source.
+func ImportLine(source string, hash string, alias string) string {
+	line := fmt.Sprintf("import \"%s\" sha256:%s", source, hash)
+	if alias != "" {
+		line += " as " + alias
+	}
+	return line
+}
+
+// fetchImportHash reads the module a source refers to, relative to base, and
+// returns its hash, caching remote modules along the way.
+func fetchImportHash(source string, base string) (string, error) {
+	location, err := ResolveModuleLocation(source, base)
+	if err != nil {
+		return "", err
+	}
+	content, err := FetchModule(location)
+	if err != nil {
+		return "", fmt.Errorf("Could not read module %s: %s", location, err)
+	}
+	if isRemoteLocation(location) {
+		if err := CacheModule(content); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not cache module %s: %s\n", location, err)
+		}
+	}
+	return ContentHash(content), nil
+}
+
+// runLookupCommand implements 'msh lookup <source>', printing the pinned
+// import line for source. Local sources are relative to the current directory.
+func runLookupCommand(args []string) int {
+	if len(args) != 1 || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprintln(os.Stderr, "Usage: msh lookup <source>")
+		return 1
+	}
+
+	hash, err := fetchImportHash(args[0], "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	fmt.Println(ImportLine(args[0], hash, ""))
+	return 0
+}
+
+// runAddCommand implements 'msh add <source> <file>'. If file already
+// imports source, its hash is updated in place, keeping any 'as' name.
+// Otherwise the pinned line is inserted after the existing imports, or at
+// the top of the file (below a #! line).
+func runAddCommand(args []string) int {
+	if len(args) != 2 || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprintln(os.Stderr, "Usage: msh add <source> <file>")
+		return 1
+	}
+	source, target := args[0], args[1]
+
+	info, err := os.Stat(target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	contentBytes, err := os.ReadFile(target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	hash, err := fetchImportHash(source, target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	updated, err := addImportLine(string(contentBytes), target, source, hash)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", target, err)
+		return 1
+	}
+
+	if err := os.WriteFile(target, []byte(updated), info.Mode().Perm()); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// addImportLine returns content with the import of source pinned to hash.
+func addImportLine(content string, path string, source string, hash string) (string, error) {
+	lexer := NewLexer(content, &TokenFile{path})
+	tokens, err := lexer.Tokenize()
+	if err != nil {
+		return "", err
+	}
+
+	runes := []rune(content)
+	lastImportLine := 0
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i].Type {
+		case LEFT_SQUARE_BRACKET, LEFT_PAREN, LEFT_CURLY, GRID_OPEN:
+			depth++
+		case RIGHT_SQUARE_BRACKET, RIGHT_PAREN, RIGHT_CURLY, GRID_CLOSE:
+			depth--
+		}
+		// As in the parser, 'import' is a directive only at the start of a
+		// top-level line.
+		if tokens[i].Type != IMPORT || i+2 >= len(tokens) || depth > 0 {
+			continue
+		}
+		if i > 0 && tokens[i-1].Line+strings.Count(tokens[i-1].Lexeme, "\n") == tokens[i].Line {
+			continue
+		}
+		lastImportLine = tokens[i].Line
+
+		sourceToken, hashToken := tokens[i+1], tokens[i+2]
+		var tokenSource string
+		switch sourceToken.Type {
+		case STRING:
+			tokenSource, err = ParseRawString(sourceToken.Lexeme)
+			if err != nil {
+				continue
+			}
+		case SINGLEQUOTESTRING:
+			tokenSource = sourceToken.Lexeme[1 : len(sourceToken.Lexeme)-1]
+		default:
+			continue
+		}
+		if tokenSource != source {
+			continue
+		}
+
+		pin := []rune("sha256:" + hash)
+		if hashToken.Type == CONTENTHASH {
+			end := hashToken.Start + len([]rune(hashToken.Lexeme))
+			return string(runes[:hashToken.Start]) + string(pin) + string(runes[end:]), nil
+		}
+		// An unpinned import: insert the pin after the source.
+		end := sourceToken.Start + len([]rune(sourceToken.Lexeme))
+		return string(runes[:end]) + " " + string(pin) + string(runes[end:]), nil
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	insertAt := lastImportLine
+	if insertAt == 0 && len(lines) > 0 && strings.HasPrefix(lines[0], "#!") {
+		insertAt = 1
+	}
+	if insertAt > 0 && !strings.HasSuffix(lines[insertAt-1], "\n") {
+		lines[insertAt-1] += "\n"
+	}
+
+	var sb strings.Builder
+	for _, line := range lines[:insertAt] {
+		sb.WriteString(line)
+	}
+	sb.WriteString(ImportLine(source, hash, ""))
+	sb.WriteString("\n")
+	for _, line := range lines[insertAt:] {
+		sb.WriteString(line)
+	}
+	return sb.String(), nil
+}
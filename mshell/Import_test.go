@@ -0,0 +1,225 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLexContentHash(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	cases := []struct {
+		input string
+		want  []TokenType
+	}{
+		{"import \"a.msh\" sha256:" + hash, []TokenType{IMPORT, STRING, CONTENTHASH, EOF}},
+		{"sha256:" + hash + " as ns", []TokenType{CONTENTHASH, AS, LITERAL, EOF}},
+		// Not 64 lowercase hex digits: lexes as before.
+		{"sha256:abc", []TokenType{LITERAL, COLON, LITERAL, EOF}},
+		{"sha256:" + hash + "0", []TokenType{LITERAL, COLON, LITERAL, EOF}},
+		{"imports", []TokenType{LITERAL, EOF}},
+	}
+	for _, tc := range cases {
+		toks, err := NewLexer(tc.input, nil).Tokenize()
+		if err != nil {
+			t.Errorf("%q: %v", tc.input, err)
+			continue
+		}
+		got := make([]TokenType, len(toks))
+		for i, tok := range toks {
+			got[i] = tok.Type
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%q: got %v, want %v", tc.input, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%q: got %v, want %v", tc.input, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func writeModule(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return ContentHash([]byte(content))
+}
+
+func definitionNames(defs []MShellDefinition) []string {
+	names := make([]string, len(defs))
+	for i, def := range defs {
+		names[i] = def.Name
+	}
+	return names
+}
+
+func TestResolveImportsNamespacesDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	baseHash := writeModule(t, dir, "base.msh", "def inc (int -- int) 1 + end\n")
+	libHash := writeModule(t, dir, "lib.msh", ImportLine("base.msh", baseHash, "")+"\ndef inc2 (int -- int) inc inc end\n")
+
+	file, err := parseMShellInput(ImportLine("lib.msh", libHash, "lib")+"\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := ResolveImports(file, filepath.Join(dir, "main.msh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Join(definitionNames(defs), " ")
+	if got != "lib.inc lib.inc2" {
+		t.Fatalf("definitions = %q, want %q", got, "lib.inc lib.inc2")
+	}
+	calls := 0
+	for _, item := range defs[1].Items {
+		if tok, ok := item.(Token); ok && tok.Type == LITERAL {
+			if tok.Lexeme != "lib.inc" {
+				t.Errorf("call in lib.inc2 = %q, want lib.inc", tok.Lexeme)
+			}
+			calls++
+		}
+	}
+	if calls != 2 {
+		t.Errorf("lib.inc2 has %d calls, want 2", calls)
+	}
+}
+
+func TestResolveImportsRejectsHashMismatch(t *testing.T) {
+	dir := t.TempDir()
+	writeModule(t, dir, "lib.msh", "def one (-- int) 1 end\n")
+
+	file, err := parseMShellInput(ImportLine("lib.msh", strings.Repeat("0", 64), "")+"\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ResolveImports(file, filepath.Join(dir, "main.msh"))
+	if err == nil || !strings.Contains(err.Error(), "Hash mismatch") {
+		t.Fatalf("error = %v, want hash mismatch", err)
+	}
+}
+
+func TestResolveImportsRejectsTopLevelItems(t *testing.T) {
+	dir := t.TempDir()
+	hash := writeModule(t, dir, "lib.msh", "\"side effect\" wl\n")
+
+	file, err := parseMShellInput(ImportLine("lib.msh", hash, "")+"\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ResolveImports(file, filepath.Join(dir, "main.msh"))
+	if err == nil || !strings.Contains(err.Error(), "only contain definitions and imports") {
+		t.Fatalf("error = %v, want top-level item rejection", err)
+	}
+}
+
+func TestFetchPinnedModuleCachesRemote(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	content := "def one (-- int) 1 end\n"
+	hash := ContentHash([]byte(content))
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		got, err := FetchPinnedModule(server.URL+"/mod.msh", hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Fatalf("content = %q, want %q", got, content)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("requests = %d, want 1 (second fetch should hit the cache)", requests)
+	}
+}
+
+func TestResolveModuleLocation(t *testing.T) {
+	cases := []struct {
+		source, base, want string
+	}{
+		{"gh:owner/repo", "", "https://raw.githubusercontent.com/owner/repo/HEAD/mod.msh"},
+		{"gh:owner/repo@v1/lib/x.msh", "", "https://raw.githubusercontent.com/owner/repo/v1/lib/x.msh"},
+		{"util.msh", "https://example.com/lib/mod.msh", "https://example.com/lib/util.msh"},
+		{"util.msh", filepath.Join("scripts", "main.msh"), filepath.Join("scripts", "util.msh")},
+		{"util.msh", "", "util.msh"},
+	}
+	for _, tc := range cases {
+		got, err := ResolveModuleLocation(tc.source, tc.base)
+		if err != nil {
+			t.Errorf("%q from %q: %v", tc.source, tc.base, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q from %q = %q, want %q", tc.source, tc.base, got, tc.want)
+		}
+	}
+}
+
+func TestAddImportLine(t *testing.T) {
+	oldHash, newHash := strings.Repeat("a", 64), strings.Repeat("b", 64)
+
+	got, err := addImportLine("#!/usr/bin/env msh\n1 wl\n", "x.msh", "lib.msh", newHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "#!/usr/bin/env msh\n" + ImportLine("lib.msh", newHash, "") + "\n1 wl\n"
+	if got != want {
+		t.Errorf("insert:\ngot  %q\nwant %q", got, want)
+	}
+
+	got, err = addImportLine(ImportLine("lib.msh", oldHash, "lib")+"\n1 wl\n", "x.msh", "lib.msh", newHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = ImportLine("lib.msh", newHash, "lib") + "\n1 wl\n"
+	if got != want {
+		t.Errorf("update:\ngot  %q\nwant %q", got, want)
+	}
+
+	got, err = addImportLine(ImportLine("a.msh", oldHash, "")+"\n1 wl\n", "x.msh", "lib.msh", newHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = ImportLine("a.msh", oldHash, "") + "\n" + ImportLine("lib.msh", newHash, "") + "\n1 wl\n"
+	if got != want {
+		t.Errorf("append after imports:\ngot  %q\nwant %q", got, want)
+	}
+
+	// An 'import' command argument is not a directive.
+	content := "[git\nimport \"lib.msh\" x] ;\n"
+	got, err = addImportLine(content, "x.msh", "lib.msh", newHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = ImportLine("lib.msh", newHash, "") + "\n" + content
+	if got != want {
+		t.Errorf("argument:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestParseImportAsArgument(t *testing.T) {
+	for _, src := range []string{"[echo import] ;\n", "[terraform import aws_x.y id] ;\n", "[echo\nimport 'a.msh'] ;\n", "1 import\n"} {
+		file, err := NewMShellParser(NewLexer(src, nil)).ParseFile()
+		if err != nil {
+			t.Errorf("%q: %v", src, err)
+			continue
+		}
+		if len(file.Imports) != 0 {
+			t.Errorf("%q: parsed as an import directive", src)
+		}
+	}
+}
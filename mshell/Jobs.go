@@ -0,0 +1,383 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JobState is the state of an entry in the interactive job table.
+type JobState int
+
+const (
+	JobRunning JobState = iota
+	JobStopped
+	JobDone
+)
+
+// jobPollInterval is how often a foreground job is checked for a stop.
+const jobPollInterval = 50 * time.Millisecond
+
+// Job is a process group started by the shell that can be stopped, resumed,
+// and moved between the foreground and the background. A job only enters
+// the job table once it is stopped (Ctrl-Z) or started with a trailing '&'.
+type Job struct {
+	Id       int
+	Pgid     int
+	Command  string
+	State    JobState
+	done     chan struct{}
+	exitCode int
+}
+
+// startJob tracks a launched process group. wait reaps the group and
+// returns its exit code; it runs on its own goroutine so a stopped job does
+// not block the shell.
+func startJob(pgid int, command string, wait func() int) *Job {
+	job := &Job{
+		Pgid:    pgid,
+		Command: command,
+		State:   JobRunning,
+		done:    make(chan struct{}),
+	}
+	go func() {
+		job.exitCode = wait()
+		close(job.done)
+	}()
+	return job
+}
+
+// finished reports whether the job has exited, updating its state if so.
+func (job *Job) finished() bool {
+	select {
+	case <-job.done:
+		job.State = JobDone
+		return true
+	default:
+		return false
+	}
+}
+
+// waitForeground blocks until the job exits or stops. It returns true when
+// the job exited, in which case job.exitCode holds its exit code.
+func (job *Job) waitForeground() bool {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-job.done:
+			job.State = JobDone
+			return true
+		case <-ticker.C:
+			if ProcessGroupStopped(job.Pgid) {
+				job.State = JobStopped
+				return false
+			}
+		}
+	}
+}
+
+func (job *Job) stateString() string {
+	switch job.State {
+	case JobRunning:
+		return "Running"
+	case JobStopped:
+		return "Stopped"
+	default:
+		if job.exitCode != 0 {
+			return fmt.Sprintf("Exit %d", job.exitCode)
+		}
+		return "Done"
+	}
+}
+
+// JobTable is the interactive shell's list of background and stopped jobs,
+// in the order they were added. The last job is the current job ('+'), the
+// one before it the previous job ('-').
+type JobTable struct {
+	jobs []*Job
+}
+
+func NewJobTable() *JobTable {
+	return &JobTable{jobs: make([]*Job, 0)}
+}
+
+// Add puts a job in the table, numbering it one past the highest job number
+// in use, and makes it the current job.
+func (table *JobTable) Add(job *Job) {
+	if job.Id == 0 {
+		job.Id = 1
+		for _, other := range table.jobs {
+			if other.Id >= job.Id {
+				job.Id = other.Id + 1
+			}
+		}
+	} else {
+		table.Remove(job)
+	}
+	table.jobs = append(table.jobs, job)
+}
+
+func (table *JobTable) Remove(job *Job) {
+	for i, other := range table.jobs {
+		if other == job {
+			table.jobs = append(table.jobs[:i], table.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Lookup finds a job by number. Job number 0 is the current job.
+func (table *JobTable) Lookup(id int) (*Job, bool) {
+	if len(table.jobs) == 0 {
+		return nil, false
+	}
+	if id == 0 {
+		return table.jobs[len(table.jobs)-1], true
+	}
+	for _, job := range table.jobs {
+		if job.Id == id {
+			return job, true
+		}
+	}
+	return nil, false
+}
+
+func (table *JobTable) marker(job *Job) string {
+	n := len(table.jobs)
+	if n > 0 && table.jobs[n-1] == job {
+		return "+"
+	}
+	if n > 1 && table.jobs[n-2] == job {
+		return "-"
+	}
+	return " "
+}
+
+func (table *JobTable) printJob(w io.Writer, job *Job) {
+	fmt.Fprintf(w, "[%d]%s  %-8s %s\n", job.Id, table.marker(job), job.stateString(), job.Command)
+}
+
+// Suspend records a foreground job that was just stopped and prints its
+// '[n]+  Stopped' line.
+func (table *JobTable) Suspend(job *Job) {
+	job.State = JobStopped
+	table.Add(job)
+	table.printJob(os.Stderr, job)
+}
+
+// Background records a job started with a trailing '&' and prints its job
+// number and process group, like '[1] 12345'.
+func (table *JobTable) Background(job *Job) {
+	table.Add(job)
+	fmt.Fprintf(os.Stderr, "[%d] %d\n", job.Id, job.Pgid)
+}
+
+// refresh updates each job's state: finished jobs become Done and running
+// jobs that stopped (a background job reading the terminal) become Stopped.
+// It returns the jobs whose state changed.
+func (table *JobTable) refresh() []*Job {
+	changed := make([]*Job, 0)
+	for _, job := range table.jobs {
+		if job.State == JobDone {
+			continue
+		}
+		if job.finished() {
+			changed = append(changed, job)
+		} else if job.State == JobRunning && ProcessGroupStopped(job.Pgid) {
+			job.State = JobStopped
+			changed = append(changed, job)
+		}
+	}
+	return changed
+}
+
+// removeDone drops every finished job from the table.
+func (table *JobTable) removeDone() {
+	kept := table.jobs[:0]
+	for _, job := range table.jobs {
+		if job.State != JobDone {
+			kept = append(kept, job)
+		}
+	}
+	table.jobs = kept
+}
+
+// Report prints a line for every job that finished or stopped since the last
+// report. The interactive shell calls it before each prompt.
+func (table *JobTable) Report(w io.Writer) {
+	changed := table.refresh()
+	for _, job := range changed {
+		table.printJob(w, job)
+	}
+	table.removeDone()
+}
+
+// List prints every job in the table, as the 'jobs' builtin.
+func (table *JobTable) List(w io.Writer) {
+	table.refresh()
+	for _, job := range table.jobs {
+		table.printJob(w, job)
+	}
+	table.removeDone()
+}
+
+// jobCommandText renders a command list or pipeline the way the job table
+// shows it, e.g. 'sort data.txt | uniq -c'.
+func jobCommandText(obj MShellObject) string {
+	switch value := obj.(type) {
+	case *MShellPipe:
+		stages := make([]string, len(value.List.Items))
+		for i, item := range value.List.Items {
+			stages[i] = jobCommandText(item)
+		}
+		return strings.Join(stages, " | ")
+	case *MShellList:
+		args := make([]string, len(value.Items))
+		for i, item := range value.Items {
+			if item.IsCommandLineable() {
+				args[i] = item.CommandLine()
+			} else {
+				args[i] = item.DebugString()
+			}
+		}
+		return strings.Join(args, " ")
+	default:
+		return obj.DebugString()
+	}
+}
+
+// jobIdArgument pops the optional job number for 'fg' and 'bg'. With no
+// integer on top of the stack, the current job (0) is used.
+func jobIdArgument(t Token, stack *MShellStack) (int, error) {
+	top, err := stack.Peek()
+	if err != nil {
+		return 0, nil
+	}
+	asInt, ok := top.(MShellInt)
+	if !ok {
+		return 0, nil
+	}
+	stack.Pop()
+	if asInt.Value <= 0 {
+		return 0, fmt.Errorf("%d:%d: Job numbers start at 1, received %d.\n", t.Line, t.Column, asInt.Value)
+	}
+	return asInt.Value, nil
+}
+
+func (state *EvalState) lookupJob(t Token, stack *MShellStack) (*Job, EvalResult) {
+	if state.Jobs == nil {
+		return nil, state.FailWithMessage(fmt.Sprintf("%d:%d: '%s': no job control in this shell.\n", t.Line, t.Column, t.Lexeme))
+	}
+	id, err := jobIdArgument(t, stack)
+	if err != nil {
+		return nil, state.FailWithMessage(err.Error())
+	}
+	state.Jobs.refresh()
+	job, ok := state.Jobs.Lookup(id)
+	if !ok {
+		if id == 0 {
+			return nil, state.FailWithMessage(fmt.Sprintf("%d:%d: '%s': no current job.\n", t.Line, t.Column, t.Lexeme))
+		}
+		return nil, state.FailWithMessage(fmt.Sprintf("%d:%d: '%s': no such job %%%d.\n", t.Line, t.Column, t.Lexeme, id))
+	}
+	return job, SimpleSuccess()
+}
+
+// evaluateJobs implements 'jobs', listing the job table.
+func (state *EvalState) evaluateJobs(t Token, context ExecuteContext) EvalResult {
+	if state.Jobs == nil {
+		return SimpleSuccess()
+	}
+	var w io.Writer = os.Stdout
+	if context.StandardOutput != nil {
+		w = context.StandardOutput
+	}
+	state.Jobs.List(w)
+	return SimpleSuccess()
+}
+
+// evaluateFg implements 'fg' and 'n fg': the job is given the terminal,
+// continued if stopped, and waited on until it exits or stops again.
+func (state *EvalState) evaluateFg(t Token, stack *MShellStack) EvalResult {
+	job, result := state.lookupJob(t, stack)
+	if job == nil {
+		return result
+	}
+
+	fmt.Fprintln(os.Stderr, job.Command)
+	if job.finished() {
+		state.Jobs.Remove(job)
+		return SimpleSuccess()
+	}
+
+	terminal := resolveControlTerminalEndpoint(os.Stdin, os.Stdin)
+	lease, err := acquireForeground(terminal, job.Pgid)
+	if err != nil {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: Error acquiring terminal control for job %%%d: %s\n", t.Line, t.Column, job.Id, err))
+	}
+	if lease == nil {
+		// No terminal to hand over; still resume the job.
+		ContinueProcessGroup(job.Pgid)
+	}
+	job.State = JobRunning
+
+	exited := job.waitForeground()
+	if err := lease.Release(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: reclaiming terminal control: %s\n", err)
+	}
+
+	if !exited {
+		state.Jobs.Suspend(job)
+		return SimpleSuccess()
+	}
+	state.Jobs.Remove(job)
+	return SimpleSuccess()
+}
+
+// evaluateBg implements 'bg' and 'n bg': a stopped job is continued without
+// giving it the terminal.
+func (state *EvalState) evaluateBg(t Token, stack *MShellStack) EvalResult {
+	job, result := state.lookupJob(t, stack)
+	if job == nil {
+		return result
+	}
+
+	if job.finished() {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: 'bg': job %%%d has already finished.\n", t.Line, t.Column, job.Id))
+	}
+	if job.State == JobRunning {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: 'bg': job %%%d is already running in the background.\n", t.Line, t.Column, job.Id))
+	}
+
+	if err := ContinueProcessGroup(job.Pgid); err != nil {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: Error continuing job %%%d: %s\n", t.Line, t.Column, job.Id, err))
+	}
+	job.State = JobRunning
+	fmt.Fprintf(os.Stderr, "[%d]%s %s &\n", job.Id, state.Jobs.marker(job), job.Command)
+	return SimpleSuccess()
+}
+
+// isJobBuiltin reports whether an interactive command names a job control
+// builtin. These always run as builtins, even where a same-named executable
+// exists on PATH (macOS ships /usr/bin/fg, /usr/bin/bg, and /usr/bin/jobs).
+func isJobBuiltin(name string) bool {
+	return name == "jobs" || name == "fg" || name == "bg"
+}
+
+// rewriteJobCommand turns the familiar 'fg %2' or 'bg 2' interactive forms
+// into the postfix '2 fg' / '2 bg'. Any other input is returned unchanged.
+func rewriteJobCommand(input string) string {
+	fields := strings.Fields(input)
+	if len(fields) != 2 || (fields[0] != "fg" && fields[0] != "bg") {
+		return input
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(fields[1], "%"))
+	if err != nil {
+		return input
+	}
+	return fmt.Sprintf("%d %s", id, fields[0])
+}
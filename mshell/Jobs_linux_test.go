@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestJobStopHelper runs only inside the PTY created by
+// TestForegroundCommandStopsIntoJobTable. Once it has read a line from the
+// terminal, and so is certainly in the foreground, the child stops itself the
+// way Ctrl-Z would stop it, so the shell must hand the terminal back, list the
+// job as stopped, and let 'fg' run it to completion.
+func TestJobStopHelper(t *testing.T) {
+	if os.Getenv(terminalHandoffHelperEnv) != "1" {
+		t.Skip("job stop helper")
+	}
+
+	list := NewList(3)
+	list.Items[0] = MShellString{Content: "sh"}
+	list.Items[1] = MShellString{Content: "-c"}
+	list.Items[2] = MShellString{Content: "IFS= read -r _; kill -STOP $$; printf 'RESUMED\\n'"}
+	list.StdinBehavior = STDIN_FILE
+	list.StandardInputFile = "/dev/tty"
+
+	state := &EvalState{Jobs: NewJobTable()}
+	context := ExecuteContext{
+		StandardOutput: os.Stdout,
+		StandardError:  os.Stderr,
+		Pbm:            NewPathBinManager(),
+	}
+
+	result, exitCode, _, _ := RunProcess(*list, context, state)
+	if !result.Success || exitCode != stoppedExitCode {
+		t.Fatalf("RunProcess result.Success = %v, exitCode = %d; want stopped", result.Success, exitCode)
+	}
+	job, ok := state.Jobs.Lookup(1)
+	if !ok || job.State != JobStopped {
+		t.Fatalf("job 1 = %v; want a stopped job", job)
+	}
+
+	stack := MShellStack{MShellInt{1}}
+	result = state.evaluateFg(Token{Lexeme: "fg"}, &stack)
+	if !result.Success {
+		t.Fatal("fg failed")
+	}
+	if _, ok := state.Jobs.Lookup(1); ok {
+		t.Fatal("finished job is still in the table")
+	}
+	fmt.Fprintln(os.Stdout, "FG_DONE")
+}
+
+func TestForegroundCommandStopsIntoJobTable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("PTY integration test")
+	}
+	runPipedStdinPTYHelper(t, "TestJobStopHelper", "go\n", "[1]+  Stopped", "RESUMED", "FG_DONE")
+}
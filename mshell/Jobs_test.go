@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestJobTableNumberingAndMarkers(t *testing.T) {
+	table := NewJobTable()
+	first := &Job{Command: "sleep 10", State: JobStopped, done: make(chan struct{})}
+	second := &Job{Command: "sort big.txt | uniq -c", State: JobRunning, done: make(chan struct{})}
+	table.Add(first)
+	table.Add(second)
+
+	if first.Id != 1 || second.Id != 2 {
+		t.Fatalf("job ids = %d, %d; want 1, 2", first.Id, second.Id)
+	}
+
+	current, ok := table.Lookup(0)
+	if !ok || current != second {
+		t.Fatalf("current job = %v; want job 2", current)
+	}
+
+	var out bytes.Buffer
+	table.List(&out)
+	want := "[1]-  Stopped  sleep 10\n[2]+  Running  sort big.txt | uniq -c\n"
+	if out.String() != want {
+		t.Fatalf("jobs output = %q; want %q", out.String(), want)
+	}
+
+	// A finished job is reported once and then dropped from the table.
+	close(first.done)
+	out.Reset()
+	table.Report(&out)
+	if out.String() != "[1]-  Done     sleep 10\n" {
+		t.Fatalf("report = %q", out.String())
+	}
+	if _, ok := table.Lookup(1); ok {
+		t.Fatal("finished job is still in the table")
+	}
+
+	third := &Job{Command: "make", done: make(chan struct{})}
+	table.Add(third)
+	if third.Id != 3 {
+		t.Fatalf("new job id = %d; want 3", third.Id)
+	}
+}
+
+func TestRewriteJobCommand(t *testing.T) {
+	cases := map[string]string{
+		"fg %2":   "2 fg",
+		"bg 1":    "1 bg",
+		"fg":      "fg",
+		"fg %x":   "fg %x",
+		"ls %2":   "ls %2",
+		"fg 1 2":  "fg 1 2",
+		"  bg %3": "3 bg",
+	}
+	for input, want := range cases {
+		if got := rewriteJobCommand(input); got != want {
+			t.Errorf("rewriteJobCommand(%q) = %q; want %q", input, got, want)
+		}
+	}
+}
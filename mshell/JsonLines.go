@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// scanJsonLines calls fn with each non-blank line of NDJSON input, one line
+// at a time, so files larger than memory can be read.
+func scanJsonLines(r io.Reader, fn func(lineNum int, line []byte) error) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	for lineNum := 1; ; lineNum++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if lineNum == 1 {
+			line = bytes.TrimPrefix(line, []byte("\ufeff"))
+		}
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			if fnErr := fn(lineNum, trimmed); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// parseJsonLines reads every line as a JSON value, converted as parseJson
+// does.
+func parseJsonLines(r io.Reader) (*MShellList, error) {
+	list := NewList(0)
+	err := scanJsonLines(r, func(lineNum int, line []byte) error {
+		var value any
+		if err := json.Unmarshal(line, &value); err != nil {
+			return fmt.Errorf("line %d: %s", lineNum, err.Error())
+		}
+		list.Items = append(list.Items, ParseJsonObjToMshell(value))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+type jsonLinesGridOptions struct {
+	columns []string // nil to take every key, in order of first appearance
+	maxRows int      // -1 for no limit
+}
+
+// parseJsonLinesGridOptions reads the readJsonLinesGrid options dictionary.
+func parseJsonLinesGridOptions(dict *MShellDict) (jsonLinesGridOptions, error) {
+	options := jsonLinesGridOptions{maxRows: -1}
+	for key := range dict.Items {
+		if key != "columns" && key != "maxRows" {
+			return options, fmt.Errorf("Unknown option '%s', expected one of columns, maxRows", key)
+		}
+	}
+	if val, ok, err := intOption(dict, "maxRows"); err != nil {
+		return options, err
+	} else if ok {
+		if val < 0 {
+			return options, fmt.Errorf("Option 'maxRows' must be >= 0")
+		}
+		options.maxRows = val
+	}
+	if item, ok := dict.Items["columns"]; ok {
+		list, ok := item.(*MShellList)
+		if !ok {
+			return options, fmt.Errorf("Option 'columns' must be a list of strings, found %s", item.TypeName())
+		}
+		seen := make(map[string]struct{}, len(list.Items))
+		options.columns = make([]string, 0, len(list.Items))
+		for _, column := range list.Items {
+			name, ok := column.(MShellString)
+			if !ok {
+				return options, fmt.Errorf("Option 'columns' must be a list of strings, found %s", column.TypeName())
+			}
+			if _, exists := seen[name.Content]; exists {
+				return options, fmt.Errorf("Option 'columns' has duplicate column '%s'", name.Content)
+			}
+			seen[name.Content] = struct{}{}
+			options.columns = append(options.columns, name.Content)
+		}
+	}
+	return options, nil
+}
+
+// jsonLinesCell converts one top-level member value for a grid cell. Unlike
+// parseJson, integer literals stay integers, and null becomes none.
+func jsonLinesCell(raw json.RawMessage) (MShellObject, error) {
+	switch c := raw[0]; {
+	case c == 'n':
+		return &Maybe{obj: nil}, nil
+	case c == '-' || c >= '0' && c <= '9':
+		if !bytes.ContainsAny(raw, ".eE") {
+			if i, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+				return MShellInt{Value: int(i)}, nil
+			}
+		}
+		f, err := strconv.ParseFloat(string(raw), 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return nil, err
+		}
+		return MShellFloat{Value: f}, nil
+	}
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	return ParseJsonObjToMshell(value), nil
+}
+
+// jsonLinesColumnBuilder appends cells straight into a GridColumn's typed
+// storage. The first non-null value picks the type: ints, floats, or
+// strings. Ints widen to floats when a decimal arrives; any other conflict,
+// or a bool, list, or dict, widens the column to generic.
+type jsonLinesColumnBuilder struct {
+	col     *GridColumn
+	typed   bool // set once the first non-null value is seen
+	pending int  // nulls seen before that
+}
+
+func (b *jsonLinesColumnBuilder) add(cell MShellObject) {
+	col := b.col
+	if isNoneCell(cell) {
+		if b.typed {
+			appendNone(col)
+		} else {
+			b.pending++
+		}
+		return
+	}
+
+	if !b.typed {
+		b.typed = true
+		switch cell.(type) {
+		case MShellInt:
+			col.ColType = COL_INT
+		case MShellFloat:
+			col.ColType = COL_FLOAT
+		case MShellString:
+			col.ColType = COL_STRING
+		default:
+			col.ColType = COL_GENERIC
+		}
+		for ; b.pending > 0; b.pending-- {
+			appendNone(col)
+		}
+	}
+
+	switch v := cell.(type) {
+	case MShellInt:
+		if col.ColType == COL_INT {
+			col.IntData = append(col.IntData, int64(v.Value))
+			return
+		}
+		if col.ColType == COL_FLOAT {
+			col.FloatData = append(col.FloatData, float64(v.Value))
+			return
+		}
+	case MShellFloat:
+		if col.ColType == COL_INT {
+			widenIntColumnToFloat(col)
+		}
+		if col.ColType == COL_FLOAT {
+			col.FloatData = append(col.FloatData, v.Value)
+			return
+		}
+	case MShellString:
+		if col.ColType == COL_STRING {
+			col.StringData = append(col.StringData, v.Content)
+			return
+		}
+	}
+	widenColumnToGeneric(col)
+	col.GenericData = append(col.GenericData, cell)
+}
+
+// finish pads a column that never saw a value with none.
+func (b *jsonLinesColumnBuilder) finish() *GridColumn {
+	for ; b.pending > 0; b.pending-- {
+		appendNone(b.col)
+	}
+	return b.col
+}
+
+// widenIntColumnToFloat converts an int column to floats, keeping its nulls.
+func widenIntColumnToFloat(col *GridColumn) {
+	col.FloatData = make([]float64, len(col.IntData))
+	for i, n := range col.IntData {
+		if col.Nulls.has(i) {
+			col.FloatData[i] = math.NaN()
+		} else {
+			col.FloatData[i] = float64(n)
+		}
+	}
+	col.IntData = nil
+	col.ColType = COL_FLOAT
+}
+
+// readJsonLinesGrid streams NDJSON objects into a grid. Columns come from
+// the keys in order of first appearance, or options.columns when set; a key
+// missing from a line gives none for that row.
+func readJsonLinesGrid(r io.Reader, options jsonLinesGridOptions) (*MShellGrid, error) {
+	var builders []*jsonLinesColumnBuilder
+	index := make(map[string]int)
+	addColumn := func(name string, rows int) {
+		index[name] = len(builders)
+		builders = append(builders, &jsonLinesColumnBuilder{col: &GridColumn{Name: name}, pending: rows})
+	}
+	for _, name := range options.columns {
+		addColumn(name, 0)
+	}
+	rows := 0
+	// line holds this line's cells by column, so a repeated key can be
+	// replaced before anything is appended.
+	var line []MShellObject
+
+	errStop := errors.New("stop")
+	err := scanJsonLines(r, func(lineNum int, text []byte) error {
+		if options.maxRows >= 0 && rows >= options.maxRows {
+			return errStop
+		}
+		dec := json.NewDecoder(bytes.NewReader(text))
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return fmt.Errorf("line %d: expected a JSON object", lineNum)
+		}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return fmt.Errorf("line %d: %s", lineNum, err.Error())
+			}
+			key := tok.(string)
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return fmt.Errorf("line %d: %s", lineNum, err.Error())
+			}
+			i, ok := index[key]
+			if !ok {
+				if options.columns != nil {
+					continue
+				}
+				i = len(builders)
+				addColumn(key, rows)
+			}
+			cell, err := jsonLinesCell(raw)
+			if err != nil {
+				return fmt.Errorf("line %d: %s", lineNum, err.Error())
+			}
+			for len(line) <= i {
+				line = append(line, nil)
+			}
+			// A repeated key; the last value wins, as in parseJson.
+			line[i] = cell
+		}
+		if _, err := dec.Token(); err != nil {
+			return fmt.Errorf("line %d: %s", lineNum, err.Error())
+		}
+		if _, err := dec.Token(); err != io.EOF {
+			return fmt.Errorf("line %d: unexpected data after the JSON object", lineNum)
+		}
+		for i, b := range builders {
+			if i < len(line) && line[i] != nil {
+				b.add(line[i])
+				line[i] = nil
+			} else {
+				b.add(&Maybe{obj: nil})
+			}
+		}
+		rows++
+		return nil
+	})
+	if err != nil && err != errStop {
+		return nil, err
+	}
+
+	grid := NewGrid()
+	grid.RowCount = rows
+	for _, b := range builders {
+		grid.AddColumn(b.finish())
+	}
+	return grid, nil
+}
+
+// writeJsonLinesList writes each item of list as one line of compact JSON,
+// matching the grid writer.
+func writeJsonLinesList(w io.Writer, list *MShellList) error {
+	bw := bufio.NewWriter(w)
+	for _, item := range list.Items {
+		bw.WriteString(compactJson(item.ToJson()))
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
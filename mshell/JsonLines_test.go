@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseJsonLines(t *testing.T) {
+	list, err := parseJsonLines(strings.NewReader("\ufeff{\"a\": 1}\r\n\n[true, null]\n\"text\""))
+	if err != nil {
+		t.Fatalf("parseJsonLines error: %v", err)
+	}
+	if got, want := list.ToJson(), `[{"a": 1}, [true, null], "text"]`; got != want {
+		t.Errorf("parseJsonLines = %s, want %s", got, want)
+	}
+
+	if _, err := parseJsonLines(strings.NewReader("{}\n{\"a\": }\n")); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("parseJsonLines error = %v, want one for line 2", err)
+	}
+}
+
+func TestReadJsonLinesGrid(t *testing.T) {
+	src := `{"id": 1, "level": "info", "ms": 12}
+{"id": 2, "level": "warn", "ms": 3.5, "tags": ["slow"]}
+
+{"level": "info", "id": 3, "ms": null}
+`
+	grid, err := readJsonLinesGrid(strings.NewReader(src), jsonLinesGridOptions{maxRows: -1})
+	if err != nil {
+		t.Fatalf("readJsonLinesGrid error: %v", err)
+	}
+	if grid.RowCount != 3 {
+		t.Fatalf("RowCount = %d, want 3", grid.RowCount)
+	}
+	var names []string
+	for _, col := range grid.Columns {
+		names = append(names, col.Name)
+	}
+	if got := strings.Join(names, " "); got != "id level ms tags" {
+		t.Errorf("columns = %s, want id level ms tags", got)
+	}
+	if col := grid.GetColumn("id"); col.ColType != COL_INT || col.IntData[2] != 3 {
+		t.Errorf("id column should be ints ending in 3, got type %d", col.ColType)
+	}
+	ms := grid.GetColumn("ms")
+	if f, ok := ms.Get(0).(MShellFloat); !ok || f.Value != 12 {
+		t.Errorf("ms[0] = %v, want the float 12", ms.Get(0))
+	}
+	if m, ok := ms.Get(2).(*Maybe); !ok || m.obj != nil {
+		t.Errorf("ms[2] = %v, want none", ms.Get(2))
+	}
+	if ms.ColType != COL_FLOAT || ms.Nulls == nil || !ms.Nulls.has(2) {
+		t.Errorf("ms should be a float column with row 2 masked, got type %d", ms.ColType)
+	}
+	if col := grid.GetColumn("level"); col.ColType != COL_STRING {
+		t.Errorf("level column type = %d, want COL_STRING", col.ColType)
+	}
+	if m, ok := grid.GetColumn("tags").Get(0).(*Maybe); !ok || m.obj != nil {
+		t.Errorf("tags[0] should be none")
+	}
+
+	grid, err = readJsonLinesGrid(strings.NewReader(src), jsonLinesGridOptions{columns: []string{"level", "host"}, maxRows: 2})
+	if err != nil {
+		t.Fatalf("readJsonLinesGrid error: %v", err)
+	}
+	if grid.RowCount != 2 || len(grid.Columns) != 2 || grid.Columns[1].Name != "host" {
+		t.Errorf("columns and maxRows options not applied: %d rows, %d columns", grid.RowCount, len(grid.Columns))
+	}
+
+	if _, err := readJsonLinesGrid(strings.NewReader("{}\n[1]\n"), jsonLinesGridOptions{maxRows: -1}); err == nil || err.Error() != "line 2: expected a JSON object" {
+		t.Errorf("readJsonLinesGrid error = %v, want line 2: expected a JSON object", err)
+	}
+}
+
+func TestReadJsonLinesGridWidening(t *testing.T) {
+	src := `{"n": null, "code": 1, "x": 1}
+{"n": 5, "code": "E2", "x": 2, "x": 3}
+`
+	grid, err := readJsonLinesGrid(strings.NewReader(src), jsonLinesGridOptions{maxRows: -1})
+	if err != nil {
+		t.Fatalf("readJsonLinesGrid error: %v", err)
+	}
+	n := grid.GetColumn("n")
+	if n.ColType != COL_INT || !n.Nulls.has(0) || n.IntData[1] != 5 {
+		t.Errorf("n should be an int column with a leading none, got type %d", n.ColType)
+	}
+	code := grid.GetColumn("code")
+	if code.ColType != COL_GENERIC {
+		t.Errorf("code column type = %d, want COL_GENERIC after an int then a string", code.ColType)
+	}
+	if s, ok := code.Get(1).(MShellString); !ok || s.Content != "E2" {
+		t.Errorf("code[1] = %v, want E2", code.Get(1))
+	}
+	if x := grid.GetColumn("x"); x.ColType != COL_INT || x.IntData[1] != 3 {
+		t.Errorf("x should be ints with the repeated key's last value 3, got type %d", x.ColType)
+	}
+}
+
+func TestWriteJsonLinesListCompact(t *testing.T) {
+	list, err := parseJsonLines(strings.NewReader(`{"a": [1, "x, y"], "b": {"c": null}}`))
+	if err != nil {
+		t.Fatalf("parseJsonLines error: %v", err)
+	}
+	var sb strings.Builder
+	if err := writeJsonLinesList(&sb, list); err != nil {
+		t.Fatalf("writeJsonLinesList error: %v", err)
+	}
+	if got, want := sb.String(), "{\"a\":[1,\"x, y\"],\"b\":{\"c\":null}}\n"; got != want {
+		t.Errorf("writeJsonLinesList = %q, want %q", got, want)
+	}
+}
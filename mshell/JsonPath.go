@@ -0,0 +1,733 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONPath (RFC 9535) subset for jsonPath: the root '$', '.name' and
+// "['name']" members, indices and slices, '*' wildcards, '..' descendants,
+// unions, and '?' filters with comparisons, '&&', '||', '!', existence
+// tests, and length(). Dictionary members are visited in sorted key order,
+// as mshell dictionaries do not keep insertion order.
+
+type jsonPathSegment struct {
+	descendant bool
+	selectors  []jsonPathSelector
+}
+
+type jsonPathSelector struct {
+	kind   byte // 'n' name, 'i' index, 's' slice, '*' wildcard, '?' filter
+	name   string
+	index  int
+	slice  [3]*int // start, end, step
+	filter jsonPathExpr
+}
+
+type jsonPathParser struct {
+	src string
+	pos int
+}
+
+func (p *jsonPathParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid JSONPath '%s' at offset %d: %s", p.src, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *jsonPathParser) peek() byte {
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *jsonPathParser) skipSpace() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\n\r", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// compileJsonPath parses a query, which must start at the root '$'.
+func compileJsonPath(src string) ([]jsonPathSegment, error) {
+	p := &jsonPathParser{src: src}
+	p.skipSpace()
+	if p.peek() != '$' {
+		return nil, p.errorf("a query must start with '$'")
+	}
+	p.pos++
+	segments, err := p.parseSegments()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected '%c'", p.src[p.pos])
+	}
+	return segments, nil
+}
+
+func isJsonPathNameChar(c byte, first bool) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80 || !first && (c >= '0' && c <= '9' || c == '-')
+}
+
+// parseSegments reads the segments following '$' or '@'.
+func (p *jsonPathParser) parseSegments() ([]jsonPathSegment, error) {
+	var segments []jsonPathSegment
+	for {
+		var segment jsonPathSegment
+		switch {
+		case strings.HasPrefix(p.src[p.pos:], ".."):
+			p.pos += 2
+			segment.descendant = true
+			if p.peek() == '[' {
+				selectors, err := p.parseBracket()
+				if err != nil {
+					return nil, err
+				}
+				segment.selectors = selectors
+			} else {
+				selector, err := p.parseDotSelector()
+				if err != nil {
+					return nil, err
+				}
+				segment.selectors = []jsonPathSelector{selector}
+			}
+		case p.peek() == '.':
+			p.pos++
+			selector, err := p.parseDotSelector()
+			if err != nil {
+				return nil, err
+			}
+			segment.selectors = []jsonPathSelector{selector}
+		case p.peek() == '[':
+			selectors, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			segment.selectors = selectors
+		default:
+			return segments, nil
+		}
+		segments = append(segments, segment)
+	}
+}
+
+func (p *jsonPathParser) parseDotSelector() (jsonPathSelector, error) {
+	if p.peek() == '*' {
+		p.pos++
+		return jsonPathSelector{kind: '*'}, nil
+	}
+	start := p.pos
+	for p.pos < len(p.src) && isJsonPathNameChar(p.src[p.pos], p.pos == start) {
+		p.pos++
+	}
+	if p.pos == start {
+		return jsonPathSelector{}, p.errorf("expected a member name")
+	}
+	return jsonPathSelector{kind: 'n', name: p.src[start:p.pos]}, nil
+}
+
+func (p *jsonPathParser) parseBracket() ([]jsonPathSelector, error) {
+	p.pos++ // '['
+	var selectors []jsonPathSelector
+	for {
+		p.skipSpace()
+		selector, err := p.parseBracketSelector()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return selectors, nil
+		default:
+			return nil, p.errorf("expected ',' or ']'")
+		}
+	}
+}
+
+func (p *jsonPathParser) parseBracketSelector() (jsonPathSelector, error) {
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		return jsonPathSelector{kind: '*'}, nil
+	case c == '\'' || c == '"':
+		name, err := p.parseString()
+		return jsonPathSelector{kind: 'n', name: name}, err
+	case c == '?':
+		p.pos++
+		filter, err := p.parseLogical()
+		return jsonPathSelector{kind: '?', filter: filter}, err
+	}
+
+	// An index or a slice.
+	var parts [3]*int
+	part := 0
+	for {
+		p.skipSpace()
+		if c := p.peek(); c == '-' || c >= '0' && c <= '9' {
+			n, err := p.parseInt()
+			if err != nil {
+				return jsonPathSelector{}, err
+			}
+			parts[part] = &n
+			p.skipSpace()
+		}
+		if p.peek() != ':' || part == 2 {
+			break
+		}
+		p.pos++
+		part++
+	}
+	if part == 0 {
+		if parts[0] == nil {
+			return jsonPathSelector{}, p.errorf("expected a selector")
+		}
+		return jsonPathSelector{kind: 'i', index: *parts[0]}, nil
+	}
+	if parts[2] != nil && *parts[2] == 0 {
+		return jsonPathSelector{}, p.errorf("slice step cannot be 0")
+	}
+	return jsonPathSelector{kind: 's', slice: parts}, nil
+}
+
+func (p *jsonPathParser) parseInt() (int, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for c := p.peek(); c >= '0' && c <= '9'; c = p.peek() {
+		p.pos++
+	}
+	n, err := strconv.Atoi(p.src[start:p.pos])
+	if err != nil {
+		return 0, p.errorf("invalid integer '%s'", p.src[start:p.pos])
+	}
+	return n, nil
+}
+
+// parseString reads a single- or double-quoted string with JSON escapes.
+func (p *jsonPathParser) parseString() (string, error) {
+	quote := p.peek()
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.src[p.pos]
+		if c == quote {
+			p.pos++
+			return sb.String(), nil
+		}
+		if c != '\\' {
+			sb.WriteByte(c)
+			p.pos++
+			continue
+		}
+		p.pos++
+		escaped := p.peek()
+		p.pos++
+		switch escaped {
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case '/', '\\', '\'', '"':
+			sb.WriteByte(escaped)
+		case 'u':
+			if p.pos+4 > len(p.src) {
+				return "", p.errorf("invalid escape")
+			}
+			code, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+			if err != nil {
+				return "", p.errorf("invalid escape '\\u%s'", p.src[p.pos:p.pos+4])
+			}
+			sb.WriteRune(rune(code))
+			p.pos += 4
+		default:
+			return "", p.errorf("invalid escape '\\%c'", escaped)
+		}
+	}
+}
+
+// Filter expressions.
+
+type jsonPathExpr interface {
+	// eval returns the expression's value, or ok false for Nothing: a path
+	// that selects no node, or more than one.
+	eval(current, root MShellObject) (value MShellObject, ok bool)
+}
+
+type jsonPathLogical struct {
+	op          string // "&&", "||", or "!"
+	left, right jsonPathExpr
+}
+
+type jsonPathCompare struct {
+	op          string
+	left, right jsonPathExpr
+}
+
+type jsonPathLiteral struct {
+	value MShellObject
+}
+
+type jsonPathQuery struct {
+	relative bool
+	segments []jsonPathSegment
+	// exists turns the query into a test that it selects at least one node.
+	exists bool
+}
+
+type jsonPathLength struct {
+	arg jsonPathExpr
+}
+
+func (p *jsonPathParser) parseLogical() (jsonPathExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !strings.HasPrefix(p.src[p.pos:], "||") {
+			return left, nil
+		}
+		p.pos += 2
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &jsonPathLogical{op: "||", left: left, right: right}
+	}
+}
+
+func (p *jsonPathParser) parseAnd() (jsonPathExpr, error) {
+	left, err := p.parseBasic()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !strings.HasPrefix(p.src[p.pos:], "&&") {
+			return left, nil
+		}
+		p.pos += 2
+		right, err := p.parseBasic()
+		if err != nil {
+			return nil, err
+		}
+		left = &jsonPathLogical{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *jsonPathParser) parseBasic() (jsonPathExpr, error) {
+	p.skipSpace()
+	if p.peek() == '!' && !strings.HasPrefix(p.src[p.pos:], "!=") {
+		p.pos++
+		operand, err := p.parseBasic()
+		if err != nil {
+			return nil, err
+		}
+		return &jsonPathLogical{op: "!", left: operand}, nil
+	}
+	if p.peek() == '(' {
+		p.pos++
+		expr, err := p.parseLogical()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.peek() != ')' {
+			return nil, p.errorf("expected ')'")
+		}
+		p.pos++
+		return expr, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			p.pos += len(op)
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return &jsonPathCompare{op: op, left: left, right: right}, nil
+		}
+	}
+	if query, ok := left.(*jsonPathQuery); ok {
+		query.exists = true
+		return query, nil
+	}
+	return nil, p.errorf("expected a comparison")
+}
+
+func (p *jsonPathParser) parseOperand() (jsonPathExpr, error) {
+	p.skipSpace()
+	rest := p.src[p.pos:]
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.pos++
+		segments, err := p.parseSegments()
+		if err != nil {
+			return nil, err
+		}
+		return &jsonPathQuery{relative: c == '@', segments: segments}, nil
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		return &jsonPathLiteral{value: MShellString{Content: s}}, err
+	case c == '-' || c >= '0' && c <= '9':
+		start := p.pos
+		for p.pos < len(p.src) && strings.IndexByte("+-.eE0123456789", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		text := p.src[start:p.pos]
+		if i, err := strconv.Atoi(text); err == nil {
+			return &jsonPathLiteral{value: MShellInt{Value: i}}, nil
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number '%s'", text)
+		}
+		return &jsonPathLiteral{value: MShellFloat{Value: f}}, nil
+	case strings.HasPrefix(rest, "true"):
+		p.pos += 4
+		return &jsonPathLiteral{value: MShellBool{Value: true}}, nil
+	case strings.HasPrefix(rest, "false"):
+		p.pos += 5
+		return &jsonPathLiteral{value: MShellBool{Value: false}}, nil
+	case strings.HasPrefix(rest, "null"):
+		p.pos += 4
+		return &jsonPathLiteral{value: MShellNull{}}, nil
+	case strings.HasPrefix(rest, "length("):
+		p.pos += len("length(")
+		arg, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.peek() != ')' {
+			return nil, p.errorf("expected ')'")
+		}
+		p.pos++
+		return &jsonPathLength{arg: arg}, nil
+	}
+	return nil, p.errorf("expected a value, '@', or '$'")
+}
+
+func (e *jsonPathLogical) eval(current, root MShellObject) (MShellObject, bool) {
+	left := jsonPathTruth(e.left, current, root)
+	switch e.op {
+	case "!":
+		return MShellBool{Value: !left}, true
+	case "&&":
+		return MShellBool{Value: left && jsonPathTruth(e.right, current, root)}, true
+	}
+	return MShellBool{Value: left || jsonPathTruth(e.right, current, root)}, true
+}
+
+// jsonPathTruth evaluates a filter's test.
+func jsonPathTruth(expr jsonPathExpr, current, root MShellObject) bool {
+	value, ok := expr.eval(current, root)
+	if !ok {
+		return false
+	}
+	b, isBool := value.(MShellBool)
+	return !isBool || b.Value
+}
+
+func (e *jsonPathCompare) eval(current, root MShellObject) (MShellObject, bool) {
+	left, leftOk := e.left.eval(current, root)
+	right, rightOk := e.right.eval(current, root)
+	var result bool
+	switch e.op {
+	case "==", "!=":
+		equal := leftOk == rightOk && (!leftOk || jsonPathEqual(left, right))
+		result = equal == (e.op == "==")
+	default:
+		if leftOk && rightOk {
+			if cmp, comparable := jsonPathOrder(left, right); comparable {
+				switch e.op {
+				case "<":
+					result = cmp < 0
+				case "<=":
+					result = cmp <= 0
+				case ">":
+					result = cmp > 0
+				case ">=":
+					result = cmp >= 0
+				}
+			}
+		}
+	}
+	return MShellBool{Value: result}, true
+}
+
+func (e *jsonPathLiteral) eval(current, root MShellObject) (MShellObject, bool) {
+	return e.value, true
+}
+
+func (e *jsonPathQuery) eval(current, root MShellObject) (MShellObject, bool) {
+	start := root
+	if e.relative {
+		start = current
+	}
+	nodes := applyJsonPath(e.segments, start, root)
+	if e.exists {
+		return MShellBool{Value: len(nodes) > 0}, true
+	}
+	if len(nodes) != 1 {
+		return nil, false
+	}
+	return nodes[0], true
+}
+
+func (e *jsonPathLength) eval(current, root MShellObject) (MShellObject, bool) {
+	value, ok := e.arg.eval(current, root)
+	if !ok {
+		return nil, false
+	}
+	switch typed := value.(type) {
+	case MShellString:
+		return MShellInt{Value: len([]rune(typed.Content))}, true
+	case *MShellList:
+		return MShellInt{Value: len(typed.Items)}, true
+	case *MShellDict:
+		return MShellInt{Value: len(typed.Items)}, true
+	}
+	return nil, false
+}
+
+func jsonPathNumber(obj MShellObject) (float64, bool) {
+	switch typed := obj.(type) {
+	case MShellInt:
+		return float64(typed.Value), true
+	case MShellFloat:
+		return typed.Value, true
+	}
+	return 0, false
+}
+
+// jsonPathOrder compares two numbers or two strings.
+func jsonPathOrder(left, right MShellObject) (int, bool) {
+	if l, ok := jsonPathNumber(left); ok {
+		if r, ok := jsonPathNumber(right); ok {
+			switch {
+			case l < r:
+				return -1, true
+			case l > r:
+				return 1, true
+			}
+			return 0, true
+		}
+		return 0, false
+	}
+	l, lok := left.(MShellString)
+	r, rok := right.(MShellString)
+	if !lok || !rok {
+		return 0, false
+	}
+	return strings.Compare(l.Content, r.Content), true
+}
+
+func jsonPathEqual(left, right MShellObject) bool {
+	if l, ok := jsonPathNumber(left); ok {
+		r, ok := jsonPathNumber(right)
+		return ok && l == r
+	}
+	switch l := left.(type) {
+	case MShellString:
+		r, ok := right.(MShellString)
+		return ok && l.Content == r.Content
+	case MShellBool:
+		r, ok := right.(MShellBool)
+		return ok && l.Value == r.Value
+	case MShellNull:
+		_, ok := right.(MShellNull)
+		return ok
+	case *MShellList:
+		r, ok := right.(*MShellList)
+		if !ok || len(l.Items) != len(r.Items) {
+			return false
+		}
+		for i := range l.Items {
+			if !jsonPathEqual(l.Items[i], r.Items[i]) {
+				return false
+			}
+		}
+		return true
+	case *MShellDict:
+		r, ok := right.(*MShellDict)
+		if !ok || len(l.Items) != len(r.Items) {
+			return false
+		}
+		for key, value := range l.Items {
+			other, ok := r.Items[key]
+			if !ok || !jsonPathEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Evaluation.
+
+// jsonPathChildren returns the values of a list or dictionary, the latter
+// in sorted key order.
+func jsonPathChildren(obj MShellObject) []MShellObject {
+	switch typed := obj.(type) {
+	case *MShellList:
+		return typed.Items
+	case *MShellDict:
+		keys := make([]string, 0, len(typed.Items))
+		for key := range typed.Items {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		values := make([]MShellObject, len(keys))
+		for i, key := range keys {
+			values[i] = typed.Items[key]
+		}
+		return values
+	}
+	return nil
+}
+
+func applyJsonPath(segments []jsonPathSegment, start, root MShellObject) []MShellObject {
+	nodes := []MShellObject{start}
+	for _, segment := range segments {
+		var next []MShellObject
+		for _, node := range nodes {
+			targets := []MShellObject{node}
+			if segment.descendant {
+				targets = jsonPathDescendants(node, nil)
+			}
+			for _, target := range targets {
+				for _, selector := range segment.selectors {
+					next = append(next, selector.apply(target, root)...)
+				}
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// jsonPathDescendants returns node and everything below it, parents before
+// children.
+func jsonPathDescendants(node MShellObject, out []MShellObject) []MShellObject {
+	out = append(out, node)
+	for _, child := range jsonPathChildren(node) {
+		out = jsonPathDescendants(child, out)
+	}
+	return out
+}
+
+func (s jsonPathSelector) apply(node, root MShellObject) []MShellObject {
+	switch s.kind {
+	case 'n':
+		if dict, ok := node.(*MShellDict); ok {
+			if value, ok := dict.Items[s.name]; ok {
+				return []MShellObject{value}
+			}
+		}
+	case '*':
+		return jsonPathChildren(node)
+	case 'i':
+		if list, ok := node.(*MShellList); ok {
+			i := s.index
+			if i < 0 {
+				i += len(list.Items)
+			}
+			if i >= 0 && i < len(list.Items) {
+				return []MShellObject{list.Items[i]}
+			}
+		}
+	case 's':
+		if list, ok := node.(*MShellList); ok {
+			return jsonPathSlice(list.Items, s.slice)
+		}
+	case '?':
+		var out []MShellObject
+		for _, child := range jsonPathChildren(node) {
+			if jsonPathTruth(s.filter, child, root) {
+				out = append(out, child)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// jsonPathSlice applies a start:end:step slice as RFC 9535 defines it.
+func jsonPathSlice(items []MShellObject, parts [3]*int) []MShellObject {
+	n := len(items)
+	step := 1
+	if parts[2] != nil {
+		step = *parts[2]
+	}
+	normalize := func(i int) int {
+		if i < 0 {
+			return i + n
+		}
+		return i
+	}
+	var out []MShellObject
+	if step > 0 {
+		start, end := 0, n
+		if parts[0] != nil {
+			start = normalize(*parts[0])
+		}
+		if parts[1] != nil {
+			end = normalize(*parts[1])
+		}
+		start = int(math.Min(math.Max(float64(start), 0), float64(n)))
+		end = int(math.Min(math.Max(float64(end), 0), float64(n)))
+		for i := start; i < end; i += step {
+			out = append(out, items[i])
+		}
+		return out
+	}
+	start, end := n-1, -n-1
+	if parts[0] != nil {
+		start = normalize(*parts[0])
+	}
+	if parts[1] != nil {
+		end = normalize(*parts[1])
+	}
+	start = int(math.Min(math.Max(float64(start), -1), float64(n-1)))
+	end = int(math.Min(math.Max(float64(end), -1), float64(n-1)))
+	for i := start; i > end; i += step {
+		out = append(out, items[i])
+	}
+	return out
+}
+
+// evalJsonPath returns the values a query selects from obj, in order.
+func evalJsonPath(obj MShellObject, query string) (*MShellList, error) {
+	segments, err := compileJsonPath(query)
+	if err != nil {
+		return nil, err
+	}
+	return &MShellList{Items: append([]MShellObject{}, applyJsonPath(segments, obj, obj)...)}, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEvalJsonPath(t *testing.T) {
+	var decoded any
+	if err := json.Unmarshal([]byte(`{
+  "store": {
+    "book": [
+      {"title": "Sayings", "price": 8.95, "tags": ["old"]},
+      {"title": "Sword", "price": 12.99, "isbn": "0-553"},
+      {"title": "Moby Dick", "price": 8.99, "isbn": "0-395"},
+      {"title": "Rings", "price": 22.99}
+    ],
+    "bicycle": {"color": "red", "price": 19.95}
+  },
+  "limit": 10
+}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	doc := ParseJsonObjToMshell(decoded)
+
+	for _, tt := range []struct {
+		query string
+		want  string
+	}{
+		{"$.store.book[0].title", `["Sayings"]`},
+		{"$['store']['bicycle'].color", `["red"]`},
+		{"$.store.book[-1].title", `["Rings"]`},
+		{"$.store.book[1:3].title", `["Sword", "Moby Dick"]`},
+		{"$.store.book[::-2].title", `["Rings", "Sword"]`},
+		{"$.store.book[0,2].price", `[8.95, 8.99]`},
+		{"$.store.bicycle.*", `["red", 19.95]`},
+		{"$..price", `[19.95, 8.95, 12.99, 8.99, 22.99]`},
+		{"$.store.book[?@.isbn].title", `["Sword", "Moby Dick"]`},
+		{"$.store.book[?(@.price < 10 && !@.isbn)].title", `["Sayings"]`},
+		{"$.store.book[?@.price > $.limit || @.title == 'Sayings'].title", `["Sayings", "Sword", "Rings"]`},
+		{"$.store.book[?length(@.title) == 5].title", `["Sword", "Rings"]`},
+		{"$.missing", `[]`},
+	} {
+		result, err := evalJsonPath(doc, tt.query)
+		if err != nil {
+			t.Errorf("evalJsonPath(%q) error: %v", tt.query, err)
+			continue
+		}
+		if got := result.ToJson(); got != tt.want {
+			t.Errorf("evalJsonPath(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestCompileJsonPathErrors(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		want  string
+	}{
+		{"store.book", "must start with '$'"},
+		{"$.book[", "expected a selector"},
+		{"$.book[0:1:0]", "slice step cannot be 0"},
+		{"$.book[?@.price <]", "expected a value"},
+		{"$['unterminated]", "unterminated string"},
+		{"$.book]", "unexpected ']'"},
+	} {
+		if _, err := compileJsonPath(tt.query); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("compileJsonPath(%q) error = %v, want one containing %q", tt.query, err, tt.want)
+		}
+	}
+}
@@ -111,6 +111,9 @@ const (
 	TRY
 	FAIL_KEYWORD
 	PURE
+
+	IMPORT      // import "source" sha256:<hash> [as name]
+	CONTENTHASH // sha256:<64 hex digits>, the pin on an import
 )
 
 func (t TokenType) String() string {
@@ -295,6 +298,10 @@ func (t TokenType) String() string {
 		return "FAIL_KEYWORD"
 	case PURE:
 		return "PURE"
+	case IMPORT:
+		return "IMPORT"
+	case CONTENTHASH:
+		return "CONTENTHASH"
 	default:
 		return "UNKNOWN"
 	}
@@ -526,6 +533,15 @@ func (l *Lexer) parseLiteralOrKeyword() Token {
 
 	// Check for prefix quote syntax: literal ending with '.' (e.g., "filter.", "map.")
 	lexeme := l.input[l.start:l.current]
+
+	// Content hash pin on an import: sha256:<64 hex digits>. Anything else
+	// keeps lexing as a literal followed by ':'.
+	if string(lexeme) == "sha256" && l.peek() == ':' && l.isContentHashDigest(1) {
+		for i := 0; i < 65; i++ {
+			l.advance()
+		}
+		return l.makeToken(CONTENTHASH)
+	}
 	if len(lexeme) > 1 && lexeme[len(lexeme)-1] == '.' {
 		return l.makeToken(PREFIXQUOTE)
 	}
@@ -534,6 +550,18 @@ func (l *Lexer) parseLiteralOrKeyword() Token {
 	return l.makeToken(tokenType)
 }
 
+// isContentHashDigest reports whether exactly 64 lowercase hex digits start
+// at the given offset from the current position.
+func (l *Lexer) isContentHashDigest(offset int) bool {
+	for i := 0; i < 64; i++ {
+		c := l.peekAt(offset + i)
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return !isAllowedLiteral(l.peekAt(offset + 64))
+}
+
 func (l *Lexer) literalOrKeywordType() TokenType {
 	switch l.input[l.start] {
 	case '-':
@@ -602,6 +630,8 @@ func (l *Lexer) literalOrKeywordType() TokenType {
 				return l.checkKeyword(2, "", IF)
 			} else if c == 'n' {
 				return l.checkKeyword(2, "t", TYPEINT)
+			} else if c == 'm' {
+				return l.checkKeyword(2, "port", IMPORT)
 			}
 		}
 	case 'l':
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"golang.org/x/net/html"
+	"math"
 	"os"
 	"regexp"
 	"slices"
@@ -17,18 +18,20 @@ import (
 
 // TruncateMiddle truncates a string to a maximum length, adding "..." in the middle if necessary.
 func TruncateMiddle(s string, maxLen int) string {
-	if len(s) <= maxLen || maxLen <= 3 {
+	runes := []rune(s)
+	if len(runes) <= maxLen || maxLen <= 3 {
 		// No truncation needed or maxLen too small to fit "..."
 		return s
 	}
 
-	// Calculate lengths of the parts around "..."
+	// Calculate lengths of the parts around "...", counted in runes so
+	// multi-byte characters are never split.
 	half := (maxLen - 3) / 2
 	remainder := (maxLen - 3) % 2
-	start := s[:half]                // First part of the string
-	end := s[len(s)-half-remainder:] // Last part of the string
+	start := runes[:half]                    // First part of the string
+	end := runes[len(runes)-half-remainder:] // Last part of the string
 
-	return start + "..." + end
+	return string(start) + "..." + string(end)
 }
 
 type Jsonable interface {
@@ -787,6 +790,7 @@ type MShellList struct {
 	InPlaceFile     string // File path for in-place modification with <>
 	StdoutToStderr  bool   // 1>&2: stdout goes to stderr's destination
 	StderrToStdout  bool   // 2>&1: stderr goes to stdout's destination
+	ParallelJobs    int    // > 0 marks a 'parallel' group: the items run concurrently, at most this many at once
 }
 
 // initLength creates list like: make([]MShellObject, initLength)
@@ -940,9 +944,10 @@ type MShellPath struct {
 }
 
 type MShellPipe struct {
-	List           MShellList
-	StdoutBehavior StdoutBehavior
-	StderrBehavior StderrBehavior
+	List            MShellList
+	StdoutBehavior  StdoutBehavior
+	StderrBehavior  StderrBehavior
+	RunInBackground bool
 }
 
 // Pipes only support captures as stream destinations.
@@ -2203,6 +2208,7 @@ type GridColumn struct {
 	StringData   []string
 	DateTimeData []time.Time
 	GenericData  []MShellObject   // Fallback for mixed types
+	Nulls        nullMask         // none rows of a typed column; nil when there are none
 }
 
 // NewGridColumn creates a new column with the given name and row count
@@ -2217,6 +2223,9 @@ func NewGridColumn(name string, rowCount int) *GridColumn {
 
 // Get returns the value at the given row index
 func (col *GridColumn) Get(index int) MShellObject {
+	if col.Nulls.has(index) {
+		return &Maybe{obj: nil}
+	}
 	switch col.ColType {
 	case COL_INT:
 		return MShellInt{Value: int(col.IntData[index])}
@@ -2231,24 +2240,36 @@ func (col *GridColumn) Get(index int) MShellObject {
 	}
 }
 
-// Set sets the value at the given row index
+// Set sets the value at the given row index. A none value in a typed
+// column marks the row in the null mask.
 func (col *GridColumn) Set(index int, value MShellObject) {
+	if col.ColType != COL_GENERIC && isNoneCell(value) {
+		col.Nulls.set(index)
+		if col.ColType == COL_FLOAT {
+			col.FloatData[index] = math.NaN()
+		}
+		return
+	}
 	switch col.ColType {
 	case COL_INT:
 		if intVal, ok := value.(MShellInt); ok {
 			col.IntData[index] = int64(intVal.Value)
+			col.Nulls.unset(index)
 		}
 	case COL_FLOAT:
 		if floatVal, ok := value.(MShellFloat); ok {
 			col.FloatData[index] = floatVal.Value
+			col.Nulls.unset(index)
 		}
 	case COL_STRING:
 		if strVal, ok := value.(MShellString); ok {
 			col.StringData[index] = strVal.Content
+			col.Nulls.unset(index)
 		}
 	case COL_DATETIME:
 		if dtVal, ok := value.(*MShellDateTime); ok {
 			col.DateTimeData[index] = dtVal.Time
+			col.Nulls.unset(index)
 		}
 	default:
 		col.GenericData[index] = value
@@ -2271,6 +2292,51 @@ func (col *GridColumn) Len() int {
 	}
 }
 
+// nullMask marks the none rows of a typed column, one bit per row. Rows
+// past the end of the mask are not none, so a nil mask means the column has
+// no nulls and appending values needs no mask update.
+type nullMask []uint64
+
+func (m nullMask) has(row int) bool {
+	word := row >> 6
+	return word < len(m) && m[word]&(1<<(row&63)) != 0
+}
+
+func (m *nullMask) set(row int) {
+	word := row >> 6
+	for len(*m) <= word {
+		*m = append(*m, 0)
+	}
+	(*m)[word] |= 1 << (row & 63)
+}
+
+func (m *nullMask) unset(row int) {
+	if word := row >> 6; word < len(*m) {
+		(*m)[word] &^= 1 << (row & 63)
+	}
+}
+
+// appendNone appends a none row to a column. Typed columns keep their
+// storage: the row is marked in the null mask and holds a zero value, or NaN
+// in a float column.
+func appendNone(col *GridColumn) {
+	row := col.Len()
+	switch col.ColType {
+	case COL_INT:
+		col.IntData = append(col.IntData, 0)
+	case COL_FLOAT:
+		col.FloatData = append(col.FloatData, math.NaN())
+	case COL_STRING:
+		col.StringData = append(col.StringData, "")
+	case COL_DATETIME:
+		col.DateTimeData = append(col.DateTimeData, time.Time{})
+	default:
+		col.GenericData = append(col.GenericData, &Maybe{obj: nil})
+		return
+	}
+	col.Nulls.set(row)
+}
+
 // MShellGrid - Columnar storage for maximum performance
 type MShellGrid struct {
 	Meta     *MShellDict           // Optional grid-level metadata (nil if none)
@@ -180,8 +180,15 @@ func loadStartupFile(path string, description string, stack *MShellStack, contex
 		return fmt.Errorf("error parsing %s at %s: %w", description, path, err)
 	}
 
+	importedDefinitions, err := ResolveImports(parsedFile, path)
+	if err != nil {
+		return fmt.Errorf("error importing modules for %s at %s: %w", description, path, err)
+	}
+
 	*definitions = append(*definitions, parsedFile.Definitions...)
+	*definitions = append(*definitions, importedDefinitions...)
 	state.AddCompletionDefinitions(parsedFile.Definitions)
+	state.AddCompletionDefinitions(importedDefinitions)
 
 	if len(parsedFile.Items) > 0 {
 		callStackItem := CallStackItem{
@@ -481,6 +488,26 @@ func main() {
 		return
 	}
 
+	if len(os.Args) >= 2 && os.Args[1] == "add" {
+		os.Exit(runAddCommand(os.Args[2:]))
+		return
+	}
+
+	if len(os.Args) >= 2 && os.Args[1] == "lookup" {
+		os.Exit(runLookupCommand(os.Args[2:]))
+		return
+	}
+
+	if len(os.Args) >= 2 && os.Args[1] == "test" {
+		os.Exit(runTestCommand(os.Args[2:]))
+		return
+	}
+
+	if len(os.Args) >= 2 && os.Args[1] == "fmt" {
+		os.Exit(runFmtCommand(os.Args[2:]))
+		return
+	}
+
 	if len(os.Args) >= 2 && os.Args[1] == "completions" {
 		os.Exit(runCompletionsCommand(os.Args[2:]))
 		return
@@ -542,6 +569,10 @@ func main() {
 			fmt.Println("Usage: mshell [OPTION].. [ARG].. < FILE")
 			fmt.Println("Usage: mshell [OPTION].. -c INPUT [ARG]..")
 			fmt.Println("Usage: msh bin <command>")
+			fmt.Println("Usage: msh add <source> <file>")
+			fmt.Println("Usage: msh lookup <source>")
+			fmt.Println("Usage: msh test [--tap | --junit] [path]..")
+			fmt.Println("Usage: msh fmt [--check] [path]..")
 			fmt.Println("Usage: msh edit <target>")
 			fmt.Println("Usage: msh completions <shell>")
 			fmt.Println("Usage: msh lsp")
@@ -558,6 +589,10 @@ func main() {
 			fmt.Println("  -c INPUT     Execute INPUT as the program, before positional args")
 			fmt.Println("  -h, --help   Print this help message")
 			fmt.Println("  bin          Manage msh_bins.txt entries")
+			fmt.Println("  add          Pin an import of a module in a file, adding or updating its hash")
+			fmt.Println("  lookup       Print the pinned import line for a module")
+			fmt.Println("  test         Run the test_* definitions in *_test.msh files")
+			fmt.Println("  fmt          Format mshell source files")
 			fmt.Println("  edit         Edit common msh files")
 			fmt.Println("  completions  Print shell completion script")
 			fmt.Println("  fm           Open the built-in file manager")
@@ -699,6 +734,7 @@ func main() {
 				LoopDepth:      0,
 				StopOnError:    false,
 				CallStack:      callStack,
+				Jobs:           NewJobTable(),
 			},
 			initCallStackItem: CallStackItem{
 				MShellParseItem: nil,
@@ -831,12 +867,22 @@ func main() {
 		os.Exit(1)
 		return
 	}
+	importedDefinitions, err := ResolveImports(file, inputFilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error importing modules: %s\n", err)
+		os.Exit(1)
+		return
+	}
+
 	allDefinitions = append(allDefinitions, startupDefinitions...)
 	allDefinitions = append(allDefinitions, file.Definitions...)
+	allDefinitions = append(allDefinitions, importedDefinitions...)
 	state.AddCompletionDefinitions(file.Definitions)
+	state.AddCompletionDefinitions(importedDefinitions)
 
 	if checkTypes {
-		errs, ok := TypeCheckProgram(file, startupDefinitions)
+		signatureDefinitions := append(append([]MShellDefinition{}, startupDefinitions...), importedDefinitions...)
+		errs, ok := TypeCheckProgram(file, signatureDefinitions)
 		if !ok {
 			for _, e := range errs {
 				fmt.Fprintln(os.Stderr, e)
@@ -2003,7 +2049,6 @@ func (state *TermState) ClearScreen() {
 	// state.promptRow = state.numPromptLines
 }
 
-var aliases map[string]string
 var history []string
 
 var historyToSave []HistoryItem
@@ -2583,24 +2628,6 @@ func (state *TermState) InteractiveMode() error {
 
 	state.stdInState = stdInState
 
-	// TODO: Read from file? Something like a snippet engine?
-	aliases = map[string]string{
-		"s":  "git status -u",
-		"v":  "nvim",
-		"mk": "mkdir",
-		"ls": "eza -l",
-		"gi": "nvim .gitignore",
-		"a":  "git add",
-		"d":  "git diff -w",
-		"dc": "git diff -w --cached",
-		"c":  "git commit",
-		"p":  "git push",
-		"u":  "'..' cd",
-		"gu": "[git add -u]? ([git status -u];) iff",
-		"ga": "[git add -A]? ([git status -u];) iff",
-		"fp": "git fetch --prune",
-	}
-
 	// Put terminal into raw mode
 	oldState, err := term.MakeRaw(state.stdInFd)
 	if err != nil {
@@ -2614,11 +2641,25 @@ func (state *TermState) InteractiveMode() error {
 	state.l = NewLexer("", nil)
 	state.p = &MShellParser{lexer: state.l}
 
+	// Abbreviations saved at an earlier prompt load first, so init.msh can
+	// override them. Only changes made at the prompt are saved.
+	abbreviations := state.evalState.abbreviationTable()
+	abbrPath, abbrPathErr := abbreviationPath()
+	if abbrPathErr == nil {
+		if err := abbreviations.LoadPersisted(abbrPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading abbreviations: %s\n", err)
+		}
+	}
+
 	stdLibDefs, err := stdLibDefinitions(&state.stack, state.context, &state.evalState)
 	if err != nil {
 		return fmt.Errorf("Error loading standard library: %s\n", err)
 	}
 
+	if abbrPathErr == nil {
+		abbreviations.Persist(abbrPath)
+	}
+
 	state.stdLibDefs = stdLibDefs
 
 	history = make([]string, 0)
@@ -2853,21 +2894,9 @@ func (state *TermState) ExecuteCurrentCommand() (bool, int) {
 	currentCommandStr := strings.TrimSpace(string(state.currentCommand))
 
 	if state.index == len(state.currentCommand) {
-		// Walk back to last whitespace, check if final element is an alias.
-		i := state.index
-		for {
-			if i == 0 || state.currentCommand[i-1] == ' ' || state.currentCommand[i-1] == '[' {
-				break
-			}
-			i--
-		}
-
-		lastWord := string(state.currentCommand[i:state.index])
-		alias, aliasSet := aliases[lastWord]
-		if aliasSet {
-			currentCommandStr = currentCommandStr[:i] + alias
-			state.currentCommand = []rune(currentCommandStr)
-			state.index = len(state.currentCommand)
+		// Expand an abbreviation that is the final word.
+		if state.expandAbbreviation(false) {
+			currentCommandStr = strings.TrimSpace(string(state.currentCommand))
 		}
 
 		// // Update the UI.
@@ -2878,7 +2907,7 @@ func (state *TermState) ExecuteCurrentCommand() (bool, int) {
 		// fmt.Fprintf(os.Stdout, "\033[%dG", state.promptLength+1+state.index+1)
 	}
 
-	// This render should handle stored tokens on aliases, cleared out history completion/tab completion.
+	// This render should handle stored tokens on abbreviations, cleared out history completion/tab completion.
 	state.Render(false)
 
 	if len(currentCommandStr) > 0 {
@@ -2908,6 +2937,9 @@ func (state *TermState) ExecuteCurrentCommand() (bool, int) {
 	p := state.p
 	l := state.l
 
+	// 'fg %2' is shell syntax for '2 fg'.
+	currentCommandStr = rewriteJobCommand(currentCommandStr)
+
 	state.Logf("Executing Command: '%s'\n", currentCommandStr)
 	state.l.resetInput(currentCommandStr)
 
@@ -2916,7 +2948,7 @@ func (state *TermState) ExecuteCurrentCommand() (bool, int) {
 	var parsed *MShellFile
 	var err error
 
-	if p.curr.Type == LITERAL {
+	if p.curr.Type == LITERAL && !isJobBuiltin(p.curr.Lexeme) {
 		// Check for known commands. If so, we'll essentially wrap the entire command in a list to execute
 		literalStr := p.curr.Lexeme
 
@@ -2983,6 +3015,7 @@ func (state *TermState) ExecuteCurrentCommand() (bool, int) {
 
 	if len(parsed.Items) > 0 {
 		state.initCallStackItem.MShellParseItem = parsed.Items[0]
+		prevTopGrid := topGridObject(state.stack)
 		result := state.evalState.Evaluate(parsed.Items, &state.stack, state.context, state.stdLibDefs, state.initCallStackItem)
 
 		if result.ExitCalled {
@@ -2991,10 +3024,15 @@ func (state *TermState) ExecuteCurrentCommand() (bool, int) {
 
 		if !result.Success {
 			fmt.Fprintf(os.Stderr, "Error evaluating input.\n")
+		} else if topGrid := topGridObject(state.stack); topGrid != nil && topGrid != prevTopGrid {
+			// Preview a grid that just landed on the stack.
+			renderGridTable(os.Stdout, topGrid, terminalTableOptions(os.Stdout))
 		}
 	}
 
 PromptPrint:
+	state.evalState.Jobs.Report(os.Stderr)
+
 	// State.index reset must be before ensurePromptNewline and printPrompt as those can consume typed characters while waiting for terminal response
 	state.index = 0
 	state.ensurePromptNewline()
@@ -3424,56 +3462,8 @@ func (state *TermState) HandleToken(token TerminalToken) (bool, error) {
 			state.PushChars([]rune{' '})
 		} else if t.Char == 32 {
 			// Space
-			// Check for aliases. Split current command by whitespace, and check if last word is in aliases.
-			// If it is, replace last word with alias value.
-
-			i := state.index - 1
-			if len(state.currentCommand) > 0 {
-				for {
-					if i < 0 || state.currentCommand[i] == ' ' || state.currentCommand[i] == '[' {
-						break
-					}
-					i--
-				}
-			}
-
-			lastWord := string(state.currentCommand[i+1 : state.index])
-
-			aliasValue, aliasSet := aliases[lastWord]
-			if aliasSet {
-				// Erase starting at beginning of last word
-				// fmt.Fprintf(os.Stdout, "\033[%dG", state.promptLength+1+i+1)
-				// fmt.Fprintf(os.Stdout, "\033[K")
-
-				// Print alias value
-				// fmt.Fprint(os.Stdout, aliasValue)
-
-				// Print the space
-				// fmt.Fprintf(os.Stdout, " ")
-
-				// Print the rest of the command
-				// fmt.Fprintf(os.Stdout, "%s", string(state.currentCommand[state.index:]))
-
-				// Update current command
-				startText := state.currentCommand[:i+1]
-				endText := state.currentCommand[state.index:]
-
-				state.currentCommand = state.currentCommand[:0]
-
-				state.currentCommand = append(state.currentCommand, startText...)
-				state.currentCommand = append(state.currentCommand, []rune(aliasValue)...)
-				state.currentCommand = append(state.currentCommand, ' ')
-				state.currentCommand = append(state.currentCommand, endText...)
-
-				// state.currentCommand = append(state.currentCommand, ' ')
-				// state.currentCommand = append(state.currentCommand, state.currentCommand[state.index:]...)
-
-				state.Logf("Alias: %s -> %s\n", lastWord, aliasValue)
-				state.Logf("Current command: %s\n", string(state.currentCommand))
-
-				// Move cursor to end of the alias
-				state.index = i + 1 + len(aliasValue) + 1
-				// fmt.Fprintf(os.Stdout, "\033[%dG", state.promptLength+1+state.index)
+			// Expand an abbreviation in command position, e.g. 'gco' -> 'git checkout'.
+			if state.expandAbbreviation(true) {
 				state.resetHistorySearch()
 			} else {
 				state.PushChars([]rune{rune(t.Char)})
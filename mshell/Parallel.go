@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ParallelResult is the outcome of one child of a 'parallel' group.
+type ParallelResult struct {
+	Result   EvalResult
+	ExitCode int
+	Stdout   []byte
+	Stderr   []byte
+	Started  bool
+}
+
+// RunParallel runs the command lists of a 'parallel' group concurrently, with
+// at most group.ParallelJobs running at once. Results are returned in input
+// order. Each child runs in its own process group, without the terminal
+// foreground and without the shell's stdin, unless the child (or the group)
+// sets its own '<'.
+//
+// When failFast is set, the first child that fails stops any queued children
+// from launching and kills the ones still running. Otherwise every child runs
+// to completion.
+func (state *EvalState) RunParallel(group MShellList, context ExecuteContext, failFast bool) ([]ParallelResult, int, EvalResult) {
+	children := make([]MShellList, len(group.Items))
+	for i, item := range group.Items {
+		asList, ok := item.(*MShellList)
+		if !ok {
+			return nil, 1, state.FailWithMessage(fmt.Sprintf("Item %d (%s) in a parallel group is not a command list.\n", i, item.DebugString()))
+		}
+		if asList.ParallelJobs > 0 {
+			return nil, 1, state.FailWithMessage(fmt.Sprintf("Item %d in a parallel group is itself a parallel group, which is not supported.\n", i))
+		}
+		if name := parallelCommandName(asList); name == "cd" || name == "cdh" || name == "cdp" {
+			return nil, 1, state.FailWithMessage(fmt.Sprintf("Item %d in a parallel group runs '%s', which changes the shell's working directory and cannot run in parallel.\n", i, name))
+		}
+		children[i] = *asList
+	}
+
+	if group.InPlaceFile != "" {
+		return nil, 1, state.FailWithMessage("Cannot use an in-place redirect ('<>') on a parallel group.\n")
+	}
+
+	// Group-level file redirects are opened once and shared, so every child
+	// appends to the same file instead of truncating each other's output.
+	var groupStdout, groupStderr *os.File
+	if group.StandardOutputFile != "" {
+		file, err := openRedirectFile(group.StandardOutputFile, group.AppendOutput)
+		if err != nil {
+			return nil, 1, state.FailWithMessage(fmt.Sprintf("Error opening file %s for writing: %s\n", group.StandardOutputFile, err.Error()))
+		}
+		defer file.Close()
+		groupStdout = file
+	}
+	if group.StandardErrorFile != "" {
+		if group.StandardErrorFile == group.StandardOutputFile {
+			if group.AppendOutput != group.AppendError {
+				return nil, 1, state.FailWithMessage(fmt.Sprintf("Cannot redirect stdout and stderr to the same file '%s' with different append modes.\n", group.StandardOutputFile))
+			}
+			groupStderr = groupStdout
+		} else {
+			file, err := openRedirectFile(group.StandardErrorFile, group.AppendError)
+			if err != nil {
+				return nil, 1, state.FailWithMessage(fmt.Sprintf("Error opening file %s for writing: %s\n", group.StandardErrorFile, err.Error()))
+			}
+			defer file.Close()
+			groupStderr = file
+		}
+	}
+
+	contexts := make([]ExecuteContext, len(children))
+	for i := range children {
+		child := &children[i]
+		childContext := ExecuteContext{
+			StandardInput:  bytes.NewReader(nil),
+			StandardOutput: context.StandardOutput,
+			StandardError:  context.StandardError,
+			Variables:      context.Variables,
+			Pbm:            context.Pbm,
+			// Run as a single stage pipeline: the child gets its own process
+			// group, is registered for killing, and does not take the terminal.
+			InPipeline:    true,
+			PipelineGroup: NewPipelineGroup(1),
+			LaunchOnce:    &sync.Once{},
+		}
+
+		if child.StdinBehavior == STDIN_NONE && group.StdinBehavior != STDIN_NONE {
+			child.StdinBehavior = group.StdinBehavior
+			child.StandardInputContents = group.StandardInputContents
+			child.StandardInputBinary = group.StandardInputBinary
+			child.StandardInputFile = group.StandardInputFile
+		}
+
+		if child.StdoutDestinationDesc() == "" {
+			if group.StdoutBehavior != STDOUT_NONE {
+				child.StdoutBehavior = group.StdoutBehavior
+			} else if group.StdoutToStderr {
+				child.StdoutToStderr = true
+			} else if groupStdout != nil {
+				childContext.StandardOutput = groupStdout
+			}
+		}
+
+		if child.StderrDestinationDesc() == "" {
+			if group.StderrBehavior != STDERR_NONE {
+				child.StderrBehavior = group.StderrBehavior
+			} else if group.StderrToStdout {
+				child.StderrToStdout = true
+			} else if groupStderr != nil {
+				childContext.StandardError = groupStderr
+			}
+		}
+
+		contexts[i] = childContext
+	}
+
+	results := make([]ParallelResult, len(children))
+	// Workers never touch the caller's state. Each child runs with its own
+	// EvalState whose TryDepth makes a setup failure record into errs[i]
+	// instead of printing; the first one is reported after every worker is
+	// done.
+	errs := make([]*TryError, len(children))
+	callStack := make(CallStack, len(state.CallStack))
+	copy(callStack, state.CallStack)
+	slots := make(chan struct{}, group.ParallelJobs)
+
+	var mu sync.Mutex
+	failedIndex := -1
+
+	// killRest stops every other child that is still running, along with
+	// anything those children started. Children that already exited are
+	// unaffected.
+	killRest := func(except int) {
+		for i := range contexts {
+			if i != except {
+				contexts[i].PipelineGroup.killProcessGroup()
+				contexts[i].PipelineGroup.killProcesses()
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := range children {
+		slots <- struct{}{}
+
+		mu.Lock()
+		stop := failFast && failedIndex >= 0
+		mu.Unlock()
+		if stop {
+			<-slots
+			break
+		}
+
+		results[i].Started = true
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			childState := &EvalState{
+				StopOnError: state.StopOnError,
+				CallStack:   callStack,
+				TryDepth:    1,
+				Jobs:        state.Jobs,
+			}
+			result, exitCode, stdout, stderr := RunProcess(children[i], contexts[i], childState)
+			errs[i] = childState.TryError
+			results[i].Result = result
+			results[i].ExitCode = exitCode
+			results[i].Stdout = stdout
+			results[i].Stderr = stderr
+
+			if err := contexts[i].PipelineGroup.closeTerminal(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: closing retained terminal: %s\n", err)
+			}
+
+			if !failFast || (result.Success && exitCode == 0) {
+				return
+			}
+
+			mu.Lock()
+			first := failedIndex < 0
+			if first {
+				failedIndex = i
+			}
+			mu.Unlock()
+			if first {
+				killRest(i)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// A child that could not be set up (e.g. a redirect file that could not
+	// be opened) fails the whole group, reported in input order.
+	for i, r := range results {
+		if r.Started && !r.Result.Success {
+			if errs[i] == nil {
+				return results, r.ExitCode, r.Result
+			}
+			return results, r.ExitCode, state.FailWithMessage(errs[i].Message + "\n")
+		}
+	}
+
+	if failedIndex >= 0 {
+		return results, results[failedIndex].ExitCode, SimpleSuccess()
+	}
+
+	for _, r := range results {
+		if r.ExitCode != 0 {
+			return results, r.ExitCode, SimpleSuccess()
+		}
+	}
+	return results, 0, SimpleSuccess()
+}
+
+// parallelCommandName returns the command a parallel child runs, the first
+// argument after flattening nested lists, or "" if there is none.
+func parallelCommandName(list *MShellList) string {
+	for _, item := range list.Items {
+		if inner, ok := item.(*MShellList); ok {
+			if name := parallelCommandName(inner); name != "" {
+				return name
+			}
+		} else if item.IsCommandLineable() {
+			return item.CommandLine()
+		} else {
+			return ""
+		}
+	}
+	return ""
+}
+
+func openRedirectFile(path string, appendOutput bool) (*os.File, error) {
+	if appendOutput {
+		return os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	}
+	return os.Create(path)
+}
+
+// executeParallel runs a 'parallel' group for ';', '!', or '?' and pushes the
+// results. If any child captures stdout, a list with one entry per child is
+// pushed; children that did not capture contribute an empty string. Captured
+// stderr follows the same rule. '?' then pushes the list of exit codes.
+func (state *EvalState) executeParallel(t Token, group *MShellList, stack *MShellStack, context ExecuteContext) EvalResult {
+	failFast := state.StopOnError || t.Type == BANG
+	results, exitCode, result := state.RunParallel(*group, context, failFast)
+	if !result.Success {
+		return result
+	}
+
+	if failFast && exitCode != 0 {
+		if state.TryDepth > 0 {
+			state.recordTryError(fmt.Sprintf("%d:%d: Parallel command exited with code %d.\n", t.Line, t.Column, exitCode), exitCode)
+		}
+		return EvalResult{false, false, -1, exitCode, false}
+	}
+
+	stdoutModes := make([]StdoutBehavior, len(group.Items))
+	stderrModes := make([]StderrBehavior, len(group.Items))
+	anyStdout, anyStderr := false, false
+	for i, item := range group.Items {
+		child := item.(*MShellList)
+		stdoutModes[i] = child.StdoutBehavior
+		if stdoutModes[i] == STDOUT_NONE && child.StdoutDestinationDesc() == "" {
+			stdoutModes[i] = group.StdoutBehavior
+		}
+		stderrModes[i] = child.StderrBehavior
+		if stderrModes[i] == STDERR_NONE && child.StderrDestinationDesc() == "" {
+			stderrModes[i] = group.StderrBehavior
+		}
+		anyStdout = anyStdout || stdoutModes[i] != STDOUT_NONE
+		anyStderr = anyStderr || stderrModes[i] != STDERR_NONE
+	}
+
+	if anyStdout {
+		outputs := NewList(len(results))
+		for i, r := range results {
+			outputs.Items[i] = capturedStreamObject(stdoutModes[i], r.Stdout)
+		}
+		stack.Push(outputs)
+	}
+
+	if anyStderr {
+		outputs := NewList(len(results))
+		for i, r := range results {
+			outputs.Items[i] = capturedStreamObject(StdoutBehavior(stderrModes[i]), r.Stderr)
+		}
+		stack.Push(outputs)
+	}
+
+	if t.Type == QUESTION {
+		exitCodes := NewList(len(results))
+		for i, r := range results {
+			exitCodes.Items[i] = MShellInt{r.ExitCode}
+		}
+		stack.Push(exitCodes)
+	}
+
+	return SimpleSuccess()
+}
+
+// capturedStreamObject converts captured bytes to the value a capture mode
+// pushes. StderrBehavior values convert directly, as the two enums share
+// their numbering.
+func capturedStreamObject(mode StdoutBehavior, output []byte) MShellObject {
+	switch mode {
+	case STDOUT_LINES:
+		lines := NewList(0)
+		scanner := bufio.NewScanner(bytes.NewReader(output))
+		for scanner.Scan() {
+			lines.Items = append(lines.Items, MShellString{scanner.Text()})
+		}
+		return lines
+	case STDOUT_STRIPPED:
+		return MShellString{strings.TrimSpace(string(output))}
+	case STDOUT_BINARY:
+		return MShellBinary(output)
+	default:
+		return MShellString{string(output)}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunParallelFailingChildInTry runs children whose redirects cannot be
+// opened inside a 'try' body. Run with -race: the workers must not record
+// the failure on the shared state themselves.
+func TestRunParallelFailingChildInTry(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing", "out.txt")
+	group := NewList(8)
+	for i := range group.Items {
+		child := NewList(2)
+		child.Items[0] = MShellString{Content: "echo"}
+		child.Items[1] = MShellString{Content: "x"}
+		child.StdoutBehavior = STDOUT_NONE
+		child.StandardOutputFile = missing
+		group.Items[i] = child
+	}
+	group.ParallelJobs = 4
+
+	state := &EvalState{TryDepth: 1}
+	context := ExecuteContext{
+		StandardOutput: os.Stdout,
+		StandardError:  os.Stderr,
+		Pbm:            NewPathBinManager(),
+	}
+	_, exitCode, result := state.RunParallel(*group, context, false)
+	if result.Success || exitCode != 1 {
+		t.Fatalf("RunParallel = success %v, exit code %d, want a failure with exit code 1", result.Success, exitCode)
+	}
+	if state.TryError == nil || !strings.Contains(state.TryError.Message, "Error opening file "+missing) {
+		t.Errorf("TryError = %+v, want the redirect error", state.TryError)
+	}
+}
+
+func TestRunParallelRejectsDirectoryChanges(t *testing.T) {
+	for _, name := range []string{"cd", "cdh", "cdp"} {
+		inner := NewList(1)
+		inner.Items[0] = MShellString{Content: name}
+		child := NewList(1)
+		child.Items[0] = inner
+		echo := NewList(1)
+		echo.Items[0] = MShellString{Content: "true"}
+		group := NewList(2)
+		group.Items[0] = echo
+		group.Items[1] = child
+		group.ParallelJobs = 2
+
+		state := &EvalState{TryDepth: 1}
+		_, _, result := state.RunParallel(*group, ExecuteContext{Pbm: NewPathBinManager()}, false)
+		if result.Success || state.TryError == nil || !strings.Contains(state.TryError.Message, "Item 1 in a parallel group runs '"+name+"'") {
+			t.Errorf("%s child: TryError = %+v, want a rejection", name, state.TryError)
+		}
+	}
+}
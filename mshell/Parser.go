@@ -26,6 +26,19 @@ type MShellFile struct {
 	Version     string // Set by VER "x.y.z" directive; empty if not specified.
 	VersionLine int    // Line of the VER token when Version is set
 	VersionCol  int    // Column of the VER token when Version is set
+	Imports     []MShellImport
+}
+
+// MShellImport is a top-level `import "source" sha256:<hash> [as name]`
+// directive. Source is the unquoted string; Hash is the hex digest without
+// the "sha256:" prefix.
+type MShellImport struct {
+	Source      string
+	Hash        string
+	Alias       string
+	ImportToken Token
+	SourceToken Token
+	HashToken   Token
 }
 
 type MShellParseList struct {
@@ -481,6 +494,8 @@ func (m *MShellParseMatchBlock) DebugString() string {
 type MShellDefinition struct {
 	Name      string
 	NameToken Token
+	DefToken  Token // The 'def' keyword
+	EndToken  Token // The closing 'end'
 	Items     []MShellParseItem
 	Inputs    []MShellParseItem // type-expression AST for signature inputs
 	Outputs   []MShellParseItem // type-expression AST for signature outputs
@@ -539,6 +554,12 @@ type MShellParser struct {
 	lexer *Lexer
 	curr  Token
 	initialized bool
+	// rename maps literal names to new names as they are scanned. Used to
+	// namespace the definitions of a module imported with 'as'.
+	rename map[string]string
+	// prevEndLine is the line the previous token ended on, so 'import' can
+	// tell whether it starts a line.
+	prevEndLine int
 }
 
 type parserPanic struct {
@@ -552,6 +573,7 @@ func NewMShellParser(lexer *Lexer) *MShellParser {
 func (parser *MShellParser) ResetInput(input string) {
 	parser.lexer.resetInput(input)
 	parser.initialized = false
+	parser.prevEndLine = 0
 }
 
 func (parser *MShellParser) recoverPanic(err *error) {
@@ -574,7 +596,7 @@ func (parser *MShellParser) scanToken() Token {
 	if token.Type == ERROR {
 		panic(parserPanic{err: errors.New(token.Lexeme)})
 	}
-	return token
+	return parser.renameToken(token)
 }
 
 func (parser *MShellParser) scanTokenAll() Token {
@@ -582,6 +604,23 @@ func (parser *MShellParser) scanTokenAll() Token {
 	if token.Type == ERROR {
 		panic(parserPanic{err: errors.New(token.Lexeme)})
 	}
+	return parser.renameToken(token)
+}
+
+func (parser *MShellParser) renameToken(token Token) Token {
+	if parser.rename == nil {
+		return token
+	}
+	switch token.Type {
+	case LITERAL:
+		if newName, ok := parser.rename[token.Lexeme]; ok {
+			token.Lexeme = newName
+		}
+	case PREFIXQUOTE:
+		if newName, ok := parser.rename[strings.TrimSuffix(token.Lexeme, ".")]; ok {
+			token.Lexeme = newName + "."
+		}
+	}
 	return token
 }
 
@@ -593,6 +632,9 @@ func (parser *MShellParser) ensureInitialized() {
 }
 
 func (parser *MShellParser) NextToken() {
+	if parser.initialized {
+		parser.prevEndLine = parser.curr.Line + strings.Count(parser.curr.Lexeme, "\n")
+	}
 	parser.curr = parser.scanToken()
 	parser.initialized = true
 }
@@ -671,13 +713,14 @@ func (parser *MShellParser) ParseFile() (file *MShellFile, err error) {
 			}
 			file.Items = append(file.Items, pq)
 	case DEF:
+		defToken := parser.curr
 		_ = parser.Match(parser.curr, DEF)
 		if parser.curr.Type != LITERAL {
 			return file, fmt.Errorf("%d: %d: Expected a name for the definition, got %s", parser.curr.Line, parser.curr.Column, parser.curr.Type)
 		}
 
 		nameToken := parser.curr
-		def := MShellDefinition{Name: parser.curr.Lexeme, NameToken: nameToken, Items: []MShellParseItem{}}
+		def := MShellDefinition{Name: parser.curr.Lexeme, NameToken: nameToken, DefToken: defToken, Items: []MShellParseItem{}}
 		_ = parser.Match(parser.curr, LITERAL)
 
 		if parser.curr.Type == LEFT_CURLY {
@@ -709,6 +752,7 @@ func (parser *MShellParser) ParseFile() (file *MShellFile, err error) {
 				}
 			}
 
+			def.EndToken = parser.curr
 			file.Definitions = append(file.Definitions, def)
 			_ = parser.Match(parser.curr, END)
 			// return file, errors.New("DEF Not implemented")
@@ -743,6 +787,21 @@ func (parser *MShellParser) ParseFile() (file *MShellFile, err error) {
 				return file, fmt.Errorf("%d:%d: Expected a string after VER, got %s", t.Line, t.Column, t.Type)
 			}
 			parser.NextToken()
+		case IMPORT:
+			if parser.prevEndLine == parser.curr.Line {
+				// Not at the start of a line, so an ordinary word.
+				item, err := parser.ParseItem()
+				if err != nil {
+					return file, err
+				}
+				file.Items = append(file.Items, item)
+				break
+			}
+			imp, err := parser.ParseImport()
+			if err != nil {
+				return file, err
+			}
+			file.Imports = append(file.Imports, imp)
 		// case
 		// parser.ParseItem()
 		default:
@@ -1102,11 +1161,60 @@ func (parser *MShellParser) ParseItem() (MShellParseItem, error) {
 		return parser.ParsePrefixQuote()
 	case AS:
 		return parser.ParseAsCast()
+	case IMPORT:
+		// 'import' is a directive only at the start of a top-level line.
+		// Anywhere else, like a command argument, it is a plain literal.
+		parser.curr.Type = LITERAL
+		parser.curr = parser.renameToken(parser.curr)
+		return parser.ParseSimple(), nil
 	default:
 		return parser.ParseSimple(), nil
 	}
 }
 
+// ParseImport parses `import "source" sha256:<hash> [as name]`. The hash is
+// required; 'msh lookup' prints the line for a source.
+func (parser *MShellParser) ParseImport() (MShellImport, error) {
+	imp := MShellImport{ImportToken: parser.curr}
+	parser.NextToken()
+
+	t := parser.curr
+	switch t.Type {
+	case STRING:
+		source, err := ParseRawString(t.Lexeme)
+		if err != nil {
+			return imp, fmt.Errorf("%d:%d: Invalid string in import: %s", t.Line, t.Column, err)
+		}
+		imp.Source = source
+	case SINGLEQUOTESTRING:
+		imp.Source = t.Lexeme[1 : len(t.Lexeme)-1]
+	default:
+		return imp, fmt.Errorf("%d:%d: Expected a string source after import, got %s", t.Line, t.Column, t.Type)
+	}
+	imp.SourceToken = t
+	parser.NextToken()
+
+	t = parser.curr
+	if t.Type != CONTENTHASH {
+		return imp, fmt.Errorf("%d:%d: Expected a sha256:<hash> pin after import %q, got %s. Run 'msh lookup %s' to get the pinned line", t.Line, t.Column, imp.Source, t.Type, imp.Source)
+	}
+	imp.Hash = strings.TrimPrefix(t.Lexeme, "sha256:")
+	imp.HashToken = t
+	parser.NextToken()
+
+	if parser.curr.Type == AS {
+		parser.NextToken()
+		t = parser.curr
+		if t.Type != LITERAL || strings.Contains(t.Lexeme, ".") {
+			return imp, fmt.Errorf("%d:%d: Expected a namespace name after 'as' in import, got %s", t.Line, t.Column, t.Type)
+		}
+		imp.Alias = t.Lexeme
+		parser.NextToken()
+	}
+
+	return imp, nil
+}
+
 func (parser *MShellParser) ParseStaticItem() (MShellParseItem, error) {
 	switch parser.curr.Type {
 	case LEFT_SQUARE_BRACKET:
@@ -275,6 +275,17 @@ func ContinueProcessGroup(pgid int) error {
 	return syscall.Kill(-pgid, syscall.SIGCONT)
 }
 
+// stoppedExitCode is the exit code reported for a job that was stopped
+// (Ctrl-Z) instead of exiting: -(128 + SIGTSTP), like a signal death.
+const stoppedExitCode = -(signalBase + int(syscall.SIGTSTP))
+
+// ProcessGroupStopped always reports false on macOS: without waitid(P_PGID)
+// a stop can only be observed with wait4(WUNTRACED), which would also reap
+// exits out from under cmd.Wait.  Stopped jobs are still resumed by 'fg'.
+func ProcessGroupStopped(pgid int) bool {
+	return false
+}
+
 // KillProcessGroup terminates every process in a failed foreground launch.
 func KillProcessGroup(pgid int) error {
 	return syscall.Kill(-pgid, syscall.SIGKILL)
@@ -266,6 +266,25 @@ func ContinueProcessGroup(pgid int) error {
 	return syscall.Kill(-pgid, syscall.SIGCONT)
 }
 
+// stoppedExitCode is the exit code reported for a job that was stopped
+// (Ctrl-Z) instead of exiting: -(128 + SIGTSTP), like a signal death.
+const stoppedExitCode = -(signalBase + int(syscall.SIGTSTP))
+
+// cldStopped is the siginfo code waitid reports for a stopped child.
+const cldStopped = 5
+
+// ProcessGroupStopped reports whether a child in the process group has
+// stopped since the last call.  waitid(WSTOPPED|WNOHANG) only consumes the
+// stop notification, never an exit, so it does not race with cmd.Wait.
+func ProcessGroupStopped(pgid int) bool {
+	if pgid <= 0 {
+		return false
+	}
+	var info unix.Siginfo
+	err := unix.Waitid(unix.P_PGID, pgid, &info, unix.WSTOPPED|unix.WNOHANG, nil)
+	return err == nil && info.Signo == int32(syscall.SIGCHLD) && info.Code == cldStopped
+}
+
 // KillProcessGroup terminates every process in a failed foreground launch.
 func KillProcessGroup(pgid int) error {
 	return syscall.Kill(-pgid, syscall.SIGKILL)
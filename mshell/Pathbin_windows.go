@@ -442,6 +442,15 @@ func ContinueProcessGroup(pgid int) error {
 	return nil
 }
 
+// stoppedExitCode is unused on Windows, which has no job stop signal.
+const stoppedExitCode = -(signalBase + 20)
+
+// ProcessGroupStopped always reports false: Windows consoles have no Ctrl-Z
+// job suspension.
+func ProcessGroupStopped(pgid int) bool {
+	return false
+}
+
 // KillProcessGroup cannot terminate a Windows process tree without a Job Object.
 // Callers still kill the immediate os.Process while the isolated backend is built.
 func KillProcessGroup(pgid int) error {
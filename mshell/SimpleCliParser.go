@@ -6,10 +6,11 @@ import (
 
 // SimpleCliParser parses simple CLI-style commands with pipes and redirects.
 // Grammar:
-//   CLI : item+ ('<' item)? ('|' item+)* ('>' item)?
+//   CLI : item+ ('<' item)? ('|' item+)* ('>' item)? '&'?
 //
 // Items are parsed using the normal MShellParser, which handles lists, dicts, etc.
-// Only PIPE, LESSTHAN, and GREATERTHAN are treated specially at the top level.
+// Only PIPE, LESSTHAN, GREATERTHAN, and a trailing AMPERSAND are treated specially
+// at the top level.
 
 // SimpleCliCommand represents a single command with its arguments (parsed items)
 type SimpleCliCommand struct {
@@ -21,6 +22,7 @@ type SimpleCliPipeline struct {
 	Commands       []SimpleCliCommand // At least one command
 	StdinRedirect  MShellParseItem    // Optional stdin redirect (can be path, string, etc.)
 	StdoutRedirect MShellParseItem    // Optional stdout redirect
+	Background     bool               // Trailing '&': run as a background job
 }
 
 // MShellSimpleCliParser parses simple CLI-style input using MShellParser for items
@@ -38,7 +40,7 @@ func NewMShellSimpleCliParser(lexer *Lexer) *MShellSimpleCliParser {
 // isSimpleCliDelimiter returns true if the token delimits commands/redirects in simple CLI mode
 func isSimpleCliDelimiter(t Token) bool {
 	switch t.Type {
-	case EOF, PIPE, LESSTHAN, GREATERTHAN:
+	case EOF, PIPE, LESSTHAN, GREATERTHAN, AMPERSAND:
 		return true
 	default:
 		return false
@@ -87,8 +89,8 @@ func (p *MShellSimpleCliParser) Parse() (pipeline *SimpleCliPipeline, err error)
 		}
 		result.StdinRedirect = item
 
-		// After stdin redirect, only valid tokens are: PIPE, GREATERTHAN, or EOF
-		if p.parser.curr.Type != PIPE && p.parser.curr.Type != GREATERTHAN && p.parser.curr.Type != EOF {
+		// After stdin redirect, only valid tokens are: PIPE, GREATERTHAN, AMPERSAND, or EOF
+		if p.parser.curr.Type != PIPE && p.parser.curr.Type != GREATERTHAN && p.parser.curr.Type != AMPERSAND && p.parser.curr.Type != EOF {
 			return nil, &SimpleCliParseError{
 				Message: "only a single item expected after '<'",
 				Token:   p.parser.curr,
@@ -119,8 +121,8 @@ func (p *MShellSimpleCliParser) Parse() (pipeline *SimpleCliPipeline, err error)
 		}
 		result.StdoutRedirect = item
 
-		// After stdout redirect, only valid token is EOF
-		if p.parser.curr.Type != EOF {
+		// After stdout redirect, only valid tokens are AMPERSAND or EOF
+		if p.parser.curr.Type != AMPERSAND && p.parser.curr.Type != EOF {
 			return nil, &SimpleCliParseError{
 				Message: "only a single item expected after '>'",
 				Token:   p.parser.curr,
@@ -128,6 +130,12 @@ func (p *MShellSimpleCliParser) Parse() (pipeline *SimpleCliPipeline, err error)
 		}
 	}
 
+	// Check for optional trailing '&'
+	if p.parser.curr.Type == AMPERSAND {
+		p.parser.NextToken() // consume '&'
+		result.Background = true
+	}
+
 	// Should be at EOF now
 	if p.parser.curr.Type != EOF {
 		return nil, &SimpleCliParseError{Message: "unexpected token after command", Token: p.parser.curr}
@@ -136,7 +144,7 @@ func (p *MShellSimpleCliParser) Parse() (pipeline *SimpleCliPipeline, err error)
 	return result, nil
 }
 
-// parseCommand parses items until we hit a delimiter (PIPE, LESSTHAN, GREATERTHAN, EOF)
+// parseCommand parses items until we hit a delimiter (PIPE, LESSTHAN, GREATERTHAN, AMPERSAND, EOF)
 func (p *MShellSimpleCliParser) parseCommand() (SimpleCliCommand, error) {
 	cmd := SimpleCliCommand{Items: make([]MShellParseItem, 0)}
 
@@ -252,8 +260,11 @@ func (pipeline *SimpleCliPipeline) ToMShellFile() (*MShellFile, error) {
 		items = []MShellParseItem{
 			outerList,
 			Token{Type: PIPE, Lexeme: "|"},
-			Token{Type: EXECUTE, Lexeme: ";"},
 		}
+		if pipeline.Background {
+			items = append(items, Token{Type: AMPERSAND, Lexeme: "&"})
+		}
+		items = append(items, Token{Type: EXECUTE, Lexeme: ";"})
 	} else {
 		// Single command: [cmd items] redirect < redirect > ;
 		cmdList := &MShellParseList{
@@ -275,6 +286,10 @@ func (pipeline *SimpleCliPipeline) ToMShellFile() (*MShellFile, error) {
 			items = append(items, Token{Type: GREATERTHAN, Lexeme: ">"})
 		}
 
+		if pipeline.Background {
+			items = append(items, Token{Type: AMPERSAND, Lexeme: "&"})
+		}
+
 		items = append(items, Token{Type: EXECUTE, Lexeme: ";"})
 	}
 
@@ -335,6 +335,51 @@ func TestSimpleCliParser_ToMShellFile_PipelineWithRedirect(t *testing.T) {
 	}
 }
 
+func TestSimpleCliParser_ToMShellFile_Background(t *testing.T) {
+	input := "sort big.txt | uniq -c > counts.txt &"
+	l := NewLexer(input, nil)
+	p := NewMShellSimpleCliParser(l)
+
+	pipeline, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !pipeline.Background {
+		t.Error("Expected trailing '&' to mark the pipeline as background")
+	}
+
+	file, fileErr := pipeline.ToMShellFile()
+	if fileErr != nil {
+		t.Fatalf("Unexpected error: %v", fileErr)
+	}
+
+	// Should have: [outer list] | & ;
+	if len(file.Items) != 4 {
+		t.Fatalf("Expected 4 items, got %d", len(file.Items))
+	}
+
+	ampToken, ok := file.Items[2].(Token)
+	if !ok || ampToken.Type != AMPERSAND {
+		t.Errorf("Expected AMPERSAND token, got %T %v", file.Items[2], file.Items[2])
+	}
+
+	execToken, ok := file.Items[3].(Token)
+	if !ok || execToken.Type != EXECUTE {
+		t.Errorf("Expected EXECUTE token, got %T %v", file.Items[3], file.Items[3])
+	}
+}
+
+func TestSimpleCliParser_ErrorItemsAfterBackground(t *testing.T) {
+	input := "sleep 10 & ls"
+	l := NewLexer(input, nil)
+	p := NewMShellSimpleCliParser(l)
+
+	_, err := p.Parse()
+	if err == nil {
+		t.Error("Expected error for items after '&', got nil")
+	}
+}
+
 func TestSimpleCliParser_ToMShellFile_ListArgument(t *testing.T) {
 	input := "numargs 1 2 [4 5 6]"
 	l := NewLexer(input, nil)
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TestCaseResult is the outcome of one 'test_*' definition run by 'msh test'.
+type TestCaseResult struct {
+	File     string
+	Name     string
+	Passed   bool
+	Message  string
+	Duration time.Duration
+}
+
+type testOutputFormat int
+
+const (
+	testOutputSummary testOutputFormat = iota
+	testOutputTap
+	testOutputJunit
+)
+
+// runTestCommand implements 'msh test [--tap | --junit] [path]..'. Each path
+// is a test file, or a directory searched recursively for '*_test.msh' files.
+// Every 'def test_*' in those files runs in a fresh EvalState. The exit code
+// is 0 when every test passes and 1 otherwise.
+func runTestCommand(args []string) int {
+	format := testOutputSummary
+	paths := make([]string, 0)
+	for _, arg := range args {
+		switch arg {
+		case "--tap":
+			format = testOutputTap
+		case "--junit":
+			format = testOutputJunit
+		case "-h", "--help":
+			fmt.Println("Usage: msh test [--tap | --junit] [PATH]..")
+			fmt.Println("")
+			fmt.Println("Runs every 'def test_*' definition in the given files, or in the")
+			fmt.Println("'*_test.msh' files found under the given directories (default: .).")
+			fmt.Println("")
+			fmt.Println("Options:")
+			fmt.Println("  --tap    Print results in TAP version 13 format")
+			fmt.Println("  --junit  Print results as JUnit XML")
+			return 0
+		default:
+			if strings.HasPrefix(arg, "-") {
+				fmt.Fprintf(os.Stderr, "Unknown option for msh test: %s\n", arg)
+				return 1
+			}
+			paths = append(paths, arg)
+		}
+	}
+	if len(paths) == 0 {
+		paths = append(paths, ".")
+	}
+
+	files, err := discoverTestFiles(paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	var stack MShellStack
+	loadState := EvalState{PositionalArgs: []string{}, CallStack: make(CallStack, 0, 10)}
+	loadContext := ExecuteContext{Variables: map[string]MShellObject{}, Pbm: NewPathBinManager()}
+	startupDefinitions, err := loadStartupDefinitions(startupLoadOptions{
+		version:           mshellVersion,
+		allowEnvOverrides: true,
+	}, &stack, loadContext, &loadState)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, formatStartupErrorMessage(err, "", "", 0, 0))
+		return 1
+	}
+
+	results := make([]TestCaseResult, 0)
+	for _, path := range files {
+		results = append(results, runTestFile(path, startupDefinitions, loadContext.Pbm)...)
+	}
+
+	switch format {
+	case testOutputTap:
+		writeTapResults(os.Stdout, results)
+	case testOutputJunit:
+		if err := writeJunitResults(os.Stdout, results); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing JUnit XML: %s\n", err)
+			return 1
+		}
+	default:
+		writeSummaryResults(os.Stdout, results)
+	}
+
+	for _, result := range results {
+		if !result.Passed {
+			return 1
+		}
+	}
+	return 0
+}
+
+// discoverTestFiles expands the 'msh test' path arguments into a sorted list
+// of test files. Files named directly are used as-is.
+func discoverTestFiles(paths []string) ([]string, error) {
+	files := make([]string, 0)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		found := make([]string, 0)
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(d.Name(), "_test.msh") {
+				found = append(found, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// runTestFile runs the 'test_*' definitions of one file, in source order.
+// Top-level code in a test file is not run. A file that cannot be parsed or
+// whose imports cannot be resolved is reported as a single failed test.
+func runTestFile(path string, startupDefinitions []MShellDefinition, pbm IPathBinManager) []TestCaseResult {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return []TestCaseResult{{File: path, Name: "(load)", Message: err.Error()}}
+	}
+	file, err := parseMShellInput(string(source), &TokenFile{path})
+	if err != nil {
+		return []TestCaseResult{{File: path, Name: "(parse)", Message: err.Error()}}
+	}
+	imported, err := ResolveImports(file, path)
+	if err != nil {
+		return []TestCaseResult{{File: path, Name: "(import)", Message: err.Error()}}
+	}
+
+	definitions := make([]MShellDefinition, 0, len(startupDefinitions)+len(file.Definitions)+len(imported))
+	definitions = append(definitions, startupDefinitions...)
+	definitions = append(definitions, file.Definitions...)
+	definitions = append(definitions, imported...)
+
+	results := make([]TestCaseResult, 0)
+	for _, def := range file.Definitions {
+		if !strings.HasPrefix(def.Name, "test_") {
+			continue
+		}
+		started := time.Now()
+		passed, message := runTestDefinition(def, definitions, pbm)
+		results = append(results, TestCaseResult{
+			File:     path,
+			Name:     def.Name,
+			Passed:   passed,
+			Message:  message,
+			Duration: time.Since(started),
+		})
+	}
+	return results
+}
+
+// runTestDefinition evaluates one test on an empty stack in a fresh
+// EvalState. Failures are collected the way 'try' collects them, so the
+// runner reports the message instead of printing a call stack.
+func runTestDefinition(def MShellDefinition, definitions []MShellDefinition, pbm IPathBinManager) (bool, string) {
+	state := EvalState{
+		PositionalArgs: []string{},
+		CallStack:      make(CallStack, 0, 10),
+		TryDepth:       1,
+	}
+	var stack MShellStack
+	context := ExecuteContext{Variables: map[string]MShellObject{}, Pbm: pbm}
+	callStackItem := CallStackItem{MShellParseItem: def.NameToken, Name: def.Name, CallStackType: CALLSTACKDEF}
+
+	result := state.Evaluate(def.Items, &stack, context, definitions, callStackItem)
+	if result.ExitCalled {
+		if result.ExitCode == 0 {
+			return true, ""
+		}
+		return false, fmt.Sprintf("Test called exit with code %d.", result.ExitCode)
+	}
+	if result.Success {
+		return true, ""
+	}
+	if state.TryError != nil {
+		message := state.TryError.Message
+		if state.TryError.Line > 0 {
+			message = fmt.Sprintf("%d:%d: %s", state.TryError.Line, state.TryError.Column, message)
+		}
+		return false, message
+	}
+	return false, fmt.Sprintf("Failed with exit code %d.", result.ExitCode)
+}
+
+func testDisplayName(result TestCaseResult) string {
+	return result.File + " " + result.Name
+}
+
+func writeSummaryResults(w io.Writer, results []TestCaseResult) {
+	failed := 0
+	for _, result := range results {
+		if result.Passed {
+			fmt.Fprintf(w, "ok   %s\n", testDisplayName(result))
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "FAIL %s\n", testDisplayName(result))
+		for _, line := range strings.Split(result.Message, "\n") {
+			fmt.Fprintf(w, "     %s\n", line)
+		}
+	}
+	fmt.Fprintf(w, "\n%d tests, %d passed, %d failed\n", len(results), len(results)-failed, failed)
+}
+
+func writeTapResults(w io.Writer, results []TestCaseResult) {
+	fmt.Fprintln(w, "TAP version 13")
+	fmt.Fprintf(w, "1..%d\n", len(results))
+	for i, result := range results {
+		if result.Passed {
+			fmt.Fprintf(w, "ok %d - %s\n", i+1, testDisplayName(result))
+			continue
+		}
+		fmt.Fprintf(w, "not ok %d - %s\n", i+1, testDisplayName(result))
+		fmt.Fprintln(w, "  ---")
+		fmt.Fprintln(w, "  message: |")
+		for _, line := range strings.Split(result.Message, "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+		fmt.Fprintln(w, "  ...")
+	}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func junitSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// writeJunitResults writes one <testsuite> per test file.
+func writeJunitResults(w io.Writer, results []TestCaseResult) error {
+	suites := junitTestSuites{Tests: len(results)}
+	index := make(map[string]int)
+	suiteTimes := make([]time.Duration, 0)
+	for _, result := range results {
+		i, ok := index[result.File]
+		if !ok {
+			i = len(suites.Suites)
+			index[result.File] = i
+			suites.Suites = append(suites.Suites, junitTestSuite{Name: result.File})
+			suiteTimes = append(suiteTimes, 0)
+		}
+		suite := &suites.Suites[i]
+		suite.Tests++
+		suiteTimes[i] += result.Duration
+
+		testCase := junitTestCase{Name: result.Name, Classname: result.File, Time: junitSeconds(result.Duration)}
+		if !result.Passed {
+			suite.Failures++
+			suites.Failures++
+			firstLine, _, _ := strings.Cut(result.Message, "\n")
+			testCase.Failure = &junitFailure{Message: firstLine, Text: result.Message}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	for i := range suites.Suites {
+		suites.Suites[i].Time = junitSeconds(suiteTimes[i])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// assertionText renders a value for assertion messages. It is DebugString
+// without the truncation of long strings, and with sorted dictionary keys, so
+// two renderings differ exactly when the values do.
+func assertionText(obj MShellObject) string {
+	switch value := obj.(type) {
+	case MShellString:
+		return strconv.Quote(value.Content)
+	case *MShellList:
+		parts := make([]string, len(value.Items))
+		for i, item := range value.Items {
+			parts[i] = assertionText(item)
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	case *MShellDict:
+		keys := make([]string, 0, len(value.Items))
+		for key := range value.Items {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = strconv.Quote(key) + ": " + assertionText(value.Items[key])
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	default:
+		return obj.DebugString()
+	}
+}
+
+// assertionValuesEqual compares two values structurally. Lists and
+// dictionaries compare element by element; everything else uses Equals.
+func assertionValuesEqual(expected, actual MShellObject) bool {
+	switch e := expected.(type) {
+	case *MShellList:
+		a, ok := actual.(*MShellList)
+		if !ok || len(a.Items) != len(e.Items) {
+			return false
+		}
+		for i := range e.Items {
+			if !assertionValuesEqual(e.Items[i], a.Items[i]) {
+				return false
+			}
+		}
+		return true
+	case *MShellDict:
+		a, ok := actual.(*MShellDict)
+		if !ok || len(a.Items) != len(e.Items) {
+			return false
+		}
+		for key, value := range e.Items {
+			other, ok := a.Items[key]
+			if !ok || !assertionValuesEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	default:
+		if expected.TypeName() != actual.TypeName() {
+			return false
+		}
+		equal, err := expected.Equals(actual)
+		if err != nil {
+			return assertionText(expected) == assertionText(actual)
+		}
+		return equal
+	}
+}
+
+// assertionDiff shows where two renderings differ: a caret under the first
+// differing character, or '-'/'+' lines for multi-line values.
+func assertionDiff(expected, actual string) string {
+	var sb strings.Builder
+	if !strings.Contains(expected, "\n") && !strings.Contains(actual, "\n") {
+		e, a := []rune(expected), []rune(actual)
+		at := 0
+		for at < len(e) && at < len(a) && e[at] == a[at] {
+			at++
+		}
+		fmt.Fprintf(&sb, "  expected: %s\n", expected)
+		fmt.Fprintf(&sb, "  actual:   %s\n", actual)
+		fmt.Fprintf(&sb, "            %s^", strings.Repeat(" ", at))
+		return sb.String()
+	}
+
+	expectedLines := strings.Split(expected, "\n")
+	actualLines := strings.Split(actual, "\n")
+	n := max(len(expectedLines), len(actualLines))
+	lines := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		var e, a string
+		hasE, hasA := i < len(expectedLines), i < len(actualLines)
+		if hasE {
+			e = expectedLines[i]
+		}
+		if hasA {
+			a = actualLines[i]
+		}
+		if hasE && hasA && e == a {
+			lines = append(lines, "  "+e)
+			continue
+		}
+		if hasE {
+			lines = append(lines, "- "+e)
+		}
+		if hasA {
+			lines = append(lines, "+ "+a)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// evaluateAssertEq implements `actual expected assertEq`.
+func (state *EvalState) evaluateAssertEq(t Token, stack *MShellStack) EvalResult {
+	expected, actual, err := stack.Pop2(t)
+	if err != nil {
+		return state.FailWithMessage(err.Error())
+	}
+	if assertionValuesEqual(expected, actual) {
+		return SimpleSuccess()
+	}
+	diff := assertionDiff(assertionText(expected), assertionText(actual))
+	return state.FailWithMessage(fmt.Sprintf("%d:%d: assertEq failed.\n%s\n", t.Line, t.Column, diff))
+}
+
+// evaluateAssertTrue implements `condition assertTrue`.
+func (state *EvalState) evaluateAssertTrue(t Token, stack *MShellStack) EvalResult {
+	obj, err := stack.Pop1(t)
+	if err != nil {
+		return state.FailWithMessage(err.Error())
+	}
+	value, ok := obj.(MShellBool)
+	if !ok {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: Expected a boolean for 'assertTrue', received a %s.\n", t.Line, t.Column, obj.TypeName()))
+	}
+	if !value.Value {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: assertTrue failed: the condition was false.\n", t.Line, t.Column))
+	}
+	return SimpleSuccess()
+}
+
+// evaluateAssertFails implements `(quote) assertFails`. The quotation runs
+// like a 'try' body; the assertion passes when it fails. Either way the stack
+// is restored to what it was before the quotation ran.
+func (state *EvalState) evaluateAssertFails(t Token, stack *MShellStack, context ExecuteContext, definitions []MShellDefinition) EvalResult {
+	obj, err := stack.Pop1(t)
+	if err != nil {
+		return state.FailWithMessage(err.Error())
+	}
+	quote, ok := obj.(*MShellQuotation)
+	if !ok {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: Expected a quotation for 'assertFails', received a %s.\n", t.Line, t.Column, obj.TypeName()))
+	}
+
+	savedStack := make(MShellStack, len(*stack))
+	copy(savedStack, *stack)
+	callStackLen := len(state.CallStack)
+	loopDepth := state.LoopDepth
+	outerError := state.TryError
+
+	state.TryDepth++
+	state.TryError = nil
+	result, err := state.EvaluateQuote(*quote, stack, context, definitions)
+	state.TryDepth--
+	state.TryError = outerError
+
+	if err != nil {
+		return state.FailWithMessage(err.Error())
+	}
+	if result.ExitCalled {
+		return result
+	}
+
+	state.CallStack = state.CallStack[:callStackLen]
+	state.LoopDepth = loopDepth
+	*stack = append((*stack)[:0], savedStack...)
+
+	if result.Success {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: assertFails failed: the quotation succeeded.\n", t.Line, t.Column))
+	}
+	return SimpleSuccess()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunTestFileReportsEachTestDefinition(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "math_test.msh")
+	source := `def double (int -- int) 2 * end
+
+def test_double ( -- )
+    4 double 8 assertEq
+end
+
+def test_wrong ( -- )
+    [1 2] (double) map [2 5] assertEq
+end
+
+def helper ( -- ) end
+`
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results := runTestFile(path, nil, NewPathBinManager())
+	if len(results) != 2 {
+		t.Fatalf("got %d results; want 2", len(results))
+	}
+	if !results[0].Passed || results[0].Name != "test_double" {
+		t.Errorf("test_double = %+v; want passed", results[0])
+	}
+	if results[1].Passed {
+		t.Fatalf("test_wrong passed; want failure")
+	}
+	if !strings.HasPrefix(results[1].Message, "8:30: assertEq failed.") || !strings.Contains(results[1].Message, "actual:   [2 4]") {
+		t.Errorf("unexpected failure message:\n%s", results[1].Message)
+	}
+}
+
+func TestDiscoverTestFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b_test.msh", "a_test.msh", "helpers.msh", filepath.Join("sub", "c_test.msh")} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := discoverTestFiles([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "a_test.msh"),
+		filepath.Join(dir, "b_test.msh"),
+		filepath.Join(dir, "sub", "c_test.msh"),
+	}
+	if strings.Join(files, ",") != strings.Join(want, ",") {
+		t.Errorf("files = %v; want %v", files, want)
+	}
+}
+
+func TestTapAndJunitOutput(t *testing.T) {
+	results := []TestCaseResult{
+		{File: "a_test.msh", Name: "test_ok", Passed: true},
+		{File: "a_test.msh", Name: "test_bad", Message: "1:1: boom"},
+	}
+
+	var tap bytes.Buffer
+	writeTapResults(&tap, results)
+	wantTap := "TAP version 13\n1..2\nok 1 - a_test.msh test_ok\nnot ok 2 - a_test.msh test_bad\n  ---\n  message: |\n    1:1: boom\n  ...\n"
+	if tap.String() != wantTap {
+		t.Errorf("TAP output = %q; want %q", tap.String(), wantTap)
+	}
+
+	var junit bytes.Buffer
+	if err := writeJunitResults(&junit, results); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<testsuites tests="2" failures="1">`,
+		`<testsuite name="a_test.msh" tests="2" failures="1"`,
+		`<failure message="1:1: boom">1:1: boom</failure>`,
+	} {
+		if !strings.Contains(junit.String(), want) {
+			t.Errorf("JUnit output missing %q:\n%s", want, junit.String())
+		}
+	}
+}
+
+func TestAssertionDiffMultiline(t *testing.T) {
+	got := assertionDiff("a\nb\nc", "a\nx\nc")
+	want := "  a\n- b\n+ x\n  c"
+	if got != want {
+		t.Errorf("diff = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,780 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseToml reads TOML 1.0. Offset date-times keep their offset, local
+// date-times and dates are read as UTC, and local times, which have no
+// date, stay strings.
+
+type tomlParser struct {
+	src  string
+	pos  int
+	root *MShellDict
+	// Tables opened by a [header] or by dotted keys, which may not be opened
+	// again by another [header].
+	defined map[*MShellDict]bool
+	// Inline tables and arrays, which are complete once written.
+	sealed map[MShellObject]bool
+}
+
+func parseToml(src string) (*MShellDict, error) {
+	src = strings.TrimPrefix(strings.ReplaceAll(src, "\r\n", "\n"), "\ufeff")
+	p := &tomlParser{
+		src:     src,
+		root:    NewDict(),
+		defined: make(map[*MShellDict]bool),
+		sealed:  make(map[MShellObject]bool),
+	}
+	current := p.root
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return p.root, nil
+		}
+		var err error
+		if p.peek() == '[' {
+			current, err = p.parseTableHeader()
+		} else {
+			err = p.parseKeyValue(current)
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.skipInlineSpace()
+		if p.peek() == '#' {
+			p.skipToLineEnd()
+		}
+		if !p.eof() && p.peek() != '\n' {
+			return nil, p.errorf("expected a new line after the value")
+		}
+	}
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	line := strings.Count(p.src[:min(p.pos, len(p.src))], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) skipInlineSpace() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+func (p *tomlParser) skipToLineEnd() {
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+}
+
+// skipBlank moves past whitespace, line breaks and comments.
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\n':
+			p.pos++
+		case '#':
+			p.skipToLineEnd()
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) parseTableHeader() (*MShellDict, error) {
+	array := strings.HasPrefix(p.src[p.pos:], "[[")
+	if array {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	keys, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.src[p.pos:], closing) {
+		return nil, p.errorf("expected '%s' after table name", closing)
+	}
+	p.pos += len(closing)
+
+	parent, err := p.descend(p.root, keys[:len(keys)-1], false)
+	if err != nil {
+		return nil, err
+	}
+	last := keys[len(keys)-1]
+	existing, exists := parent.Items[last]
+
+	if array {
+		list, ok := existing.(*MShellList)
+		if exists && (!ok || p.sealed[list]) {
+			return nil, p.errorf("'%s' is not an array of tables", strings.Join(keys, "."))
+		}
+		if !exists {
+			list = &MShellList{Items: []MShellObject{}}
+			parent.Items[last] = list
+		}
+		table := NewDict()
+		list.Items = append(list.Items, table)
+		p.defined[table] = true
+		return table, nil
+	}
+
+	if !exists {
+		table := NewDict()
+		parent.Items[last] = table
+		p.defined[table] = true
+		return table, nil
+	}
+	table, ok := existing.(*MShellDict)
+	if !ok || p.defined[table] || p.sealed[table] {
+		return nil, p.errorf("table '%s' is defined more than once", strings.Join(keys, "."))
+	}
+	p.defined[table] = true
+	return table, nil
+}
+
+// descend walks keys from table, creating tables as needed. Through an
+// array of tables it follows the last element. Tables reached through
+// dotted keys are marked so a later [header] cannot reopen them.
+func (p *tomlParser) descend(table *MShellDict, keys []string, dotted bool) (*MShellDict, error) {
+	for i, key := range keys {
+		next, exists := table.Items[key]
+		if !exists {
+			child := NewDict()
+			table.Items[key] = child
+			if dotted {
+				p.defined[child] = true
+			}
+			table = child
+			continue
+		}
+		switch typed := next.(type) {
+		case *MShellDict:
+			if p.sealed[typed] || (dotted && !p.defined[typed]) {
+				return nil, p.errorf("cannot add keys to '%s'", strings.Join(keys[:i+1], "."))
+			}
+			table = typed
+		case *MShellList:
+			if dotted || p.sealed[typed] || len(typed.Items) == 0 {
+				return nil, p.errorf("'%s' is not a table", strings.Join(keys[:i+1], "."))
+			}
+			last, ok := typed.Items[len(typed.Items)-1].(*MShellDict)
+			if !ok {
+				return nil, p.errorf("'%s' is not a table", strings.Join(keys[:i+1], "."))
+			}
+			table = last
+		default:
+			return nil, p.errorf("'%s' is already a value, not a table", strings.Join(keys[:i+1], "."))
+		}
+	}
+	return table, nil
+}
+
+func (p *tomlParser) parseKeyValue(table *MShellDict) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if p.peek() != '=' {
+		return p.errorf("expected '=' after key '%s'", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipInlineSpace()
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+	target, err := p.descend(table, keys[:len(keys)-1], true)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, exists := target.Items[last]; exists {
+		return p.errorf("key '%s' is defined more than once", strings.Join(keys, "."))
+	}
+	target.Items[last] = value
+	return nil
+}
+
+// parseKey reads a bare, quoted or dotted key and the spaces after it.
+func (p *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		p.skipInlineSpace()
+		switch c := p.peek(); {
+		case c == '"':
+			s, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, s)
+		case c == '\'':
+			s, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, s)
+		default:
+			start := p.pos
+			for isTomlBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected a key")
+			}
+			keys = append(keys, p.src[start:p.pos])
+		}
+		p.skipInlineSpace()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func isTomlBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) parseValue() (MShellObject, error) {
+	rest := p.src[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, `"""`):
+		s, err := p.parseMultiLineBasicString()
+		return MShellString{Content: s}, err
+	case strings.HasPrefix(rest, `"`):
+		s, err := p.parseBasicString()
+		return MShellString{Content: s}, err
+	case strings.HasPrefix(rest, `'''`):
+		s, err := p.parseMultiLineLiteralString()
+		return MShellString{Content: s}, err
+	case strings.HasPrefix(rest, `'`):
+		s, err := p.parseLiteralString()
+		return MShellString{Content: s}, err
+	case strings.HasPrefix(rest, "["):
+		return p.parseArray()
+	case strings.HasPrefix(rest, "{"):
+		return p.parseInlineTable()
+	}
+
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\n,]}#", rune(p.peek())) {
+		p.pos++
+	}
+	token := p.src[start:p.pos]
+	// A date and time may be separated by a space.
+	if tomlDateRegex.MatchString(token) && p.peek() == ' ' && p.pos+3 < len(p.src) && isDigit(p.src[p.pos+1]) && isDigit(p.src[p.pos+2]) && p.src[p.pos+3] == ':' {
+		p.pos++
+		for !p.eof() && !strings.ContainsRune(" \t\n,]}#", rune(p.peek())) {
+			p.pos++
+		}
+		token = p.src[start:p.pos]
+	}
+	if token == "" {
+		return nil, p.errorf("expected a value")
+	}
+	value, ok := tomlScalar(token)
+	if !ok {
+		return nil, p.errorf("invalid value '%s'", token)
+	}
+	return value, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+var tomlDateRegex = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`)
+var tomlDateTimeRegex = regexp.MustCompile(`^([0-9]{4})-([0-9]{2})-([0-9]{2})(?:[Tt ]([0-9]{2}):([0-9]{2}):([0-9]{2})(\.[0-9]+)?([Zz]|[-+][0-9]{2}:[0-9]{2})?)?$`)
+var tomlTimeRegex = regexp.MustCompile(`^[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?$`)
+var tomlDecimalRegex = regexp.MustCompile(`^[-+]?(0|[1-9](_?[0-9])*)$`)
+var tomlPrefixedIntRegex = regexp.MustCompile(`^0(x[0-9A-Fa-f](_?[0-9A-Fa-f])*|o[0-7](_?[0-7])*|b[01](_?[01])*)$`)
+var tomlFloatRegex = regexp.MustCompile(`^[-+]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][-+]?[0-9](_?[0-9])*)?$`)
+
+// tomlScalar converts a bare token to a boolean, number or date-time.
+func tomlScalar(token string) (MShellObject, bool) {
+	switch token {
+	case "true":
+		return MShellBool{Value: true}, true
+	case "false":
+		return MShellBool{Value: false}, true
+	case "inf", "+inf":
+		return MShellFloat{Value: math.Inf(1)}, true
+	case "-inf":
+		return MShellFloat{Value: math.Inf(-1)}, true
+	case "nan", "+nan", "-nan":
+		return MShellFloat{Value: math.NaN()}, true
+	}
+
+	if tomlDecimalRegex.MatchString(token) || tomlPrefixedIntRegex.MatchString(token) {
+		i, err := strconv.ParseInt(token, 0, 64)
+		if err != nil {
+			return nil, false
+		}
+		return MShellInt{Value: int(i)}, true
+	}
+	if tomlFloatRegex.MatchString(token) {
+		f, err := strconv.ParseFloat(strings.ReplaceAll(token, "_", ""), 64)
+		if err != nil {
+			return nil, false
+		}
+		return MShellFloat{Value: f}, true
+	}
+	if tomlTimeRegex.MatchString(token) {
+		return MShellString{Content: token}, true
+	}
+	if m := tomlDateTimeRegex.FindStringSubmatch(token); m != nil {
+		t, err := tomlDateTime(m)
+		if err != nil {
+			return nil, false
+		}
+		return &MShellDateTime{Time: t, OriginalString: token}, true
+	}
+	return nil, false
+}
+
+func tomlDateTime(m []string) (time.Time, error) {
+	layout := "2006-01-02"
+	value := m[1] + "-" + m[2] + "-" + m[3]
+	if m[4] != "" {
+		layout += "T15:04:05"
+		value += "T" + m[4] + ":" + m[5] + ":" + m[6]
+		if m[7] != "" {
+			layout += ".999999999"
+			value += m[7]
+		}
+		if m[8] != "" {
+			layout += "Z07:00"
+			value += strings.ToUpper(m[8])
+		}
+	}
+	return time.Parse(layout, value)
+}
+
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.pos++ // '"'
+	var sb strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.peek()
+		switch c {
+		case '"':
+			p.pos++
+			return sb.String(), nil
+		case '\\':
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *tomlParser) parseMultiLineBasicString() (string, error) {
+	p.pos += 3
+	if p.peek() == '\n' {
+		p.pos++
+	}
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated multi-line string")
+		}
+		if strings.HasPrefix(p.src[p.pos:], `"""`) {
+			p.pos += 3
+			// Up to two quotes may directly precede the closing delimiter.
+			for i := 0; i < 2 && p.peek() == '"'; i++ {
+				sb.WriteByte('"')
+				p.pos++
+			}
+			return sb.String(), nil
+		}
+		c := p.peek()
+		if c != '\\' {
+			sb.WriteByte(c)
+			p.pos++
+			continue
+		}
+		// A backslash at the end of a line trims the following whitespace.
+		i := p.pos + 1
+		for i < len(p.src) && (p.src[i] == ' ' || p.src[i] == '\t') {
+			i++
+		}
+		if i < len(p.src) && p.src[i] == '\n' {
+			p.pos = i
+			for !p.eof() && strings.ContainsRune(" \t\n", rune(p.peek())) {
+				p.pos++
+			}
+			continue
+		}
+		if err := p.parseEscape(&sb); err != nil {
+			return "", err
+		}
+	}
+}
+
+func (p *tomlParser) parseEscape(sb *strings.Builder) error {
+	p.pos++ // '\\'
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'b':
+		sb.WriteByte('\b')
+	case 't':
+		sb.WriteByte('\t')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'r':
+		sb.WriteByte('\r')
+	case 'e':
+		sb.WriteByte('\x1b')
+	case '"':
+		sb.WriteByte('"')
+	case '\\':
+		sb.WriteByte('\\')
+	case 'u', 'U':
+		width := 4
+		if c == 'U' {
+			width = 8
+		}
+		if p.pos+width > len(p.src) {
+			return p.errorf("invalid escape '\\%c'", c)
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+width], 16, 32)
+		if err != nil {
+			return p.errorf("invalid escape '\\%c%s'", c, p.src[p.pos:p.pos+width])
+		}
+		p.pos += width
+		sb.WriteRune(rune(code))
+	default:
+		return p.errorf("invalid escape '\\%c'", c)
+	}
+	return nil
+}
+
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.pos++ // '\''
+	start := p.pos
+	for !p.eof() && p.peek() != '\'' && p.peek() != '\n' {
+		p.pos++
+	}
+	if p.peek() != '\'' {
+		return "", p.errorf("unterminated string")
+	}
+	s := p.src[start:p.pos]
+	p.pos++
+	return s, nil
+}
+
+func (p *tomlParser) parseMultiLineLiteralString() (string, error) {
+	p.pos += 3
+	if p.peek() == '\n' {
+		p.pos++
+	}
+	end := strings.Index(p.src[p.pos:], `'''`)
+	if end < 0 {
+		return "", p.errorf("unterminated multi-line string")
+	}
+	end += p.pos
+	for i := 0; i < 2 && end+3 < len(p.src) && p.src[end+3] == '\''; i++ {
+		end++
+	}
+	s := p.src[p.pos:end]
+	p.pos = end + 3
+	return s, nil
+}
+
+func (p *tomlParser) parseArray() (MShellObject, error) {
+	p.pos++ // '['
+	list := &MShellList{Items: []MShellObject{}}
+	p.sealed[list] = true
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			p.pos++
+			return list, nil
+		}
+		item, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, item)
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (MShellObject, error) {
+	p.pos++ // '{'
+	table := NewDict()
+	p.defined[table] = true
+	for first := true; ; first = false {
+		p.skipInlineSpace()
+		if p.peek() == '}' && first {
+			p.pos++
+			break
+		}
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipInlineSpace()
+		if p.peek() == '}' {
+			p.pos++
+			break
+		}
+		if p.peek() != ',' {
+			return nil, p.errorf("expected ',' or '}' in inline table")
+		}
+		p.pos++
+	}
+	p.seal(table)
+	return table, nil
+}
+
+// seal marks an inline table and the tables inside it as complete.
+func (p *tomlParser) seal(obj MShellObject) {
+	p.sealed[obj] = true
+	if dict, ok := obj.(*MShellDict); ok {
+		for _, value := range dict.Items {
+			p.seal(value)
+		}
+	}
+}
+
+// writeToml serialises a dictionary as a TOML document. Keys are written in
+// sorted order, scalars and arrays first, then tables, then arrays of
+// tables.
+func writeToml(obj MShellObject) (string, error) {
+	if maybe, ok := obj.(*Maybe); ok && maybe.obj != nil {
+		obj = maybe.obj
+	}
+	dict, ok := obj.(*MShellDict)
+	if !ok {
+		return "", fmt.Errorf("a TOML document must be a dictionary, not a %s", obj.TypeName())
+	}
+	var sb strings.Builder
+	if err := writeTomlTable(&sb, nil, dict, false); err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(sb.String(), "\n"), nil
+}
+
+// isTomlTableArray reports whether obj is written as [[name]] sections.
+func isTomlTableArray(obj MShellObject) bool {
+	list, ok := unwrapTomlValue(obj).(*MShellList)
+	if !ok || len(list.Items) == 0 {
+		return false
+	}
+	for _, item := range list.Items {
+		if _, ok := unwrapTomlValue(item).(*MShellDict); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func unwrapTomlValue(obj MShellObject) MShellObject {
+	if maybe, ok := obj.(*Maybe); ok && maybe.obj != nil {
+		return maybe.obj
+	}
+	return obj
+}
+
+func writeTomlTable(sb *strings.Builder, path []string, dict *MShellDict, arrayElement bool) error {
+	keys := make([]string, 0, len(dict.Items))
+	for key := range dict.Items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var values, tables, tableArrays []string
+	for _, key := range keys {
+		value := unwrapTomlValue(dict.Items[key])
+		if _, ok := value.(*MShellDict); ok {
+			tables = append(tables, key)
+		} else if isTomlTableArray(value) {
+			tableArrays = append(tableArrays, key)
+		} else {
+			values = append(values, key)
+		}
+	}
+
+	header := make([]string, len(path))
+	for i, name := range path {
+		header[i] = tomlKeyText(name)
+	}
+	if arrayElement {
+		fmt.Fprintf(sb, "\n[[%s]]\n", strings.Join(header, "."))
+	} else if len(path) > 0 && (len(values) > 0 || len(tables)+len(tableArrays) == 0) {
+		fmt.Fprintf(sb, "\n[%s]\n", strings.Join(header, "."))
+	}
+	for _, key := range values {
+		text, err := tomlValueText(dict.Items[key])
+		if err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(append(path, key), "."), err)
+		}
+		fmt.Fprintf(sb, "%s = %s\n", tomlKeyText(key), text)
+	}
+	for _, key := range tables {
+		child := unwrapTomlValue(dict.Items[key]).(*MShellDict)
+		if err := writeTomlTable(sb, append(path[:len(path):len(path)], key), child, false); err != nil {
+			return err
+		}
+	}
+	for _, key := range tableArrays {
+		for _, item := range unwrapTomlValue(dict.Items[key]).(*MShellList).Items {
+			child := unwrapTomlValue(item).(*MShellDict)
+			if err := writeTomlTable(sb, append(path[:len(path):len(path)], key), child, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func tomlKeyText(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for i := 0; i < len(key); i++ {
+		if !isTomlBareKeyChar(key[i]) {
+			return tomlStringText(key)
+		}
+	}
+	return key
+}
+
+// tomlStringText writes s as a basic string.
+func tomlStringText(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < ' ' || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// tomlValueText formats a value written after 'key ='.
+func tomlValueText(obj MShellObject) (string, error) {
+	switch typed := obj.(type) {
+	case MShellString:
+		return tomlStringText(typed.Content), nil
+	case MShellPath:
+		return tomlStringText(typed.Path), nil
+	case MShellLiteral:
+		return tomlStringText(typed.LiteralText), nil
+	case MShellInt:
+		return strconv.Itoa(typed.Value), nil
+	case MShellFloat:
+		switch {
+		case math.IsNaN(typed.Value):
+			return "nan", nil
+		case math.IsInf(typed.Value, 1):
+			return "inf", nil
+		case math.IsInf(typed.Value, -1):
+			return "-inf", nil
+		}
+		s := strconv.FormatFloat(typed.Value, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s, nil
+	case MShellBool:
+		return strconv.FormatBool(typed.Value), nil
+	case *MShellDateTime:
+		return formatStructuredDateTime(typed.Time), nil
+	case *Maybe:
+		if typed.obj == nil {
+			return "", fmt.Errorf("TOML has no null value")
+		}
+		return tomlValueText(typed.obj)
+	case MShellNull:
+		return "", fmt.Errorf("TOML has no null value")
+	case *MShellList:
+		parts := make([]string, len(typed.Items))
+		for i, item := range typed.Items {
+			text, err := tomlValueText(item)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = text
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	case *MShellDict:
+		keys := make([]string, 0, len(typed.Items))
+		for key := range typed.Items {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if len(keys) == 0 {
+			return "{}", nil
+		}
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			text, err := tomlValueText(typed.Items[key])
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", key, err)
+			}
+			parts[i] = tomlKeyText(key) + " = " + text
+		}
+		return "{ " + strings.Join(parts, ", ") + " }", nil
+	}
+	return "", fmt.Errorf("cannot write a %s as TOML", obj.TypeName())
+}
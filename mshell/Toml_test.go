@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseToml(t *testing.T) {
+	src := `title = "demo" # comment
+site."example.com" = true
+
+[package]
+version = '0.1.0'
+released = 1979-05-27T07:32:00-08:00
+day = 1979-05-27
+size = 1_000
+ratio = 0.5
+desc = """
+one \
+  two"""
+
+[dependencies]
+serde = { version = "1.0", features = ["derive"] }
+
+[[bin]]
+name = "a"
+
+[[bin]]
+name = "b"
+[bin.extra]
+x = 1
+`
+	dict, err := parseToml(src)
+	if err != nil {
+		t.Fatalf("parseToml error: %v", err)
+	}
+	pkg := dict.Items["package"].(*MShellDict)
+	released := pkg.Items["released"].(*MShellDateTime).Time
+	if _, offset := released.Zone(); offset != -8*3600 || released.Hour() != 7 {
+		t.Errorf("released = %s, want 07:32 at -08:00", released)
+	}
+	if day := pkg.Items["day"].(*MShellDateTime).Time; !day.Equal(time.Date(1979, time.May, 27, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("day = %s, want 1979-05-27 UTC", day)
+	}
+	if got := pkg.Items["desc"].(MShellString).Content; got != "one two" {
+		t.Errorf("desc = %q, want \"one two\"", got)
+	}
+
+	delete(pkg.Items, "released")
+	delete(pkg.Items, "day")
+	want := `{"bin": [{"name": "a"}, {"extra": {"x": 1}, "name": "b"}], "dependencies": {"serde": {"features": ["derive"], "version": "1.0"}}, "package": {"desc": "one two", "ratio": 0.5, "size": 1000, "version": "0.1.0"}, "site": {"example.com": true}, "title": "demo"}`
+	if got := dict.ToJson(); got != want {
+		t.Errorf("parseToml = %s, want %s", got, want)
+	}
+}
+
+func TestParseTomlErrors(t *testing.T) {
+	for _, tt := range []struct {
+		src  string
+		want string
+	}{
+		{"a = 1\na = 2\n", "line 2: key 'a' is defined more than once"},
+		{"[a]\n[a]\n", "table 'a' is defined more than once"},
+		{"a = 1\n[a.b]\n", "already a value"},
+		{"a = { b = 1 }\n[a]\n", "defined more than once"},
+		{"a = 1 2\n", "expected a new line"},
+		{"a = 0x\n", "invalid value"},
+	} {
+		if _, err := parseToml(tt.src); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseToml(%q) error = %v, want one containing %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestWriteTomlRoundTrip(t *testing.T) {
+	bin := NewDict()
+	bin.Items["name"] = MShellString{Content: "cli \"main\""}
+	pkg := NewDict()
+	pkg.Items["version"] = MShellString{Content: "0.1.0"}
+	pkg.Items["edition"] = MShellInt{Value: 2021}
+	pkg.Items["released"] = &MShellDateTime{Time: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)}
+	doc := NewDict()
+	doc.Items["package"] = pkg
+	doc.Items["bin"] = &MShellList{Items: []MShellObject{bin}}
+	doc.Items["weights"] = &MShellList{Items: []MShellObject{MShellFloat{Value: 1}, MShellFloat{Value: 2.5}}}
+
+	text, err := writeToml(doc)
+	if err != nil {
+		t.Fatalf("writeToml error: %v", err)
+	}
+	want := `weights = [1.0, 2.5]
+
+[package]
+edition = 2021
+released = 2024-03-01
+version = "0.1.0"
+
+[[bin]]
+name = "cli \"main\""
+`
+	if text != want {
+		t.Errorf("writeToml =\n%s\nwant\n%s", text, want)
+	}
+
+	parsed, err := parseToml(text)
+	if err != nil {
+		t.Fatalf("parseToml error: %v", err)
+	}
+	if got, want := parsed.ToJson(), doc.ToJson(); got != want {
+		t.Errorf("round trip = %s, want %s", got, want)
+	}
+
+	doc.Items["missing"] = MShellNull{}
+	if _, err := writeToml(doc); err == nil || !strings.Contains(err.Error(), "missing: TOML has no null value") {
+		t.Errorf("writeToml with a null: error = %v", err)
+	}
+	if _, err := writeToml(&MShellList{}); err == nil {
+		t.Errorf("writeToml of a list: expected an error")
+	}
+}
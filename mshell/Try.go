@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TryError is the failure captured while a `try` body is running. It is
+// recorded by FailWithMessage (and the `!` exit path) instead of being
+// printed, then handed to the handler quotation as an error dictionary.
+type TryError struct {
+	Message   string
+	Line      int
+	Column    int
+	ExitCode  int
+	CallStack []CallStackItem
+}
+
+// recordTryError stores the first failure raised inside a `try` body. Later
+// failures during the same unwind (wrappers re-failing with their own
+// message) are ignored so the handler sees the innermost cause.
+func (state *EvalState) recordTryError(message string, exitCode int) {
+	if state.TryError != nil {
+		return
+	}
+
+	line, column, rest, ok := splitLineColPrefix(message)
+	if !ok {
+		rest = message
+		// Fall back to the innermost call stack item that has a position.
+		for i := len(state.CallStack) - 1; i >= 0; i-- {
+			if state.CallStack[i].MShellParseItem != nil {
+				startToken := state.CallStack[i].MShellParseItem.GetStartToken()
+				line, column = startToken.Line, startToken.Column
+				break
+			}
+		}
+	}
+
+	callStack := make([]CallStackItem, len(state.CallStack))
+	copy(callStack, state.CallStack)
+
+	state.TryError = &TryError{
+		Message:   strings.TrimRight(rest, "\n"),
+		Line:      line,
+		Column:    column,
+		ExitCode:  exitCode,
+		CallStack: callStack,
+	}
+}
+
+// splitLineColPrefix splits the "line:col: " prefix that evaluator error
+// messages start with.
+func splitLineColPrefix(message string) (int, int, string, bool) {
+	first := strings.IndexByte(message, ':')
+	if first <= 0 {
+		return 0, 0, message, false
+	}
+	line, err := strconv.Atoi(message[:first])
+	if err != nil {
+		return 0, 0, message, false
+	}
+	rest := message[first+1:]
+	second := strings.IndexByte(rest, ':')
+	if second <= 0 {
+		return 0, 0, message, false
+	}
+	column, err := strconv.Atoi(rest[:second])
+	if err != nil {
+		return 0, 0, message, false
+	}
+	return line, column, strings.TrimLeft(rest[second+1:], " "), true
+}
+
+// ToDict converts the captured failure into the dictionary pushed for the
+// `try` handler: message, line, column, exitCode, and callStack, where each
+// call stack entry is a dictionary of name, line, column, and file.
+func (e *TryError) ToDict() *MShellDict {
+	frames := NewList(0)
+	for _, item := range e.CallStack {
+		frame := NewDict()
+		frame.Items["name"] = MShellString{item.Name}
+		line, column, file := 0, 0, ""
+		if item.MShellParseItem != nil {
+			startToken := item.MShellParseItem.GetStartToken()
+			line, column = startToken.Line, startToken.Column
+			if startToken.TokenFile != nil {
+				file = startToken.TokenFile.Path
+			}
+		}
+		frame.Items["line"] = MShellInt{line}
+		frame.Items["column"] = MShellInt{column}
+		frame.Items["file"] = MShellString{file}
+		frames.Items = append(frames.Items, frame)
+	}
+
+	dict := NewDict()
+	dict.Items["message"] = MShellString{e.Message}
+	dict.Items["line"] = MShellInt{e.Line}
+	dict.Items["column"] = MShellInt{e.Column}
+	dict.Items["exitCode"] = MShellInt{e.ExitCode}
+	dict.Items["callStack"] = frames
+	return dict
+}
+
+// evaluateTry implements `(body) (handler) try`. The body runs on the
+// current stack. If it fails, the stack is restored to what it was before
+// the body ran, the error dictionary is pushed, and the handler runs.
+// `exit` is not an error and passes straight through.
+func (state *EvalState) evaluateTry(t Token, stack *MShellStack, context ExecuteContext, definitions []MShellDefinition) EvalResult {
+	obj1, obj2, err := stack.Pop2(t)
+	if err != nil {
+		return state.FailWithMessage(err.Error())
+	}
+
+	handler, ok := obj1.(*MShellQuotation)
+	if !ok {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: Expected a handler quotation on top of the stack for 'try', received a %s.\n", t.Line, t.Column, obj1.TypeName()))
+	}
+
+	body, ok := obj2.(*MShellQuotation)
+	if !ok {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: Expected a body quotation below the handler for 'try', received a %s.\n", t.Line, t.Column, obj2.TypeName()))
+	}
+
+	savedStack := make(MShellStack, len(*stack))
+	copy(savedStack, *stack)
+	callStackLen := len(state.CallStack)
+	loopDepth := state.LoopDepth
+	outerError := state.TryError
+
+	state.TryDepth++
+	state.TryError = nil
+	result, err := state.EvaluateQuote(*body, stack, context, definitions)
+	state.TryDepth--
+
+	caught := state.TryError
+	state.TryError = outerError
+
+	if err != nil {
+		return state.FailWithMessage(err.Error())
+	}
+
+	if result.Success || result.ExitCalled {
+		return result
+	}
+
+	// Frames abandoned by the failure never popped their call stack items.
+	state.CallStack = state.CallStack[:callStackLen]
+	state.LoopDepth = loopDepth
+
+	if caught == nil {
+		// A failure that never went through FailWithMessage, e.g. a process
+		// killed under 'soe'.
+		caught = &TryError{
+			Message:  fmt.Sprintf("Failed with exit code %d.", result.ExitCode),
+			Line:     t.Line,
+			Column:   t.Column,
+			ExitCode: result.ExitCode,
+		}
+	}
+
+	*stack = append((*stack)[:0], savedStack...)
+	stack.Push(caught.ToDict())
+
+	result, err = state.EvaluateQuote(*handler, stack, context, definitions)
+	if err != nil {
+		return state.FailWithMessage(err.Error())
+	}
+	return result
+}
+
+// evaluateFail implements `"message" fail`, raising an error that an
+// enclosing `try` can catch. Outside of `try` it stops the script like any
+// other failure, with exit code 1.
+func (state *EvalState) evaluateFail(t Token, stack *MShellStack) EvalResult {
+	obj, err := stack.Pop1(t)
+	if err != nil {
+		return state.FailWithMessage(err.Error())
+	}
+
+	message, err := obj.CastString()
+	if err != nil {
+		return state.FailWithMessage(fmt.Sprintf("%d:%d: Expected a string message for 'fail', received a %s.\n", t.Line, t.Column, obj.TypeName()))
+	}
+
+	return state.FailWithMessage(fmt.Sprintf("%d:%d: %s\n", t.Line, t.Column, message))
+}
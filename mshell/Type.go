@@ -35,6 +35,7 @@ const (
 	TidDateTime // date/time literal (YYYY-MM-DD[THH:MM[:SS]]) and now/date ops
 	TidBottom   // divergent: exit, infinite loop, (Phase 2) propagated fail
 	TidNull     // JSON `null`; distinct from `none` (the empty Maybe case)
+	TidGridScan // lazy CSV scan plan from scanCsv; opaque to the checker
 )
 
 // TypeKind categorizes a TypeNode. The interpretation of TypeNode.A, B, and
@@ -219,6 +220,7 @@ func NewTypeArena() *TypeArena {
 		TKPrim, // TidDateTime
 		TKPrim, // TidBottom
 		TKPrim, // TidNull
+		TKPrim, // TidGridScan
 	}
 	for i := range primitives {
 		// Encode the primitive id directly in A so the cons key stays unique.
@@ -739,7 +741,7 @@ func (t *NameTable) Name(id NameId) string {
 func IsReservedTypeName(name string) bool {
 	switch name {
 	case "int", "float", "str", "bool", "bytes", "none", "null",
-		"path", "datetime", "Maybe", "Grid", "GridView", "GridRow":
+		"path", "datetime", "Maybe", "Grid", "GridView", "GridRow", "GridScan":
 		return true
 	}
 	return false
@@ -54,7 +54,7 @@ func TestReconcileSameTypes(t *testing.T) {
 	entry := c.captureBranch()
 	a1 := runArm(c, entry, false, func() { c.stack.Push(TidInt) })
 	a2 := runArm(c, entry, false, func() { c.stack.Push(TidInt) })
-	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, entry, mkTok(IF, "if"))
+	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, nil, entry, mkTok(IF, "if"))
 	if errs := c.Errors(); len(errs) != 0 {
 		t.Fatalf("unexpected errors: %+v", errs)
 	}
@@ -68,7 +68,7 @@ func TestReconcileDifferentTypesUnion(t *testing.T) {
 	entry := c.captureBranch()
 	a1 := runArm(c, entry, false, func() { c.stack.Push(TidInt) })
 	a2 := runArm(c, entry, false, func() { c.stack.Push(TidStr) })
-	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, entry, mkTok(IF, "if"))
+	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, nil, entry, mkTok(IF, "if"))
 	if errs := c.Errors(); len(errs) != 0 {
 		t.Fatalf("unexpected errors: %+v", errs)
 	}
@@ -87,7 +87,7 @@ func TestReconcileStackSizeMismatch(t *testing.T) {
 		c.stack.Push(TidInt)
 		c.stack.Push(TidStr)
 	})
-	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, entry, mkTok(IF, "if"))
+	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, nil, entry, mkTok(IF, "if"))
 	errs := c.Errors()
 	if len(errs) != 1 || errs[0].Kind != TErrBranchStackSize {
 		t.Fatalf("expected stack-size error, got %+v", errs)
@@ -104,7 +104,7 @@ func TestReconcileVarSetLiftsToMaybeBound(t *testing.T) {
 	y := c.names.Intern("y")
 	a1 := runArm(c, entry, false, func() { c.vars.bound[x] = TidInt })
 	a2 := runArm(c, entry, false, func() { c.vars.bound[y] = TidStr })
-	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, entry, mkTok(IF, "if"))
+	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, nil, entry, mkTok(IF, "if"))
 	if errs := c.Errors(); len(errs) != 0 {
 		t.Fatalf("unexpected errors: %+v", errs)
 	}
@@ -128,7 +128,7 @@ func TestReconcileVarTypeUnion(t *testing.T) {
 	x := c.names.Intern("x")
 	a1 := runArm(c, entry, false, func() { c.vars.bound[x] = TidInt })
 	a2 := runArm(c, entry, false, func() { c.vars.bound[x] = TidStr })
-	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, entry, mkTok(IF, "if"))
+	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, nil, entry, mkTok(IF, "if"))
 	if errs := c.Errors(); len(errs) != 0 {
 		t.Fatalf("unexpected errors: %+v", errs)
 	}
@@ -148,7 +148,7 @@ func TestReconcilePreBoundStaysBound(t *testing.T) {
 	entry := c.captureBranch()
 	a1 := runArm(c, entry, false, func() {})
 	a2 := runArm(c, entry, false, func() { c.vars.bound[x] = TidStr })
-	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, entry, mkTok(IF, "if"))
+	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, nil, entry, mkTok(IF, "if"))
 	if errs := c.Errors(); len(errs) != 0 {
 		t.Fatalf("unexpected errors: %+v", errs)
 	}
@@ -171,7 +171,7 @@ func TestReconcileMaybeFromOneArmLeaks(t *testing.T) {
 	x := c.names.Intern("x")
 	a1 := runArm(c, entry, false, func() { c.vars.bound[x] = TidInt })
 	a2 := runArm(c, entry, false, func() {}) // implicit no-else arm
-	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, entry, mkTok(IF, "if"))
+	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, nil, entry, mkTok(IF, "if"))
 	if errs := c.Errors(); len(errs) != 0 {
 		t.Fatalf("unexpected errors: %+v", errs)
 	}
@@ -194,7 +194,7 @@ func TestReconcileDivergedArmDropped(t *testing.T) {
 		c.stack.Push(TidStr)
 		c.stack.Push(TidStr)
 	})
-	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, entry, mkTok(IF, "if"))
+	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, nil, entry, mkTok(IF, "if"))
 	if errs := c.Errors(); len(errs) != 0 {
 		t.Fatalf("unexpected errors: %+v", errs)
 	}
@@ -210,7 +210,7 @@ func TestReconcileAllDivergedMarksDiverged(t *testing.T) {
 	entry := c.captureBranch()
 	a1 := runArm(c, entry, true, func() {})
 	a2 := runArm(c, entry, true, func() {})
-	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, entry, mkTok(IF, "if"))
+	c.reconcileArmBranches([]quoteBranch{a1, a2}, []string{"arm 1", "arm 2"}, nil, entry, mkTok(IF, "if"))
 	if errs := c.Errors(); len(errs) != 0 {
 		t.Fatalf("unexpected errors: %+v", errs)
 	}
@@ -347,7 +347,7 @@ func TestReconcileWithMaybePattern(t *testing.T) {
 	noneArm := runArm(c, entry, false, func() {
 		c.stack.Push(TidInt)
 	})
-	c.reconcileArmBranches([]quoteBranch{justArm, noneArm}, []string{"arm 1", "arm 2"}, entry, mkTok(MATCH, "match"))
+	c.reconcileArmBranches([]quoteBranch{justArm, noneArm}, []string{"arm 1", "arm 2"}, nil, entry, mkTok(MATCH, "match"))
 	if errs := c.Errors(); len(errs) != 0 {
 		t.Fatalf("unexpected errors: %+v", errs)
 	}
@@ -185,10 +185,10 @@ func builtinSigsByName(arena *TypeArena, names *NameTable) map[NameId][]QuoteSig
 	// silently waves through programs that crash at runtime (e.g.
 	// `2026-01-01 wl` on a datetime). Use `str` to coerce other types
 	// first (`1.5 str wl`).
-	r.reg("wl", "(str | int -- )")  // write line
-	r.reg("wle", "(str | int -- )") // write line stderr
-	r.reg("w", "(str | int | bytes -- )")
-	r.reg("we", "(str | int | bytes -- )")
+	r.reg("wl", "(str | int | Grid | GridView -- )")  // write line; grids render as a table
+	r.reg("wle", "(str | int | Grid | GridView -- )") // write line stderr
+	r.reg("w", "(str | int | bytes | Grid | GridView -- )")
+	r.reg("we", "(str | int | bytes | Grid | GridView -- )")
 	r.reg("wln", "( -- )") // write just a newline
 	r.reg("stack", "( -- )")
 	r.reg("defs", "( -- )")
@@ -337,6 +337,7 @@ func builtinSigsByName(arena *TypeArena, names *NameTable) map[NameId][]QuoteSig
 		"([t] (t -- bool) -- [t])",
 		"({v} (v -- bool) -- {v})",
 		"(Grid | GridView (GridRow -- bool) -- GridView)",
+		"(GridScan (GridRow -- bool) -- GridScan)",
 	)
 	r.reg("each", "([t] (t -- ) -- )")
 	// The key-extractor must produce a str since dict keys are always str.
@@ -403,6 +404,8 @@ func builtinSigsByName(arena *TypeArena, names *NameTable) map[NameId][]QuoteSig
 	r.reg("numFmt", "(int "+numFmtOpts+" -- str)", "(float "+numFmtOpts+" -- str)")
 	r.reg("countSubStr", "(str str -- int)")
 	r.reg("toJson", "(t -- str)") // generic conversion to JSON
+	r.reg("toYaml", "(t -- str)")
+	r.reg("toToml", "(t -- str)")
 
 	// ----- Grid ops -----
 	//
@@ -416,17 +419,19 @@ func builtinSigsByName(arena *TypeArena, names *NameTable) map[NameId][]QuoteSig
 	r.reg("gridMeta", "(Grid | GridView -- Maybe[{v}])")
 	r.reg("gridColMeta", "(Grid | GridView str -- Maybe[{v}])")
 	r.reg("gridCol", "(Grid | GridView str -- [t])")
-	r.reg("select", "(Grid | GridView [str] -- Grid)")
-	r.reg("exclude", "(Grid | GridView [str] -- Grid)")
+	r.reg("select", "(Grid | GridView [str] -- Grid)", "(GridScan [str] -- GridScan)")
+	r.reg("exclude", "(Grid | GridView [str] -- Grid)", "(GridScan [str] -- GridScan)")
 	r.reg("gridRenameCol", "(Grid str str -- Grid)")
 	r.reg("gridCompact", "(Grid | GridView -- Grid)")
 	r.reg("derive", "(Grid | GridView str {v} (GridRow -- t) -- Grid)")
 	r.reg("gridValues", "(Grid | GridView -- [[t]])")
 	r.reg("toGrid", "([t] -- Grid)")
+	r.reg("gridPager", "(Grid | GridView -- )")
 	r.reg("toDict", "(GridRow -- {v})")
 	r.add("map", "(Grid | GridView (GridRow -- {v}) -- Grid)")
 	r.add("each", "(Grid | GridView (GridRow -- ) -- )")
 	r.reg("sortBy", "(Grid | GridView str | [str] -- GridView)")
+	r.reg("gridSort", "(Grid | GridView [str | {col: str, desc?: bool, nulls?: str}] -- Grid)")
 	r.reg("gridSetCell", "(Grid str int t -- Grid)")
 	r.reg("gridAddCol", "(Grid str [t] -- Grid)", "(Grid str t -- Grid)")
 	r.reg("gridRemoveCol", "(Grid str -- Grid)")
@@ -437,18 +442,87 @@ func builtinSigsByName(arena *TypeArena, names *NameTable) map[NameId][]QuoteSig
 		"(GridView (GridRow GridRow -- int) -- GridView)",
 	)
 	r.reg("parseCsv", "(str | path -- [[str]])")
+	// readCsvGrid options are all optional; the dict itself may be omitted.
+	csvGridOpts := "{delimiter?: str, quote?: str, header?: bool, comment?: str, nulls?: [str], stripBom?: bool, lazyQuotes?: bool, maxRows?: int, inferRows?: int, types?: {str: str}}"
+	r.reg("readCsvGrid", "(str | path -- Grid)", "(str | path "+csvGridOpts+" -- Grid)")
+	// scanCsv takes the same options but only reads the file when collect or
+	// groupBy runs the plan.
+	r.reg("scanCsv", "(path -- GridScan)", "(path "+csvGridOpts+" -- GridScan)")
+	r.reg("collect", "(GridScan -- Grid)")
+	// Grid writers return the text, or stream it to a path ('-' for stdout).
+	// toCsv also keeps its list-of-rows form.
+	delimitedOpts := "{header?: bool, quoteAll?: bool, lineEnding?: str, \"null\"?: str, dateFmt?: str, numFmt?: " + numFmtOpts + "}"
+	csvOpts := "{delimiter?: str, header?: bool, quoteAll?: bool, lineEnding?: str, \"null\"?: str, dateFmt?: str, numFmt?: " + numFmtOpts + "}"
+	jsonLinesOpts := "{dateFmt?: str}"
+	markdownOpts := "{\"null\"?: str, dateFmt?: str, numFmt?: " + numFmtOpts + "}"
+	gridWriterSigs := func(opts string) []string {
+		return []string{
+			"(Grid | GridView -- str)",
+			"(Grid | GridView " + opts + " -- str)",
+			"(Grid | GridView path -- )",
+			"(Grid | GridView path " + opts + " -- )",
+		}
+	}
+	r.reg("toCsv", append([]string{"([[str]] -- str)"}, gridWriterSigs(csvOpts)...)...)
+	r.reg("toTsv", gridWriterSigs(delimitedOpts)...)
+	// toJsonLines also writes a list, one item per line.
+	r.reg("toJsonLines", append([]string{"([t] -- str)", "([t] path -- )"}, gridWriterSigs(jsonLinesOpts)...)...)
+	r.reg("toMarkdownTable", gridWriterSigs(markdownOpts)...)
 	r.reg("parseJson", "(str | path | bytes -- t)")
+	// parseJsonLines and readJsonLinesGrid read standard input for the path '-'.
+	r.reg("parseJsonLines", "(str | path | bytes -- [t])")
+	r.reg("readJsonLinesGrid", "(str | path | bytes -- Grid)", "(str | path | bytes {columns?: [str], maxRows?: int} -- Grid)")
+	r.reg("jsonPath", "(t str -- [u])")
+	r.reg("parseYaml", "(str | path | bytes -- t)")
+	r.reg("parseToml", "(str | path | bytes -- t)")
 	// parseExcel: a cell is a string, a float (numbers and dates), a
 	// bool, or a None Maybe (error cells like #DIV/0!). The Maybe carries
 	// a free inner type because an error cell is always None, mirroring
 	// how `none` itself is typed.
+	// With options, date-formatted cells may become datetimes and a
+	// `formulas` grid of formula text may be added.
+	parseExcelOpts := "{sheets?: [str | int], dates?: bool, formulas?: bool, merged?: bool}"
 	r.reg("parseExcel",
 		"(path | bytes -- [{name: str, data: [[str | float | bool | Maybe[v]]], hidden: bool, visibility: str}])",
+		"(path | bytes "+parseExcelOpts+" -- [{name: str, data: [[str | float | bool | datetime | Maybe[v]]], hidden: bool, visibility: str, formulas?: [[str]]}])",
 	)
+	r.reg("readExcelGrid", "(path | bytes -- Grid)", "(path | bytes {sheet?: str | int, header?: bool, dates?: bool, merged?: bool} -- Grid)")
+	// toExcel takes one sheet's data, a dict of sheet name to data, or a
+	// list of {name, data} sheets like parseExcel returns. It returns the
+	// workbook bytes or writes them to a path ('-' for stdout).
+	excelData := "Grid | GridView | [[t]]"
+	excelOpts := "{header?: bool, widths?: [int | float]}"
+	var toExcelSigs []string
+	for _, data := range []string{excelData, "{" + excelData + "}", "[{name: str, data: " + excelData + "}]"} {
+		toExcelSigs = append(toExcelSigs,
+			"("+data+" -- bytes)",
+			"("+data+" "+excelOpts+" -- bytes)",
+			"("+data+" path -- )",
+			"("+data+" path "+excelOpts+" -- )",
+		)
+	}
+	r.reg("toExcel", toExcelSigs...)
 	for _, name := range []string{"mkdir", "mkdirp"} {
 		r.reg(name, "(str | path -- )")
 	}
 	r.reg("cd", "(str | path -- )")
+	// parallel: tryParallel builds the group's command type; these sigs
+	// only describe the operands.
+	r.reg("parallel", "([[a]] -- [[a]])", "([[a]] int -- [[a]])")
+	// Test assertions. assertFails is checked by tryAssertFails when its
+	// operand is a quotation; this sig only covers other operands.
+	r.reg("assertEq", "(a a -- )")
+	r.reg("assertTrue", "(bool -- )")
+	r.reg("assertFails", "(( -- ) -- )")
+	// abbr : interactive abbreviation, name then expansion
+	r.reg("abbr", "(str str -- )")
+	r.reg("abbrRemove", "(str -- )")
+	r.reg("abbrs", "( -- {str: str})")
+	// Job control: 'fg' and 'bg' take an optional job number.
+	r.reg("jobs", "( -- )")
+	for _, name := range []string{"fg", "bg"} {
+		r.reg(name, "( -- )", "(int -- )")
+	}
 	// setenv : set an environment variable, value then name
 	r.reg("setenv", "(str str -- )")
 	// unsetenv : remove an environment variable by name
@@ -485,6 +559,7 @@ func builtinSigsByName(arena *TypeArena, names *NameTable) map[NameId][]QuoteSig
 	r.reg("toOleDate", "(datetime -- float)")
 	// Stack order matches the runtime: datetime below, count on top.
 	r.reg("addDays", "(datetime int | float -- datetime)")
+	r.reg("dateTrunc", "(datetime str -- datetime)")
 	r.reg("reSplit", "(str str -- [str])")
 	for _, name := range []string{"rm", "rmf"} {
 		r.reg(name, "(str | path -- )")
@@ -540,6 +615,9 @@ func builtinSigsByName(arena *TypeArena, names *NameTable) map[NameId][]QuoteSig
 	r.reg("reFindAllIndex", "(str str -- [[int]])")
 	r.reg("parseLinkHeader", "(str -- [{v}])")
 	r.reg("parseHtml", "(str | path -- {v})")
+	r.reg("parseXml", "(str | path | bytes -- {v})")
+	r.reg("xpath", "({v} str -- [t])")
+	r.reg("cssSelect", "({v} str -- [{v}])")
 	// httpGet / httpPost: the request dict requires a stringable `url`
 	// plus optional `timeout` (int), `followRedirects` (bool), `headers`
 	// ({str: str}), and `body`
@@ -584,6 +662,15 @@ func builtinSigsByName(arena *TypeArena, names *NameTable) map[NameId][]QuoteSig
 	// `join` already has a `[str] str -- str` overload; add the grid form
 	// so `g1 g2 (:k?) (:k?) join` type-checks.
 	r.add("join", "(Grid Grid (GridRow -- k) (GridRow -- k) -- Grid)")
+	// Semi and anti joins keep the left rows with and without a match.
+	for _, name := range []string{"semiJoin", "antiJoin"} {
+		r.reg(name, "(Grid | GridView Grid | GridView (GridRow -- k) (GridRow -- k) -- GridView)")
+	}
+	// Set operations compare whole rows; both sides need the same column names.
+	r.reg("distinct", "(Grid | GridView -- GridView)", "(Grid | GridView [str] -- GridView)")
+	for _, name := range []string{"except", "intersect"} {
+		r.reg(name, "(Grid | GridView Grid | GridView -- GridView)")
+	}
 
 	// ----- Zip ops -----
 	// All zip ops accept str|path for path-like args.
@@ -636,13 +723,14 @@ func builtinSigsByName(arena *TypeArena, names *NameTable) map[NameId][]QuoteSig
 	// groupBy list form: bucket by a str key.
 	r.reg("groupBy", "([t] (t -- str) -- {[t]})")
 	// groupBy grid form:
-	//   (Grid|GridView [str] [{agg: (GridView -- V)}] -- Grid)
+	//   (Grid|GridView [str] [{agg: (GridView -- V), name?: str}] -- Grid)
 	//
 	// The spec element is a shape with a required `agg` quotation that
 	// consumes the per-group GridView and produces the value placed in
-	// the new column. Width subtyping permits callers to add the optional
-	// `name` (str) and `meta` ({str: ...}) fields without listing them
-	// here; the runtime validates their types.
+	// the new column, and an optional `name` (str). Width subtyping permits
+	// callers to add the optional `meta` ({str: ...}) field without listing
+	// it here; the runtime validates its type. Named aggregations with a
+	// str `agg` are only accepted by the GridScan form below.
 	//
 	// `V` is declared as a generic on the inner `agg` quote rather than
 	// on the outer sig — a shape the sig grammar can't express — so each
@@ -674,9 +762,35 @@ func builtinSigsByName(arena *TypeArena, names *NameTable) map[NameId][]QuoteSig
 			Outputs: []TypeId{gridU},
 		})
 	}
+	// A GridScan only accepts named aggregations, which fold one column
+	// with sum, mean, min, max, count, first, or last.
+	r.add("groupBy", "(GridScan [str] [{agg: str, col?: str, name?: str}] -- Grid)")
 
 	// pivot : rowKeys, colKey, per-cell aggregation.
 	r.reg("pivot", "(Grid | GridView [str] str (GridView -- t) -- Grid)")
+	// window : partition keys, sort keys, {outputCol: spec}. A spec is a
+	// function name or a {fn, col, n} dictionary, so the values stay
+	// untyped.
+	// unpivot : id columns, name column, value column.
+	r.reg("unpivot", "(Grid | GridView [str] str str -- Grid)")
+	r.reg("window", "(Grid | GridView [str] [str] {v} -- Grid)")
+	// Null handling. A null is a none cell or a NaN float; the column list
+	// defaults to every column. fillNull's constant `value` may be of any
+	// type, so it is left to width subtyping.
+	fillNullOpts := "{strategy?: str, limit?: int}"
+	r.reg("fillNull", "(Grid | GridView "+fillNullOpts+" -- Grid)", "(Grid | GridView [str] "+fillNullOpts+" -- Grid)")
+	r.reg("dropNull", "(Grid | GridView -- GridView)", "(Grid | GridView [str] -- GridView)")
+	r.reg("isNullCol", "(Grid | GridView str -- [bool])")
+	r.reg("nullCount", "(Grid | GridView -- {int})")
+	// Statistics read numeric columns directly; none and NaN cells are
+	// skipped.
+	r.reg("describe", "(Grid | GridView -- Grid)")
+	r.reg("quantile", "(Grid | GridView str float | int -- float)")
+	r.reg("corr", "(Grid | GridView str str -- float)")
+	r.reg("histogram", "(Grid | GridView str int -- Grid)", "(Grid | GridView str [float] -- Grid)")
+	r.reg("resample",
+		"(Grid | GridView str str {str} -- Grid)",
+		"(Grid | GridView str str {str} {fillGaps?: bool} -- Grid)")
 
 	// ----- Maybe ops -----
 
@@ -690,6 +804,10 @@ func builtinSigsByName(arena *TypeArena, names *NameTable) map[NameId][]QuoteSig
 	return r.out
 }
 
+// tryErrorDictSig is the shape of the dict a `try` handler receives.
+// Keep in lockstep with TryError.ToDict.
+const tryErrorDictSig = "{message: str, line: int, column: int, exitCode: int, callStack: [{name: str, line: int, column: int, file: str}]}"
+
 // builtinSigsByToken returns sigs for ops that have dedicated lexer
 // tokens. The map values are slices so overload dispatch drives
 // token-typed builtins the same way it drives LITERAL ones.
@@ -835,6 +953,14 @@ func builtinSigsByToken(arena *TypeArena, names *NameTable) map[TokenType][]Quot
 		"(bool | int ( -- t) ( -- t) -- t)",
 	)
 
+	// TRY: body quote and handler quote. The handler starts from the
+	// stack the body started from, plus the error dict. Only thunk-shaped
+	// arms are covered here; tryTry handles arbitrary quote pairs.
+	trySigs := sigs(
+		"(( -- ) ("+tryErrorDictSig+" -- ) -- )",
+		"(( -- t) ("+tryErrorDictSig+" -- t) -- t)",
+	)
+
 	return map[TokenType][]QuoteSig{
 		PLUS:                    plusOverloads,
 		MINUS:                   minusOverloads,
@@ -861,6 +987,9 @@ func builtinSigsByToken(arena *TypeArena, names *NameTable) map[TokenType][]Quot
 		NOTEQUAL:                eqSigs,
 		QUESTION:                questionSigs,
 		IFF:                     iffSigs,
+		TRY:                     trySigs,
+		// FAIL_KEYWORD: raises an error, so like `exit` it diverges.
+		FAIL_KEYWORD: {{Inputs: []TypeId{TidStr}, Outputs: []TypeId{TidBottom}}},
 		// LOOP: pop a quote with no net stack effect.
 		LOOP: sigs("(( -- ) -- )"),
 		// BREAK / CONTINUE: no stack effect on the surrounding scope.
@@ -125,7 +125,7 @@ func (c *Checker) CheckProgram(file *MShellFile) {
 	// already recorded.
 	initial := []quoteBranch{c.initialTopBranch(c.stack.items)}
 	c.stack.items = c.stack.items[:0]
-	branches := c.driveBranchesOverItems(initial, file.Items)
+	branches := c.driveStatementItems(initial, file.Items)
 	c.reconcileTopLevelBranches(file, branches)
 	c.dedupeUnwrapDiagnostics()
 }
@@ -324,7 +324,7 @@ func (c *Checker) checkDefBody(def *MShellDefinition) {
 	initial := []quoteBranch{c.initialTopBranch(c.stack.items)}
 	c.stack.items = c.stack.items[:0]
 
-	branches := c.driveBranchesOverItems(initial, def.Items)
+	branches := c.driveStatementItems(initial, def.Items)
 
 	expected := instSig.Outputs
 	if len(branches) == 0 {
@@ -560,6 +560,8 @@ func (c *Checker) checkParseItem(item MShellParseItem) {
 		// TKOverloadedQuote so the consumption site can resolve.
 		sigs := c.InferQuoteSigItems(it.Items)
 		c.stack.Push(c.arena.MakeOverloadedQuote(sigs))
+		c.recordQuoteHint(it.StartToken, it.EndToken, c.stack.Top())
+		c.recordQuoteLit(it.StartToken)
 		return
 
 	case *MShellParsePrefixQuote:
@@ -578,6 +580,7 @@ func (c *Checker) checkParseItem(item MShellParseItem) {
 		// whose element is a shape with a free var).
 		sigs := c.InferQuoteSigItems(it.Items)
 		c.stack.Push(c.arena.MakeOverloadedQuote(sigs))
+		c.recordQuoteHint(it.StartToken, it.StartToken, c.stack.Top())
 		callTok := it.StartToken
 		callTok.Type = LITERAL
 		callTok.Lexeme = funcName
@@ -830,14 +833,16 @@ func (c *Checker) checkIfBlock(ifBlock *MShellParseIfBlock) {
 	entry := c.captureBranch()
 	var armBranches []quoteBranch
 	var armLabels []string
+	var armPos []Token
 
 	walkArm := func(body []MShellParseItem, label string) {
 		c.loadBranch(entry)
 		c.diverged = false
-		spawned := c.driveBranchesOverItems([]quoteBranch{c.captureBranch()}, body)
+		spawned := c.driveStatementItems([]quoteBranch{c.captureBranch()}, body)
 		for _, b := range spawned {
 			armBranches = append(armBranches, b)
 			armLabels = append(armLabels, label)
+			armPos = append(armPos, armStartToken(body, startTok))
 		}
 	}
 
@@ -870,10 +875,11 @@ func (c *Checker) checkIfBlock(ifBlock *MShellParseIfBlock) {
 			}
 		}
 		label := fmt.Sprintf("else if #%d", i+1)
-		spawned := c.driveBranchesOverItems([]quoteBranch{c.captureBranch()}, elseIf.Body)
+		spawned := c.driveStatementItems([]quoteBranch{c.captureBranch()}, elseIf.Body)
 		for _, b := range spawned {
 			armBranches = append(armBranches, b)
 			armLabels = append(armLabels, label)
+			armPos = append(armPos, armStartToken(elseIf.Body, startTok))
 		}
 	}
 
@@ -883,17 +889,27 @@ func (c *Checker) checkIfBlock(ifBlock *MShellParseIfBlock) {
 		// Implicit do-nothing arm: the if block may not fire at all.
 		armBranches = append(armBranches, entry)
 		armLabels = append(armLabels, "no-arm")
+		armPos = append(armPos, startTok)
 	}
 
-	c.reconcileArmBranches(armBranches, armLabels, entry, startTok)
+	c.reconcileArmBranches(armBranches, armLabels, armPos, entry, startTok)
+}
+
+// armStartToken returns the token a branch diagnostic should point at
+// for an arm body: its first item, or fallback for an empty body.
+func armStartToken(body []MShellParseItem, fallback Token) Token {
+	if len(body) == 0 {
+		return fallback
+	}
+	return body[0].GetStartToken()
 }
 
 // reconcileArmBranches takes the surviving branches from all arms of
 // an if- or match-block and chooses a post-state for the surrounding
-// walker. `labels` runs parallel to armBranches and gives each
-// branch a short source-snippet (the match arm's pattern, or the
-// if-block's arm role) used purely in the TErrBranchStackSize
-// diagnostic.
+// walker. `labels` and `positions` run parallel to armBranches and
+// give each branch a short source-snippet (the match arm's pattern, or
+// the if-block's arm role) and the token where the arm starts, used
+// purely in the TErrBranchStackSize diagnostic.
 //
 //   - 0 surviving branches → all arms errored; fall back to the
 //     pre-construct state so downstream code stays coherent.
@@ -908,7 +924,7 @@ func (c *Checker) checkIfBlock(ifBlock *MShellParseIfBlock) {
 //   - multiple branches that agree on size → fan out via
 //     branchSpawn so per-slot type differences propagate; downstream
 //     constraints may narrow them.
-func (c *Checker) reconcileArmBranches(armBranches []quoteBranch, labels []string, entry quoteBranch, startTok Token) {
+func (c *Checker) reconcileArmBranches(armBranches []quoteBranch, labels []string, positions []Token, entry quoteBranch, startTok Token) {
 	if len(armBranches) == 0 {
 		c.loadBranch(entry)
 		return
@@ -917,7 +933,7 @@ func (c *Checker) reconcileArmBranches(armBranches []quoteBranch, labels []strin
 		c.loadBranch(armBranches[0])
 		return
 	}
-	liveBranches, liveLabels := filterLiveBranchesWithLabels(armBranches, labels)
+	liveBranches, liveLabels, livePositions := filterLiveBranchesWithLabels(armBranches, labels, positions)
 	if len(liveBranches) <= 1 {
 		if len(liveBranches) == 1 {
 			c.loadBranch(liveBranches[0])
@@ -936,9 +952,10 @@ func (c *Checker) reconcileArmBranches(armBranches []quoteBranch, labels []strin
 	}
 	if !sizesAgree {
 		c.errors = append(c.errors, TypeError{
-			Kind: TErrBranchStackSize,
-			Pos:  startTok,
-			Hint: c.formatArmBranchSizes(liveBranches, liveLabels),
+			Kind:    TErrBranchStackSize,
+			Pos:     startTok,
+			Hint:    c.formatArmBranchSizes(liveBranches, liveLabels),
+			Related: armBranchSizeRelated(liveBranches, liveLabels, livePositions, startTok),
 		})
 		c.loadBranch(liveBranches[0])
 		return
@@ -1059,11 +1076,13 @@ func (c *Checker) joinArmBranches(branches []quoteBranch) {
 }
 
 // filterLiveBranchesWithLabels mirrors filterLiveBranches but also
-// keeps a parallel labels slice in sync, dropping the label of any
-// diverged branch.
-func filterLiveBranchesWithLabels(branches []quoteBranch, labels []string) ([]quoteBranch, []string) {
+// keeps the parallel labels and positions slices in sync, dropping
+// the entries of any diverged branch. A missing position is left as
+// the zero Token.
+func filterLiveBranchesWithLabels(branches []quoteBranch, labels []string, positions []Token) ([]quoteBranch, []string, []Token) {
 	live := make([]quoteBranch, 0, len(branches))
 	liveLabels := make([]string, 0, len(branches))
+	livePositions := make([]Token, 0, len(branches))
 	for i, b := range branches {
 		if b.diverged {
 			continue
@@ -1074,8 +1093,37 @@ func filterLiveBranchesWithLabels(branches []quoteBranch, labels []string) ([]qu
 			lbl = labels[i]
 		}
 		liveLabels = append(liveLabels, lbl)
+		var pos Token
+		if i < len(positions) {
+			pos = positions[i]
+		}
+		livePositions = append(livePositions, pos)
+	}
+	return live, liveLabels, livePositions
+}
+
+// armBranchSizeRelated builds one related location per live branch of
+// a TErrBranchStackSize error, naming the branch and how many stack
+// items it leaves. Branches without a known position fall back to the
+// construct's start token.
+func armBranchSizeRelated(branches []quoteBranch, labels []string, positions []Token, startTok Token) []TypeErrorRelated {
+	related := make([]TypeErrorRelated, 0, len(branches))
+	for i, b := range branches {
+		pos := startTok
+		if i < len(positions) && positions[i].Line > 0 {
+			pos = positions[i]
+		}
+		noun := "items"
+		if len(b.stack) == 1 {
+			noun = "item"
+		}
+		msg := fmt.Sprintf("Branch %d leaves %d stack %s", i+1, len(b.stack), noun)
+		if i < len(labels) && labels[i] != "" {
+			msg = fmt.Sprintf("Branch %d (%s) leaves %d stack %s", i+1, labels[i], len(b.stack), noun)
+		}
+		related = append(related, TypeErrorRelated{Pos: pos, Message: msg})
 	}
-	return live, liveLabels
+	return related
 }
 
 // formatArmBranchSizes renders one line per surviving arm-branch with
@@ -1201,6 +1249,7 @@ func (c *Checker) checkMatchBlock(matchBlock *MShellParseMatchBlock) {
 
 	var armBranches []quoteBranch
 	var armLabels []string
+	var armPos []Token
 	tags := make([]MatchArmTag, 0, len(matchBlock.Arms))
 	for _, arm := range matchBlock.Arms {
 		c.loadBranch(entry)
@@ -1233,15 +1282,16 @@ func (c *Checker) checkMatchBlock(matchBlock *MShellParseMatchBlock) {
 		}
 
 		label := truncatePatternSnippet(formatPatternSnippet(arm.Pattern))
-		spawned := c.driveBranchesOverItems([]quoteBranch{c.captureBranch()}, arm.Body)
+		spawned := c.driveStatementItems([]quoteBranch{c.captureBranch()}, arm.Body)
 		for _, b := range spawned {
 			armBranches = append(armBranches, b)
 			armLabels = append(armLabels, label)
+			armPos = append(armPos, armStartToken(arm.Pattern, startTok))
 		}
 		tags = append(tags, info.Tag)
 	}
 	c.CheckMatchExhaustive(subject, tags, startTok)
-	c.reconcileArmBranches(armBranches, armLabels, entry, startTok)
+	c.reconcileArmBranches(armBranches, armLabels, armPos, entry, startTok)
 }
 
 // armPattern is the single interpretation of a match arm pattern. One
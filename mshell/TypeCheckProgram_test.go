@@ -1085,3 +1085,70 @@ func TestTypeCheckInterpolationUnknownIdentPosition(t *testing.T) {
 		t.Fatalf("expected diagnostic at line 1, column 10; got %v", errs)
 	}
 }
+
+func TestTypeCheckProgramTryArmsAgree(t *testing.T) {
+	cases := []string{
+		`("body" wl) (:message? wl) try`,
+		`(1) (drop 2) try str wl`,
+		`("boom" fail) (e! @e :exitCode?) try str wl`,
+		`("x" fail 1) (drop 2) try str wl`,
+	}
+	for _, src := range cases {
+		errs, ok := parseAndCheck(t, src)
+		if !ok || len(errs) != 0 {
+			t.Fatalf("expected %q to type-check; errs=%v", src, errs)
+		}
+	}
+}
+
+func TestTypeCheckProgramTryArmsDisagree(t *testing.T) {
+	cases := []string{
+		// Handler leaves the error dict on the stack.
+		`(1) (2) try`,
+		// Body pushes an int, handler pushes nothing.
+		`(1) (drop) try`,
+		// Body and handler push different counts.
+		`(1 2) (drop 3) try`,
+	}
+	for _, src := range cases {
+		errs, ok := parseAndCheck(t, src)
+		if ok {
+			t.Fatalf("expected %q to fail arm reconciliation; errs=%v", src, errs)
+		}
+	}
+}
+
+func TestTypeCheckProgramFailRequiresString(t *testing.T) {
+	errs, ok := parseAndCheck(t, `1 fail`)
+	if ok {
+		t.Fatalf("expected 'fail' on an int to be rejected; errs=%v", errs)
+	}
+}
+
+func TestTypeCheckProgramParallelOutputs(t *testing.T) {
+	cases := []string{
+		// `?` pushes the exit codes in input order.
+		`[[echo a] [echo b]] parallel ? :0: str wl`,
+		// Group capture: one string per child.
+		`[[echo a] [echo b]] 2 parallel * ! (wl) each`,
+		// Both streams: stdout list, then stderr list.
+		`[[echo a] [echo b]] parallel * ^ ; (wl) each (wl) each`,
+		// A child's own capture is honored.
+		`[[echo a] * [echo b]] parallel ; (wl) each`,
+		// No captures: nothing is pushed.
+		`[[echo a] [echo b]] parallel !`,
+	}
+	for _, src := range cases {
+		errs, ok := parseAndCheck(t, src)
+		if !ok || len(errs) != 0 {
+			t.Fatalf("expected %q to type-check; errs=%v", src, errs)
+		}
+	}
+}
+
+func TestTypeCheckProgramParallelRejectsNonCommands(t *testing.T) {
+	errs, ok := parseAndCheck(t, `[1 2] parallel ;`)
+	if ok {
+		t.Fatalf("expected 'parallel' on a list of ints to be rejected; errs=%v", errs)
+	}
+}
@@ -126,6 +126,21 @@ type Checker struct {
 	listDepth int
 
 	currentFn *FnContext
+
+	// hints, when set, collects per-line stacks and quote signatures
+	// for editor inlay hints. See TypeHints.go.
+	hints *StackHints
+
+	// quoteLits maps a stack slot to the quote literal last pushed
+	// there, so `iff` and `try` can point branch diagnostics at their
+	// arms.
+	quoteLits map[int]quoteLit
+}
+
+// quoteLit is a quote literal's type and the token it starts at.
+type quoteLit struct {
+	quote TypeId
+	start Token
 }
 
 // NewChecker constructs a fresh checker with the given arena and name table.
@@ -338,6 +353,10 @@ func (c *Checker) checkOne(tok Token) {
 			if c.tryIff(tok) {
 				return
 			}
+		case TRY:
+			if c.tryTry(tok) {
+				return
+			}
 		case BREAK, CONTINUE:
 			c.diverged = true
 			return
@@ -393,6 +412,12 @@ func (c *Checker) checkOne(tok Token) {
 		if tok.Lexeme == "pivot" && c.tryPivot(tok) {
 			return
 		}
+		if tok.Lexeme == "parallel" && c.tryParallel(tok) {
+			return
+		}
+		if tok.Lexeme == "assertFails" && c.tryAssertFails() {
+			return
+		}
 		if c.tryRejectPathWrite(tok) {
 			return
 		}
@@ -536,31 +561,102 @@ func (c *Checker) tryIff(tok Token) bool {
 		})
 	}
 
+	truePos := c.quoteLitPos(c.stack.Len() - 1)
+	falsePos := truePos
+	if hasFalse {
+		truePos = c.quoteLitPos(c.stack.Len() - 2)
+	}
+
 	c.stack.items = c.stack.items[:baseLen]
 	allowedBindings := c.commonIffBindings(trueQuote, falseQuote, hasFalse)
 
 	entry := c.captureBranch()
 	var armBranches []quoteBranch
 	var armLabels []string
-	runArm := func(quote TypeId, label string, apply bool) {
+	var armPos []Token
+	runArm := func(quote TypeId, label string, pos Token, apply bool) {
 		c.loadBranch(entry)
 		if apply {
 			c.applyQuoteArm(quote, tok, allowedBindings)
 		}
 		armBranches = append(armBranches, c.captureBranch())
 		armLabels = append(armLabels, label)
+		armPos = append(armPos, pos)
 	}
 
-	runArm(trueQuote, "true", true)
+	runArm(trueQuote, "true", truePos, true)
 	if hasFalse {
-		runArm(falseQuote, "false", true)
+		runArm(falseQuote, "false", falsePos, true)
 	} else {
 		// Implicit do-nothing arm: with one quote, the false case
 		// leaves the entry state untouched.
-		runArm(TidNothing, "no-arm", false)
+		runArm(TidNothing, "no-arm", tok, false)
 	}
 
-	c.reconcileArmBranches(armBranches, armLabels, entry, tok)
+	c.reconcileArmBranches(armBranches, armLabels, armPos, entry, tok)
+	return true
+}
+
+// tryTry checks `(body) (handler) try` as two arms from the same entry
+// state: the body arm applies the body quote, the handler arm pushes the
+// error dict and applies the handler. Both arms must leave the same stack.
+func (c *Checker) tryTry(tok Token) bool {
+	if c.stack.Len() < 2 {
+		return false
+	}
+	handlerQuote := c.subst.Apply(c.arena, c.stack.items[c.stack.Len()-1])
+	bodyQuote := c.subst.Apply(c.arena, c.stack.items[c.stack.Len()-2])
+	if c.arena.Kind(handlerQuote) != TKQuote || c.arena.Kind(bodyQuote) != TKQuote {
+		return false
+	}
+	positions := []Token{c.quoteLitPos(c.stack.Len() - 2), c.quoteLitPos(c.stack.Len() - 1)}
+	c.stack.items = c.stack.items[:c.stack.Len()-2]
+	allowedBindings := c.commonIffBindings(bodyQuote, handlerQuote, true)
+	errorDict := parseBuiltinSig(c, "( -- "+tryErrorDictSig+")").Outputs[0]
+
+	entry := c.captureBranch()
+	c.applyQuoteArm(bodyQuote, tok, allowedBindings)
+	bodyBranch := c.captureBranch()
+
+	c.loadBranch(entry)
+	c.stack.Push(errorDict)
+	c.applyQuoteArm(handlerQuote, tok, allowedBindings)
+	handlerBranch := c.captureBranch()
+
+	c.reconcileArmBranches([]quoteBranch{bodyBranch, handlerBranch}, []string{"try", "handler"}, positions, entry, tok)
+	return true
+}
+
+// recordQuoteLit notes that the quote literal starting at start was just
+// pushed.
+func (c *Checker) recordQuoteLit(start Token) {
+	if c.quoteLits == nil {
+		c.quoteLits = make(map[int]quoteLit)
+	}
+	c.quoteLits[c.stack.Len()-1] = quoteLit{quote: c.stack.Top(), start: start}
+}
+
+// quoteLitPos returns the start token of the quote literal in stack slot
+// idx, or a zero Token when the slot holds a quote from somewhere else.
+func (c *Checker) quoteLitPos(idx int) Token {
+	if lit, ok := c.quoteLits[idx]; ok && lit.quote == c.stack.items[idx] {
+		return lit.start
+	}
+	return Token{}
+}
+
+// tryAssertFails checks `(quote) assertFails`. The quotation is expected to
+// fail and the stack is restored afterward, so only the quotation itself is
+// consumed; its effect is never applied.
+func (c *Checker) tryAssertFails() bool {
+	if c.stack.Len() < 1 {
+		return false
+	}
+	quote := c.subst.Apply(c.arena, c.stack.items[c.stack.Len()-1])
+	if c.arena.Kind(quote) != TKQuote {
+		return false
+	}
+	c.stack.items = c.stack.items[:c.stack.Len()-1]
 	return true
 }
 
@@ -779,6 +875,90 @@ func (c *Checker) tryPivot(tok Token) bool {
 	return true
 }
 
+// parallelBrand names the brand that wraps the argv of a `parallel` group's
+// command type, so tryExecCommand can tell it apart from a single command.
+const parallelBrand = "Parallel"
+
+// tryParallel types `parallel` on a list of command lists, with or without
+// a job limit. The result is a command whose argv is the list wrapped in the
+// Parallel brand, so `*` / `^` / redirects apply to the group like any other
+// command. Returns false when the operand is not a known list, leaving the
+// registered sigs to report the mismatch.
+func (c *Checker) tryParallel(tok Token) bool {
+	depth := 1
+	if c.stack.Len() >= 1 && c.subst.Apply(c.arena, c.stack.Top()) == TidInt {
+		depth = 2
+	}
+	if c.stack.Len() < depth {
+		return false
+	}
+	list := c.subst.Apply(c.arena, c.stack.items[c.stack.Len()-depth])
+	if c.arena.Kind(list) != TKList {
+		return false
+	}
+	for _, child := range c.parallelChildren(list) {
+		switch c.arena.Kind(child) {
+		case TKList, TKCommand, TKVar:
+		default:
+			c.errors = append(c.errors, TypeError{
+				Kind:   TErrTypeMismatch,
+				Pos:    tok,
+				Actual: child,
+				Hint:   fmt.Sprintf("parallel expects a list of command lists; found an item of type %s", FormatType(c.arena, c.names, child)),
+			})
+		}
+	}
+	c.stack.items = c.stack.items[:c.stack.Len()-depth]
+	argv := c.arena.MakeBrand(c.names.Intern(parallelBrand), list)
+	c.stack.Push(c.arena.MakeCommand(argv, CommandCaptureNone, CommandCaptureNone))
+	return true
+}
+
+// parallelChildren returns the possible item types of a parallel group's
+// list: the arms of its element type when that is a union.
+func (c *Checker) parallelChildren(list TypeId) []TypeId {
+	elem := c.subst.Apply(c.arena, TypeId(c.arena.Node(list).A))
+	if c.arena.Kind(elem) == TKUnion {
+		return c.arena.UnionMembers(elem)
+	}
+	return []TypeId{elem}
+}
+
+// parallelCapture returns the element type of the list a parallel group
+// pushes for one stream, or false when no child captures that stream. A
+// child without its own destination takes the group's; a child that ends
+// up uncaptured contributes an empty string.
+func (c *Checker) parallelCapture(list TypeId, group CommandCaptureMode, isStdout bool) (TypeId, bool) {
+	var types []TypeId
+	captured := false
+	for _, child := range c.parallelChildren(list) {
+		mode := CommandCaptureNone
+		if n := c.arena.Node(child); n.Kind == TKCommand {
+			mode = CommandCaptureMode(n.Extra)
+			if isStdout {
+				mode = CommandCaptureMode(n.B)
+			}
+		}
+		if mode == CommandCaptureNone {
+			mode = group
+		}
+		switch mode {
+		case CommandCaptureStr:
+			types, captured = append(types, TidStr), true
+		case CommandCaptureBytes:
+			types, captured = append(types, TidBytes), true
+		case CommandCaptureLines:
+			types, captured = append(types, c.arena.MakeList(TidStr)), true
+		default:
+			types = append(types, TidStr)
+		}
+	}
+	if !captured {
+		return 0, false
+	}
+	return c.arena.MakeUnion(types, 0), true
+}
+
 // commandParts decomposes a command-like operand into its argv type and
 // per-stream destination states. A plain list is a command with both
 // streams unclaimed.
@@ -1070,6 +1250,21 @@ func (c *Checker) tryExecCommand(tok Token) bool {
 		return false
 	}
 	c.stack.items = c.stack.items[:c.stack.Len()-1]
+	if argv := c.arena.Node(TypeId(n.A)); argv.Kind == TKBrand && NameId(argv.A) == c.names.Intern(parallelBrand) {
+		// A parallel group pushes one list per captured stream, then the
+		// list of exit codes for `?`.
+		list := c.subst.Apply(c.arena, TypeId(argv.B))
+		if elem, ok := c.parallelCapture(list, CommandCaptureMode(n.B), true); ok {
+			c.stack.Push(c.arena.MakeList(elem))
+		}
+		if elem, ok := c.parallelCapture(list, CommandCaptureMode(n.Extra), false); ok {
+			c.stack.Push(c.arena.MakeList(elem))
+		}
+		if tok.Type == QUESTION {
+			c.stack.Push(c.arena.MakeList(TidInt))
+		}
+		return true
+	}
 	pushCapture := func(mode CommandCaptureMode) {
 		switch mode {
 		case CommandCaptureLines:
@@ -74,6 +74,18 @@ type TypeError struct {
 	ArgIndex int    // 0-based index into the failing sig's inputs (TypeMismatch only)
 	Name     string // identifier name for UnknownIdentifier
 	Hint     string
+	// Related points at secondary source locations that explain the
+	// error, e.g. each arm of an if/match whose stack sizes disagree.
+	// Only the language server reads it, as diagnostic related
+	// information; the CLI shows the same detail through Hint, which the
+	// checker fills separately (formatArmBranchSizes for arm sizes).
+	Related []TypeErrorRelated
+}
+
+// TypeErrorRelated is a secondary location attached to a TypeError.
+type TypeErrorRelated struct {
+	Pos     Token
+	Message string
 }
 
 // Format builds a human-readable message. The arena and name table are
@@ -176,6 +188,8 @@ func FormatType(arena *TypeArena, names *NameTable, id TypeId) string {
 		return "none"
 	case TidNull:
 		return "null"
+	case TidGridScan:
+		return "GridScan"
 	case TidPath:
 		return "path"
 	case TidDateTime:
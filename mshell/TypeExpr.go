@@ -631,7 +631,7 @@ func (parser *MShellParser) applyMaybeArgs(node *TypeNamed, errs *[]TypeError) {
 // shape with that field name.
 func isPrimitiveLiteralType(lex string) bool {
 	switch lex {
-	case "bytes", "null", "Maybe", "Grid", "GridView", "GridRow":
+	case "bytes", "null", "Maybe", "Grid", "GridView", "GridRow", "GridScan":
 		return true
 	}
 	return false
@@ -740,6 +740,8 @@ func (c *Checker) resolveTypeExpr(node MShellParseItem, ctx *typeResolveCtx) Typ
 			return c.arena.MakeGridView(0)
 		case "GridRow":
 			return c.arena.MakeGridRow(0)
+		case "GridScan":
+			return TidGridScan
 		case "Maybe":
 			if len(n.Args) != 1 {
 				return TidNothing
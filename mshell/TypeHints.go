@@ -0,0 +1,136 @@
+package main
+
+// Stack-shape hints for editors. When a StackHints recorder is attached to
+// the Checker, the statement walks (top level, def bodies, if/match arms)
+// record the inferred stack after each source line, and quote literals
+// record their inferred signature. The language server turns these into
+// inlay hints; the CLI never enables the recorder.
+
+import "strings"
+
+// stackHintMaxItems caps how many stack slots a line hint renders. Deeper
+// slots are elided with a leading ellipsis so hints stay short.
+const stackHintMaxItems = 6
+
+// StackHint is one recorded hint. Offset is the rune offset just past the
+// item the hint follows.
+type StackHint struct {
+	Line   int
+	Offset int
+	Label  string
+}
+
+// StackHints collects hints during a check pass. Lines is keyed by the
+// 1-based line the hint sits on and Quotes by the quote's start offset;
+// a later record for the same key replaces an earlier one, so the stack
+// after a whole `if ... end` wins over the arm walked inside it.
+type StackHints struct {
+	Lines  map[int]StackHint
+	Quotes map[int]StackHint
+}
+
+// NewStackHints returns an empty recorder.
+func NewStackHints() *StackHints {
+	return &StackHints{
+		Lines:  make(map[int]StackHint),
+		Quotes: make(map[int]StackHint),
+	}
+}
+
+// RecordStackHints attaches a recorder to the checker. Pass nil to stop
+// recording.
+func (c *Checker) RecordStackHints(h *StackHints) {
+	c.hints = h
+}
+
+// driveStatementItems is driveBranchesOverItems for statement bodies. With
+// a recorder attached it steps one item at a time and records the stack
+// after each item that ends its source line.
+func (c *Checker) driveStatementItems(initial []quoteBranch, body []MShellParseItem) []quoteBranch {
+	if c.hints == nil {
+		return c.driveBranchesOverItems(initial, body)
+	}
+	branches := initial
+	for i, item := range body {
+		branches = c.driveBranchesOverItems(branches, body[i:i+1])
+		if len(branches) == 0 {
+			return branches
+		}
+		end := item.GetEndToken()
+		line, offset := tokenEndPosition(end)
+		if i+1 < len(body) && body[i+1].GetStartToken().Line == line {
+			continue
+		}
+		if label, ok := c.branchStackLabel(branches); ok {
+			c.hints.Lines[line] = StackHint{Line: line, Offset: offset, Label: label}
+		}
+	}
+	return branches
+}
+
+// recordQuoteHint records the inferred signature of a quote literal. at is
+// the token the hint follows.
+func (c *Checker) recordQuoteHint(start Token, at Token, quote TypeId) {
+	if c.hints == nil || start.Line == 0 {
+		return
+	}
+	line, offset := tokenEndPosition(at)
+	c.hints.Quotes[start.Start] = StackHint{
+		Line:   line,
+		Offset: offset,
+		Label:  FormatType(c.arena, c.names, quote),
+	}
+}
+
+// branchStackLabel renders the stacks of the live, non-inferring branches,
+// bottom first. Distinct renderings are joined with " | ". Quote-body
+// inference is skipped: its stacks are relative to synthesized inputs.
+func (c *Checker) branchStackLabel(branches []quoteBranch) (string, bool) {
+	saved := c.captureBranch()
+	defer c.loadBranch(saved)
+	var labels []string
+	seen := make(map[string]bool)
+	for _, b := range branches {
+		if b.diverged || b.inferring {
+			continue
+		}
+		c.loadBranch(b)
+		label := c.formatStackHint(c.stack.Snapshot())
+		if !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+	if len(labels) == 0 {
+		return "", false
+	}
+	return strings.Join(labels, " | "), true
+}
+
+// formatStackHint renders a stack as `( -- a b )` with the top on the
+// right, matching how the values were pushed.
+func (c *Checker) formatStackHint(stack []TypeId) string {
+	if len(stack) == 0 {
+		return "( -- )"
+	}
+	var sb strings.Builder
+	sb.WriteString("( --")
+	start := 0
+	if len(stack) > stackHintMaxItems {
+		start = len(stack) - stackHintMaxItems
+		sb.WriteString(" …")
+	}
+	for _, t := range stack[start:] {
+		sb.WriteByte(' ')
+		sb.WriteString(FormatType(c.arena, c.names, c.subst.Apply(c.arena, t)))
+	}
+	sb.WriteString(" )")
+	return sb.String()
+}
+
+// tokenEndPosition returns the 1-based line a token ends on and the rune
+// offset just past it. Multi-line string lexemes end below their start.
+func tokenEndPosition(tok Token) (int, int) {
+	line := tok.Line + strings.Count(tok.Lexeme, "\n")
+	return line, tok.Start + len([]rune(tok.Lexeme))
+}
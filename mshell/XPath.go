@@ -0,0 +1,934 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// queryNode wraps a node dictionary from parseHtml or parseXml with the
+// parent and sibling links that the dictionaries leave out, so xpath and
+// cssSelect can move around the tree.
+type queryNode struct {
+	dict     *MShellDict
+	parent   *queryNode
+	children []*queryNode
+	index    int // position among the parent's children
+	order    int // position in document order
+}
+
+// buildQueryTree links root and its descendants, numbering them in
+// document order.
+func buildQueryTree(root *MShellDict) *queryNode {
+	count := 0
+	var build func(dict *MShellDict, parent *queryNode, index int) *queryNode
+	build = func(dict *MShellDict, parent *queryNode, index int) *queryNode {
+		n := &queryNode{dict: dict, parent: parent, index: index, order: count}
+		count++
+		if list, ok := dict.Items["children"].(*MShellList); ok {
+			for _, item := range list.Items {
+				if child, ok := item.(*MShellDict); ok {
+					n.children = append(n.children, build(child, n, len(n.children)))
+				}
+			}
+		}
+		return n
+	}
+	return build(root, nil, 0)
+}
+
+func (n *queryNode) tag() string {
+	s, _ := n.dict.Items["tag"].(MShellString)
+	return s.Content
+}
+
+func (n *queryNode) text() string {
+	s, _ := n.dict.Items["text"].(MShellString)
+	return s.Content
+}
+
+func (n *queryNode) attr(name string) (string, bool) {
+	attrs, ok := n.dict.Items["attr"].(*MShellDict)
+	if !ok {
+		return "", false
+	}
+	value, ok := attrs.Items[name].(MShellString)
+	return value.Content, ok
+}
+
+// attrNames returns the node's attribute names in source order when the
+// node records it (parseXml does), otherwise in sorted order.
+func (n *queryNode) attrNames() []string {
+	attrs, ok := n.dict.Items["attr"].(*MShellDict)
+	if !ok {
+		return nil
+	}
+	if order, ok := n.dict.Items["attrOrder"].(*MShellList); ok && len(order.Items) == len(attrs.Items) {
+		names := make([]string, 0, len(order.Items))
+		for _, item := range order.Items {
+			name, ok := item.(MShellString)
+			if !ok {
+				break
+			}
+			names = append(names, name.Content)
+		}
+		if len(names) == len(order.Items) {
+			return names
+		}
+	}
+	names := make([]string, 0, len(attrs.Items))
+	for name := range attrs.Items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// descendants returns the nodes below n in document order.
+func (n *queryNode) descendants() []*queryNode {
+	var out []*queryNode
+	var walk func(*queryNode)
+	walk = func(node *queryNode) {
+		for _, child := range node.children {
+			out = append(out, child)
+			walk(child)
+		}
+	}
+	walk(n)
+	return out
+}
+
+// sortQueryNodes puts nodes in document order, dropping duplicates.
+func sortQueryNodes(nodes []*queryNode) []*queryNode {
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].order < nodes[j].order })
+	out := nodes[:0]
+	for i, n := range nodes {
+		if i == 0 || n != nodes[i-1] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// XPath 1.0 subset: location paths with the child, descendant, parent,
+// ancestor, sibling, self and attribute axes and their abbreviations,
+// predicates, unions, comparisons, and the common string and node-set
+// functions. An element's string value is its own text.
+
+type xpathToken struct {
+	kind byte // 'n' name, 's' string, '#' number, 'o' operator
+	text string
+	num  float64
+}
+
+func lexXPath(src string) ([]xpathToken, error) {
+	var toks []xpathToken
+	isNameStart := func(c byte) bool {
+		return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80
+	}
+	isNameChar := func(c byte) bool {
+		return isNameStart(c) || c >= '0' && c <= '9' || c == '-' || c == '.'
+	}
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(src[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string in XPath expression")
+			}
+			toks = append(toks, xpathToken{kind: 's', text: src[i+1 : i+1+end]})
+			i += end + 2
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			num, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number '%s' in XPath expression", src[start:i])
+			}
+			toks = append(toks, xpathToken{kind: '#', text: src[start:i], num: num})
+		case isNameStart(c):
+			start := i
+			for i < len(src) {
+				if isNameChar(src[i]) {
+					i++
+				} else if src[i] == ':' && i+1 < len(src) && (isNameStart(src[i+1]) || src[i+1] == '*') {
+					// A prefixed name such as svg:rect or svg:*.
+					i++
+					if src[i] == '*' {
+						i++
+						break
+					}
+				} else {
+					break
+				}
+			}
+			toks = append(toks, xpathToken{kind: 'n', text: src[start:i]})
+		default:
+			op := ""
+			for _, candidate := range []string{"//", "..", "::", "!=", "<=", ">=", "/", ".", "[", "]", "(", ")", "@", ",", "|", "=", "<", ">", "*"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character '%c' in XPath expression", c)
+			}
+			toks = append(toks, xpathToken{kind: 'o', text: op})
+			i += len(op)
+		}
+	}
+	return toks, nil
+}
+
+type xpathParser struct {
+	toks []xpathToken
+	pos  int
+}
+
+func (p *xpathParser) peekAt(offset int) xpathToken {
+	if p.pos+offset >= len(p.toks) {
+		return xpathToken{}
+	}
+	return p.toks[p.pos+offset]
+}
+
+func (p *xpathParser) isOp(text string) bool {
+	t := p.peekAt(0)
+	return t.kind == 'o' && t.text == text
+}
+
+func (p *xpathParser) isName(text string) bool {
+	t := p.peekAt(0)
+	return t.kind == 'n' && t.text == text
+}
+
+func (p *xpathParser) expectOp(text string) error {
+	if !p.isOp(text) {
+		return p.unexpected(fmt.Sprintf("'%s'", text))
+	}
+	p.pos++
+	return nil
+}
+
+func (p *xpathParser) unexpected(want string) error {
+	t := p.peekAt(0)
+	if t.kind == 0 {
+		return fmt.Errorf("expected %s at the end of the XPath expression", want)
+	}
+	return fmt.Errorf("expected %s in XPath expression, found '%s'", want, t.text)
+}
+
+// compileXPath parses an XPath expression.
+func compileXPath(src string) (xpathExpr, error) {
+	toks, err := lexXPath(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &xpathParser{toks: toks}
+	expr, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, p.unexpected("the end of the expression")
+	}
+	return expr, nil
+}
+
+// xpathPrecedence lists binary operators from loosest to tightest.
+var xpathPrecedence = [][]string{{"or"}, {"and"}, {"=", "!="}, {"<", "<=", ">", ">="}, {"|"}}
+
+func (p *xpathParser) parseBinary(level int) (xpathExpr, error) {
+	if level == len(xpathPrecedence) {
+		return p.parsePath()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, candidate := range xpathPrecedence[level] {
+			if p.isOp(candidate) || (level < 2 && p.isName(candidate)) {
+				op = candidate
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &xpathBinary{op: op, left: left, right: right}
+	}
+}
+
+var xpathAxes = map[string]bool{
+	"child": true, "descendant": true, "descendant-or-self": true, "parent": true,
+	"ancestor": true, "ancestor-or-self": true, "following-sibling": true,
+	"preceding-sibling": true, "self": true, "attribute": true,
+}
+
+func (p *xpathParser) startsStep() bool {
+	t := p.peekAt(0)
+	return t.kind == 'n' || t.kind == 'o' && (t.text == "." || t.text == ".." || t.text == "@" || t.text == "*")
+}
+
+func (p *xpathParser) parsePath() (xpathExpr, error) {
+	path := &xpathPath{}
+	t := p.peekAt(0)
+	switch {
+	case p.isOp("/"):
+		p.pos++
+		path.absolute = true
+		if !p.startsStep() {
+			return path, nil
+		}
+	case p.isOp("//"):
+		p.pos++
+		path.absolute = true
+		path.steps = append(path.steps, xpathDescendantStep())
+	case t.kind == 's' || t.kind == '#' || p.isOp("(") ||
+		(t.kind == 'n' && p.peekAt(1).kind == 'o' && p.peekAt(1).text == "(" && t.text != "node" && t.text != "text"):
+		filter, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		path.filter = filter
+		for p.isOp("[") {
+			pred, err := p.parsePredicate()
+			if err != nil {
+				return nil, err
+			}
+			path.filterPreds = append(path.filterPreds, pred)
+		}
+		if p.isOp("/") {
+			p.pos++
+		} else if p.isOp("//") {
+			p.pos++
+			path.steps = append(path.steps, xpathDescendantStep())
+		} else {
+			return path, nil
+		}
+	}
+
+	for {
+		step, err := p.parseStep()
+		if err != nil {
+			return nil, err
+		}
+		if len(path.steps) > 0 && path.steps[len(path.steps)-1].terminal {
+			return nil, fmt.Errorf("attribute and text() steps must come last in an XPath expression")
+		}
+		path.steps = append(path.steps, step)
+		if p.isOp("/") {
+			p.pos++
+		} else if p.isOp("//") {
+			p.pos++
+			path.steps = append(path.steps, xpathDescendantStep())
+		} else {
+			return path, nil
+		}
+	}
+}
+
+func xpathDescendantStep() xpathStep {
+	return xpathStep{axis: "descendant-or-self", test: "node()"}
+}
+
+func (p *xpathParser) parseStep() (xpathStep, error) {
+	if p.isOp(".") {
+		p.pos++
+		return xpathStep{axis: "self", test: "node()"}, nil
+	}
+	if p.isOp("..") {
+		p.pos++
+		return xpathStep{axis: "parent", test: "node()"}, nil
+	}
+
+	step := xpathStep{axis: "child"}
+	if p.isOp("@") {
+		p.pos++
+		step.axis = "attribute"
+	} else if t := p.peekAt(0); t.kind == 'n' && p.peekAt(1).kind == 'o' && p.peekAt(1).text == "::" {
+		if !xpathAxes[t.text] {
+			return step, fmt.Errorf("unsupported XPath axis '%s'", t.text)
+		}
+		step.axis = t.text
+		p.pos += 2
+	}
+
+	t := p.peekAt(0)
+	switch {
+	case p.isOp("*"):
+		step.test = "*"
+		p.pos++
+	case t.kind == 'n' && (t.text == "node" || t.text == "text") && p.peekAt(1).kind == 'o' && p.peekAt(1).text == "(":
+		p.pos += 2
+		if err := p.expectOp(")"); err != nil {
+			return step, err
+		}
+		step.test = t.text + "()"
+	case t.kind == 'n':
+		step.test = t.text
+		p.pos++
+	default:
+		return step, p.unexpected("a step")
+	}
+
+	for p.isOp("[") {
+		pred, err := p.parsePredicate()
+		if err != nil {
+			return step, err
+		}
+		step.preds = append(step.preds, pred)
+	}
+	step.terminal = step.axis == "attribute" || step.test == "text()"
+	if step.terminal && len(step.preds) > 0 {
+		return step, fmt.Errorf("predicates on attribute and text() steps are not supported")
+	}
+	if step.test == "text()" && step.axis != "child" {
+		return step, fmt.Errorf("text() is only supported on the child axis")
+	}
+	return step, nil
+}
+
+func (p *xpathParser) parsePredicate() (xpathExpr, error) {
+	p.pos++ // '['
+	expr, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectOp("]"); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+func (p *xpathParser) parsePrimary() (xpathExpr, error) {
+	t := p.peekAt(0)
+	switch {
+	case t.kind == 's':
+		p.pos++
+		return &xpathLiteral{value: xpathString(t.text)}, nil
+	case t.kind == '#':
+		p.pos++
+		return &xpathLiteral{value: xpathNumber(t.num)}, nil
+	case p.isOp("("):
+		p.pos++
+		expr, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+
+	call := &xpathCall{name: t.text}
+	if _, ok := xpathFunctions[call.name]; !ok {
+		return nil, fmt.Errorf("unknown XPath function '%s'", call.name)
+	}
+	p.pos += 2 // name '('
+	for !p.isOp(")") {
+		arg, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		if !p.isOp(",") {
+			break
+		}
+		p.pos++
+	}
+	if err := p.expectOp(")"); err != nil {
+		return nil, err
+	}
+	return call, nil
+}
+
+// xpathValue is a node-set, a string, a number, or a boolean. Attribute and
+// text() steps give node-sets of strings.
+type xpathValue struct {
+	kind  byte // 'S' node-set, 's' string, '#' number, 'b' boolean
+	nodes []*queryNode
+	strs  []string
+	str   string
+	num   float64
+	b     bool
+}
+
+func xpathString(s string) xpathValue  { return xpathValue{kind: 's', str: s} }
+func xpathNumber(n float64) xpathValue { return xpathValue{kind: '#', num: n} }
+func xpathBool(b bool) xpathValue      { return xpathValue{kind: 'b', b: b} }
+
+// items returns the string value of each member of a node-set.
+func (v xpathValue) items() []string {
+	out := make([]string, 0, len(v.nodes)+len(v.strs))
+	for _, n := range v.nodes {
+		out = append(out, n.text())
+	}
+	return append(out, v.strs...)
+}
+
+func (v xpathValue) toString() string {
+	switch v.kind {
+	case 'S':
+		if items := v.items(); len(items) > 0 {
+			return items[0]
+		}
+		return ""
+	case '#':
+		if v.num == math.Trunc(v.num) && !math.IsInf(v.num, 0) {
+			return strconv.FormatFloat(v.num, 'f', -1, 64)
+		}
+		return strconv.FormatFloat(v.num, 'g', -1, 64)
+	case 'b':
+		return strconv.FormatBool(v.b)
+	}
+	return v.str
+}
+
+func (v xpathValue) toNumber() float64 {
+	switch v.kind {
+	case '#':
+		return v.num
+	case 'b':
+		if v.b {
+			return 1
+		}
+		return 0
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(v.toString()), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return n
+}
+
+func (v xpathValue) toBool() bool {
+	switch v.kind {
+	case 'S':
+		return len(v.nodes)+len(v.strs) > 0
+	case '#':
+		return v.num != 0 && !math.IsNaN(v.num)
+	case 'b':
+		return v.b
+	}
+	return v.str != ""
+}
+
+type xpathContext struct {
+	node *queryNode
+	root *queryNode
+	pos  int
+	size int
+}
+
+type xpathExpr interface {
+	eval(ctx xpathContext) (xpathValue, error)
+}
+
+type xpathLiteral struct {
+	value xpathValue
+}
+
+func (e *xpathLiteral) eval(ctx xpathContext) (xpathValue, error) {
+	return e.value, nil
+}
+
+type xpathBinary struct {
+	op          string
+	left, right xpathExpr
+}
+
+func (e *xpathBinary) eval(ctx xpathContext) (xpathValue, error) {
+	left, err := e.left.eval(ctx)
+	if err != nil {
+		return xpathValue{}, err
+	}
+	switch e.op {
+	case "or":
+		if left.toBool() {
+			return xpathBool(true), nil
+		}
+	case "and":
+		if !left.toBool() {
+			return xpathBool(false), nil
+		}
+	}
+	right, err := e.right.eval(ctx)
+	if err != nil {
+		return xpathValue{}, err
+	}
+	switch e.op {
+	case "or", "and":
+		return xpathBool(right.toBool()), nil
+	case "|":
+		if left.kind != 'S' || right.kind != 'S' {
+			return xpathValue{}, fmt.Errorf("'|' needs node-sets on both sides")
+		}
+		return xpathValue{
+			kind:  'S',
+			nodes: sortQueryNodes(append(append([]*queryNode{}, left.nodes...), right.nodes...)),
+			strs:  append(append([]string{}, left.strs...), right.strs...),
+		}, nil
+	}
+	return xpathBool(xpathCompare(e.op, left, right)), nil
+}
+
+// xpathCompare applies a comparison with XPath's rules: a node-set matches
+// when any of its members does.
+func xpathCompare(op string, left, right xpathValue) bool {
+	if left.kind == 'S' || right.kind == 'S' {
+		if left.kind == 'b' || right.kind == 'b' {
+			return compareXPathScalars(op, xpathBool(left.toBool()), xpathBool(right.toBool()))
+		}
+		leftItems := []xpathValue{left}
+		if left.kind == 'S' {
+			leftItems = leftItems[:0]
+			for _, s := range left.items() {
+				leftItems = append(leftItems, xpathString(s))
+			}
+		}
+		rightItems := []xpathValue{right}
+		if right.kind == 'S' {
+			rightItems = rightItems[:0]
+			for _, s := range right.items() {
+				rightItems = append(rightItems, xpathString(s))
+			}
+		}
+		for _, l := range leftItems {
+			for _, r := range rightItems {
+				if compareXPathScalars(op, l, r) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return compareXPathScalars(op, left, right)
+}
+
+func compareXPathScalars(op string, left, right xpathValue) bool {
+	if op == "=" || op == "!=" {
+		var equal bool
+		switch {
+		case left.kind == 'b' || right.kind == 'b':
+			equal = left.toBool() == right.toBool()
+		case left.kind == '#' || right.kind == '#':
+			equal = left.toNumber() == right.toNumber()
+		default:
+			equal = left.toString() == right.toString()
+		}
+		return equal == (op == "=")
+	}
+	l, r := left.toNumber(), right.toNumber()
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	}
+	return l >= r
+}
+
+type xpathStep struct {
+	axis     string
+	test     string // a name, "prefix:*", "*", "node()", or "text()"
+	preds    []xpathExpr
+	terminal bool // attribute and text() steps select strings
+}
+
+type xpathPath struct {
+	absolute    bool
+	filter      xpathExpr
+	filterPreds []xpathExpr
+	steps       []xpathStep
+}
+
+func (e *xpathPath) eval(ctx xpathContext) (xpathValue, error) {
+	var current []*queryNode
+	switch {
+	case e.filter != nil:
+		value, err := e.filter.eval(ctx)
+		if err != nil {
+			return xpathValue{}, err
+		}
+		if len(e.filterPreds) == 0 && len(e.steps) == 0 {
+			return value, nil
+		}
+		if value.kind != 'S' || len(value.strs) > 0 {
+			return xpathValue{}, fmt.Errorf("predicates and steps need a node-set of elements")
+		}
+		current, err = applyXPathPredicates(value.nodes, e.filterPreds, ctx.root)
+		if err != nil {
+			return xpathValue{}, err
+		}
+	case e.absolute:
+		current = []*queryNode{ctx.root}
+	default:
+		current = []*queryNode{ctx.node}
+	}
+
+	for _, step := range e.steps {
+		if step.terminal {
+			return xpathValue{kind: 'S', strs: xpathTerminalStep(current, step)}, nil
+		}
+		var next []*queryNode
+		for _, n := range current {
+			var candidates []*queryNode
+			for _, c := range xpathAxisNodes(n, step.axis) {
+				if xpathNameMatches(c, step.test) {
+					candidates = append(candidates, c)
+				}
+			}
+			selected, err := applyXPathPredicates(candidates, step.preds, ctx.root)
+			if err != nil {
+				return xpathValue{}, err
+			}
+			next = append(next, selected...)
+		}
+		current = sortQueryNodes(next)
+	}
+	return xpathValue{kind: 'S', nodes: current}, nil
+}
+
+// applyXPathPredicates filters nodes, given in axis order, by each predicate
+// in turn. A number predicate selects by 1-based position.
+func applyXPathPredicates(nodes []*queryNode, preds []xpathExpr, root *queryNode) ([]*queryNode, error) {
+	for _, pred := range preds {
+		var kept []*queryNode
+		for i, n := range nodes {
+			value, err := pred.eval(xpathContext{node: n, root: root, pos: i + 1, size: len(nodes)})
+			if err != nil {
+				return nil, err
+			}
+			if value.kind == '#' {
+				if value.num == float64(i+1) {
+					kept = append(kept, n)
+				}
+			} else if value.toBool() {
+				kept = append(kept, n)
+			}
+		}
+		nodes = kept
+	}
+	return nodes, nil
+}
+
+func xpathTerminalStep(nodes []*queryNode, step xpathStep) []string {
+	out := []string{}
+	for _, n := range nodes {
+		if step.test == "text()" {
+			if text := n.text(); text != "" {
+				out = append(out, text)
+			}
+			continue
+		}
+		for _, name := range n.attrNames() {
+			if step.test == "*" || step.test == "node()" || name == step.test ||
+				strings.HasSuffix(step.test, ":*") && strings.HasPrefix(name, strings.TrimSuffix(step.test, "*")) {
+				value, _ := n.attr(name)
+				out = append(out, value)
+			}
+		}
+	}
+	return out
+}
+
+// xpathAxisNodes returns the nodes on an axis from n, nearest first.
+func xpathAxisNodes(n *queryNode, axis string) []*queryNode {
+	switch axis {
+	case "child":
+		return n.children
+	case "descendant":
+		return n.descendants()
+	case "descendant-or-self":
+		return append([]*queryNode{n}, n.descendants()...)
+	case "parent":
+		if n.parent != nil {
+			return []*queryNode{n.parent}
+		}
+	case "ancestor", "ancestor-or-self":
+		var out []*queryNode
+		if axis == "ancestor-or-self" {
+			out = append(out, n)
+		}
+		for a := n.parent; a != nil; a = a.parent {
+			out = append(out, a)
+		}
+		return out
+	case "following-sibling":
+		if n.parent != nil {
+			return n.parent.children[n.index+1:]
+		}
+	case "preceding-sibling":
+		var out []*queryNode
+		if n.parent != nil {
+			for i := n.index - 1; i >= 0; i-- {
+				out = append(out, n.parent.children[i])
+			}
+		}
+		return out
+	case "self":
+		return []*queryNode{n}
+	}
+	return nil
+}
+
+// xpathNameMatches applies a node test to an element. The document node,
+// with its empty tag, only matches node().
+func xpathNameMatches(n *queryNode, test string) bool {
+	tag := n.tag()
+	switch {
+	case test == "node()":
+		return true
+	case tag == "":
+		return false
+	case test == "*":
+		return true
+	case strings.HasSuffix(test, ":*"):
+		return strings.HasPrefix(tag, strings.TrimSuffix(test, "*"))
+	}
+	return tag == test
+}
+
+type xpathCall struct {
+	name string
+	args []xpathExpr
+}
+
+// xpathFunctions maps each function to its minimum and maximum argument
+// counts; -1 means any number.
+var xpathFunctions = map[string][2]int{
+	"position": {0, 0}, "last": {0, 0}, "count": {1, 1}, "not": {1, 1},
+	"true": {0, 0}, "false": {0, 0}, "boolean": {1, 1}, "string": {0, 1},
+	"number": {0, 1}, "concat": {2, -1}, "contains": {2, 2}, "starts-with": {2, 2},
+	"ends-with": {2, 2}, "normalize-space": {0, 1}, "string-length": {0, 1},
+	"name": {0, 1}, "local-name": {0, 1},
+}
+
+func (e *xpathCall) eval(ctx xpathContext) (xpathValue, error) {
+	limits := xpathFunctions[e.name]
+	if len(e.args) < limits[0] || (limits[1] >= 0 && len(e.args) > limits[1]) {
+		return xpathValue{}, fmt.Errorf("wrong number of arguments to %s()", e.name)
+	}
+	args := make([]xpathValue, len(e.args))
+	for i, arg := range e.args {
+		value, err := arg.eval(ctx)
+		if err != nil {
+			return xpathValue{}, err
+		}
+		args[i] = value
+	}
+	// Functions of one optional argument default to the context node.
+	self := xpathValue{kind: 'S', nodes: []*queryNode{ctx.node}}
+	if len(args) == 0 {
+		args = append(args, self)
+	}
+
+	switch e.name {
+	case "position":
+		return xpathNumber(float64(ctx.pos)), nil
+	case "last":
+		return xpathNumber(float64(ctx.size)), nil
+	case "count":
+		if args[0].kind != 'S' {
+			return xpathValue{}, fmt.Errorf("count() needs a node-set")
+		}
+		return xpathNumber(float64(len(args[0].nodes) + len(args[0].strs))), nil
+	case "not":
+		return xpathBool(!args[0].toBool()), nil
+	case "true":
+		return xpathBool(true), nil
+	case "false":
+		return xpathBool(false), nil
+	case "boolean":
+		return xpathBool(args[0].toBool()), nil
+	case "string":
+		return xpathString(args[0].toString()), nil
+	case "number":
+		return xpathNumber(args[0].toNumber()), nil
+	case "concat":
+		var sb strings.Builder
+		for _, arg := range args {
+			sb.WriteString(arg.toString())
+		}
+		return xpathString(sb.String()), nil
+	case "contains":
+		return xpathBool(strings.Contains(args[0].toString(), args[1].toString())), nil
+	case "starts-with":
+		return xpathBool(strings.HasPrefix(args[0].toString(), args[1].toString())), nil
+	case "ends-with":
+		return xpathBool(strings.HasSuffix(args[0].toString(), args[1].toString())), nil
+	case "normalize-space":
+		return xpathString(strings.Join(strings.Fields(args[0].toString()), " ")), nil
+	case "string-length":
+		return xpathNumber(float64(len([]rune(args[0].toString())))), nil
+	case "name", "local-name":
+		if args[0].kind != 'S' {
+			return xpathValue{}, fmt.Errorf("%s() needs a node-set", e.name)
+		}
+		if len(args[0].nodes) == 0 {
+			return xpathString(""), nil
+		}
+		name := args[0].nodes[0].tag()
+		if e.name == "local-name" {
+			name = name[strings.IndexByte(name, ':')+1:]
+		}
+		return xpathString(name), nil
+	}
+	return xpathValue{}, fmt.Errorf("unknown XPath function '%s'", e.name)
+}
+
+// evalXPath runs expr against the tree under root. The root is the node a
+// leading '/' refers to. Node-sets of elements become their dictionaries,
+// attribute and text() selections become strings, and any other result is
+// returned as a one-item list.
+func evalXPath(root *MShellDict, expr string) (*MShellList, error) {
+	compiled, err := compileXPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	tree := buildQueryTree(root)
+	value, err := compiled.eval(xpathContext{node: tree, root: tree, pos: 1, size: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewList(0)
+	switch value.kind {
+	case 'S':
+		for _, n := range value.nodes {
+			result.Items = append(result.Items, n.dict)
+		}
+		for _, s := range value.strs {
+			result.Items = append(result.Items, MShellString{Content: s})
+		}
+	case 's':
+		result.Items = append(result.Items, MShellString{Content: value.str})
+	case 'b':
+		result.Items = append(result.Items, MShellBool{Value: value.b})
+	case '#':
+		if value.num == math.Trunc(value.num) && math.Abs(value.num) < 1<<53 {
+			result.Items = append(result.Items, MShellInt{Value: int(value.num)})
+		} else {
+			result.Items = append(result.Items, MShellFloat{Value: value.num})
+		}
+	}
+	return result, nil
+}
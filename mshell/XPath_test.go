@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func xpathTestDoc(t *testing.T) *MShellDict {
+	t.Helper()
+	doc, err := parseXml(strings.NewReader(`<catalog>
+  <item id="a1" type="tool"><name>Hammer</name><price>12.5</price></item>
+  <item id="b2" type="part"><name>Nail</name><price>0.1</price></item>
+  <group><item id="c3" type="tool"><name>Saw</name><price>30</price></item></group>
+</catalog>`))
+	if err != nil {
+		t.Fatalf("parseXml error: %v", err)
+	}
+	return doc
+}
+
+func TestEvalXPath(t *testing.T) {
+	doc := xpathTestDoc(t)
+	for _, tt := range []struct {
+		expr string
+		want string
+	}{
+		{"/catalog/item/@id", `["a1", "b2"]`},
+		{"//item/@id", `["a1", "b2", "c3"]`},
+		{"//item[@type='tool']/name/text()", `["Hammer", "Saw"]`},
+		{"//item[price > 10 and not(@id='c3')]/@id", `["a1"]`},
+		{"//item[2]/@id", `["b2"]`},
+		{"(//item)[last()]/@id", `["c3"]`},
+		{"//name[.='Saw']/../@id", `["c3"]`},
+		{"//item[starts-with(name, 'N')]/following-sibling::*/@id", `[]`},
+		{"//item[1]/following-sibling::item/@id", `["b2"]`},
+		{"//price/ancestor::group/item/@type", `["tool"]`},
+		{"count(//item[@type='tool'])", `[2]`},
+		{"string(//item[@id='b2']/name)", `["Nail"]`},
+		{"//item/@id | //group/@*", `["a1", "b2", "c3"]`},
+	} {
+		result, err := evalXPath(doc, tt.expr)
+		if err != nil {
+			t.Errorf("evalXPath(%q) error: %v", tt.expr, err)
+			continue
+		}
+		if got := result.ToJson(); got != tt.want {
+			t.Errorf("evalXPath(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+
+	nodes, err := evalXPath(doc, "//group/*")
+	if err != nil {
+		t.Fatalf("evalXPath error: %v", err)
+	}
+	group := doc.Items["children"].(*MShellList).Items[0].(*MShellDict).Items["children"].(*MShellList).Items[2].(*MShellDict)
+	if len(nodes.Items) != 1 || nodes.Items[0] != group.Items["children"].(*MShellList).Items[0] {
+		t.Errorf("//group/* should return the node dictionary itself, got %s", nodes.ToJson())
+	}
+}
+
+func TestCompileXPathErrors(t *testing.T) {
+	for _, tt := range []struct {
+		expr string
+		want string
+	}{
+		{"//item[", "at the end"},
+		{"//item/@id/name", "must come last"},
+		{"following::item", "unsupported XPath axis"},
+		{"frobnicate(1)", "unknown XPath function"},
+		{"//item[@id='a1]", "unterminated string"},
+	} {
+		if _, err := compileXPath(tt.expr); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("compileXPath(%q) error = %v, want one containing %q", tt.expr, err, tt.want)
+		}
+	}
+}
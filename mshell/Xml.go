@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+const xmlNamespaceURI = "http://www.w3.org/XML/1998/namespace"
+
+// newXmlNodeDict creates a node dictionary in the shape nodeToDict gives
+// parseHtml, plus the element's namespace URI under ns and its attribute
+// names in source order under attrOrder.
+func newXmlNodeDict(tag string, ns string) *MShellDict {
+	d := NewDict()
+	d.Items["tag"] = MShellString{Content: tag}
+	d.Items["attr"] = NewDict()
+	d.Items["attrOrder"] = NewList(0)
+	d.Items["children"] = NewList(0)
+	d.Items["text"] = MShellString{Content: ""}
+	d.Items["ns"] = MShellString{Content: ns}
+	return d
+}
+
+// xmlQualifiedName writes a name as it appeared in the source, with its
+// prefix.
+func xmlQualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// parseXml reads a well-formed XML document into node dictionaries. The
+// result is a document node with an empty tag whose one child is the root
+// element, as parseHtml returns. Tags and attribute names keep their
+// prefixes; each element's text is its own character data, CDATA sections
+// included, trimmed at both ends. Comments and processing instructions are
+// dropped.
+func parseXml(r io.Reader) (*MShellDict, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+
+	type xmlFrame struct {
+		node  *MShellDict
+		name  xml.Name
+		text  strings.Builder
+		scope map[string]string
+	}
+	doc := newXmlNodeDict("", "")
+	stack := []*xmlFrame{{node: doc, scope: map[string]string{"xml": xmlNamespaceURI}}}
+	sawRoot := false
+
+	for {
+		tok, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if len(stack) == 1 {
+				if sawRoot {
+					return nil, fmt.Errorf("line %d: more than one root element", xmlLine(decoder))
+				}
+				sawRoot = true
+			}
+
+			scope := top.scope
+			copied := false
+			for _, attr := range tok.Attr {
+				if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					if !copied {
+						copied = true
+						scope = make(map[string]string, len(top.scope)+1)
+						for prefix, uri := range top.scope {
+							scope[prefix] = uri
+						}
+					}
+					if attr.Name.Space == "xmlns" {
+						scope[attr.Name.Local] = attr.Value
+					} else {
+						scope[""] = attr.Value
+					}
+				}
+			}
+			ns, ok := scope[tok.Name.Space]
+			if !ok && tok.Name.Space != "" {
+				return nil, fmt.Errorf("line %d: undeclared namespace prefix '%s'", xmlLine(decoder), tok.Name.Space)
+			}
+
+			node := newXmlNodeDict(xmlQualifiedName(tok.Name), ns)
+			attrs := node.Items["attr"].(*MShellDict)
+			attrOrder := node.Items["attrOrder"].(*MShellList)
+			for _, attr := range tok.Attr {
+				key := xmlQualifiedName(attr.Name)
+				if _, dup := attrs.Items[key]; dup {
+					return nil, fmt.Errorf("line %d: attribute '%s' appears more than once", xmlLine(decoder), key)
+				}
+				attrs.Items[key] = MShellString{Content: attr.Value}
+				attrOrder.Items = append(attrOrder.Items, MShellString{Content: key})
+			}
+			children := top.node.Items["children"].(*MShellList)
+			children.Items = append(children.Items, node)
+			stack = append(stack, &xmlFrame{node: node, name: tok.Name, scope: scope})
+		case xml.EndElement:
+			if len(stack) == 1 || tok.Name != top.name {
+				return nil, fmt.Errorf("line %d: unexpected end element </%s>", xmlLine(decoder), xmlQualifiedName(tok.Name))
+			}
+			top.node.Items["text"] = MShellString{Content: strings.TrimSpace(top.text.String())}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) == 1 {
+				if strings.TrimSpace(string(tok)) != "" {
+					return nil, fmt.Errorf("line %d: text outside the root element", xmlLine(decoder))
+				}
+				continue
+			}
+			top.text.Write(tok)
+		}
+	}
+
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("element <%s> is never closed", xmlQualifiedName(stack[len(stack)-1].name))
+	}
+	if !sawRoot {
+		return nil, fmt.Errorf("no root element")
+	}
+	return doc, nil
+}
+
+func xmlLine(decoder *xml.Decoder) int {
+	line, _ := decoder.InputPos()
+	return line
+}
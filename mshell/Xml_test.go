@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseXmlNamespacesAndCData(t *testing.T) {
+	src := `<?xml version="1.0"?>
+<!-- feed -->
+<feed xmlns="urn:feed" xmlns:p="urn:price">
+  <item id="a1" p:currency="USD">
+    <p:price>12.50</p:price>
+    <note><![CDATA[ a <b> & c ]]></note>
+  </item>
+</feed>`
+	doc, err := parseXml(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parseXml error: %v", err)
+	}
+	if tag := doc.Items["tag"].(MShellString).Content; tag != "" {
+		t.Errorf("document tag = %q, want empty", tag)
+	}
+	root := doc.Items["children"].(*MShellList).Items[0].(*MShellDict)
+	if ns := root.Items["ns"].(MShellString).Content; ns != "urn:feed" {
+		t.Errorf("root ns = %q, want urn:feed", ns)
+	}
+
+	item := root.Items["children"].(*MShellList).Items[0].(*MShellDict)
+	if got := item.Items["attr"].(*MShellDict).Items["p:currency"].(MShellString).Content; got != "USD" {
+		t.Errorf("p:currency = %q, want USD", got)
+	}
+	children := item.Items["children"].(*MShellList).Items
+	price := children[0].(*MShellDict)
+	if tag, ns := price.Items["tag"].(MShellString).Content, price.Items["ns"].(MShellString).Content; tag != "p:price" || ns != "urn:price" {
+		t.Errorf("price tag, ns = %q, %q, want p:price, urn:price", tag, ns)
+	}
+	if text := children[1].(*MShellDict).Items["text"].(MShellString).Content; text != "a <b> & c" {
+		t.Errorf("CDATA text = %q, want \"a <b> & c\"", text)
+	}
+}
+
+func TestParseXmlAttributeOrder(t *testing.T) {
+	doc, err := parseXml(strings.NewReader(`<svg><rect y="2" x="1" width="3" fill="red" data-id="r"/></svg>`))
+	if err != nil {
+		t.Fatalf("parseXml error: %v", err)
+	}
+	svg := doc.Items["children"].(*MShellList).Items[0].(*MShellDict)
+	if got := svg.Items["attrOrder"].(*MShellList).ToJson(); got != "[]" {
+		t.Errorf("svg attrOrder = %s, want []", got)
+	}
+	rect := svg.Items["children"].(*MShellList).Items[0].(*MShellDict)
+	want := `["y", "x", "width", "fill", "data-id"]`
+	if got := rect.Items["attrOrder"].(*MShellList).ToJson(); got != want {
+		t.Errorf("rect attrOrder = %s, want %s", got, want)
+	}
+	values, err := evalXPath(doc, "//rect/@*")
+	if err != nil {
+		t.Fatalf("evalXPath error: %v", err)
+	}
+	if got := values.ToJson(); got != `["2", "1", "3", "red", "r"]` {
+		t.Errorf("//rect/@* = %s, want the values in source order", got)
+	}
+}
+
+func TestParseXmlErrors(t *testing.T) {
+	for _, tt := range []struct {
+		src  string
+		want string
+	}{
+		{"<a><b></a>", "unexpected end element </a>"},
+		{"<a></a><b></b>", "more than one root element"},
+		{"<a>", "<a> is never closed"},
+		{"<x:a></x:a>", "undeclared namespace prefix 'x'"},
+		{"<a/> trailing", "text outside the root element"},
+		{"<a b='1' b='2'/>", "attribute 'b' appears more than once"},
+		{"", "no root element"},
+	} {
+		if _, err := parseXml(strings.NewReader(tt.src)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseXml(%q) error = %v, want one containing %q", tt.src, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,1152 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// parseYaml reads the YAML 1.2 used in configuration files: block and flow
+// collections, plain, quoted and block scalars, anchors, aliases and merge
+// keys. Plain scalars resolve with the core schema, plus timestamps, so
+// 'on' and 'yes' stay strings. Complex '?' keys are not supported.
+
+type yamlParser struct {
+	src     string
+	pos     int
+	anchors map[string]MShellObject
+}
+
+// parseYamlDocuments returns every document in the stream, in order.
+func parseYamlDocuments(src string) ([]MShellObject, error) {
+	src = strings.TrimPrefix(strings.ReplaceAll(src, "\r\n", "\n"), "\ufeff")
+	p := &yamlParser{src: src, anchors: make(map[string]MShellObject)}
+	docs := []MShellObject{}
+	for {
+		p.skipBlank()
+		for !p.eof() && p.col() == 0 && p.peek() == '%' {
+			p.skipToLineEnd()
+			p.skipBlank()
+		}
+		if p.eof() {
+			break
+		}
+		if p.atMarker("...") {
+			p.pos += 3
+			continue
+		}
+		if p.atMarker("---") {
+			p.pos += 3
+			p.skipBlank()
+		}
+
+		var doc MShellObject = MShellNull{}
+		if !p.eof() && !p.atMarker("---") && !p.atMarker("...") {
+			var err error
+			doc, err = p.parseBlockNode(-1)
+			if err != nil {
+				return nil, err
+			}
+		}
+		docs = append(docs, doc)
+
+		p.skipBlank()
+		if p.atMarker("...") {
+			p.pos += 3
+		} else if !p.eof() && !p.atMarker("---") {
+			return nil, p.errorf("unexpected content after the document")
+		}
+	}
+	return docs, nil
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	line := strings.Count(p.src[:p.pos], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *yamlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *yamlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *yamlParser) peekAt(i int) byte {
+	if i >= len(p.src) {
+		return 0
+	}
+	return p.src[i]
+}
+
+// col returns the zero-based column of the current position.
+func (p *yamlParser) col() int {
+	return p.pos - (strings.LastIndexByte(p.src[:p.pos], '\n') + 1)
+}
+
+// isBreakOrSpace reports whether position i holds a space, tab, newline, or
+// is past the end of the input.
+func (p *yamlParser) isBreakOrSpace(i int) bool {
+	c := p.peekAt(i)
+	return c == 0 || c == ' ' || c == '\t' || c == '\n'
+}
+
+func (p *yamlParser) atMarker(marker string) bool {
+	return p.col() == 0 && strings.HasPrefix(p.src[p.pos:], marker) && p.isBreakOrSpace(p.pos+3)
+}
+
+func (p *yamlParser) atDocumentBoundary() bool {
+	return p.eof() || p.atMarker("---") || p.atMarker("...")
+}
+
+func (p *yamlParser) skipToLineEnd() {
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+}
+
+func (p *yamlParser) skipInlineSpace() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+// skipBlank moves past spaces, comments and line breaks to the next content.
+func (p *yamlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\n':
+			p.pos++
+		case '#':
+			p.skipToLineEnd()
+		default:
+			return
+		}
+	}
+}
+
+// atLineEnd reports whether only a comment or nothing is left on the line.
+func (p *yamlParser) atLineEnd() bool {
+	return p.eof() || p.peek() == '\n' || p.peek() == '#'
+}
+
+// parseProperties reads an optional anchor and tag in either order.
+func (p *yamlParser) parseProperties() (anchor string, tag string) {
+	for {
+		switch p.peek() {
+		case '&':
+			start := p.pos + 1
+			p.pos++
+			for !p.isBreakOrSpace(p.pos) && !strings.ContainsRune(",[]{}", rune(p.peek())) {
+				p.pos++
+			}
+			anchor = p.src[start:p.pos]
+		case '!':
+			start := p.pos
+			for !p.isBreakOrSpace(p.pos) {
+				p.pos++
+			}
+			tag = p.src[start:p.pos]
+		default:
+			return anchor, tag
+		}
+		p.skipInlineSpace()
+	}
+}
+
+// parseBlockNode parses the node at the current position, which must be
+// indented more than parent to belong to it.
+func (p *yamlParser) parseBlockNode(parent int) (MShellObject, error) {
+	if p.atDocumentBoundary() || p.col() <= parent {
+		return MShellNull{}, nil
+	}
+	anchor, tag := p.parseProperties()
+	if anchor != "" || tag != "" {
+		if p.atLineEnd() {
+			p.skipBlank()
+			if p.atDocumentBoundary() || p.col() <= parent {
+				return p.finishNode(anchor, yamlScalar("", false, tag))
+			}
+		}
+	}
+
+	var node MShellObject
+	var err error
+	c := p.peek()
+	switch {
+	case c == '*':
+		node, err = p.parseAlias()
+	case c == '-' && p.isBreakOrSpace(p.pos+1):
+		node, err = p.parseBlockSequence(p.col())
+	case c == '?' && p.isBreakOrSpace(p.pos+1):
+		return nil, p.errorf("complex mapping keys are not supported")
+	case c == '|' || c == '>':
+		var text string
+		text, err = p.parseBlockScalar(parent)
+		node = MShellString{Content: text}
+	case p.lineHasMappingKey():
+		node, err = p.parseBlockMapping(p.col())
+	case c == '[' || c == '{':
+		node, err = p.parseFlowNode()
+	case c == '"' || c == '\'':
+		var text string
+		text, err = p.parseQuoted()
+		node = yamlScalar(text, true, tag)
+	default:
+		node = yamlScalar(p.parsePlainBlock(parent), false, tag)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.finishNode(anchor, node)
+}
+
+func (p *yamlParser) finishNode(anchor string, node MShellObject) (MShellObject, error) {
+	if anchor != "" {
+		p.anchors[anchor] = node
+	}
+	return node, nil
+}
+
+func (p *yamlParser) parseAlias() (MShellObject, error) {
+	p.pos++
+	start := p.pos
+	for !p.isBreakOrSpace(p.pos) && !strings.ContainsRune(",[]{}", rune(p.peek())) {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	node, ok := p.anchors[name]
+	if !ok {
+		return nil, p.errorf("unknown alias '*%s'", name)
+	}
+	return copyYamlValue(node), nil
+}
+
+// copyYamlValue copies collections so that editing one alias of an anchor
+// does not change the others.
+func copyYamlValue(obj MShellObject) MShellObject {
+	switch typed := obj.(type) {
+	case *MShellList:
+		items := make([]MShellObject, len(typed.Items))
+		for i, item := range typed.Items {
+			items[i] = copyYamlValue(item)
+		}
+		return &MShellList{Items: items}
+	case *MShellDict:
+		dict := NewDict()
+		for key, value := range typed.Items {
+			dict.Items[key] = copyYamlValue(value)
+		}
+		return dict
+	}
+	return obj
+}
+
+// lineHasMappingKey reports whether the current line starts with 'key:'.
+func (p *yamlParser) lineHasMappingKey() bool {
+	i := p.pos
+	switch p.peek() {
+	case '"', '\'':
+		quote := p.peek()
+		i++
+		for ; i < len(p.src) && p.src[i] != '\n'; i++ {
+			if quote == '"' && p.src[i] == '\\' {
+				i++
+			} else if p.src[i] == quote {
+				if quote == '\'' && p.peekAt(i+1) == '\'' {
+					i++
+					continue
+				}
+				break
+			}
+		}
+		if p.peekAt(i) != quote {
+			return false
+		}
+		i++
+		for p.peekAt(i) == ' ' || p.peekAt(i) == '\t' {
+			i++
+		}
+		return p.peekAt(i) == ':' && p.isBreakOrSpace(i+1)
+	case '[', '{', '#', '|', '>', '*', '&', '!':
+		return false
+	}
+	for ; i < len(p.src) && p.src[i] != '\n'; i++ {
+		if p.src[i] == ':' && p.isBreakOrSpace(i+1) {
+			return true
+		}
+		if p.src[i] == '#' && i > p.pos && (p.src[i-1] == ' ' || p.src[i-1] == '\t') {
+			return false
+		}
+	}
+	return false
+}
+
+// parseMappingKey reads a key and the colon after it.
+func (p *yamlParser) parseMappingKey() (string, error) {
+	var key string
+	if p.peek() == '"' || p.peek() == '\'' {
+		text, err := p.parseQuoted()
+		if err != nil {
+			return "", err
+		}
+		key = text
+		p.skipInlineSpace()
+	} else {
+		start := p.pos
+		for !p.eof() && !(p.peek() == ':' && p.isBreakOrSpace(p.pos+1)) {
+			p.pos++
+		}
+		key = strings.TrimRight(p.src[start:p.pos], " \t")
+	}
+	if p.peek() != ':' {
+		return "", p.errorf("expected ':' after key '%s'", key)
+	}
+	p.pos++
+	return key, nil
+}
+
+func (p *yamlParser) parseBlockMapping(indent int) (MShellObject, error) {
+	dict := NewDict()
+	var merges []MShellObject
+	for {
+		key, err := p.parseMappingKey()
+		if err != nil {
+			return nil, err
+		}
+		p.skipInlineSpace()
+
+		var value MShellObject = MShellNull{}
+		if p.atLineEnd() {
+			p.skipBlank()
+			if !p.atDocumentBoundary() && p.col() == indent && p.peek() == '-' && p.isBreakOrSpace(p.pos+1) {
+				// A sequence may sit at the same indentation as its key.
+				value, err = p.parseBlockSequence(indent)
+			} else {
+				value, err = p.parseBlockNode(indent)
+			}
+		} else if p.lineHasMappingKey() {
+			return nil, p.errorf("a mapping value cannot start another mapping on the same line")
+		} else if p.peek() == '-' && p.isBreakOrSpace(p.pos+1) {
+			return nil, p.errorf("a mapping value cannot start a block sequence on the same line")
+		} else {
+			value, err = p.parseBlockNode(indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if key == "<<" {
+			merges = append(merges, value)
+		} else {
+			if _, exists := dict.Items[key]; exists {
+				return nil, p.errorf("duplicate key '%s'", key)
+			}
+			dict.Items[key] = value
+		}
+
+		p.skipBlank()
+		if p.atDocumentBoundary() || p.col() < indent {
+			break
+		}
+		if p.col() > indent {
+			return nil, p.errorf("bad indentation of a mapping entry")
+		}
+		if !p.lineHasMappingKey() {
+			return nil, p.errorf("expected a mapping key")
+		}
+	}
+
+	// Explicit keys take precedence over merged ones, whatever their order.
+	for _, merge := range merges {
+		sources := []MShellObject{merge}
+		if list, ok := merge.(*MShellList); ok {
+			sources = list.Items
+		}
+		for _, source := range sources {
+			sourceDict, ok := source.(*MShellDict)
+			if !ok {
+				return nil, p.errorf("merge key '<<' expects a mapping or a list of mappings")
+			}
+			for key, value := range sourceDict.Items {
+				if _, exists := dict.Items[key]; !exists {
+					dict.Items[key] = value
+				}
+			}
+		}
+	}
+	return dict, nil
+}
+
+func (p *yamlParser) parseBlockSequence(indent int) (MShellObject, error) {
+	list := &MShellList{Items: []MShellObject{}}
+	for {
+		p.pos++ // '-'
+		p.skipInlineSpace()
+		if p.atLineEnd() {
+			p.skipBlank()
+		}
+		item, err := p.parseBlockNode(indent)
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, item)
+
+		p.skipBlank()
+		if p.atDocumentBoundary() || p.col() < indent {
+			break
+		}
+		if p.col() > indent {
+			return nil, p.errorf("bad indentation of a sequence entry")
+		}
+		if !(p.peek() == '-' && p.isBreakOrSpace(p.pos+1)) {
+			break
+		}
+	}
+	return list, nil
+}
+
+// cutComment removes a trailing ' #' comment and surrounding spaces.
+func cutYamlComment(line string) string {
+	for i := 1; i < len(line); i++ {
+		if line[i] == '#' && (line[i-1] == ' ' || line[i-1] == '\t') {
+			line = line[:i]
+			break
+		}
+	}
+	return strings.Trim(line, " \t")
+}
+
+// parsePlainBlock reads a plain scalar, folding continuation lines that are
+// indented more than parent.
+func (p *yamlParser) parsePlainBlock(parent int) string {
+	start := p.pos
+	p.skipToLineEnd()
+	text := cutYamlComment(p.src[start:p.pos])
+
+	for !p.eof() {
+		lineEnd := p.pos
+		empties := 0
+		i := p.pos + 1
+		var next string
+		for i < len(p.src) {
+			end := strings.IndexByte(p.src[i:], '\n')
+			if end < 0 {
+				end = len(p.src)
+			} else {
+				end += i
+			}
+			line := p.src[i:end]
+			if strings.Trim(line, " \t") == "" {
+				empties++
+				i = end + 1
+				continue
+			}
+			next = line
+			break
+		}
+		indent := len(next) - len(strings.TrimLeft(next, " "))
+		content := strings.TrimLeft(next, " \t")
+		if next == "" || indent <= parent || strings.HasPrefix(content, "#") ||
+			(indent == 0 && (strings.HasPrefix(next, "---") || strings.HasPrefix(next, "..."))) {
+			p.pos = lineEnd
+			break
+		}
+		if strings.Contains(content, ": ") || strings.HasSuffix(content, ":") || strings.HasPrefix(content, "- ") {
+			p.pos = lineEnd
+			break
+		}
+		if empties == 0 {
+			text += " "
+		} else {
+			text += strings.Repeat("\n", empties)
+		}
+		p.pos = i + len(next)
+		text += cutYamlComment(content)
+	}
+	return text
+}
+
+// parseQuoted reads a single- or double-quoted scalar, which may span lines.
+func (p *yamlParser) parseQuoted() (string, error) {
+	quote := p.peek()
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated quoted string")
+		}
+		c := p.peek()
+		switch {
+		case c == quote && quote == '\'' && p.peekAt(p.pos+1) == '\'':
+			sb.WriteByte('\'')
+			p.pos += 2
+		case c == quote:
+			p.pos++
+			return sb.String(), nil
+		case c == '\\' && quote == '"':
+			if p.peekAt(p.pos+1) == '\n' {
+				// An escaped line break joins the lines without a space.
+				p.pos += 2
+				p.skipInlineSpace()
+				continue
+			}
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+		case c == '\n' || ((c == ' ' || c == '\t') && p.foldsHere()):
+			trimmed := strings.TrimRight(sb.String(), " \t")
+			sb.Reset()
+			sb.WriteString(trimmed)
+			p.skipInlineSpace()
+			breaks := 0
+			for p.peek() == '\n' {
+				breaks++
+				p.pos++
+				p.skipInlineSpace()
+			}
+			if breaks == 1 {
+				sb.WriteByte(' ')
+			} else if breaks > 1 {
+				sb.WriteString(strings.Repeat("\n", breaks-1))
+			}
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// foldsHere reports whether the whitespace at the current position runs up
+// to a line break, so it is trimmed by line folding.
+func (p *yamlParser) foldsHere() bool {
+	i := p.pos
+	for p.peekAt(i) == ' ' || p.peekAt(i) == '\t' {
+		i++
+	}
+	return p.peekAt(i) == '\n'
+}
+
+func (p *yamlParser) parseEscape(sb *strings.Builder) error {
+	p.pos++ // '\\'
+	c := p.peek()
+	p.pos++
+	simple := map[byte]string{
+		'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f",
+		'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\",
+		'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+	}
+	if s, ok := simple[c]; ok {
+		sb.WriteString(s)
+		return nil
+	}
+	width := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+	if width == 0 || p.pos+width > len(p.src) {
+		return p.errorf("invalid escape '\\%c'", c)
+	}
+	code, err := strconv.ParseUint(p.src[p.pos:p.pos+width], 16, 32)
+	if err != nil {
+		return p.errorf("invalid escape '\\%c%s'", c, p.src[p.pos:p.pos+width])
+	}
+	p.pos += width
+	sb.WriteRune(rune(code))
+	return nil
+}
+
+// parseBlockScalar reads a literal '|' or folded '>' scalar.
+func (p *yamlParser) parseBlockScalar(parent int) (string, error) {
+	folded := p.peek() == '>'
+	p.pos++
+	chomp := byte(' ')
+	explicit := 0
+	for i := 0; i < 2; i++ {
+		c := p.peek()
+		if c == '-' || c == '+' {
+			chomp = c
+			p.pos++
+		} else if c >= '1' && c <= '9' {
+			explicit = int(c - '0')
+			p.pos++
+		}
+	}
+	p.skipInlineSpace()
+	if !p.atLineEnd() {
+		return "", p.errorf("unexpected text after block scalar indicator")
+	}
+	p.skipToLineEnd()
+
+	contentIndent := -1
+	if explicit > 0 {
+		contentIndent = max(parent, 0) + explicit
+	}
+	var lines []string
+	for !p.eof() {
+		start := p.pos + 1
+		end := strings.IndexByte(p.src[start:], '\n')
+		if end < 0 {
+			end = len(p.src)
+		} else {
+			end += start
+		}
+		line := p.src[start:end]
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if strings.TrimLeft(line, " ") == "" {
+			if contentIndent >= 0 && len(line) > contentIndent {
+				lines = append(lines, line[contentIndent:])
+			} else {
+				lines = append(lines, "")
+			}
+			p.pos = end
+			continue
+		}
+		if contentIndent < 0 {
+			if indent <= parent {
+				break
+			}
+			contentIndent = indent
+		}
+		if indent < contentIndent || (indent == 0 && (strings.HasPrefix(line, "---") || strings.HasPrefix(line, "..."))) {
+			break
+		}
+		lines = append(lines, line[contentIndent:])
+		p.pos = end
+	}
+
+	// Trailing blank lines belong to chomping, not the content.
+	last := len(lines) - 1
+	for last >= 0 && lines[last] == "" {
+		last--
+	}
+	trailing := len(lines) - 1 - last
+	body := lines[:last+1]
+
+	var text string
+	if folded {
+		text = foldYamlLines(body)
+	} else {
+		text = strings.Join(body, "\n")
+	}
+	switch chomp {
+	case '-':
+	case '+':
+		if len(lines) > 0 {
+			text += "\n" + strings.Repeat("\n", trailing)
+			if len(body) == 0 {
+				text = text[1:]
+			}
+		}
+	default:
+		if len(body) > 0 {
+			text += "\n"
+		}
+	}
+	return text, nil
+}
+
+// foldYamlLines joins the lines of a folded scalar. Adjacent lines join with
+// a space, blank lines become line breaks, and more-indented lines keep
+// their breaks.
+func foldYamlLines(lines []string) string {
+	var sb strings.Builder
+	moreIndented := func(line string) bool {
+		return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+	}
+	prev := -1
+	for i, line := range lines {
+		if line == "" {
+			if prev < 0 {
+				sb.WriteByte('\n')
+			}
+			continue
+		}
+		if prev >= 0 {
+			empties := i - prev - 1
+			switch {
+			case moreIndented(lines[prev]) || moreIndented(line):
+				sb.WriteString(strings.Repeat("\n", empties+1))
+			case empties == 0:
+				sb.WriteByte(' ')
+			default:
+				sb.WriteString(strings.Repeat("\n", empties))
+			}
+		}
+		sb.WriteString(line)
+		prev = i
+	}
+	return sb.String()
+}
+
+// skipFlowSpace moves past whitespace, line breaks and comments inside a
+// flow collection.
+func (p *yamlParser) skipFlowSpace() {
+	for !p.eof() {
+		c := p.peek()
+		if c == ' ' || c == '\t' || c == '\n' {
+			p.pos++
+		} else if c == '#' && (p.pos == 0 || p.isBreakOrSpace(p.pos-1)) {
+			p.skipToLineEnd()
+		} else {
+			return
+		}
+	}
+}
+
+func (p *yamlParser) parseFlowNode() (MShellObject, error) {
+	p.skipFlowSpace()
+	anchor, tag := p.parseProperties()
+	p.skipFlowSpace()
+
+	var node MShellObject
+	var err error
+	switch p.peek() {
+	case '[':
+		node, err = p.parseFlowSequence()
+	case '{':
+		node, err = p.parseFlowMapping()
+	case '*':
+		node, err = p.parseAlias()
+	case '"', '\'':
+		var text string
+		text, err = p.parseQuoted()
+		node = yamlScalar(text, true, tag)
+	default:
+		node = yamlScalar(p.parsePlainFlow(), false, tag)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.finishNode(anchor, node)
+}
+
+// parsePlainFlow reads a plain scalar inside a flow collection.
+func (p *yamlParser) parsePlainFlow() string {
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if strings.ContainsRune(",[]{}\n", rune(c)) {
+			break
+		}
+		if c == ':' && (p.isBreakOrSpace(p.pos+1) || strings.ContainsRune(",[]{}", rune(p.peekAt(p.pos+1)))) {
+			break
+		}
+		if c == '#' && p.pos > start && (p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+			break
+		}
+		p.pos++
+	}
+	return strings.Trim(p.src[start:p.pos], " \t")
+}
+
+func (p *yamlParser) parseFlowSequence() (MShellObject, error) {
+	p.pos++ // '['
+	list := &MShellList{Items: []MShellObject{}}
+	for {
+		p.skipFlowSpace()
+		if p.peek() == ']' {
+			p.pos++
+			return list, nil
+		}
+		item, err := p.parseFlowNode()
+		if err != nil {
+			return nil, err
+		}
+		p.skipFlowSpace()
+		if p.peek() == ':' {
+			// A single 'key: value' pair inside a sequence is a one-entry mapping.
+			p.pos++
+			value, err := p.parseFlowValue()
+			if err != nil {
+				return nil, err
+			}
+			pair := NewDict()
+			pair.Items[yamlKeyString(item)] = value
+			item = pair
+			p.skipFlowSpace()
+		}
+		list.Items = append(list.Items, item)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected ',' or ']' in flow sequence")
+		}
+	}
+}
+
+func (p *yamlParser) parseFlowMapping() (MShellObject, error) {
+	p.pos++ // '{'
+	dict := NewDict()
+	for {
+		p.skipFlowSpace()
+		if p.peek() == '}' {
+			p.pos++
+			return dict, nil
+		}
+		key := ""
+		if c := p.peek(); c == '"' || c == '\'' {
+			text, err := p.parseQuoted()
+			if err != nil {
+				return nil, err
+			}
+			key = text
+		} else {
+			key = p.parsePlainFlow()
+		}
+		p.skipFlowSpace()
+		var value MShellObject = MShellNull{}
+		var err error
+		if p.peek() == ':' {
+			p.pos++
+			value, err = p.parseFlowValue()
+			if err != nil {
+				return nil, err
+			}
+			p.skipFlowSpace()
+		}
+		if _, exists := dict.Items[key]; exists {
+			return nil, p.errorf("duplicate key '%s'", key)
+		}
+		dict.Items[key] = value
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+		default:
+			return nil, p.errorf("expected ',' or '}' in flow mapping")
+		}
+	}
+}
+
+// parseFlowValue reads the value after a ':' in a flow collection, which is
+// null when left out.
+func (p *yamlParser) parseFlowValue() (MShellObject, error) {
+	p.skipFlowSpace()
+	if c := p.peek(); c == ',' || c == '}' || c == ']' {
+		return MShellNull{}, nil
+	}
+	return p.parseFlowNode()
+}
+
+// yamlKeyString converts a parsed key to the string used in the dictionary.
+func yamlKeyString(key MShellObject) string {
+	switch typed := key.(type) {
+	case MShellString:
+		return typed.Content
+	case MShellNull:
+		return "null"
+	}
+	return key.DebugString()
+}
+
+var yamlIntRegex = regexp.MustCompile(`^[-+]?[0-9]+$`)
+var yamlFloatRegex = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+var yamlTimestampRegex = regexp.MustCompile(`^([0-9]{4})-([0-9]{1,2})-([0-9]{1,2})(?:(?:[Tt]|[ \t]+)([0-9]{1,2}):([0-9]{2}):([0-9]{2})(\.[0-9]+)?[ \t]*(Z|[-+][0-9]{1,2}(?::?[0-9]{2})?)?)?$`)
+
+// yamlScalar resolves a scalar's text to an mshell value. Quoted scalars and
+// those tagged '!!str' or '!' stay strings.
+func yamlScalar(text string, quoted bool, tag string) MShellObject {
+	if quoted || tag == "!!str" || tag == "!" {
+		return MShellString{Content: text}
+	}
+
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return MShellNull{}
+	case "true", "True", "TRUE":
+		return MShellBool{Value: true}
+	case "false", "False", "FALSE":
+		return MShellBool{Value: false}
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return MShellFloat{Value: math.Inf(1)}
+	case "-.inf", "-.Inf", "-.INF":
+		return MShellFloat{Value: math.Inf(-1)}
+	case ".nan", ".NaN", ".NAN":
+		return MShellFloat{Value: math.NaN()}
+	}
+
+	if yamlIntRegex.MatchString(text) {
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return MShellInt{Value: int(i)}
+		}
+	}
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0o") {
+		if i, err := strconv.ParseInt(text, 0, 64); err == nil {
+			return MShellInt{Value: int(i)}
+		}
+	}
+	if yamlFloatRegex.MatchString(text) {
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return MShellFloat{Value: f}
+		}
+	}
+	if t, ok := parseYamlTimestamp(text); ok {
+		return &MShellDateTime{Time: t, OriginalString: text}
+	}
+	return MShellString{Content: text}
+}
+
+// parseYamlTimestamp parses a YAML timestamp. Times without a zone are UTC.
+func parseYamlTimestamp(text string) (time.Time, bool) {
+	m := yamlTimestampRegex.FindStringSubmatch(text)
+	if m == nil {
+		return time.Time{}, false
+	}
+	num := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	year, month, day := num(m[1]), num(m[2]), num(m[3])
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+	nanos := 0
+	if m[7] != "" {
+		frac := (m[7][1:] + "000000000")[:9]
+		nanos = num(frac)
+	}
+	loc := time.UTC
+	if zone := m[8]; zone != "" && zone != "Z" {
+		sign := 1
+		if zone[0] == '-' {
+			sign = -1
+		}
+		zone = strings.ReplaceAll(zone[1:], ":", "")
+		hours, minutes := zone, "0"
+		if len(zone) > 2 {
+			hours, minutes = zone[:len(zone)-2], zone[len(zone)-2:]
+		}
+		loc = time.FixedZone("", sign*(num(hours)*3600+num(minutes)*60))
+	}
+	return time.Date(year, time.Month(month), day, num(m[4]), num(m[5]), num(m[6]), nanos, loc), true
+}
+
+// writeYaml serialises obj as a block-style YAML document. Dictionary keys
+// are written in sorted order, as with toJson.
+func writeYaml(obj MShellObject) (string, error) {
+	var sb strings.Builder
+	if isYamlBlockCollection(obj) {
+		if err := writeYamlBlock(&sb, obj, 0); err != nil {
+			return "", err
+		}
+		return sb.String(), nil
+	}
+	if s, ok := yamlMultiLineString(obj); ok {
+		writeYamlBlockScalar(&sb, s, 2)
+		return sb.String(), nil
+	}
+	scalar, err := yamlScalarText(obj)
+	if err != nil {
+		return "", err
+	}
+	return scalar + "\n", nil
+}
+
+func isYamlBlockCollection(obj MShellObject) bool {
+	switch typed := obj.(type) {
+	case *MShellDict:
+		return len(typed.Items) > 0
+	case *MShellList:
+		return len(typed.Items) > 0
+	case *Maybe:
+		return typed.obj != nil && isYamlBlockCollection(typed.obj)
+	}
+	return false
+}
+
+func writeYamlBlock(sb *strings.Builder, obj MShellObject, indent int) error {
+	pad := strings.Repeat(" ", indent)
+	switch typed := obj.(type) {
+	case *Maybe:
+		return writeYamlBlock(sb, typed.obj, indent)
+	case *MShellDict:
+		keys := make([]string, 0, len(typed.Items))
+		for key := range typed.Items {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sb.WriteString(pad)
+			sb.WriteString(yamlStringText(key))
+			sb.WriteByte(':')
+			if err := writeYamlValue(sb, typed.Items[key], indent+2); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	case *MShellList:
+		for i, item := range typed.Items {
+			sb.WriteString(pad)
+			sb.WriteByte('-')
+			if isYamlBlockCollection(item) {
+				// The first line of a nested collection shares the '- ' line.
+				var nested strings.Builder
+				if err := writeYamlBlock(&nested, item, indent+2); err != nil {
+					return fmt.Errorf("item %d: %w", i, err)
+				}
+				sb.WriteByte(' ')
+				sb.WriteString(nested.String()[indent+2:])
+				continue
+			}
+			if err := writeYamlValue(sb, item, indent+2); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeYamlValue writes the value following a 'key:' or '-' indicator.
+func writeYamlValue(sb *strings.Builder, obj MShellObject, indent int) error {
+	if isYamlBlockCollection(obj) {
+		sb.WriteByte('\n')
+		return writeYamlBlock(sb, obj, indent)
+	}
+	if s, ok := yamlMultiLineString(obj); ok {
+		sb.WriteByte(' ')
+		writeYamlBlockScalar(sb, s, indent)
+		return nil
+	}
+	scalar, err := yamlScalarText(obj)
+	if err != nil {
+		return err
+	}
+	sb.WriteByte(' ')
+	sb.WriteString(scalar)
+	sb.WriteByte('\n')
+	return nil
+}
+
+// yamlMultiLineString reports whether obj is a string best written as a
+// literal block scalar.
+func yamlMultiLineString(obj MShellObject) (string, bool) {
+	s, ok := obj.(MShellString)
+	if !ok || !strings.Contains(strings.TrimRight(s.Content, "\n"), "\n") {
+		return "", false
+	}
+	if strings.HasPrefix(s.Content, " ") || strings.HasPrefix(s.Content, "\t") || strings.ContainsAny(s.Content, "\r\x00") || !utf8.ValidString(s.Content) {
+		return "", false
+	}
+	return s.Content, true
+}
+
+func writeYamlBlockScalar(sb *strings.Builder, s string, indent int) {
+	body := strings.TrimRight(s, "\n")
+	switch trailing := len(s) - len(body); {
+	case trailing == 0:
+		sb.WriteString("|-\n")
+	case trailing == 1:
+		sb.WriteString("|\n")
+	default:
+		sb.WriteString("|+\n")
+		body = s[:len(s)-1]
+	}
+	pad := strings.Repeat(" ", indent)
+	for _, line := range strings.Split(body, "\n") {
+		if line != "" {
+			sb.WriteString(pad)
+			sb.WriteString(line)
+		}
+		sb.WriteByte('\n')
+	}
+}
+
+// yamlScalarText formats a value that fits on one line.
+func yamlScalarText(obj MShellObject) (string, error) {
+	switch typed := obj.(type) {
+	case MShellString:
+		return yamlStringText(typed.Content), nil
+	case MShellPath:
+		return yamlStringText(typed.Path), nil
+	case MShellLiteral:
+		return yamlStringText(typed.LiteralText), nil
+	case MShellInt:
+		return strconv.Itoa(typed.Value), nil
+	case MShellFloat:
+		return yamlFloatText(typed.Value), nil
+	case MShellBool:
+		return strconv.FormatBool(typed.Value), nil
+	case MShellNull:
+		return "null", nil
+	case *Maybe:
+		if typed.obj == nil {
+			return "null", nil
+		}
+		return yamlScalarText(typed.obj)
+	case *MShellDateTime:
+		return formatStructuredDateTime(typed.Time), nil
+	case *MShellDict:
+		return "{}", nil
+	case *MShellList:
+		return "[]", nil
+	}
+	return "", fmt.Errorf("cannot write a %s as YAML", obj.TypeName())
+}
+
+func yamlFloatText(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return ".nan"
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// yamlStringText writes s plain when it would read back as the same
+// string, and double-quoted otherwise.
+func yamlStringText(s string) string {
+	if needsYamlQuotes(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func needsYamlQuotes(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+	if _, ok := yamlScalar(s, false, "").(MShellString); !ok {
+		return true
+	}
+	if strings.ContainsRune("-?:,[]{}#&*!|>'\"%@`", rune(s[0])) {
+		return true
+	}
+	// YAML 1.1 readers take these as booleans.
+	switch strings.ToLower(s) {
+	case "y", "n", "yes", "no", "on", "off":
+		return true
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return true
+	}
+	for _, r := range s {
+		if r < ' ' || r == 0x7f || r == utf8.RuneError {
+			return true
+		}
+	}
+	return false
+}
+
+// formatStructuredDateTime writes a date-time for YAML and TOML: a bare date
+// at UTC midnight, and RFC 3339 otherwise.
+func formatStructuredDateTime(t time.Time) string {
+	if t.Location() == time.UTC && t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339Nano)
+}
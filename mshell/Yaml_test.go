@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseYamlDocuments(t *testing.T) {
+	src := `# workflow
+name: CI
+on:
+  push:
+    branches: [main, "release/*"]
+defaults: &defaults
+  retries: 2
+  shell: bash
+jobs:
+- name: test
+  <<: *defaults
+  retries: 3
+  run: |
+    go vet ./...
+    go test ./...
+- name: folded
+  run: >-
+    one
+    two
+
+    three
+---
+- 0x1F
+- 1.5
+- ~
+- 2024-03-01
+- 'it''s'
+- "tab\there"
+`
+	docs, err := parseYamlDocuments(src)
+	if err != nil {
+		t.Fatalf("parseYamlDocuments error: %v", err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("document count = %d, want 2", len(docs))
+	}
+
+	want := `{"defaults": {"retries": 2, "shell": "bash"}, "jobs": [{"name": "test", "retries": 3, "run": "go vet ./...\ngo test ./...\n", "shell": "bash"}, {"name": "folded", "run": "one two\nthree"}], "name": "CI", "on": {"push": {"branches": ["main", "release/*"]}}}`
+	if got := docs[0].ToJson(); got != want {
+		t.Errorf("first document = %s, want %s", got, want)
+	}
+
+	items := docs[1].(*MShellList).Items
+	if got := items[0].(MShellInt).Value; got != 31 {
+		t.Errorf("hex int = %d, want 31", got)
+	}
+	if _, ok := items[2].(MShellNull); !ok {
+		t.Errorf("'~' = %s, want null", items[2].DebugString())
+	}
+	date, ok := items[3].(*MShellDateTime)
+	if !ok || !date.Time.Equal(time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("timestamp = %s, want 2024-03-01", items[3].DebugString())
+	}
+	if got := items[4].(MShellString).Content; got != "it's" {
+		t.Errorf("single-quoted = %q, want it's", got)
+	}
+	if got := items[5].(MShellString).Content; got != "tab\there" {
+		t.Errorf("double-quoted = %q, want tab\\there", got)
+	}
+}
+
+func TestParseYamlErrors(t *testing.T) {
+	for _, tt := range []struct {
+		src  string
+		want string
+	}{
+		{"a: 1\n  b: 2\n", "line 2: bad indentation"},
+		{"a: 1\na: 2\n", "duplicate key 'a'"},
+		{"a: *missing\n", "unknown alias"},
+		{"a: b: c\n", "same line"},
+		{"other: - not a seq\n", "cannot start a block sequence on the same line"},
+		{"other: -\n", "cannot start a block sequence on the same line"},
+		{"a: \"open\n", "unterminated"},
+	} {
+		if _, err := parseYamlDocuments(tt.src); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseYamlDocuments(%q) error = %v, want one containing %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestWriteYamlRoundTrip(t *testing.T) {
+	step := NewDict()
+	step.Items["run"] = MShellString{Content: "make\nmake test\n"}
+	step.Items["name"] = MShellString{Content: "Build: all"}
+	doc := NewDict()
+	doc.Items["on"] = MShellString{Content: "push"}
+	doc.Items["version"] = MShellString{Content: "1.10"}
+	doc.Items["steps"] = &MShellList{Items: []MShellObject{step, MShellFloat{Value: 2}}}
+	doc.Items["empty"] = &MShellList{}
+
+	text, err := writeYaml(doc)
+	if err != nil {
+		t.Fatalf("writeYaml error: %v", err)
+	}
+	want := `empty: []
+"on": push
+steps:
+  - name: "Build: all"
+    run: |
+      make
+      make test
+  - 2.0
+version: "1.10"
+`
+	if text != want {
+		t.Errorf("writeYaml =\n%s\nwant\n%s", text, want)
+	}
+
+	docs, err := parseYamlDocuments(text)
+	if err != nil {
+		t.Fatalf("parseYamlDocuments error: %v", err)
+	}
+	if got, want := docs[0].ToJson(), doc.ToJson(); got != want {
+		t.Errorf("round trip = %s, want %s", got, want)
+	}
+
+	if _, err := writeYaml(&MShellQuotation{}); err == nil {
+		t.Errorf("writeYaml of a quotation: expected an error")
+	}
+}
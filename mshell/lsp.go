@@ -7,14 +7,18 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unicode/utf16"
 	"unicode/utf8"
 
 	"go.lsp.dev/protocol"
+	lspuri "go.lsp.dev/uri"
 )
 
 const jsonrpcVersion = "2.0"
@@ -28,6 +32,11 @@ const (
 
 var errExitBeforeShutdown = errors.New("exit received before shutdown")
 
+// lspDiagnosticsDelay is how long the server waits after the last edit to
+// a document before type checking it, so a burst of keystrokes produces a
+// single check.
+const lspDiagnosticsDelay = 300 * time.Millisecond
+
 type lspServer struct {
 	in           *bufio.Reader
 	out          *bufio.Writer
@@ -42,8 +51,23 @@ type lspServer struct {
 	envNames     map[string]struct{}
 	candsBuf     []string
 	stdlibDefs   []MShellDefinition
+	stdlibURI    protocol.DocumentURI
+	initDefs     []MShellDefinition // init.msh definitions, for navigation only
+	initURI      protocol.DocumentURI
 	builtinSigs  map[string][]string // name -> formatted "(in -- out)" sigs from the type checker
 	stdlibHover  map[string][]string // name -> formatted sigs for stdlib defs
+
+	// Debounced diagnostics. diagSeq counts edits per document so a
+	// check that finishes after a newer edit is dropped instead of
+	// overwriting fresher results. diagPublishMu serializes the
+	// staleness check with the write; it is separate from diagMu so a
+	// blocked write never stalls edits or shutdown.
+	diagMu        sync.Mutex
+	diagPublishMu sync.Mutex
+	diagDelay     time.Duration
+	diagTimers    map[protocol.DocumentURI]*time.Timer
+	diagSeq       map[protocol.DocumentURI]uint64
+	diagClosed    bool
 }
 
 type lspDocument struct {
@@ -133,6 +157,7 @@ func RunLSP(in io.Reader, out io.Writer) error {
 		pathBins:  pathBins,
 		varNames:  make(map[string]struct{}),
 		envNames:  make(map[string]struct{}),
+		diagDelay: lspDiagnosticsDelay,
 	}
 
 	if defs, err := loadStdlibDefsForLSP(); err != nil {
@@ -140,6 +165,7 @@ func RunLSP(in io.Reader, out io.Writer) error {
 	} else {
 		server.stdlibDefs = defs
 	}
+	server.stdlibURI, server.initURI, server.initDefs = loadStartupNavigationForLSP()
 
 	server.builtinSigs, server.stdlibHover = buildHoverIndex(server.stdlibDefs)
 
@@ -201,7 +227,35 @@ func loadStdlibDefsForLSP() ([]MShellDefinition, error) {
 	return parsed.Definitions, nil
 }
 
+// loadStartupNavigationForLSP locates the standard library and init.msh
+// the same way, and parses init.msh, so definition requests can jump into
+// both. A missing or broken init file only means its definitions are not
+// found.
+func loadStartupNavigationForLSP() (protocol.DocumentURI, protocol.DocumentURI, []MShellDefinition) {
+	stdlibSpec, initSpec, err := getStartupFileSpecs(startupLoadOptions{
+		version:           mshellVersion,
+		allowEnvOverrides: true,
+	})
+	if err != nil {
+		return "", "", nil
+	}
+	stdlibURI := lspuri.File(stdlibSpec.path)
+	initURI := lspuri.File(initSpec.path)
+
+	source, err := os.ReadFile(initSpec.path)
+	if err != nil {
+		return stdlibURI, initURI, nil
+	}
+	parsed, err := parseMShellInput(string(source), &TokenFile{initSpec.path})
+	if err != nil {
+		logLSP(fmt.Sprintf("navigation: init file %s does not parse: %v", initSpec.path, err))
+		return stdlibURI, initURI, nil
+	}
+	return stdlibURI, initURI, parsed.Definitions
+}
+
 func (s *lspServer) run() error {
+	defer s.stopDiagnostics()
 	for {
 		payload, err := s.readMessage()
 		if err != nil {
@@ -293,15 +347,23 @@ func (s *lspServer) handleMessage(msg *jsonrpcMessage) (bool, error) {
 			_ = s.sendErrorResponse(msg.ID, jsonrpcCodeInvalidParams, fmt.Sprintf("invalid initialize params: %v", err))
 			return false, nil
 		}
-		result := protocol.InitializeResult{
-			Capabilities: protocol.ServerCapabilities{
-				TextDocumentSync:  protocol.TextDocumentSyncKindFull,
-				HoverProvider:     true,
-				CodeActionProvider: true,
-				CompletionProvider: &protocol.CompletionOptions{
-					TriggerCharacters: []string{"@", "$"},
+		result := lspInitializeResult{
+			Capabilities: lspServerCapabilities{
+				ServerCapabilities: protocol.ServerCapabilities{
+					TextDocumentSync:  protocol.TextDocumentSyncKindFull,
+					HoverProvider:     true,
+					CodeActionProvider: true,
+					CompletionProvider: &protocol.CompletionOptions{
+						TriggerCharacters: []string{"@", "$"},
+					},
+					RenameProvider: &protocol.RenameOptions{PrepareProvider: true},
+					DocumentFormattingProvider: true,
+					DefinitionProvider:         true,
+					ReferencesProvider:         true,
+					DocumentSymbolProvider:     true,
+					WorkspaceSymbolProvider:    true,
 				},
-				RenameProvider: &protocol.RenameOptions{PrepareProvider: true},
+				InlayHintProvider: true,
 			},
 			ServerInfo: &protocol.ServerInfo{
 				Name:    "mshell",
@@ -351,6 +413,7 @@ func (s *lspServer) handleMessage(msg *jsonrpcMessage) (bool, error) {
 			return false, nil
 		}
 		delete(s.documents, params.TextDocument.URI)
+		s.cancelDiagnostics(params.TextDocument.URI)
 		// Clear any diagnostics the client was showing for this doc.
 		_ = s.writeNotification("textDocument/publishDiagnostics", protocol.PublishDiagnosticsParams{
 			URI:         params.TextDocument.URI,
@@ -436,6 +499,76 @@ func (s *lspServer) handleMessage(msg *jsonrpcMessage) (bool, error) {
 			return false, s.sendErrorResponse(msg.ID, jsonrpcCodeInternalError, err.Error())
 		}
 		return false, s.sendResult(msg.ID, edit)
+	case "textDocument/definition":
+		if msg.ID == nil {
+			logLSP("definition request missing id")
+			return false, nil
+		}
+		var params protocol.DefinitionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			_ = s.sendErrorResponse(msg.ID, jsonrpcCodeInvalidParams, fmt.Sprintf("invalid definition params: %v", err))
+			return false, nil
+		}
+		locations := s.definition(params)
+		if len(locations) == 0 {
+			return false, s.sendResult(msg.ID, nil)
+		}
+		return false, s.sendResult(msg.ID, locations)
+	case "textDocument/references":
+		if msg.ID == nil {
+			logLSP("references request missing id")
+			return false, nil
+		}
+		var params protocol.ReferenceParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			_ = s.sendErrorResponse(msg.ID, jsonrpcCodeInvalidParams, fmt.Sprintf("invalid references params: %v", err))
+			return false, nil
+		}
+		return false, s.sendResult(msg.ID, s.references(params))
+	case "textDocument/documentSymbol":
+		if msg.ID == nil {
+			logLSP("documentSymbol request missing id")
+			return false, nil
+		}
+		var params protocol.DocumentSymbolParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			_ = s.sendErrorResponse(msg.ID, jsonrpcCodeInvalidParams, fmt.Sprintf("invalid documentSymbol params: %v", err))
+			return false, nil
+		}
+		return false, s.sendResult(msg.ID, s.documentSymbols(params))
+	case "workspace/symbol":
+		if msg.ID == nil {
+			logLSP("workspace/symbol request missing id")
+			return false, nil
+		}
+		var params protocol.WorkspaceSymbolParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			_ = s.sendErrorResponse(msg.ID, jsonrpcCodeInvalidParams, fmt.Sprintf("invalid workspace/symbol params: %v", err))
+			return false, nil
+		}
+		return false, s.sendResult(msg.ID, s.workspaceSymbols(params))
+	case "textDocument/formatting":
+		if msg.ID == nil {
+			logLSP("formatting request missing id")
+			return false, nil
+		}
+		var params protocol.DocumentFormattingParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			_ = s.sendErrorResponse(msg.ID, jsonrpcCodeInvalidParams, fmt.Sprintf("invalid formatting params: %v", err))
+			return false, nil
+		}
+		return false, s.sendResult(msg.ID, s.formatting(params))
+	case "textDocument/inlayHint":
+		if msg.ID == nil {
+			logLSP("inlayHint request missing id")
+			return false, nil
+		}
+		var params inlayHintParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			_ = s.sendErrorResponse(msg.ID, jsonrpcCodeInvalidParams, fmt.Sprintf("invalid inlayHint params: %v", err))
+			return false, nil
+		}
+		return false, s.sendResult(msg.ID, s.inlayHints(params))
 	default:
 		if msg.ID != nil {
 			_ = s.sendErrorResponse(msg.ID, jsonrpcCodeMethodNotFound, fmt.Sprintf("method %q not found", msg.Method))
@@ -500,6 +633,113 @@ func (s *lspServer) codeActions(params protocol.CodeActionParams) []protocol.Cod
 	}}
 }
 
+// formatting replaces the whole document with its 'msh fmt' layout. A
+// document that does not parse is left alone; its parse error is already
+// published as a diagnostic.
+func (s *lspServer) formatting(params protocol.DocumentFormattingParams) []protocol.TextEdit {
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return []protocol.TextEdit{}
+	}
+	formatted, err := FormatSource(doc.Text)
+	if err != nil || formatted == doc.Text {
+		return []protocol.TextEdit{}
+	}
+	end := runeOffsetToLSPPosition(doc.Text, utf8.RuneCountInString(doc.Text))
+	return []protocol.TextEdit{{
+		Range:   protocol.Range{Start: protocol.Position{Line: 0, Character: 0}, End: end},
+		NewText: formatted,
+	}}
+}
+
+// The protocol package predates inlay hints (LSP 3.17), so the request,
+// result, and capability are declared here.
+
+// lspInitializeResult mirrors protocol.InitializeResult with the extended
+// capabilities.
+type lspInitializeResult struct {
+	Capabilities lspServerCapabilities `json:"capabilities"`
+	ServerInfo   *protocol.ServerInfo  `json:"serverInfo,omitempty"`
+}
+
+type lspServerCapabilities struct {
+	protocol.ServerCapabilities
+	InlayHintProvider bool `json:"inlayHintProvider,omitempty"`
+}
+
+type inlayHintParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	Range        protocol.Range                  `json:"range"`
+}
+
+// inlayHintKindType is InlayHintKind.Type from the specification.
+const inlayHintKindType = 1
+
+type inlayHint struct {
+	Position    protocol.Position `json:"position"`
+	Label       string            `json:"label"`
+	Kind        int               `json:"kind,omitempty"`
+	PaddingLeft bool              `json:"paddingLeft,omitempty"`
+}
+
+// inlayHints type checks the document with a StackHints recorder and
+// returns the stack after each line and the signature of each quote
+// within the requested range. A document that does not parse or whose
+// imports fail gets no hints; the error is already a diagnostic.
+func (s *lspServer) inlayHints(params inlayHintParams) []inlayHint {
+	hints := []inlayHint{}
+	uri := params.TextDocument.URI
+	doc, ok := s.documents[uri]
+	if !ok {
+		return hints
+	}
+	file, err := NewMShellParser(NewLexer(doc.Text, nil)).ParseFile()
+	if err != nil {
+		return hints
+	}
+	path := ""
+	if strings.HasPrefix(string(uri), "file://") {
+		path = uri.Filename()
+	}
+	importedDefs, err := ResolveImports(file, path)
+	if err != nil {
+		return hints
+	}
+
+	checker, _, _ := s.newDocumentChecker(importedDefs)
+	recorded := NewStackHints()
+	checker.RecordStackHints(recorded)
+	checker.CheckProgram(file)
+
+	lineOffsets := make(map[int]bool, len(recorded.Lines))
+	var found []StackHint
+	for _, h := range recorded.Lines {
+		lineOffsets[h.Offset] = true
+		found = append(found, h)
+	}
+	for _, h := range recorded.Quotes {
+		// A quote ending its line is already shown in the line's stack.
+		if !lineOffsets[h.Offset] {
+			found = append(found, h)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Offset < found[j].Offset })
+
+	for _, h := range found {
+		line := uint32(h.Line - 1)
+		if line < params.Range.Start.Line || line > params.Range.End.Line {
+			continue
+		}
+		hints = append(hints, inlayHint{
+			Position:    runeOffsetToLSPPosition(doc.Text, h.Offset),
+			Label:       h.Label,
+			Kind:        inlayHintKindType,
+			PaddingLeft: true,
+		})
+	}
+	return hints
+}
+
 func collectRuntimeLists(file *MShellFile) []*MShellParseList {
 	lists := make([]*MShellParseList, 0)
 	collectRuntimeListsFromItems(&lists, file.Items)
@@ -701,20 +941,68 @@ func (s *lspServer) updateDocument(uri protocol.DocumentURI, text string) {
 		s.documents[uri] = doc
 	}
 	doc.setText(text)
-	// Run diagnostics asynchronously so the event loop can keep
-	// servicing requests; the write side is mutex-guarded so the
-	// notification interleaves safely with response writes.
-	go s.publishDiagnosticsFor(uri, doc.Text)
+	s.scheduleDiagnostics(uri, doc.Text)
+}
+
+// scheduleDiagnostics (re)starts the document's debounce timer. When it
+// fires, diagnostics run on the timer's goroutine so the event loop can
+// keep servicing requests; the write side is mutex-guarded so the
+// notification interleaves safely with response writes.
+func (s *lspServer) scheduleDiagnostics(uri protocol.DocumentURI, text string) {
+	s.diagMu.Lock()
+	defer s.diagMu.Unlock()
+	if s.diagClosed {
+		return
+	}
+	if s.diagTimers == nil {
+		s.diagTimers = make(map[protocol.DocumentURI]*time.Timer)
+		s.diagSeq = make(map[protocol.DocumentURI]uint64)
+	}
+	if t := s.diagTimers[uri]; t != nil {
+		t.Stop()
+	}
+	s.diagSeq[uri]++
+	seq := s.diagSeq[uri]
+	s.diagTimers[uri] = time.AfterFunc(s.diagDelay, func() {
+		s.publishDiagnosticsFor(uri, text, seq)
+	})
+}
+
+// cancelDiagnostics drops any pending or in-flight check for a closed
+// document.
+func (s *lspServer) cancelDiagnostics(uri protocol.DocumentURI) {
+	s.diagMu.Lock()
+	defer s.diagMu.Unlock()
+	if t := s.diagTimers[uri]; t != nil {
+		t.Stop()
+		delete(s.diagTimers, uri)
+	}
+	if s.diagSeq != nil {
+		s.diagSeq[uri]++
+	}
+}
+
+// stopDiagnostics cancels every pending check once the server exits so
+// no notification is written after run returns.
+func (s *lspServer) stopDiagnostics() {
+	s.diagMu.Lock()
+	defer s.diagMu.Unlock()
+	s.diagClosed = true
+	for uri, t := range s.diagTimers {
+		t.Stop()
+		delete(s.diagTimers, uri)
+	}
 }
 
 // publishDiagnosticsFor parses the document text and runs the static
 // type checker, converting any parse or type errors into LSP
 // diagnostics and sending a textDocument/publishDiagnostics
 // notification. An empty diagnostic list clears prior diagnostics on
-// the client. Runs on its own goroutine; it builds a private parser
-// so it doesn't race with handlers using s.parser.
-func (s *lspServer) publishDiagnosticsFor(uri protocol.DocumentURI, text string) {
-	diags := s.computeDiagnostics(text)
+// the client. seq is the edit the text belongs to; results for an
+// outdated edit are discarded. It builds a private parser so it
+// doesn't race with handlers using s.parser.
+func (s *lspServer) publishDiagnosticsFor(uri protocol.DocumentURI, text string, seq uint64) {
+	diags := s.computeDiagnostics(uri, text)
 	if diags == nil {
 		diags = []protocol.Diagnostic{}
 	}
@@ -722,12 +1010,31 @@ func (s *lspServer) publishDiagnosticsFor(uri protocol.DocumentURI, text string)
 		URI:         uri,
 		Diagnostics: diags,
 	}
+	s.diagPublishMu.Lock()
+	defer s.diagPublishMu.Unlock()
+	if !s.diagnosticsCurrent(uri, seq) {
+		return
+	}
 	if err := s.writeNotification("textDocument/publishDiagnostics", params); err != nil {
 		logLSP(fmt.Sprintf("publishDiagnostics write failed: %v", err))
 	}
 }
 
-func (s *lspServer) computeDiagnostics(text string) []protocol.Diagnostic {
+// diagnosticsCurrent reports whether seq is still the document's latest
+// edit and the server is still running.
+func (s *lspServer) diagnosticsCurrent(uri protocol.DocumentURI, seq uint64) bool {
+	s.diagMu.Lock()
+	defer s.diagMu.Unlock()
+	return !s.diagClosed && s.diagSeq[uri] == seq
+}
+
+// computeDiagnostics checks the document text. The document's file path,
+// when it has one, is used to resolve relative imports.
+func (s *lspServer) computeDiagnostics(uri protocol.DocumentURI, text string) []protocol.Diagnostic {
+	path := ""
+	if strings.HasPrefix(string(uri), "file://") {
+		path = uri.Filename()
+	}
 	lexer := NewLexer(text, nil)
 	parser := NewMShellParser(lexer)
 	file, parseErr := parser.ParseFile()
@@ -735,10 +1042,14 @@ func (s *lspServer) computeDiagnostics(text string) []protocol.Diagnostic {
 		return []protocol.Diagnostic{parseErrorToDiagnostic(parseErr)}
 	}
 
-	arena := NewTypeArena()
-	names := NewNameTable()
-	checker := NewChecker(arena, names)
-	checker.RegisterStdlibSigs(s.stdlibDefs)
+	importedDefs, importErr := ResolveImports(file, path)
+	if importErr != nil {
+		// Errors in this document's own imports are prefixed with its path.
+		msg := strings.TrimPrefix(importErr.Error(), path+":")
+		return []protocol.Diagnostic{parseErrorToDiagnostic(errors.New(msg))}
+	}
+
+	checker, arena, names := s.newDocumentChecker(importedDefs)
 	checker.CheckProgram(file)
 
 	errs := checker.Errors()
@@ -747,36 +1058,61 @@ func (s *lspServer) computeDiagnostics(text string) []protocol.Diagnostic {
 	}
 	diags := make([]protocol.Diagnostic, 0, len(errs))
 	for _, e := range errs {
-		diags = append(diags, typeErrorToDiagnostic(e, arena, names))
+		diags = append(diags, typeErrorToDiagnostic(uri, e, arena, names))
 	}
 	return diags
 }
 
-func typeErrorToDiagnostic(e TypeError, arena *TypeArena, names *NameTable) protocol.Diagnostic {
+// newDocumentChecker builds a checker that knows the standard library's
+// signatures and those of the document's imports.
+func (s *lspServer) newDocumentChecker(importedDefs []MShellDefinition) (*Checker, *TypeArena, *NameTable) {
+	arena := NewTypeArena()
+	names := NewNameTable()
+	checker := NewChecker(arena, names)
+	checker.RegisterStdlibSigs(s.stdlibDefs)
+	checker.RegisterStdlibSigs(importedDefs)
+	return checker, arena, names
+}
+
+func typeErrorToDiagnostic(uri protocol.DocumentURI, e TypeError, arena *TypeArena, names *NameTable) protocol.Diagnostic {
+	severity := protocol.DiagnosticSeverityError
+	if e.Severity == SeverityInfo {
+		severity = protocol.DiagnosticSeverityInformation
+	}
+	var related []protocol.DiagnosticRelatedInformation
+	for _, r := range e.Related {
+		related = append(related, protocol.DiagnosticRelatedInformation{
+			Location: protocol.Location{URI: uri, Range: typeErrorTokenRange(r.Pos)},
+			Message:  r.Message,
+		})
+	}
+	return protocol.Diagnostic{
+		Range:              typeErrorTokenRange(e.Pos),
+		Severity:           severity,
+		Source:             "mshell",
+		Message:            stripErrorPrefix(e.Format(arena, names)),
+		RelatedInformation: related,
+	}
+}
+
+// typeErrorTokenRange covers a type error token's lexeme, or a single
+// character for synthetic tokens without one.
+func typeErrorTokenRange(tok Token) protocol.Range {
 	line := uint32(0)
 	col := uint32(0)
-	if e.Pos.Line > 0 {
-		line = uint32(e.Pos.Line - 1)
+	if tok.Line > 0 {
+		line = uint32(tok.Line - 1)
 	}
-	if e.Pos.Column > 0 {
-		col = uint32(e.Pos.Column - 1)
+	if tok.Column > 0 {
+		col = uint32(tok.Column - 1)
 	}
-	endCol := col + uint32(utf8.RuneCountInString(e.Pos.Lexeme))
+	endCol := col + uint32(utf8.RuneCountInString(tok.Lexeme))
 	if endCol == col {
 		endCol = col + 1
 	}
-	severity := protocol.DiagnosticSeverityError
-	if e.Severity == SeverityInfo {
-		severity = protocol.DiagnosticSeverityInformation
-	}
-	return protocol.Diagnostic{
-		Range: protocol.Range{
-			Start: protocol.Position{Line: line, Character: col},
-			End:   protocol.Position{Line: line, Character: endCol},
-		},
-		Severity: severity,
-		Source:   "mshell",
-		Message:  stripErrorPrefix(e.Format(arena, names)),
+	return protocol.Range{
+		Start: protocol.Position{Line: line, Character: col},
+		End:   protocol.Position{Line: line, Character: endCol},
 	}
 }
 
@@ -1283,15 +1619,21 @@ func (s *lspServer) inFileDefSigs(text string) map[string][]string {
 	if err != nil || file == nil {
 		return nil
 	}
-	if len(file.Definitions) == 0 {
+	return formatDefSigs(file.Definitions)
+}
+
+// formatDefSigs returns a name → formatted signatures map for definitions,
+// or nil when there are none.
+func formatDefSigs(defs []MShellDefinition) map[string][]string {
+	if len(defs) == 0 {
 		return nil
 	}
 	arena := NewTypeArena()
 	names := NewNameTable()
 	checker := NewChecker(arena, names)
-	out := make(map[string][]string, len(file.Definitions))
-	for i := range file.Definitions {
-		def := &file.Definitions[i]
+	out := make(map[string][]string, len(defs))
+	for i := range defs {
+		def := &defs[i]
 		sig := checker.ResolveDefSig(def.Inputs, def.Outputs)
 		out[def.Name] = append(out[def.Name], FormatType(arena, names, arena.MakeQuote(sig)))
 	}
@@ -1445,6 +1787,328 @@ func (s *lspServer) rename(params protocol.RenameParams) (*protocol.WorkspaceEdi
 	return edit, nil
 }
 
+// navTarget is the definition name or variable under the cursor for
+// definition and references requests.
+type navTarget struct {
+	token    Token
+	name     string
+	variable bool
+	scope    []Token // the variable's scope; nil for definition names
+}
+
+// parseDocumentForNav parses an open document. The document's file path is
+// returned too, for resolving its relative imports.
+func (s *lspServer) parseDocumentForNav(uri protocol.DocumentURI) (*MShellFile, string, bool) {
+	doc, ok := s.documents[uri]
+	if !ok {
+		return nil, "", false
+	}
+	docPath := ""
+	if strings.HasPrefix(string(uri), "file://") {
+		docPath = uri.Filename()
+	}
+	file, err := NewMShellParser(NewLexer(doc.Text, nil)).ParseFile()
+	if err != nil {
+		return nil, "", false
+	}
+	return file, docPath, true
+}
+
+// findNavTarget finds the definition name or variable at a position: a
+// 'def' name, a call to a definition, a prefix quote such as 'map.', or an
+// '@name' / 'name!' variable.
+func findNavTarget(file *MShellFile, position protocol.Position) (*navTarget, bool) {
+	line := int(position.Line)
+	character := int(position.Character)
+
+	for _, def := range file.Definitions {
+		if tokenContainsPosition(def.NameToken, line, character) {
+			return &navTarget{token: def.NameToken, name: def.Name}, true
+		}
+	}
+
+	for _, scope := range collectFileScopes(file) {
+		for _, tok := range scope {
+			if !tokenContainsPosition(tok, line, character) {
+				continue
+			}
+			switch tok.Type {
+			case VARSTORE, VARRETRIEVE:
+				name := variableNameFromToken(tok)
+				if name == "" {
+					return nil, false
+				}
+				return &navTarget{token: tok, name: name, variable: true, scope: scope}, true
+			case LITERAL, PREFIXQUOTE:
+				return &navTarget{token: tok, name: definitionNameFromToken(tok)}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// definitionNameFromToken is the definition a token calls: the literal
+// itself, or the name of a prefix quote without its trailing '.'.
+func definitionNameFromToken(tok Token) string {
+	if tok.Type == PREFIXQUOTE {
+		return strings.TrimSuffix(tok.Lexeme, ".")
+	}
+	return tok.Lexeme
+}
+
+// resolveDefinition finds the definition a name calls, and the file it is
+// in, searched in the same order as when the script runs: the standard
+// library, init.msh, the document, then its imports. Definitions imported
+// from a URL are not found, as an editor cannot open them.
+func (s *lspServer) resolveDefinition(name string, uri protocol.DocumentURI, file *MShellFile, docPath string) (protocol.Location, bool) {
+	sources := []struct {
+		uri  protocol.DocumentURI
+		defs []MShellDefinition
+	}{
+		{s.stdlibURI, s.stdlibDefs},
+		{s.initURI, s.initDefs},
+		{uri, file.Definitions},
+	}
+	for _, source := range sources {
+		for _, def := range source.defs {
+			if def.Name == name && source.uri != "" {
+				return protocol.Location{URI: source.uri, Range: tokenEditRange(def.NameToken)}, true
+			}
+		}
+	}
+
+	if len(file.Imports) == 0 {
+		return protocol.Location{}, false
+	}
+	loader, err := loadImports(file, docPath)
+	if err != nil {
+		return protocol.Location{}, false
+	}
+	for i, def := range loader.Definitions {
+		location := loader.Locations[i]
+		if def.Name == name && filepath.IsAbs(location) {
+			return protocol.Location{URI: lspuri.File(location), Range: tokenEditRange(def.NameToken)}, true
+		}
+	}
+	return protocol.Location{}, false
+}
+
+// definition answers textDocument/definition. A variable goes to the
+// nearest store of it before the cursor in its scope, or to the first
+// store when the use comes first, as in a loop.
+func (s *lspServer) definition(params protocol.DefinitionParams) []protocol.Location {
+	uri := params.TextDocument.URI
+	file, docPath, ok := s.parseDocumentForNav(uri)
+	if !ok {
+		return nil
+	}
+	target, ok := findNavTarget(file, params.Position)
+	if !ok {
+		return nil
+	}
+
+	if target.variable {
+		var first, nearest *Token
+		for i, tok := range target.scope {
+			if tok.Type != VARSTORE || variableNameFromToken(tok) != target.name {
+				continue
+			}
+			if first == nil {
+				first = &target.scope[i]
+			}
+			if tok.Start <= target.token.Start {
+				nearest = &target.scope[i]
+			}
+		}
+		if nearest == nil {
+			nearest = first
+		}
+		if nearest == nil {
+			return nil
+		}
+		return []protocol.Location{{URI: uri, Range: tokenEditRange(*nearest)}}
+	}
+
+	location, ok := s.resolveDefinition(target.name, uri, file, docPath)
+	if !ok {
+		return nil
+	}
+	return []protocol.Location{location}
+}
+
+// references answers textDocument/references within the document. For a
+// variable these are its uses in the same scope, with its stores counted
+// as declarations; for a definition, every call and prefix quote of it.
+func (s *lspServer) references(params protocol.ReferenceParams) []protocol.Location {
+	uri := params.TextDocument.URI
+	locations := make([]protocol.Location, 0)
+	file, docPath, ok := s.parseDocumentForNav(uri)
+	if !ok {
+		return locations
+	}
+	target, ok := findNavTarget(file, params.Position)
+	if !ok {
+		return locations
+	}
+	includeDeclaration := params.Context.IncludeDeclaration
+
+	if target.variable {
+		for _, tok := range target.scope {
+			if tok.Type != VARSTORE && tok.Type != VARRETRIEVE {
+				continue
+			}
+			if variableNameFromToken(tok) != target.name {
+				continue
+			}
+			if tok.Type == VARSTORE && !includeDeclaration {
+				continue
+			}
+			locations = append(locations, protocol.Location{URI: uri, Range: tokenEditRange(tok)})
+		}
+		return locations
+	}
+
+	if includeDeclaration {
+		if location, ok := s.resolveDefinition(target.name, uri, file, docPath); ok {
+			locations = append(locations, location)
+		}
+	}
+	for _, scope := range collectFileScopes(file) {
+		for _, tok := range scope {
+			if (tok.Type == LITERAL || tok.Type == PREFIXQUOTE) && definitionNameFromToken(tok) == target.name {
+				locations = append(locations, protocol.Location{URI: uri, Range: tokenEditRange(tok)})
+			}
+		}
+	}
+	sort.SliceStable(locations, func(i, j int) bool {
+		a, b := locations[i], locations[j]
+		if a.URI != b.URI {
+			return a.URI == uri
+		}
+		if a.Range.Start.Line != b.Range.Start.Line {
+			return a.Range.Start.Line < b.Range.Start.Line
+		}
+		return a.Range.Start.Character < b.Range.Start.Character
+	})
+	return locations
+}
+
+// variableSymbols lists the variables stored in a scope, at their first
+// store.
+func variableSymbols(scope []Token) []protocol.DocumentSymbol {
+	symbols := make([]protocol.DocumentSymbol, 0)
+	seen := make(map[string]struct{})
+	for _, tok := range scope {
+		if tok.Type != VARSTORE {
+			continue
+		}
+		name := variableNameFromToken(tok)
+		if _, ok := seen[name]; ok || name == "" {
+			continue
+		}
+		seen[name] = struct{}{}
+		symbols = append(symbols, protocol.DocumentSymbol{
+			Name:           name,
+			Kind:           protocol.SymbolKindVariable,
+			Range:          tokenEditRange(tok),
+			SelectionRange: tokenEditRange(tok),
+		})
+	}
+	return symbols
+}
+
+// documentSymbols answers textDocument/documentSymbol: each definition, with
+// its signature and the variables its body stores, and the variables stored
+// by top-level code, in source order.
+func (s *lspServer) documentSymbols(params protocol.DocumentSymbolParams) []protocol.DocumentSymbol {
+	file, _, ok := s.parseDocumentForNav(params.TextDocument.URI)
+	if !ok {
+		return []protocol.DocumentSymbol{}
+	}
+
+	sigs := formatDefSigs(file.Definitions)
+	symbols := variableSymbols(navScopeTokens(file.Items))
+	for _, def := range file.Definitions {
+		end := tokenEditRange(def.EndToken).End
+		symbol := protocol.DocumentSymbol{
+			Name: def.Name,
+			Kind: protocol.SymbolKindFunction,
+			Range: protocol.Range{
+				Start: tokenEditRange(def.DefToken).Start,
+				End:   end,
+			},
+			SelectionRange: tokenEditRange(def.NameToken),
+			Children:       variableSymbols(navScopeTokens(def.Items)),
+		}
+		if defSigs := sigs[def.Name]; len(defSigs) > 0 {
+			symbol.Detail = defSigs[0]
+		}
+		symbols = append(symbols, symbol)
+	}
+
+	sort.SliceStable(symbols, func(i, j int) bool {
+		a, b := symbols[i].Range.Start, symbols[j].Range.Start
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Character < b.Character
+	})
+	return symbols
+}
+
+// workspaceSymbols answers workspace/symbol with the definitions in the open
+// documents, init.msh, and the standard library whose names contain the
+// query, ignoring case.
+func (s *lspServer) workspaceSymbols(params protocol.WorkspaceSymbolParams) []protocol.SymbolInformation {
+	query := strings.ToLower(params.Query)
+	symbols := make([]protocol.SymbolInformation, 0)
+	add := func(uri protocol.DocumentURI, def MShellDefinition) {
+		if uri == "" || !strings.Contains(strings.ToLower(def.Name), query) {
+			return
+		}
+		symbols = append(symbols, protocol.SymbolInformation{
+			Name:          def.Name,
+			Kind:          protocol.SymbolKindFunction,
+			Location:      protocol.Location{URI: uri, Range: tokenEditRange(def.NameToken)},
+			ContainerName: path.Base(string(uri)),
+		})
+	}
+
+	uris := make([]protocol.DocumentURI, 0, len(s.documents))
+	for uri := range s.documents {
+		uris = append(uris, uri)
+	}
+	sort.Slice(uris, func(i, j int) bool { return uris[i] < uris[j] })
+	for _, uri := range uris {
+		file, err := NewMShellParser(NewLexer(s.documents[uri].Text, nil)).ParseFile()
+		if err != nil {
+			continue
+		}
+		for _, def := range file.Definitions {
+			add(uri, def)
+		}
+	}
+	for _, def := range s.initDefs {
+		add(s.initURI, def)
+	}
+	for _, def := range s.stdlibDefs {
+		add(s.stdlibURI, def)
+	}
+	return symbols
+}
+
+// collectFileScopes returns the tokens of each variable scope in a file:
+// the top-level code first, then each definition body. A definition does
+// not see its caller's variables, so its body is a scope of its own.
+func collectFileScopes(file *MShellFile) [][]Token {
+	scopes := make([][]Token, 0, len(file.Definitions)+1)
+	scopes = append(scopes, navScopeTokens(file.Items))
+	for _, def := range file.Definitions {
+		scopes = append(scopes, navScopeTokens(def.Items))
+	}
+	return scopes
+}
+
 func collectScopeTokens(items []MShellParseItem) []Token {
 	tokens := make([]Token, 0)
 	collectTokensFromItems(&tokens, items)
@@ -1477,6 +2141,61 @@ func collectTokensFromItems(dst *[]Token, items []MShellParseItem) {
 	}
 }
 
+// navScopeTokens collects a scope's tokens for navigation, including those
+// inside if, match, prefix quote, and grid bodies.
+func navScopeTokens(items []MShellParseItem) []Token {
+	tokens := make([]Token, 0)
+	collectNavTokensFromItems(&tokens, items)
+	return tokens
+}
+
+func collectNavTokensFromItems(dst *[]Token, items []MShellParseItem) {
+	for _, item := range items {
+		switch v := item.(type) {
+		case Token:
+			*dst = append(*dst, v)
+		case *MShellParseList:
+			collectNavTokensFromItems(dst, v.Items)
+		case *MShellParseDict:
+			for _, kv := range v.Items {
+				collectNavTokensFromItems(dst, kv.Value)
+			}
+		case *MShellParseQuote:
+			collectNavTokensFromItems(dst, v.Items)
+		case *MShellIndexerList:
+			collectNavTokensFromItems(dst, v.Indexers)
+		case MShellVarstoreList:
+			for _, t := range v.VarStores {
+				*dst = append(*dst, t)
+			}
+		case *MShellParseIfBlock:
+			collectNavTokensFromItems(dst, v.IfBody)
+			for _, elseIf := range v.ElseIfs {
+				collectNavTokensFromItems(dst, elseIf.Condition)
+				collectNavTokensFromItems(dst, elseIf.Body)
+			}
+			collectNavTokensFromItems(dst, v.ElseBody)
+		case *MShellParseMatchBlock:
+			for _, arm := range v.Arms {
+				collectNavTokensFromItems(dst, arm.Pattern)
+				collectNavTokensFromItems(dst, arm.Body)
+			}
+		case *MShellParsePrefixQuote:
+			*dst = append(*dst, v.StartToken)
+			collectNavTokensFromItems(dst, v.Items)
+		case *MShellParseGrid:
+			if v.GridMeta != nil {
+				collectNavTokensFromItems(dst, []MShellParseItem{v.GridMeta})
+			}
+			for _, row := range v.Rows {
+				collectNavTokensFromItems(dst, row)
+			}
+			// Definitions are not descended into; collectFileScopes gives each
+			// body a scope of its own.
+		}
+	}
+}
+
 func tokenContainsPosition(tok Token, line, character int) bool {
 	if tok.Line-1 != line {
 		return false
@@ -12,8 +12,10 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"go.lsp.dev/protocol"
+	lspuri "go.lsp.dev/uri"
 )
 
 func TestHoverRequestForBuiltin(t *testing.T) {
@@ -1655,6 +1657,45 @@ func TestCodeActionUnavailableForUnclosedList(t *testing.T) {
 	}
 }
 
+func TestFormattingReplacesWholeDocument(t *testing.T) {
+	uri := protocol.DocumentURI("file:///format.msh")
+	doc := "def f ( -- )\n'café😀' wl\nend"
+	server := &lspServer{
+		documents: map[protocol.DocumentURI]*lspDocument{
+			uri: {Text: doc},
+		},
+	}
+
+	edits := server.formatting(protocol.DocumentFormattingParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if len(edits) != 1 {
+		t.Fatalf("expected one edit, got %+v", edits)
+	}
+	if edits[0].NewText != "def f ( -- )\n    'café😀' wl\nend\n" {
+		t.Fatalf("unexpected formatted text: %q", edits[0].NewText)
+	}
+	if edits[0].Range.Start != (protocol.Position{}) || edits[0].Range.End != (protocol.Position{Line: 2, Character: 3}) {
+		t.Fatalf("unexpected edit range: %+v", edits[0].Range)
+	}
+}
+
+func TestFormattingLeavesUnparsedDocumentAlone(t *testing.T) {
+	uri := protocol.DocumentURI("file:///format-broken.msh")
+	server := &lspServer{
+		documents: map[protocol.DocumentURI]*lspDocument{
+			uri: {Text: "( [1 2\n"},
+		},
+	}
+
+	edits := server.formatting(protocol.DocumentFormattingParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if len(edits) != 0 {
+		t.Fatalf("expected no edits for a document that does not parse, got %+v", edits)
+	}
+}
+
 func sendLSPMessage(t *testing.T, w io.Writer, payload any) {
 	t.Helper()
 	data, err := json.Marshal(payload)
@@ -1671,6 +1712,22 @@ func sendLSPMessage(t *testing.T, w io.Writer, payload any) {
 }
 
 func readLSPResponse(t *testing.T, reader *bufio.Reader) responseMessage {
+	t.Helper()
+	payload := readLSPPayload(t, reader)
+
+	var resp responseMessage
+	if err := json.Unmarshal(payload, &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	// Skip server-originated notifications (no ID) so tests that
+	// expect a specific response don't trip over diagnostics.
+	if resp.ID == nil {
+		return readLSPResponse(t, reader)
+	}
+	return resp
+}
+
+func readLSPPayload(t *testing.T, reader *bufio.Reader) []byte {
 	t.Helper()
 	headers := make(map[string]string)
 	for {
@@ -1702,15 +1759,314 @@ func readLSPResponse(t *testing.T, reader *bufio.Reader) responseMessage {
 	if _, err := io.ReadFull(reader, payload); err != nil {
 		t.Fatalf("failed to read response payload: %v", err)
 	}
+	return payload
+}
 
-	var resp responseMessage
-	if err := json.Unmarshal(payload, &resp); err != nil {
-		t.Fatalf("failed to unmarshal response: %v", err)
+func navTestServer(t *testing.T, uri protocol.DocumentURI, doc string) *lspServer {
+	t.Helper()
+	stdlib, err := parseMShellInput("def shout (str -- str)\n    toUpper '!' +\nend\n", &TokenFile{"/opt/msh/std.msh"})
+	if err != nil {
+		t.Fatalf("parse stdlib fixture: %v", err)
 	}
-	// Skip server-originated notifications (no ID) so tests that
-	// expect a specific response don't trip over diagnostics.
-	if resp.ID == nil {
-		return readLSPResponse(t, reader)
+	return &lspServer{
+		documents: map[protocol.DocumentURI]*lspDocument{
+			uri: {Text: doc},
+		},
+		stdlibDefs: stdlib.Definitions,
+		stdlibURI:  "file:///opt/msh/std.msh",
+	}
+}
+
+func navPosition(uri protocol.DocumentURI, line, character uint32) protocol.TextDocumentPositionParams {
+	return protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Position:     protocol.Position{Line: line, Character: character},
+	}
+}
+
+func TestDefinitionForVariableFindsNearestStoreInScope(t *testing.T) {
+	uri := protocol.DocumentURI("file:///nav-var.msh")
+	doc := "1 x!\n2 x!\ntrue if\n    @x wl\nend\ndef f (--)\n    3 x! @x wl\nend\n"
+	server := navTestServer(t, uri, doc)
+
+	locations := server.definition(protocol.DefinitionParams{TextDocumentPositionParams: navPosition(uri, 3, 5)})
+	if len(locations) != 1 {
+		t.Fatalf("expected one location, got %+v", locations)
+	}
+	if locations[0].URI != uri || locations[0].Range.Start != (protocol.Position{Line: 1, Character: 2}) {
+		t.Fatalf("expected the store on line 2, got %+v", locations[0])
+	}
+
+	locations = server.definition(protocol.DefinitionParams{TextDocumentPositionParams: navPosition(uri, 6, 10)})
+	if len(locations) != 1 || locations[0].Range.Start != (protocol.Position{Line: 6, Character: 6}) {
+		t.Fatalf("expected the store inside the definition, got %+v", locations)
+	}
+}
+
+func TestDefinitionForDefinitionCalls(t *testing.T) {
+	uri := protocol.DocumentURI("file:///nav-def.msh")
+	doc := "def double (int -- int)\n    2 *\nend\n4 double wl\n'hi' shout wl\n[1 2] map. double end\n"
+	server := navTestServer(t, uri, doc)
+
+	locations := server.definition(protocol.DefinitionParams{TextDocumentPositionParams: navPosition(uri, 3, 4)})
+	if len(locations) != 1 || locations[0].URI != uri || locations[0].Range.Start != (protocol.Position{Line: 0, Character: 4}) {
+		t.Fatalf("expected the in-file definition, got %+v", locations)
+	}
+
+	locations = server.definition(protocol.DefinitionParams{TextDocumentPositionParams: navPosition(uri, 4, 7)})
+	if len(locations) != 1 || locations[0].URI != "file:///opt/msh/std.msh" || locations[0].Range.Start != (protocol.Position{Line: 0, Character: 4}) {
+		t.Fatalf("expected the standard library definition, got %+v", locations)
+	}
+
+	locations = server.definition(protocol.DefinitionParams{TextDocumentPositionParams: navPosition(uri, 5, 11)})
+	if len(locations) != 1 || locations[0].Range.Start != (protocol.Position{Line: 0, Character: 4}) {
+		t.Fatalf("expected the definition called inside the prefix quote, got %+v", locations)
+	}
+
+	if locations := server.definition(protocol.DefinitionParams{TextDocumentPositionParams: navPosition(uri, 3, 10)}); len(locations) != 0 {
+		t.Fatalf("expected no definition for a builtin, got %+v", locations)
+	}
+}
+
+func TestReferencesForVariablesAndDefinitions(t *testing.T) {
+	uri := protocol.DocumentURI("file:///nav-refs.msh")
+	doc := "def double (int -- int)\n    n! @n @n +\nend\n1 n!\n@n double wl\n[1 2] (double) map\n"
+	server := navTestServer(t, uri, doc)
+
+	refs := server.references(protocol.ReferenceParams{TextDocumentPositionParams: navPosition(uri, 1, 8)})
+	if len(refs) != 2 {
+		t.Fatalf("expected two uses of n in the definition, got %+v", refs)
+	}
+	refs = server.references(protocol.ReferenceParams{
+		TextDocumentPositionParams: navPosition(uri, 1, 8),
+		Context:                    protocol.ReferenceContext{IncludeDeclaration: true},
+	})
+	if len(refs) != 3 {
+		t.Fatalf("expected the store and two uses of n, got %+v", refs)
+	}
+
+	refs = server.references(protocol.ReferenceParams{
+		TextDocumentPositionParams: navPosition(uri, 4, 4),
+		Context:                    protocol.ReferenceContext{IncludeDeclaration: true},
+	})
+	want := []protocol.Position{{Line: 0, Character: 4}, {Line: 4, Character: 3}, {Line: 5, Character: 7}}
+	if len(refs) != len(want) {
+		t.Fatalf("expected %d references to double, got %+v", len(want), refs)
+	}
+	for i := range want {
+		if refs[i].Range.Start != want[i] {
+			t.Fatalf("reference %d at %+v, want %+v", i, refs[i].Range.Start, want[i])
+		}
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	uri := protocol.DocumentURI("file:///nav-symbols.msh")
+	doc := "1 total!\ndef double (int -- int)\n    n! @n 2 *\nend\n@total double wl\n"
+	server := navTestServer(t, uri, doc)
+
+	symbols := server.documentSymbols(protocol.DocumentSymbolParams{TextDocument: protocol.TextDocumentIdentifier{URI: uri}})
+	if len(symbols) != 2 {
+		t.Fatalf("expected two symbols, got %+v", symbols)
+	}
+	if symbols[0].Name != "total" || symbols[0].Kind != protocol.SymbolKindVariable {
+		t.Fatalf("unexpected first symbol: %+v", symbols[0])
+	}
+	def := symbols[1]
+	if def.Name != "double" || def.Kind != protocol.SymbolKindFunction || def.Detail != "(int -- int)" {
+		t.Fatalf("unexpected definition symbol: %+v", def)
+	}
+	if def.Range.Start != (protocol.Position{Line: 1, Character: 0}) || def.Range.End != (protocol.Position{Line: 3, Character: 3}) {
+		t.Fatalf("unexpected definition range: %+v", def.Range)
+	}
+	if len(def.Children) != 1 || def.Children[0].Name != "n" {
+		t.Fatalf("expected the definition's variable as a child, got %+v", def.Children)
+	}
+}
+
+func TestWorkspaceSymbolsFilterByQuery(t *testing.T) {
+	uri := protocol.DocumentURI("file:///nav-workspace.msh")
+	server := navTestServer(t, uri, "def shoutTwice (str -- str) shout shout end\ndef other (--) end\n")
+
+	symbols := server.workspaceSymbols(protocol.WorkspaceSymbolParams{Query: "SHOUT"})
+	if len(symbols) != 2 {
+		t.Fatalf("expected two symbols, got %+v", symbols)
+	}
+	if symbols[0].Name != "shoutTwice" || symbols[0].Location.URI != uri {
+		t.Fatalf("unexpected document symbol: %+v", symbols[0])
+	}
+	if symbols[1].Name != "shout" || symbols[1].Location.URI != "file:///opt/msh/std.msh" || symbols[1].ContainerName != "std.msh" {
+		t.Fatalf("unexpected standard library symbol: %+v", symbols[1])
+	}
+}
+
+func TestDefinitionForImportedDefinition(t *testing.T) {
+	dir := t.TempDir()
+	hash := writeModule(t, dir, "text.msh", "def shout2 (str -- str)\n    '!' +\nend\n")
+	docPath := filepath.Join(dir, "main.msh")
+	uri := lspuri.File(docPath)
+	doc := "import \"text.msh\" sha256:" + hash + " as text\n'hi' text.shout2 wl\n"
+	server := navTestServer(t, uri, doc)
+
+	locations := server.definition(protocol.DefinitionParams{TextDocumentPositionParams: navPosition(uri, 1, 7)})
+	if len(locations) != 1 {
+		t.Fatalf("expected one location, got %+v", locations)
+	}
+	if locations[0].URI != lspuri.File(filepath.Join(dir, "text.msh")) || locations[0].Range.Start != (protocol.Position{Line: 0, Character: 4}) {
+		t.Fatalf("expected the definition in the imported module, got %+v", locations[0])
+	}
+}
+
+func TestDiagnosticsBranchMismatchRelatedInformation(t *testing.T) {
+	uri := protocol.DocumentURI("file:///branches.msh")
+	doc := "def f (bool -- int)\n    if\n        1\n    else\n        1 2\n    end\nend\n"
+	server := &lspServer{documents: map[protocol.DocumentURI]*lspDocument{}}
+
+	diags := server.computeDiagnostics(uri, doc)
+	var branchDiag *protocol.Diagnostic
+	for i := range diags {
+		if strings.Contains(diags[i].Message, "same number of stack items") {
+			branchDiag = &diags[i]
+		}
+	}
+	if branchDiag == nil {
+		t.Fatalf("expected a branch stack size diagnostic, got %+v", diags)
+	}
+	if branchDiag.Range.Start.Line != 1 {
+		t.Fatalf("expected the diagnostic on the if line, got %+v", branchDiag.Range)
+	}
+	related := branchDiag.RelatedInformation
+	if len(related) != 2 {
+		t.Fatalf("expected related information for both arms, got %+v", related)
+	}
+	if related[0].Location.URI != uri || related[0].Location.Range.Start.Line != 2 || related[0].Message != "Branch 1 (if) leaves 1 stack item" {
+		t.Fatalf("unexpected if-arm related information: %+v", related[0])
+	}
+	if related[1].Location.Range.Start.Line != 4 || related[1].Message != "Branch 2 (else) leaves 2 stack items" {
+		t.Fatalf("unexpected else-arm related information: %+v", related[1])
+	}
+}
+
+func TestDiagnosticsQuoteArmRelatedInformation(t *testing.T) {
+	uri := protocol.DocumentURI("file:///quote-arms.msh")
+	server := &lspServer{documents: map[protocol.DocumentURI]*lspDocument{}}
+	tests := []struct {
+		name  string
+		doc   string
+		lines []uint32
+		want  []string
+	}{
+		{"iff", "true\n(1)\n(1 2)\niff\n", []uint32{1, 2}, []string{"Branch 1 (true) leaves 1 stack item", "Branch 2 (false) leaves 2 stack items"}},
+		{"try", "(1)\n(drop 1 2)\ntry\n", []uint32{0, 1}, []string{"Branch 1 (try) leaves 1 stack item", "Branch 2 (handler) leaves 2 stack items"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var related []protocol.DiagnosticRelatedInformation
+			for _, diag := range server.computeDiagnostics(uri, tt.doc) {
+				if strings.Contains(diag.Message, "same number of stack items") {
+					related = diag.RelatedInformation
+				}
+			}
+			if len(related) != 2 {
+				t.Fatalf("expected related information for both arms, got %+v", related)
+			}
+			for i, want := range tt.want {
+				if related[i].Location.Range.Start.Line != tt.lines[i] || related[i].Message != want {
+					t.Fatalf("arm %d: want %q on line %d, got %+v", i+1, want, tt.lines[i], related[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDiagnosticsUseStdlibSignatures(t *testing.T) {
+	uri := protocol.DocumentURI("file:///uses-std.msh")
+	server := navTestServer(t, uri, "")
+
+	if diags := server.computeDiagnostics(uri, "'hi' shout wl\n"); len(diags) != 0 {
+		t.Fatalf("expected no diagnostics for a well-typed stdlib call, got %+v", diags)
+	}
+	diags := server.computeDiagnostics(uri, "1 shout wl\n")
+	if len(diags) == 0 {
+		t.Fatal("expected a type error for passing an int to a str stdlib def")
+	}
+}
+
+func TestDiagnosticsDebounceEdits(t *testing.T) {
+	uri := protocol.DocumentURI("file:///debounce.msh")
+	reader, writer := io.Pipe()
+	server := &lspServer{
+		out:       bufio.NewWriter(writer),
+		documents: make(map[protocol.DocumentURI]*lspDocument),
+		diagDelay: 20 * time.Millisecond,
+	}
+	defer server.stopDiagnostics()
+
+	// Only the last edit's text should be checked and published.
+	server.updateDocument(uri, "[1 2\n")
+	server.updateDocument(uri, "[1 2] (\n")
+	server.updateDocument(uri, "1 'a' +\n")
+
+	var note struct {
+		Method string                            `json:"method"`
+		Params protocol.PublishDiagnosticsParams `json:"params"`
+	}
+	if err := json.Unmarshal(readLSPPayload(t, bufio.NewReader(reader)), &note); err != nil {
+		t.Fatalf("failed to unmarshal notification: %v", err)
+	}
+	if note.Method != "textDocument/publishDiagnostics" || note.Params.URI != uri {
+		t.Fatalf("unexpected notification: %+v", note)
+	}
+	if len(note.Params.Diagnostics) != 1 {
+		t.Fatalf("expected one diagnostic, got %+v", note.Params.Diagnostics)
+	}
+	if !strings.Contains(note.Params.Diagnostics[0].Message, "+") {
+		t.Fatalf("expected the type error from the last edit, got %q", note.Params.Diagnostics[0].Message)
+	}
+}
+
+func TestInlayHintsShowLineStacksAndQuoteSignatures(t *testing.T) {
+	uri := protocol.DocumentURI("file:///hints.msh")
+	doc := "def double (int -- int)\n    2 *\nend\n\n1 'a'\n[1 2] (double) map\n@x if\n    len\nend\n"
+	server := &lspServer{documents: map[protocol.DocumentURI]*lspDocument{uri: {Text: doc}}}
+
+	hints := server.inlayHints(inlayHintParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Range:        protocol.Range{End: protocol.Position{Line: 9}},
+	})
+	var got []string
+	for _, h := range hints {
+		got = append(got, fmt.Sprintf("%d:%d %s", h.Position.Line, h.Position.Character, h.Label))
+	}
+	// The check stops at the unknown @x, so the if block gets no hints.
+	want := []string{
+		"1:7 ( -- int )",
+		"4:5 ( -- int str )",
+		"5:14 (int -- int)",
+		"5:18 ( -- int str [int] )",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected hints:\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	ranged := server.inlayHints(inlayHintParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Range:        protocol.Range{Start: protocol.Position{Line: 4}, End: protocol.Position{Line: 4}},
+	})
+	if len(ranged) != 1 || ranged[0].Label != "( -- int str )" {
+		t.Fatalf("expected only the line 5 hint, got %+v", ranged)
+	}
+}
+
+func TestInlayHintsSkipUnparsedDocument(t *testing.T) {
+	uri := protocol.DocumentURI("file:///broken.msh")
+	server := &lspServer{documents: map[protocol.DocumentURI]*lspDocument{uri: {Text: "[1 2\n"}}}
+
+	hints := server.inlayHints(inlayHintParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Range:        protocol.Range{End: protocol.Position{Line: 1}},
+	})
+	if hints == nil || len(hints) != 0 {
+		t.Fatalf("expected an empty hint list, got %+v", hints)
 	}
-	return resp
 }